@@ -0,0 +1,42 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// cpuSetSize is the size of cpu_set_t in glibc, which supports 1024 cpus
+const cpuSetSize = 1024 / 64
+
+// SetAffinity binds the thread or process to the cores by sched_setaffinity, the pid 0 means the calling thread
+func SetAffinity(pid int, cores []int) error {
+	var mask [cpuSetSize]uint64
+	for _, core := range cores {
+		if core < 0 || core >= cpuSetSize*64 {
+			return fmt.Errorf("illegal core %d", core)
+		}
+		mask[core/64] |= 1 << uint(core%64)
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, uintptr(pid), unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return fmt.Errorf("bind %d to the cores %v failed, %v", pid, cores, errno)
+	}
+	return nil
+}
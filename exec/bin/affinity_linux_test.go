@@ -0,0 +1,52 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_SetAffinity(t *testing.T) {
+	// the thread is terminated with the locked goroutine, so the affinity is not leaked to other tests
+	runtime.LockOSThread()
+
+	if err := SetAffinity(0, []int{0}); err != nil {
+		t.Fatalf("set affinity err, %v", err)
+	}
+	bytes, err := ioutil.ReadFile("/proc/thread-self/status")
+	if err != nil {
+		t.Skipf("read thread status err, %v", err)
+	}
+	for _, line := range strings.Split(string(bytes), "\n") {
+		if strings.HasPrefix(line, "Cpus_allowed_list:") {
+			if list := strings.TrimSpace(strings.TrimPrefix(line, "Cpus_allowed_list:")); list != "0" {
+				t.Errorf("unexpected cpus allowed list: %s, expected: 0", list)
+			}
+			return
+		}
+	}
+	t.Errorf("Cpus_allowed_list not found in the thread status")
+}
+
+func Test_SetAffinity_failed(t *testing.T) {
+	if err := SetAffinity(0, []int{-1}); err == nil {
+		t.Errorf("expected err for the illegal core")
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/sirupsen/logrus"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var hogDestinationIp, hogProtocol string
+var hogDestinationPort, hogRate, hogParallel, hogPacketSize int
+var hogStart, hogStop, hogNohup bool
+
+func main() {
+	flag.StringVar(&hogDestinationIp, "destination-ip", "", "the ip of the target")
+	flag.IntVar(&hogDestinationPort, "destination-port", 5001, "the port of the target")
+	flag.StringVar(&hogProtocol, "protocol", "udp", "the protocol of the traffic, udp or tcp")
+	flag.IntVar(&hogRate, "rate", 0, "total sending rate, unit is Mbit/s, 0 means unlimited")
+	flag.IntVar(&hogParallel, "parallel", 1, "the number of parallel flows")
+	flag.IntVar(&hogPacketSize, "packet-size", 1400, "the size of each packet, unit is byte")
+	flag.BoolVar(&hogStart, "start", false, "start bandwidth hog")
+	flag.BoolVar(&hogStop, "stop", false, "stop bandwidth hog")
+	flag.BoolVar(&hogNohup, "nohup", false, "nohup to run bandwidth hog")
+	bin.ParseFlagAndInitLog()
+
+	if hogDestinationIp == "" {
+		bin.PrintErrAndExit("less --destination-ip flag")
+	}
+	if hogStart {
+		startHog(hogDestinationIp, hogDestinationPort, hogProtocol, hogRate, hogParallel, hogPacketSize)
+	} else if hogStop {
+		stopHog(hogDestinationIp)
+	} else if hogNohup {
+		address := net.JoinHostPort(hogDestinationIp, strconv.Itoa(hogDestinationPort))
+		if _, err := runHog(context.Background(), hogProtocol, address, int64(hogRate)*1000*1000,
+			hogParallel, hogPacketSize); err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+		}
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+var cl = channel.NewLocalChannel()
+
+var bandwidthHogBin = exec.BandwidthHogBin
+
+var hogLogFile = util.GetNohupOutput(util.Bin, "chaos_bandwidthhog.log")
+
+func startHog(destinationIp string, destinationPort int, protocol string, rate, parallel, packetSize int) {
+	ctx := context.Background()
+	response := cl.Run(ctx, "nohup",
+		fmt.Sprintf("%s --destination-ip %s --nohup=true --destination-port %d --protocol %s --rate %d --parallel %d --packet-size %d > %s 2>&1 &",
+			path.Join(util.GetProgramPath(), bandwidthHogBin), destinationIp, destinationPort, protocol, rate, parallel, packetSize, hogLogFile))
+	if !response.Success {
+		bin.PrintErrAndExit(response.Err)
+		return
+	}
+	// check
+	time.Sleep(time.Second)
+	response = cl.Run(ctx, "grep", fmt.Sprintf("%s %s", bin.ErrPrefix, hogLogFile))
+	if response.Success {
+		errMsg := strings.TrimSpace(response.Result.(string))
+		if errMsg != "" {
+			stopHog(destinationIp)
+			bin.PrintErrAndExit(errMsg)
+			return
+		}
+	}
+	bin.PrintOutputAndExit("success")
+}
+
+func stopHog(destinationIp string) {
+	ctx := context.WithValue(context.Background(), channel.ProcessKey, bandwidthHogBin)
+	pids, err := cl.GetPidsByProcessName(fmt.Sprintf("destination-ip %s --nohup", destinationIp), ctx)
+	if err != nil {
+		logrus.Warnf("get %s pid failed, %v", bandwidthHogBin, err)
+	}
+	if len(pids) > 0 {
+		cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
+	}
+	cl.Run(ctx, "rm", fmt.Sprintf("-rf %s*", hogLogFile))
+}
+
+// runHog starts parallel flows to the address until the context is done, and returns the total bytes sent.
+// The rate is the total sending rate in bits per second, 0 means unlimited.
+func runHog(ctx context.Context, protocol, address string, rate int64, parallel, packetSize int) (int64, error) {
+	if parallel <= 0 {
+		parallel = 1
+	}
+	var sent int64
+	var wg sync.WaitGroup
+	errs := make(chan error, parallel)
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := flood(ctx, protocol, address, rate/int64(parallel), packetSize, &sent); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	return atomic.LoadInt64(&sent), <-errs
+}
+
+// flood sends packets of packetSize to the address, and sleeps when the rate is exceeded
+func flood(ctx context.Context, protocol, address string, rate int64, packetSize int, sent *int64) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, protocol, address)
+	if err != nil {
+		return fmt.Errorf("connect to %s by %s failed, %v", address, protocol, err)
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	buf := make([]byte, packetSize)
+	start := time.Now()
+	var total int64
+	for ctx.Err() == nil {
+		n, err := conn.Write(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if protocol == "udp" {
+				// the icmp port unreachable of the last packet fails the write, the traffic is still sent
+				if errors.Is(err, syscall.ECONNREFUSED) {
+					continue
+				}
+				// the send queue of the interface is full, wait for it to drain
+				if errors.Is(err, syscall.ENOBUFS) {
+					select {
+					case <-time.After(time.Millisecond):
+					case <-ctx.Done():
+					}
+					continue
+				}
+			}
+			return fmt.Errorf("write to %s failed, %v", address, err)
+		}
+		total += int64(n)
+		atomic.AddInt64(sent, int64(n))
+		if rate <= 0 {
+			continue
+		}
+		expected := time.Duration(float64(total*8) / float64(rate) * float64(time.Second))
+		if ahead := expected - time.Since(start); ahead > time.Millisecond {
+			select {
+			case <-time.After(ahead):
+			case <-ctx.Done():
+			}
+		}
+	}
+	return nil
+}
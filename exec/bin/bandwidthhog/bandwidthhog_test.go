@@ -0,0 +1,219 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	osexec "os/exec"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+const (
+	testNetns    = "chaos-bwhog-test"
+	testHostIp   = "10.211.0.1"
+	testTargetIp = "10.211.0.2"
+	sinkEnv      = "CHAOS_BWHOG_SINK"
+	sinkAddrEnv  = "CHAOS_BWHOG_SINK_ADDR"
+)
+
+// TestHelperSink is not a real test, it's the sink process started in the netns by Test_runHog_netns.
+// It prints ready after listening, and prints the received bytes after the stdin is closed.
+func TestHelperSink(t *testing.T) {
+	protocol := os.Getenv(sinkEnv)
+	if protocol == "" {
+		return
+	}
+	address := os.Getenv(sinkAddrEnv)
+	var received int64
+	if protocol == "udp" {
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			fmt.Printf("listen err, %v\n", err)
+			os.Exit(1)
+		}
+		go func() {
+			buf := make([]byte, 65535)
+			for {
+				n, _, err := conn.ReadFrom(buf)
+				if err != nil {
+					return
+				}
+				atomic.AddInt64(&received, int64(n))
+			}
+		}()
+	} else {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			fmt.Printf("listen err, %v\n", err)
+			os.Exit(1)
+		}
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					n, _ := io.Copy(ioutil.Discard, conn)
+					atomic.AddInt64(&received, n)
+				}()
+			}
+		}()
+	}
+	fmt.Println("ready")
+	io.Copy(ioutil.Discard, os.Stdin)
+	time.Sleep(200 * time.Millisecond)
+	fmt.Println(atomic.LoadInt64(&received))
+	os.Exit(0)
+}
+
+func runCommand(t *testing.T, name string, args ...string) {
+	if output, err := osexec.Command(name, args...).CombinedOutput(); err != nil {
+		t.Fatalf("%s %s failed, %v, %s", name, strings.Join(args, " "), err, output)
+	}
+}
+
+// setupNetns creates a netns which contains the target ip and connects it to the host by a veth pair
+func setupNetns(t *testing.T) func() {
+	if os.Geteuid() != 0 {
+		t.Skip("must be root to create netns")
+	}
+	if _, err := osexec.LookPath("ip"); err != nil {
+		t.Skip("ip command not found")
+	}
+	osexec.Command("ip", "netns", "del", testNetns).Run()
+	runCommand(t, "ip", "netns", "add", testNetns)
+	cleanup := func() {
+		osexec.Command("ip", "netns", "del", testNetns).Run()
+	}
+	runCommand(t, "ip", "link", "add", "bwhog0", "type", "veth", "peer", "name", "bwhog1")
+	runCommand(t, "ip", "link", "set", "bwhog1", "netns", testNetns)
+	runCommand(t, "ip", "addr", "add", testHostIp+"/24", "dev", "bwhog0")
+	runCommand(t, "ip", "link", "set", "bwhog0", "up")
+	runCommand(t, "ip", "netns", "exec", testNetns, "ip", "addr", "add", testTargetIp+"/24", "dev", "bwhog1")
+	runCommand(t, "ip", "netns", "exec", testNetns, "ip", "link", "set", "bwhog1", "up")
+	runCommand(t, "ip", "netns", "exec", testNetns, "ip", "link", "set", "lo", "up")
+	return cleanup
+}
+
+func Test_runHog_netns(t *testing.T) {
+	defer setupNetns(t)()
+	tests := []struct {
+		protocol string
+		rate     int64
+		parallel int
+	}{
+		{"udp", 16 * 1000 * 1000, 2},
+		{"tcp", 16 * 1000 * 1000, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			address := net.JoinHostPort(testTargetIp, "5001")
+			sink := osexec.Command("ip", "netns", "exec", testNetns, os.Args[0], "-test.run=^TestHelperSink$")
+			sink.Env = append(os.Environ(), sinkEnv+"="+tt.protocol, sinkAddrEnv+"="+address)
+			stdin, _ := sink.StdinPipe()
+			stdout, _ := sink.StdoutPipe()
+			if err := sink.Start(); err != nil {
+				t.Fatalf("start sink failed, %v", err)
+			}
+			defer sink.Process.Kill()
+			reader := bufio.NewReader(stdout)
+			if line, _ := reader.ReadString('\n'); strings.TrimSpace(line) != "ready" {
+				t.Fatalf("sink is not ready, %s", line)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			sent, err := runHog(ctx, tt.protocol, address, tt.rate, tt.parallel, 1400)
+			if err != nil {
+				t.Fatalf("run hog failed, %v", err)
+			}
+			stdin.Close()
+			line, _ := reader.ReadString('\n')
+			received, err := strconv.ParseInt(strings.TrimSpace(line), 10, 64)
+			if err != nil {
+				t.Fatalf("parse received bytes failed, %v, %s", err, line)
+			}
+			sink.Wait()
+
+			// 16Mbit/s for 1 second is 2MB
+			expected := tt.rate / 8
+			if sent < expected/2 || sent > expected*3/2 {
+				t.Errorf("unexpected sent bytes: %d, expected about %d", sent, expected)
+			}
+			if received == 0 || received > sent {
+				t.Errorf("unexpected received bytes: %d, sent bytes: %d", received, sent)
+			}
+		})
+	}
+}
+
+func Test_runHog_unreachable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	// no listener on the port, the tcp flow can't be established
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	address := listener.Addr().String()
+	listener.Close()
+	if _, err := runHog(ctx, "tcp", address, 0, 1, 1400); err == nil {
+		t.Errorf("expected err for unreachable tcp target")
+	}
+	// the refused udp writes are tolerated, but the other errors stop the flow
+	if sent, err := runHog(ctx, "udp", address, 0, 1, 1400); err != nil || sent == 0 {
+		t.Errorf("unexpected result for unreachable udp target: %d, %v", sent, err)
+	}
+	if _, err := runHog(context.Background(), "udp", address, 0, 1, 70000); err == nil {
+		t.Errorf("expected err for the udp packet larger than 64K")
+	}
+}
+
+func Test_stopHog(t *testing.T) {
+	var exitCode int
+	bin.ExitFunc = func(code int) {
+		exitCode = code
+	}
+	cl = channel.NewMockLocalChannel()
+	mockChannel := cl.(*channel.MockLocalChannel)
+	actualCommands := make([]string, 0)
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		actualCommands = append(actualCommands, fmt.Sprintf("%s %s", script, args))
+		return spec.ReturnSuccess("")
+	}
+	stopHog(testTargetIp)
+	if exitCode != 0 {
+		t.Errorf("unexpected result: %d, expected result: %d", exitCode, 0)
+	}
+	expectedCommand := fmt.Sprintf("rm -rf %s*", hogLogFile)
+	if len(actualCommands) == 0 || actualCommands[len(actualCommands)-1] != expectedCommand {
+		t.Errorf("unexpected commands: %+v, expected the last command: %s", actualCommands, expectedCommand)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package bintest contains the helpers shared by the tests of the chaos programs, which fake the proc and sys trees
+package bintest
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+// NewRoot creates the temp directory of the fake trees, the returned function removes it
+func NewRoot(t *testing.T) (string, func()) {
+	root, err := ioutil.TempDir("", "chaos-bintest")
+	if err != nil {
+		t.Fatalf("create temp dir err, %v", err)
+	}
+	return root, func() {
+		os.RemoveAll(root)
+	}
+}
+
+// WriteFiles writes the files relative to the root, the parent directories are created
+func WriteFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		file := path.Join(root, name)
+		if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+			t.Fatalf("create dir err, %v", err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("write file err, %v", err)
+		}
+	}
+}
+
+// Replace sets the path variable, such as bin.ProcPath, to the value and returns the function restoring it
+func Replace(variable *string, value string) func() {
+	origin := *variable
+	*variable = value
+	return func() {
+		*variable = origin
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SysPath is the mount point of sysfs, it is replaced by the fake sys tree in tests
+var SysPath = "/sys"
+
+// Mount is a line of the mountinfo
+type Mount struct {
+	// ID is the unique id of the mount, which is the mnt_id in the fdinfo of the files opened on it
+	ID string
+	// Device is the major:minor of the filesystem, the major is 0 for the filesystems without a block device
+	Device     string
+	Root       string
+	MountPoint string
+	Options    string
+	FsType     string
+	Source     string
+}
+
+// GetMounts returns the mounts of the current process in the order of the mountinfo
+func GetMounts() ([]*Mount, error) {
+	file, err := os.Open(path.Join(ProcPath, "self/mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	mounts := make([]*Mount, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 28 1 254:0 / / rw,relatime - ext4 /dev/vda rw,discard
+		fields := strings.Fields(scanner.Text())
+		separator := -1
+		for i, field := range fields {
+			if field == "-" {
+				separator = i
+				break
+			}
+		}
+		if separator < 6 || len(fields) < separator+3 {
+			continue
+		}
+		mounts = append(mounts, &Mount{
+			ID:         fields[0],
+			Device:     fields[2],
+			Root:       fields[3],
+			MountPoint: unescapeMountPath(fields[4]),
+			Options:    fields[5],
+			FsType:     fields[separator+1],
+			Source:     unescapeMountPath(fields[separator+2]),
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPath restores the space, tab, newline and backslash escaped as octal in the mountinfo
+func unescapeMountPath(p string) string {
+	return strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`).Replace(p)
+}
+
+// GetMountByPath returns the mount which the path belongs to, it's the last mounted one if the mount points overlap
+func GetMountByPath(p string) (*Mount, error) {
+	absPath, err := filepath.Abs(p)
+	if err != nil {
+		return nil, err
+	}
+	if realPath, err := filepath.EvalSymlinks(absPath); err == nil {
+		absPath = realPath
+	}
+	mounts, err := GetMounts()
+	if err != nil {
+		return nil, fmt.Errorf("get mounts failed, %v", err)
+	}
+	var found *Mount
+	for _, mount := range mounts {
+		if !isSubPath(mount.MountPoint, absPath) {
+			continue
+		}
+		if found == nil || len(mount.MountPoint) >= len(found.MountPoint) {
+			found = mount
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("mount point of %s not found", p)
+	}
+	return found, nil
+}
+
+// GetBlockDevice returns the major:minor of the whole disk backing the path, the partition is resolved to its disk
+// because the io throttling only works on the disks
+func GetBlockDevice(p string) (string, error) {
+	device, err := GetMountDevice(p)
+	if err != nil {
+		return "", err
+	}
+	return getWholeDisk(device)
+}
+
+// GetMountDevice returns the major:minor of the block device of the filesystem which the path belongs to
+func GetMountDevice(p string) (string, error) {
+	mount, err := GetMountByPath(p)
+	if err != nil {
+		return "", err
+	}
+	device := mount.Device
+	if strings.HasPrefix(device, "0:") {
+		// btrfs and the other filesystems with anonymous devices, the device is found by the source
+		if !filepath.IsAbs(mount.Source) {
+			return "", fmt.Errorf("%s is on the %s filesystem %s, which has no block device", p, mount.FsType, mount.Source)
+		}
+		source, err := filepath.EvalSymlinks(mount.Source)
+		if err != nil {
+			return "", fmt.Errorf("get the block device %s failed, %v", mount.Source, err)
+		}
+		bytes, err := ioutil.ReadFile(path.Join(SysPath, "class/block", path.Base(source), "dev"))
+		if err != nil {
+			return "", fmt.Errorf("get the device number of %s failed, %v", source, err)
+		}
+		device = strings.TrimSpace(string(bytes))
+	}
+	return device, nil
+}
+
+// GetDeviceMapperName returns the name of the device-mapper device, an error is returned if it's not a dm device
+func GetDeviceMapperName(device string) (string, error) {
+	bytes, err := ioutil.ReadFile(path.Join(SysPath, "dev/block", device, "dm/name"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("the block device %s is not a device-mapper device", device)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(bytes)), nil
+}
+
+// getWholeDisk returns the disk of the partition, or the device itself if it's not a partition
+func getWholeDisk(device string) (string, error) {
+	devicePath := path.Join(SysPath, "dev/block", device)
+	if _, err := os.Stat(path.Join(devicePath, "partition")); err != nil {
+		if _, err := os.Stat(devicePath); err != nil {
+			return "", fmt.Errorf("block device %s not found, %v", device, err)
+		}
+		return device, nil
+	}
+	// /sys/dev/block/8:1 -> ../../devices/pci0000:00/.../block/sda/sda1
+	realPath, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		return "", err
+	}
+	bytes, err := ioutil.ReadFile(path.Join(filepath.Dir(realPath), "dev"))
+	if err != nil {
+		return "", fmt.Errorf("get the disk of the partition %s failed, %v", device, err)
+	}
+	return strings.TrimSpace(string(bytes)), nil
+}
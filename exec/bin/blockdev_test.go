@@ -0,0 +1,70 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
+)
+
+func Test_GetBlockDevice(t *testing.T) {
+	root, clean := bintest.NewRoot(t)
+	defer clean()
+	mountinfo := `20 1 0:50 / / rw,relatime - overlay overlay rw,lowerdir=/l
+21 20 8:1 / /data rw,relatime - ext4 /dev/sda1 rw
+22 21 0:45 / /data/btr rw,relatime - btrfs ` + root + `/dev/dm-0 rw
+23 20 253:0 / /mnt/my\040disk rw,relatime - xfs /dev/dm-0 rw
+`
+	bintest.WriteFiles(t, root, map[string]string{
+		"proc/self/mountinfo":                      mountinfo,
+		"dev/dm-0":                                 "",
+		"sys/class/block/dm-0/dev":                 "253:0\n",
+		"sys/devices/virtual/block/dm-0/dev":       "253:0\n",
+		"sys/devices/pci/block/sda/dev":            "8:0\n",
+		"sys/devices/pci/block/sda/sda1/dev":       "8:1\n",
+		"sys/devices/pci/block/sda/sda1/partition": "1\n",
+	})
+	os.MkdirAll(path.Join(root, "sys/dev/block"), 0755)
+	os.Symlink("../../devices/pci/block/sda/sda1", path.Join(root, "sys/dev/block/8:1"))
+	os.Symlink("../../devices/virtual/block/dm-0", path.Join(root, "sys/dev/block/253:0"))
+	defer bintest.Replace(&ProcPath, path.Join(root, "proc"))()
+	defer bintest.Replace(&SysPath, path.Join(root, "sys"))()
+
+	tests := []struct {
+		path     string
+		expected string
+		wantErr  bool
+	}{
+		{path: "/data/app/log", expected: "8:0"},
+		{path: "/data/btr/db", expected: "253:0"},
+		{path: "/mnt/my disk", expected: "253:0"},
+		{path: "/home", wantErr: true},
+	}
+	for _, tt := range tests {
+		device, err := GetBlockDevice(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("GetBlockDevice(%s) err = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if device != tt.expected {
+			t.Errorf("GetBlockDevice(%s) = %s, expected %s", tt.path, device, tt.expected)
+		}
+	}
+}
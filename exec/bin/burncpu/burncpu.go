@@ -18,11 +18,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	cpu_percent "github.com/kinwe/kinwe-cpu-percent"
 	"github.com/shirou/gopsutil/cpu"
+	"io/ioutil"
 	"log"
+	"math"
 	"os"
 	"path"
 	"runtime"
@@ -45,7 +48,10 @@ var (
 	cpuCount, cpuPercent, climbTime                   int
 	slopePercent                                      float64
 	cpuList                                           string
-	cpuProcessor                                      string
+	strategy                                          string
+	cpuProfile                                        string
+	cgroupPath                                        string
+	targetPid                                         int
 )
 
 var logger *log.Logger
@@ -70,8 +76,11 @@ func main() {
 	flag.IntVar(&climbTime, "climb-time", 0, "durations(s) to climb")
 	flag.IntVar(&cpuCount, "cpu-count", cpu_percent.CPUNum(), "number of cpus")
 	flag.IntVar(&cpuPercent, "cpu-percent", 100, "percent of burn-cpu")
-	flag.StringVar(&cpuProcessor, "cpu-processor", "0", "only used for identifying process of cpu burn")
-	flag.BoolVar(&absolute, "absolute", false, "run burn cpu absolute")
+	flag.BoolVar(&absolute, "absolute", false, "run burn cpu absolute, the same as --strategy absolute")
+	flag.StringVar(&strategy, "strategy", exec.FeedbackStrategy, "the strategy of burning cpu, feedback, cgroup-quota or absolute")
+	flag.StringVar(&cpuProfile, "profile", "", "the load profile over time")
+	flag.StringVar(&cgroupPath, "cgroup-path", "", "the cpu cgroup to burn cpu in")
+	flag.IntVar(&targetPid, "target-pid", 0, "burn cpu in the cpu cgroup of the process")
 
 	bin.ParseFlagAndInitLog()
 	if cpuCount <= 0 || cpuCount > cpu_percent.CPUNum() {
@@ -79,9 +88,18 @@ func main() {
 	}
 
 	if absolute {
+		strategy = exec.AbsoluteStrategy
+	}
+	switch strategy {
+	case exec.AbsoluteStrategy:
+		absolute = true
 		burnCpu = burnCpuOn
-	} else {
+	case exec.CgroupQuotaStrategy:
+		burnCpu = burnCpuByQuota
+	case exec.FeedbackStrategy:
 		burnCpu = burnCpuIn
+	default:
+		bin.PrintErrAndExit(fmt.Sprintf("illegal strategy %s, it must be feedback, cgroup-quota or absolute", strategy))
 	}
 
 	if burnCpuStart {
@@ -91,6 +109,10 @@ func main() {
 			bin.PrintErrAndExit(errs)
 		}
 	} else if burnCpuNohup {
+		joinCgroup()
+		if cpuList != "" && strategy != exec.CgroupQuotaStrategy {
+			burnCores(parseCores(cpuList))
+		}
 		burnCpu()
 	} else {
 		bin.PrintErrAndExit("less --start or --stop flag")
@@ -110,18 +132,32 @@ func burnCpuIn() {
 		return cpu_percent.Percent(time.Second)
 	}
 
-	totalCpuPercent, err = percent()
-
+	curProcess, err = process.NewProcess(int32(os.Getpid()))
 	if err != nil {
 		bin.PrintErrAndExit(err.Error())
 	}
+	selfPercent := curProcess.CPUPercent
+
+	// measure the usage of the cgroup against its quota, so the percent is relative to what the workload can use
+	if cgroup != nil {
+		cores := cgroup.CpuQuotaCores(cpu_percent.CPUNum())
+		percent = func() ([]float64, error) {
+			p, err := cgroup.CpuPercent(time.Second, cpu_percent.CPUNum())
+			return []float64{p}, err
+		}
+		selfPercent = func() (float64, error) {
+			p, err := curProcess.CPUPercent()
+			return p / cores, err
+		}
+	}
+
+	totalCpuPercent, err = percent()
 
-	curProcess, err = process.NewProcess(int32(os.Getpid()))
 	if err != nil {
 		bin.PrintErrAndExit(err.Error())
 	}
 
-	curCpuPercent, err = curProcess.CPUPercent()
+	curCpuPercent, err = selfPercent()
 	if err != nil {
 		bin.PrintErrAndExit(err.Error())
 	}
@@ -138,7 +174,7 @@ func burnCpuIn() {
 					bin.PrintErrAndExit(err.Error())
 				}
 
-				curCpuPercent, err = curProcess.CPUPercent()
+				curCpuPercent, err = selfPercent()
 				if err != nil {
 					bin.PrintErrAndExit(err.Error())
 				}
@@ -147,22 +183,7 @@ func burnCpuIn() {
 		}
 	}()
 
-	if climbTime == 0 {
-		slopePercent = float64(cpuPercent)
-	} else {
-		var ticker *time.Ticker = time.NewTicker(1 * time.Second)
-		slopePercent = totalCpuPercent[0]
-		var startPercent = float64(cpuPercent) - slopePercent
-		go func() {
-			for range ticker.C {
-				if slopePercent < float64(cpuPercent) {
-					slopePercent += startPercent / float64(climbTime)
-				} else if slopePercent > float64(cpuPercent) {
-					slopePercent -= startPercent / float64(climbTime)
-				}
-			}
-		}()
-	}
+	driveSlopePercent(totalCpuPercent[0])
 
 	for i := 0; i < cpuCount; i++ {
 		go func() {
@@ -195,6 +216,40 @@ func burnCpuIn() {
 	select {}
 }
 
+// driveSlopePercent changes the slopePercent by the profile, or climbs from the current percent to the cpuPercent
+func driveSlopePercent(currentPercent float64) {
+	if cpuProfile != "" {
+		profile, err := exec.ParseCpuProfile(cpuProfile, cpuPercent)
+		if err != nil {
+			bin.PrintErrAndExit(err.Error())
+		}
+		startTime := time.Now()
+		slopePercent = profile.Percent(0)
+		go func() {
+			for range time.NewTicker(exec.CpuProfileTick).C {
+				slopePercent = profile.Percent(time.Since(startTime))
+			}
+		}()
+		return
+	}
+	if climbTime == 0 {
+		slopePercent = float64(cpuPercent)
+	} else {
+		var ticker *time.Ticker = time.NewTicker(1 * time.Second)
+		slopePercent = currentPercent
+		var startPercent = float64(cpuPercent) - slopePercent
+		go func() {
+			for range ticker.C {
+				if slopePercent < float64(cpuPercent) {
+					slopePercent += startPercent / float64(climbTime)
+				} else if slopePercent > float64(cpuPercent) {
+					slopePercent -= startPercent / float64(climbTime)
+				}
+			}
+		}()
+	}
+}
+
 var burnCpuBin = exec.BurnCpuBin
 
 var cl = channel.NewLocalChannel()
@@ -203,37 +258,103 @@ var stopBurnCpuFunc = stopBurnCpu
 
 var runBurnCpuFunc = runBurnCpu
 
-var bindBurnCpuFunc = bindBurnCpuByTaskset
-
 var checkBurnCpuFunc = checkBurnCpu
 
+// parseCores parses the cpu list, which is validated by the cpu executor
+func parseCores(cpuList string) []int {
+	cores := make([]int, 0)
+	for _, c := range strings.Split(cpuList, ",") {
+		core, err := strconv.Atoi(c)
+		if err != nil {
+			bin.PrintErrAndExit(fmt.Sprintf("illegal cpu core %s, %v", c, err))
+		}
+		cores = append(cores, core)
+	}
+	return cores
+}
+
+// cgroup is the target cpu cgroup which the burning process joins in
+var cgroup *bin.Cgroup
+
+// getCgroup returns the target cpu cgroup by the --cgroup-path or --target-pid flag, nil if both are absent
+func getCgroup() *bin.Cgroup {
+	if cgroupPath == "" && targetPid <= 0 {
+		return nil
+	}
+	cg, err := bin.GetCpuCgroup(cgroupPath, targetPid)
+	if err != nil {
+		bin.PrintErrAndExit(fmt.Sprintf("get the cpu cgroup failed, %v", err))
+	}
+	return cg
+}
+
+// joinCgroup moves the burning process into the target cpu cgroup, and limits the cpu count to its quota
+func joinCgroup() {
+	cgroup = getCgroup()
+	if cgroup == nil {
+		return
+	}
+	if err := cgroup.AddProcess(os.Getpid()); err != nil {
+		bin.PrintErrAndExit(err.Error())
+	}
+	if cores := int(math.Ceil(cgroup.CpuQuotaCores(cpu_percent.CPUNum()))); cores > 0 && cpuCount > cores {
+		cpuCount = cores
+	}
+}
+
 // startBurnCpu by invoke burnCpuBin with --nohup flag
 func startBurnCpu() {
 	ctx := context.Background()
+	// check the cgroup and the profile before starting the burning processes
+	getCgroup()
+	if strategy == exec.CgroupQuotaStrategy {
+		if _, err := getQuotaCgroupRoot(); err != nil {
+			bin.PrintErrAndExit(err.Error())
+		}
+	}
+	if cpuProfile != "" {
+		if _, err := exec.ParseCpuProfile(cpuProfile, cpuPercent); err != nil {
+			bin.PrintErrAndExit(err.Error())
+		}
+	}
 	if cpuList != "" {
-		cpuCount = 1
 		cores := strings.Split(cpuList, ",")
-		for _, core := range cores {
-			pid := runBurnCpuFunc(ctx, cpuCount, cpuPercent, true, core, climbTime, absolute)
-			bindBurnCpuFunc(ctx, core, pid)
+		runBurnCpuFunc(ctx, len(cores), cpuPercent, cpuList, climbTime, absolute)
+		// the load of the cores is measured in the check window
+		prev, err := bin.ReadCpuStats()
+		if err != nil {
+			bin.PrintErrAndExit(err.Error())
 		}
-	} else {
-		runBurnCpuFunc(ctx, cpuCount, cpuPercent, false, "", climbTime, absolute)
+		checkBurnCpuFunc(ctx)
+		reportCoresFunc(cores, prev)
+		return
 	}
+	runBurnCpuFunc(ctx, cpuCount, cpuPercent, "", climbTime, absolute)
 	checkBurnCpuFunc(ctx)
 }
 
 // runBurnCpu
-func runBurnCpu(ctx context.Context, cpuCount int, cpuPercent int, pidNeeded bool, processor string, climbTime int, absolute bool) int {
+func runBurnCpu(ctx context.Context, cpuCount int, cpuPercent int, cpuList string, climbTime int, absolute bool) {
 	args := fmt.Sprintf(`%s --nohup --cpu-percent %d --climb-time %d`,
 		path.Join(util.GetProgramPath(), burnCpuBin), cpuPercent, climbTime)
-	if pidNeeded {
-		args = fmt.Sprintf("%s --cpu-processor %s", args, processor)
+	if cpuList != "" {
+		args = fmt.Sprintf("%s --cpu-list %s", args, cpuList)
 	}
 	if absolute {
 		args = fmt.Sprintf("%s --absolute", args)
+	} else if strategy != "" && strategy != exec.FeedbackStrategy {
+		args = fmt.Sprintf("%s --strategy %s", args, strategy)
 	}
 	args = fmt.Sprintf("%s --cpu-count %d", args, cpuCount)
+	if cpuProfile != "" {
+		args = fmt.Sprintf("%s --profile %s", args, cpuProfile)
+	}
+	if cgroupPath != "" {
+		args = fmt.Sprintf("%s --cgroup-path %s", args, cgroupPath)
+	}
+	if targetPid > 0 {
+		args = fmt.Sprintf("%s --target-pid %d", args, targetPid)
+	}
 
 	args = fmt.Sprintf(`%s > /dev/null 2>&1 &`, args)
 	response := cl.Run(ctx, "nohup", args)
@@ -241,36 +362,145 @@ func runBurnCpu(ctx context.Context, cpuCount int, cpuPercent int, pidNeeded boo
 		stopBurnCpuFunc()
 		bin.PrintErrAndExit(response.Err)
 	}
-	if pidNeeded {
-		// parse pid
-		newCtx := context.WithValue(context.Background(), channel.ProcessKey, fmt.Sprintf("cpu-processor %s", processor))
-		pids, err := cl.GetPidsByProcessName(burnCpuBin, newCtx)
-		if err != nil {
-			stopBurnCpuFunc()
-			bin.PrintErrAndExit(fmt.Sprintf("bind cpu core failed, cannot get the burning program pid, %v", err))
+}
+
+// coreLoad is the requested and achieved load of the core
+type coreLoad struct {
+	Core      int     `json:"core"`
+	Requested float64 `json:"requested"`
+	Achieved  float64 `json:"achieved"`
+}
+
+// reportCores prints the achieved load of the cores since the prev stats, and the target recorded by the controllers
+func reportCores(cores []string, prev map[string]bin.CpuStat) {
+	cur, err := bin.ReadCpuStats()
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+	}
+	requested := float64(cpuPercent)
+	if target, err := readTarget(getTargetFile(cpuList)); err == nil {
+		requested = target
+	}
+	loads := make([]coreLoad, 0, len(cores))
+	for _, c := range cores {
+		core, _ := strconv.Atoi(c)
+		name := bin.CoreName(core)
+		loads = append(loads, coreLoad{
+			Core:      core,
+			Requested: requested,
+			Achieved:  math.Round(bin.CpuUsagePercent(prev[name], cur[name])*100) / 100,
+		})
+	}
+	bytes, _ := json.Marshal(loads)
+	bin.PrintOutputAndExit(string(bytes))
+}
+
+// getTargetFile returns the file recording the slopePercent of the burning process of the cpu list,
+// which climbs or follows the profile
+func getTargetFile(cpuList string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_burncpu_target_%s.log", cpuList))
+}
+
+// recordTarget writes the slopePercent to the target file in every burn window
+func recordTarget(targetFile string) {
+	for range time.NewTicker(burnWindow).C {
+		if err := ioutil.WriteFile(targetFile, []byte(strconv.FormatFloat(slopePercent, 'f', 2, 64)), 0644); err != nil {
+			logger.Printf("record the target percent failed, %v", err)
 		}
-		if len(pids) > 0 {
-			// return the first one
-			pid, err := strconv.Atoi(pids[0])
-			if err != nil {
-				stopBurnCpuFunc()
-				bin.PrintErrAndExit(fmt.Sprintf("bind cpu core failed, get pid failed, pids: %v, err: %v", pids, err))
-			}
-			return pid
+	}
+}
+
+func readTarget(targetFile string) (float64, error) {
+	bytes, err := ioutil.ReadFile(targetFile)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(bytes)), 64)
+}
+
+// burnCores runs a controller for each core, which is pinned to the core and holds it at the slopePercent
+func burnCores(cores []int) {
+	runtime.GOMAXPROCS(len(cores) + 1)
+	stats, err := bin.ReadCpuStats()
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+	}
+	for _, core := range cores {
+		if _, ok := stats[bin.CoreName(core)]; !ok {
+			bin.PrintErrAndExit(fmt.Sprintf("cpu core %d not found", core))
 		}
 	}
-	return -1
+	// climb from the average usage of the cores, which is sampled in a burn window to start burning in the check window
+	time.Sleep(burnWindow)
+	current, err := bin.ReadCpuStats()
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+	}
+	var usage float64
+	for _, core := range cores {
+		usage += bin.CpuUsagePercent(stats[bin.CoreName(core)], current[bin.CoreName(core)])
+	}
+	driveSlopePercent(usage / float64(len(cores)))
+	go recordTarget(getTargetFile(cpuList))
+	for _, core := range cores {
+		name := bin.CoreName(core)
+		go burnCore(core, (slopePercent-bin.CpuUsagePercent(stats[name], current[name]))/100)
+	}
+	select {}
 }
 
-// bindBurnCpu by taskset command
-func bindBurnCpuByTaskset(ctx context.Context, core string, pid int) {
-	response := cl.Run(ctx, "taskset", fmt.Sprintf("-a -cp %s %d", core, pid))
-	if !response.Success {
-		stopBurnCpuFunc()
-		bin.PrintErrAndExit(response.Err)
+const (
+	// burnWindow is the period of a busy and idle loop
+	burnWindow = 100 * time.Millisecond
+	// controlInterval is the interval to measure the core and adjust the busy ratio
+	controlInterval = time.Second
+)
+
+var reportCoresFunc = reportCores
+
+// burnCore pins the current thread to the core, and adjusts the busy ratio by the usage of the core in /proc/stat
+func burnCore(core int, busy float64) {
+	runtime.LockOSThread()
+	if err := bin.SetAffinity(0, []int{core}); err != nil {
+		bin.PrintErrAndExit(err.Error())
+	}
+	name := bin.CoreName(core)
+	stats, err := bin.ReadCpuStats()
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+	}
+	last, lastTime := stats[name], time.Now()
+	busy = math.Max(0, math.Min(1, busy))
+	for {
+		startTime := time.Now()
+		for time.Since(startTime) < time.Duration(busy*float64(burnWindow)) {
+		}
+		if idle := burnWindow - time.Since(startTime); idle > 0 {
+			time.Sleep(idle)
+		}
+		if time.Since(lastTime) < controlInterval {
+			continue
+		}
+		stats, err := bin.ReadCpuStats()
+		if err != nil {
+			bin.PrintErrAndExit(err.Error())
+		}
+		busy = nextBusy(busy, bin.CpuUsagePercent(last, stats[name]), slopePercent, absolute)
+		last, lastTime = stats[name], time.Now()
 	}
 }
 
+// nextBusy returns the busy ratio for the next interval by the achieved and the target usage of the core.
+// The absolute mode burns the target percent by itself regardless of the other processes on the core.
+func nextBusy(busy, usage, target float64, absolute bool) float64 {
+	if absolute {
+		busy = target / 100
+	} else {
+		busy += (target - usage) / 100 * 0.8
+	}
+	return math.Max(0, math.Min(1, busy))
+}
+
 // checkBurnCpu
 func checkBurnCpu(ctx context.Context) {
 	time.Sleep(time.Second)
@@ -286,6 +516,10 @@ func checkBurnCpu(ctx context.Context) {
 func stopBurnCpu() (success bool, errs string) {
 	// add grep nohup
 	ctx := context.WithValue(context.Background(), channel.ProcessKey, "nohup")
+	// the target file is removed even if the burner exits already
+	if cpuList != "" {
+		defer os.Remove(getTargetFile(cpuList))
+	}
 	pids, _ := cl.GetPidsByProcessName(burnCpuBin, ctx)
 	if pids == nil || len(pids) == 0 {
 		return true, errs
@@ -294,9 +528,103 @@ func stopBurnCpu() (success bool, errs string) {
 	if !response.Success {
 		return false, response.Err
 	}
+	if err := removeQuotaCgroup(); err != nil {
+		return false, err.Error()
+	}
 	return true, errs
 }
 
+// quotaPeriod is the cfs period of the cgroup created by the cgroup-quota strategy
+const quotaPeriod = uint64(100000)
+
+// minQuota is the minimum cfs quota accepted by the kernel
+const minQuota = int64(1000)
+
+// getQuotaCgroupRoot returns the root of the cpu hierarchy, which the cgroup of the cgroup-quota strategy is created under
+func getQuotaCgroupRoot() (*bin.Cgroup, error) {
+	cg, err := bin.GetCpuCgroup("", os.Getpid())
+	if err != nil {
+		return nil, fmt.Errorf("get the cpu cgroup failed, %v", err)
+	}
+	return cg.Root()
+}
+
+// removeQuotaCgroup removes the cgroup of the cgroup-quota strategy, the killed processes may not leave it at once
+func removeQuotaCgroup() error {
+	root, err := getQuotaCgroupRoot()
+	if err != nil {
+		// nothing is created if the cpu cgroup is not supported
+		return nil
+	}
+	cg := &bin.Cgroup{Version: root.Version, Dirs: make(map[string]string)}
+	for controller, dir := range root.Dirs {
+		cg.Dirs[controller] = path.Join(dir, burnCpuBin)
+	}
+	for i := 0; ; i++ {
+		if err = cg.Remove(); err == nil || i >= 10 {
+			return err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// burnCpuByQuota burns the cores at full speed in a cpu cgroup, whose quota is the slopePercent of the cores.
+// The load is precise regardless of the other processes, and the cores of the cpu list are bound.
+func burnCpuByQuota() {
+	root, err := getQuotaCgroupRoot()
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+	}
+	cg, err := root.NewChild(burnCpuBin)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+	}
+	var cores []int
+	count := cpuCount
+	if cpuList != "" {
+		cores = parseCores(cpuList)
+		count = len(cores)
+	}
+	setQuota := func(percent float64) {
+		quota := int64(percent / 100 * float64(count) * float64(quotaPeriod))
+		if quota < minQuota {
+			quota = minQuota
+		}
+		if err := cg.SetCpuQuota(quota, quotaPeriod); err != nil {
+			bin.PrintErrAndExit(err.Error())
+		}
+	}
+	driveSlopePercent(0)
+	setQuota(slopePercent)
+	if err := cg.AddProcess(os.Getpid()); err != nil {
+		bin.PrintErrAndExit(err.Error())
+	}
+	go func() {
+		last := slopePercent
+		for range time.NewTicker(time.Second).C {
+			if slopePercent != last {
+				last = slopePercent
+				setQuota(last)
+			}
+		}
+	}()
+
+	runtime.GOMAXPROCS(count + 1)
+	for i := 0; i < count; i++ {
+		go func(i int) {
+			if cores != nil {
+				runtime.LockOSThread()
+				if err := bin.SetAffinity(0, []int{cores[i]}); err != nil {
+					bin.PrintErrAndExit(err.Error())
+				}
+			}
+			for {
+			}
+		}(i)
+	}
+	select {}
+}
+
 func burnCpuOn() {
 
 	runtime.GOMAXPROCS(cpuCount)
@@ -340,22 +668,7 @@ func burnCpuOn() {
 		}
 	}()
 
-	if climbTime == 0 {
-		slopePercent = float64(cpuPercent)
-	} else {
-		var ticker *time.Ticker = time.NewTicker(1 * time.Second)
-		slopePercent = totalCpuPercent[0]
-		var startPercent = float64(cpuPercent) - slopePercent
-		go func() {
-			for range ticker.C {
-				if slopePercent < float64(cpuPercent) {
-					slopePercent += startPercent / float64(climbTime)
-				} else if slopePercent > float64(cpuPercent) {
-					slopePercent -= startPercent / float64(climbTime)
-				}
-			}
-		}()
-	}
+	driveSlopePercent(totalCpuPercent[0])
 
 	for i := 0; i < cpuCount; i++ {
 		go func() {
@@ -18,10 +18,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
 	"path"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
@@ -29,6 +34,7 @@ import (
 
 	"github.com/chaosblade-io/chaosblade-exec-os/exec"
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
 )
 
 func Test_startBurnCpu(t *testing.T) {
@@ -43,19 +49,34 @@ func Test_startBurnCpu(t *testing.T) {
 	}{
 		{"test1", args{"1,2,3,5", 0, 50}},
 		{"test2", args{"", 3, 50}},
-		{"test3", args{"1,2,3,4",2,10}},
+		{"test3", args{"1,2,3,4", 2, 10}},
 	}
-	runBurnCpuFunc = func(ctx context.Context, cpuCount int, cpuPercent int, pidNeeded bool, processor string, climTime int) int {
-		return 25233
+	var actualCount int
+	var actualList string
+	runBurnCpuFunc = func(ctx context.Context, cpuCount int, cpuPercent int, cpuList string, climTime int, absolute bool) {
+		actualCount, actualList = cpuCount, cpuList
 	}
-	bindBurnCpuFunc = func(ctx context.Context, core string, pid int) {}
 	checkBurnCpuFunc = func(ctx context.Context) {}
+	var reportedCores []string
+	reportCoresFunc = func(cores []string, prev map[string]bin.CpuStat) { reportedCores = cores }
+	defer func() { cpuList = "" }()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			reportedCores = nil
 			cpuList = tt.args.cpuList
 			cpuCount = tt.args.cpuCount
 			cpuPercent = tt.args.cpuPercent
 			startBurnCpu()
+			if tt.args.cpuList == "" {
+				if actualCount != tt.args.cpuCount || reportedCores != nil {
+					t.Errorf("unexpected cpu count: %d, reported cores: %v", actualCount, reportedCores)
+				}
+				return
+			}
+			// all the cores are burned by one process, which runs a controller for each core
+			if actualList != tt.args.cpuList || actualCount != 4 || len(reportedCores) != 4 {
+				t.Errorf("unexpected cpu list: %s, cpu count: %d, reported cores: %v", actualList, actualCount, reportedCores)
+			}
 		})
 	}
 }
@@ -63,15 +84,13 @@ func Test_runBurnCpu_failed(t *testing.T) {
 	type args struct {
 		cpuCount   int
 		cpuPercent int
-		pidNeeded  bool
-		processor  string
+		cpuList    string
 	}
 	burnBin := path.Join(util.GetProgramPath(), exec.BurnCpuBin)
 	as := &args{
 		cpuCount:   2,
 		cpuPercent: 50,
-		pidNeeded:  false,
-		processor:  "",
+		cpuList:    "",
 	}
 
 	var exitCode int
@@ -89,9 +108,9 @@ func Test_runBurnCpu_failed(t *testing.T) {
 		actualCommands = append(actualCommands, fmt.Sprintf("%s %s", script, args))
 		return spec.ReturnFail(spec.Code[spec.CommandNotFound], "nohup command not found")
 	}
-	expectedCommands := []string{fmt.Sprintf(`nohup %s --nohup --cpu-count 2 --cpu-percent 50 > /dev/null 2>&1 &`, burnBin)}
+	expectedCommands := []string{fmt.Sprintf(`nohup %s --nohup --cpu-percent 50 --climb-time 0 --cpu-count 2 > /dev/null 2>&1 &`, burnBin)}
 
-	runBurnCpu(context.Background(), as.cpuCount, as.cpuPercent, as.pidNeeded, as.processor, 0)
+	runBurnCpu(context.Background(), as.cpuCount, as.cpuPercent, as.cpuList, 0, false)
 	if exitCode != 1 {
 		t.Errorf("unexpected result: %d, expected result: %d", exitCode, 1)
 	}
@@ -100,38 +119,160 @@ func Test_runBurnCpu_failed(t *testing.T) {
 	}
 }
 
-func Test_bindBurnCpu(t *testing.T) {
-	type args struct {
-		core string
-		pid  int
-	}
-	as := &args{
-		core: "0",
-		pid:  25233,
+func Test_runBurnCpu_cgroup(t *testing.T) {
+	burnBin := path.Join(util.GetProgramPath(), exec.BurnCpuBin)
+	cgroupPath, targetPid = "", 1234
+	defer func() { targetPid = 0 }()
+
+	cl = channel.NewMockLocalChannel()
+	mockChannel := cl.(*channel.MockLocalChannel)
+	actualCommands := make([]string, 0)
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		actualCommands = append(actualCommands, fmt.Sprintf("%s %s", script, args))
+		return spec.ReturnSuccess("")
 	}
+	expectedCommands := []string{fmt.Sprintf(`nohup %s --nohup --cpu-percent 60 --climb-time 0 --cpu-count 2 --target-pid 1234 > /dev/null 2>&1 &`, burnBin)}
 
-	var exitCode int
-	bin.ExitFunc = func(code int) {
-		exitCode = code
+	runBurnCpu(context.Background(), 2, 60, "", 0, false)
+	if !reflect.DeepEqual(expectedCommands, actualCommands) {
+		t.Errorf("unexpected commands: %+v, expected commands: %+v", actualCommands, expectedCommands)
 	}
-	stopBurnCpuFunc = func() (bool, string) { return true, "" }
+}
+
+func Test_runBurnCpu_cpuList(t *testing.T) {
+	burnBin := path.Join(util.GetProgramPath(), exec.BurnCpuBin)
 	cl = channel.NewMockLocalChannel()
 	mockChannel := cl.(*channel.MockLocalChannel)
 	actualCommands := make([]string, 0)
 	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
 		actualCommands = append(actualCommands, fmt.Sprintf("%s %s", script, args))
-		return spec.ReturnFail(spec.Code[spec.CommandNotFound], "taskset command not found")
+		return spec.ReturnSuccess("")
 	}
-	expectedCommands := []string{fmt.Sprintf(`taskset -a -cp 0 25233`)}
+	expectedCommands := []string{fmt.Sprintf(`nohup %s --nohup --cpu-percent 50 --climb-time 0 --cpu-list 0,3 --cpu-count 2 > /dev/null 2>&1 &`, burnBin)}
 
-	bindBurnCpuByTaskset(context.Background(), as.core, as.pid)
-	if exitCode != 1 {
-		t.Errorf("unexpected result: %d, expected result: %d", exitCode, 1)
+	runBurnCpu(context.Background(), 2, 50, "0,3", 0, false)
+	if !reflect.DeepEqual(expectedCommands, actualCommands) {
+		t.Errorf("unexpected commands: %+v, expected commands: %+v", actualCommands, expectedCommands)
+	}
+}
+
+func Test_runBurnCpu_strategy(t *testing.T) {
+	burnBin := path.Join(util.GetProgramPath(), exec.BurnCpuBin)
+	strategy = exec.CgroupQuotaStrategy
+	defer func() { strategy = exec.FeedbackStrategy }()
+
+	cl = channel.NewMockLocalChannel()
+	mockChannel := cl.(*channel.MockLocalChannel)
+	actualCommands := make([]string, 0)
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		actualCommands = append(actualCommands, fmt.Sprintf("%s %s", script, args))
+		return spec.ReturnSuccess("")
 	}
+	expectedCommands := []string{fmt.Sprintf(`nohup %s --nohup --cpu-percent 30 --climb-time 0 --strategy cgroup-quota --cpu-count 1 > /dev/null 2>&1 &`, burnBin)}
+
+	runBurnCpu(context.Background(), 1, 30, "", 0, false)
 	if !reflect.DeepEqual(expectedCommands, actualCommands) {
 		t.Errorf("unexpected commands: %+v, expected commands: %+v", actualCommands, expectedCommands)
 	}
 }
+
+func Test_removeQuotaCgroup(t *testing.T) {
+	// the fake cgroup2 tree, the current process is in the cgroup /pod1
+	root, clean := bintest.NewRoot(t)
+	defer clean()
+	bintest.WriteFiles(t, root, map[string]string{
+		fmt.Sprintf("proc/%d/cgroup", os.Getpid()): "0::/pod1\n",
+		"proc/self/mountinfo":                      fmt.Sprintf("35 25 0:31 / %s/unified rw,nosuid - cgroup2 cgroup2 rw\n", root),
+		"unified/cgroup.controllers":               "cpu memory\n",
+		"unified/pod1/cgroup.procs":                "",
+	})
+	defer bintest.Replace(&bin.ProcPath, path.Join(root, "proc"))()
+
+	cgroupRoot, err := getQuotaCgroupRoot()
+	if err != nil {
+		t.Fatalf("get the root cgroup err, %v", err)
+	}
+	cg, err := cgroupRoot.NewChild(burnCpuBin)
+	if err != nil {
+		t.Fatalf("create the quota cgroup err, %v", err)
+	}
+	if err := removeQuotaCgroup(); err != nil {
+		t.Fatalf("remove the quota cgroup failed, %v", err)
+	}
+	if dir := cg.Dirs["cpu"]; dir != path.Join(root, "unified", burnCpuBin) || util.IsExist(dir) {
+		t.Errorf("the cgroup %s is not removed", dir)
+	}
+}
+
+func Test_nextBusy(t *testing.T) {
+	tests := []struct {
+		name     string
+		busy     float64
+		usage    float64
+		target   float64
+		absolute bool
+		expect   float64
+	}{
+		{"undershoot", 0.2, 30, 50, false, 0.36},
+		{"overshoot", 0.5, 70, 50, false, 0.34},
+		{"others overload the core", 0.1, 100, 50, false, 0},
+		{"saturated", 0.95, 20, 100, false, 1},
+		{"absolute", 0.2, 90, 50, true, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextBusy(tt.busy, tt.usage, tt.target, tt.absolute); math.Abs(got-tt.expect) > 0.0001 {
+				t.Errorf("unexpected busy: %f, expected: %f", got, tt.expect)
+			}
+		})
+	}
+}
+
+func Test_reportCores(t *testing.T) {
+	bin.ExitFunc = func(code int) {}
+	cpuPercent, cpuProfile, cpuList = 50, "", "0"
+	defer func() { cpuList = "" }()
+	defer os.Remove(getTargetFile(cpuList))
+
+	tests := []struct {
+		name      string
+		target    string
+		requested float64
+	}{
+		{"not recorded", "", 50},
+		{"climbing", "30.00", 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.target != "" {
+				if err := ioutil.WriteFile(getTargetFile(cpuList), []byte(tt.target), 0644); err != nil {
+					t.Fatalf("write the target file err, %v", err)
+				}
+			}
+			prev, err := bin.ReadCpuStats()
+			if err != nil {
+				t.Fatalf("read cpu stats err, %v", err)
+			}
+			time.Sleep(100 * time.Millisecond)
+			reportCores([]string{"0"}, prev)
+			var loads []coreLoad
+			if err := json.Unmarshal([]byte(bin.ExitMessageForTesting), &loads); err != nil {
+				t.Fatalf("unexpected report: %s, %v", bin.ExitMessageForTesting, err)
+			}
+			if len(loads) != 1 || loads[0].Core != 0 || loads[0].Requested != tt.requested || loads[0].Achieved < 0 || loads[0].Achieved > 100 {
+				t.Errorf("unexpected loads: %+v", loads)
+			}
+		})
+	}
+}
+
+func Test_getTargetFile(t *testing.T) {
+	// the experiments of the different cpu lists record the targets separately
+	if getTargetFile("0,3") == getTargetFile("1-2") {
+		t.Errorf("unexpected same target file %s", getTargetFile("0,3"))
+	}
+}
+
 func Test_checkBurnCpu(t *testing.T) {
 	var exitCode int
 	bin.ExitFunc = func(code int) {
@@ -155,3 +296,25 @@ func Test_stopBurnCpu(t *testing.T) {
 		})
 	}
 }
+
+func Test_stopBurnCpu_targetFile(t *testing.T) {
+	cpuList = "1,2,3"
+	defer func() { cpuList = "" }()
+	if err := ioutil.WriteFile(getTargetFile(cpuList), []byte("50.00"), 0644); err != nil {
+		t.Fatalf("write the target file err, %v", err)
+	}
+	mockChannel := channel.NewMockLocalChannel().(*channel.MockLocalChannel)
+	mockChannel.GetPidsByProcessNameFunc = func(processName string, ctx context.Context) ([]string, error) {
+		return []string{}, nil
+	}
+	cl = mockChannel
+	defer func() { cl = channel.NewLocalChannel() }()
+
+	// the burner exits already, the target file is still removed
+	if success, errs := stopBurnCpu(); !success {
+		t.Fatalf("stop burn cpu err, %s", errs)
+	}
+	if util.IsExist(getTargetFile(cpuList)) {
+		t.Errorf("the target file %s is not removed", getTargetFile(cpuList))
+	}
+}
@@ -20,22 +20,28 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"math/rand"
+	"os"
 	"path"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	"unsafe"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
-	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/sirupsen/logrus"
 
 	"github.com/chaosblade-io/chaosblade-exec-os/exec"
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
 )
 
-const count = 100
-
 var burnIODirectory, burnIOSize string
 var burnIORead, burnIOWrite, burnIOStart, burnIOStop, burnIONohup bool
+var burnIOOptions ioOptions
 
 func main() {
 	flag.StringVar(&burnIODirectory, "directory", "", "the directory where the disk is burning")
@@ -45,20 +51,21 @@ func main() {
 	flag.BoolVar(&burnIOStart, "start", false, "start burn io")
 	flag.BoolVar(&burnIOStop, "stop", false, "stop burn io")
 	flag.BoolVar(&burnIONohup, "nohup", false, "start by nohup")
+	flag.StringVar(&burnIOOptions.pattern, "pattern", seqPattern, "the access pattern, seq or rand")
+	flag.IntVar(&burnIOOptions.blockSize, "block-size", 0, "the block size of each io, unit is KB, 0 means the size flag")
+	flag.IntVar(&burnIOOptions.iodepth, "iodepth", 1, "the number of the concurrent io")
+	flag.IntVar(&burnIOOptions.rwMix, "rw-mix", 50, "the percent of the reads if both read and write")
+	flag.IntVar(&burnIOOptions.fsyncEvery, "fsync-every", 0, "fsync the file every the number of writes, 0 means never")
 	bin.ParseFlagAndInitLog()
 
 	if burnIOStart {
-		startBurnIO(burnIODirectory, burnIOSize, burnIORead, burnIOWrite)
+		startBurnIO(burnIODirectory, burnIOSize, burnIORead, burnIOWrite, burnIOOptions)
 	} else if burnIOStop {
 		stopBurnIO(burnIODirectory, burnIORead, burnIOWrite)
 	} else if burnIONohup {
-		if burnIORead {
-			go burnRead(burnIODirectory, burnIOSize)
-		}
-		if burnIOWrite {
-			go burnWrite(burnIODirectory, burnIOSize)
+		if err := burnIO(context.Background(), burnIODirectory, burnIOSize, burnIORead, burnIOWrite, burnIOOptions); err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
 		}
-		select {}
 	} else {
 		bin.PrintErrAndExit("less --start or --stop flag")
 	}
@@ -73,56 +80,98 @@ var cl = channel.NewLocalChannel()
 
 var stopBurnIOFunc = stopBurnIO
 
+const (
+	seqPattern  = "seq"
+	randPattern = "rand"
+	mb          = 1024 * 1024
+	// directAlignment is the alignment of the buffers, the offsets and the block size of O_DIRECT
+	directAlignment = 4096
+)
+
+// minFileSize is the least size of the files to burn, so the random io is not served by the cache of the device,
+// it is replaced in tests
+var minFileSize int64 = 64 * mb
+
+// blocksPerDepth is the number of the blocks in the files for each concurrent io
+const blocksPerDepth = 8
+
+// prefilledMessage is printed to the log after the read file is created, the io load begins after it
+const prefilledMessage = "the read file is created"
+
+// prefillTimeout is the max time the start waits for creating the read file, it is replaced in tests
+var prefillTimeout = 30 * time.Second
+
+// reportInterval is the interval of reporting the iops and the throughput to the log
+var reportInterval = 10 * time.Second
+
+// ioOptions are the options of the io load
+type ioOptions struct {
+	pattern string
+	// blockSize is the size of each io, unit is KB
+	blockSize  int
+	iodepth    int
+	rwMix      int
+	fsyncEvery int
+}
+
+func (o ioOptions) args() string {
+	return fmt.Sprintf("--pattern %s --block-size %d --iodepth %d --rw-mix %d --fsync-every %d",
+		o.pattern, o.blockSize, o.iodepth, o.rwMix, o.fsyncEvery)
+}
+
 // start burn io
-func startBurnIO(directory, size string, read, write bool) {
+func startBurnIO(directory, size string, read, write bool, options ioOptions) {
 	ctx := context.Background()
 	response := cl.Run(ctx, "nohup",
-		fmt.Sprintf(`%s --directory %s --size %s --read=%t --write=%t --nohup=true > %s 2>&1 &`,
-			path.Join(util.GetProgramPath(), burnIOBin), directory, size, read, write, logFile))
+		fmt.Sprintf(`%s --directory %s --size %s --read=%t --write=%t %s --nohup=true > %s 2>&1 &`,
+			path.Join(util.GetProgramPath(), burnIOBin), directory, size, read, write, options.args(), logFile))
 	if !response.Success {
 		stopBurnIOFunc(directory, read, write)
 		bin.PrintErrAndExit(response.Err)
 		return
 	}
-	// check
-	time.Sleep(time.Second)
-	response = cl.Run(ctx, "grep", fmt.Sprintf("%s %s", bin.ErrPrefix, logFile))
-	if response.Success {
-		errMsg := strings.TrimSpace(response.Result.(string))
-		if errMsg != "" {
+	// check, the read file is created before the load, so the failures of creating it are waited for
+	for startTime := time.Now(); ; {
+		time.Sleep(time.Second)
+		if errMsg := grepLog(ctx, bin.ErrPrefix); errMsg != "" {
 			stopBurnIOFunc(directory, read, write)
 			bin.PrintErrAndExit(errMsg)
 			return
 		}
+		if !read || grepLog(ctx, prefilledMessage) != "" {
+			break
+		}
+		if time.Since(startTime) >= prefillTimeout {
+			bin.PrintOutputAndExit(fmt.Sprintf("success, the load begins after the read file is created, "+
+				"the failures, the iops and the throughput are reported in %s", logFile))
+			return
+		}
+	}
+	bin.PrintOutputAndExit(fmt.Sprintf("success, the iops and the throughput are reported in %s", logFile))
+}
+
+// grepLog returns the lines of the log matched
+func grepLog(ctx context.Context, pattern string) string {
+	response := cl.Run(ctx, "grep", fmt.Sprintf(`"%s" %s`, pattern, logFile))
+	if !response.Success {
+		return ""
 	}
-	bin.PrintOutputAndExit("success")
+	return strings.TrimSpace(response.Result.(string))
 }
 
 // stop burn io,  no need to add os.Exit
 func stopBurnIO(directory string, read, write bool) {
 	ctx := context.WithValue(context.Background(), channel.ExcludeProcessKey, "--stop")
+	ctxWithKey := context.WithValue(ctx, channel.ProcessKey, burnIOBin)
 	if read {
-		// dd process
-		pids, _ := cl.GetPidsByProcessName(readFile, ctx)
-		if pids != nil && len(pids) > 0 {
-			cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
-		}
-		// chaos_burnio process
-		ctxWithKey := context.WithValue(ctx, channel.ProcessKey, burnIOBin)
-		pids, _ = cl.GetPidsByProcessName("--read=true", ctxWithKey)
+		pids, _ := cl.GetPidsByProcessName("--read=true", ctxWithKey)
 		if pids != nil && len(pids) > 0 {
 			cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
 		}
 		cl.Run(ctx, "rm", fmt.Sprintf("-rf %s*", path.Join(directory, readFile)))
 	}
 	if write {
-		// dd process
-		pids, _ := cl.GetPidsByProcessName(writeFile, ctx)
-		if pids != nil && len(pids) > 0 {
-			cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
-		}
-		ctxWithKey := context.WithValue(ctx, channel.ProcessKey, burnIOBin)
-		pids, _ = cl.GetPidsByProcessName("--write=true", ctxWithKey)
+		pids, _ := cl.GetPidsByProcessName("--write=true", ctxWithKey)
 		if pids != nil && len(pids) > 0 {
 			cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
 		}
@@ -130,40 +179,214 @@ func stopBurnIO(directory string, read, write bool) {
 	}
 }
 
-// write burn
-func burnWrite(directory, size string) {
-	if !cl.IsCommandAvailable("dd") {
-		bin.PrintErrAndExit(spec.ResponseErr[spec.CommandDdNotFound].Err)
+// ioStat is the number of the io and the bytes, which are updated atomically
+type ioStat struct {
+	ops   int64
+	bytes int64
+}
+
+func (s *ioStat) add(bytes int) int64 {
+	atomic.AddInt64(&s.bytes, int64(bytes))
+	return atomic.AddInt64(&s.ops, 1)
+}
+
+func (s *ioStat) load() ioStat {
+	return ioStat{ops: atomic.LoadInt64(&s.ops), bytes: atomic.LoadInt64(&s.bytes)}
+}
+
+// burner does the io on the read file and the write file by the iodepth goroutines
+type burner struct {
+	options   ioOptions
+	blockSize int64
+	// blocks is the number of the blocks in each file
+	blocks              int64
+	readFile, writeFile *os.File
+	// cursors are the next blocks of the sequential read and write
+	readCursor, writeCursor int64
+	reads, writes           ioStat
+}
+
+// getBlockSize returns the bytes of each io by the block-size flag in KB, or the size flag in MB
+func getBlockSize(size string, options ioOptions) (int64, error) {
+	blockSize := int64(options.blockSize) * 1024
+	if blockSize <= 0 {
+		s, err := strconv.Atoi(size)
+		if err != nil || s <= 0 {
+			return 0, fmt.Errorf("illegal size %s, it must be a positive integer", size)
+		}
+		blockSize = int64(s) * mb
+	}
+	if blockSize%directAlignment != 0 {
+		return 0, fmt.Errorf("the block size %dK must be a multiple of 4K for O_DIRECT", blockSize/1024)
 	}
+	return blockSize, nil
+}
 
-	tmpFileForWrite := path.Join(directory, writeFile)
+// burnIO does the io until the context is done or any io fails, the iops and the throughput are reported to stdout
+func burnIO(ctx context.Context, directory, size string, read, write bool, options ioOptions) error {
+	if !read && !write {
+		return fmt.Errorf("less --read or --write flag")
+	}
+	if options.pattern != seqPattern && options.pattern != randPattern {
+		return fmt.Errorf("illegal pattern %s, it must be seq or rand", options.pattern)
+	}
+	if options.iodepth <= 0 {
+		options.iodepth = 1
+	}
+	if !read {
+		options.rwMix = 0
+	} else if !write {
+		options.rwMix = 100
+	}
+	blockSize, err := getBlockSize(size, options)
+	if err != nil {
+		return err
+	}
+	b := &burner{options: options, blockSize: blockSize, blocks: getFileBlocks(blockSize, options.iodepth)}
+	if read {
+		if b.readFile, err = b.createReadFile(path.Join(directory, readFile)); err != nil {
+			return err
+		}
+		defer b.readFile.Close()
+		fmt.Printf("%s, %d blocks of %dK\n", prefilledMessage, b.blocks, blockSize/1024)
+	}
+	if write {
+		if b.writeFile, err = openDirect(path.Join(directory, writeFile), os.O_CREATE|os.O_RDWR); err != nil {
+			return err
+		}
+		defer b.writeFile.Close()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go b.report(ctx)
+	errs := make(chan error, options.iodepth)
+	var wg sync.WaitGroup
+	for i := 0; i < options.iodepth; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			if err := b.run(ctx, rand.New(rand.NewSource(seed))); err != nil {
+				errs <- err
+				cancel()
+			}
+		}(time.Now().UnixNano() + int64(i))
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// getFileBlocks returns the number of the blocks in each file, which is enough for the concurrent io and not less
+// than the minFileSize
+func getFileBlocks(blockSize int64, iodepth int) int64 {
+	blocks := int64(iodepth) * blocksPerDepth
+	if min := (minFileSize + blockSize - 1) / blockSize; blocks < min {
+		blocks = min
+	}
+	return blocks
+}
+
+// openDirect opens the file with O_DIRECT, the filesystems without O_DIRECT support, for example tmpfs before
+// linux 6.6, fall back to the buffered io
+func openDirect(name string, flag int) (*os.File, error) {
+	file, err := bin.OpenDirect(name, flag, 0644)
+	if err == nil {
+		return file, nil
+	}
+	if pathErr, ok := err.(*os.PathError); ok && pathErr.Err == syscall.EINVAL {
+		logrus.Warnf("O_DIRECT is not supported by the filesystem of %s, use the buffered io", name)
+		return os.OpenFile(name, flag, 0644)
+	}
+	return nil, err
+}
+
+// createReadFile writes the whole read file, so the reads hit the disk instead of the holes
+func (b *burner) createReadFile(name string) (*os.File, error) {
+	file, err := openDirect(name, os.O_CREATE|os.O_RDWR|os.O_TRUNC)
+	if err != nil {
+		return nil, err
+	}
+	buf := alignedBlock(int(b.blockSize))
+	rand.Read(buf)
+	for i := int64(0); i < b.blocks; i++ {
+		if _, err := file.WriteAt(buf, i*b.blockSize); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("create the file %s for reading failed, %v", name, err)
+		}
+	}
+	return file, nil
+}
+
+// run does the io one by one until the context is done
+func (b *burner) run(ctx context.Context, random *rand.Rand) error {
+	buf := alignedBlock(int(b.blockSize))
+	random.Read(buf)
 	for {
-		args := fmt.Sprintf(`if=/dev/zero of=%s bs=%sM count=%d oflag=dsync`, tmpFileForWrite, size, count)
-		response := cl.Run(context.TODO(), "dd", args)
-		if !response.Success {
-			bin.PrintAndExitWithErrPrefix(response.Err)
-			return
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		if random.Intn(100) < b.options.rwMix {
+			offset := b.nextOffset(&b.readCursor, random)
+			n, err := b.readFile.ReadAt(buf, offset)
+			if err != nil {
+				return fmt.Errorf("read %s failed, %v", b.readFile.Name(), err)
+			}
+			b.reads.add(n)
+			continue
+		}
+		offset := b.nextOffset(&b.writeCursor, random)
+		n, err := b.writeFile.WriteAt(buf, offset)
+		if err != nil {
+			return fmt.Errorf("write %s failed, %v", b.writeFile.Name(), err)
+		}
+		if ops := b.writes.add(n); b.options.fsyncEvery > 0 && ops%int64(b.options.fsyncEvery) == 0 {
+			if err := b.writeFile.Sync(); err != nil {
+				return fmt.Errorf("fsync %s failed, %v", b.writeFile.Name(), err)
+			}
 		}
 	}
 }
 
-// read burn
-func burnRead(directory, size string) {
-	// create a 600M file under the directory
-	tmpFileForRead := path.Join(directory, readFile)
-	createArgs := fmt.Sprintf("if=/dev/zero of=%s bs=%dM count=%d oflag=dsync", tmpFileForRead, 6, count)
-	response := cl.Run(context.TODO(), "dd", createArgs)
-	if !response.Success {
-		bin.PrintAndExitWithErrPrefix(
-			fmt.Sprintf("using dd command to create a temp file under %s directory for reading error, %s",
-				directory, response.Err))
+// nextOffset returns the offset of the next block, which wraps around the file in the seq pattern
+func (b *burner) nextOffset(cursor *int64, random *rand.Rand) int64 {
+	if b.options.pattern == randPattern {
+		return random.Int63n(b.blocks) * b.blockSize
 	}
+	return (atomic.AddInt64(cursor, 1) - 1) % b.blocks * b.blockSize
+}
+
+// report prints the iops and the throughput of the reads and the writes in every interval
+func (b *burner) report(ctx context.Context) {
+	ticker := time.NewTicker(reportInterval)
+	defer ticker.Stop()
+	lastReads, lastWrites, last := b.reads.load(), b.writes.load(), time.Now()
 	for {
-		args := fmt.Sprintf(`if=%s of=/dev/null bs=%sM count=%d iflag=dsync,direct,fullblock`, tmpFileForRead, size, count)
-		response = cl.Run(context.TODO(), "dd", args)
-		if !response.Success {
-			bin.PrintAndExitWithErrPrefix(fmt.Sprintf("using dd command to burn read io error, %s", response.Err))
+		select {
+		case <-ctx.Done():
 			return
+		case now := <-ticker.C:
+			reads, writes := b.reads.load(), b.writes.load()
+			seconds := now.Sub(last).Seconds()
+			fmt.Printf("%s read: %s, write: %s\n", now.Format(time.RFC3339),
+				formatRate(reads, lastReads, seconds), formatRate(writes, lastWrites, seconds))
+			lastReads, lastWrites, last = reads, writes, now
 		}
 	}
 }
+
+func formatRate(current, last ioStat, seconds float64) string {
+	return fmt.Sprintf("%.0f iops %.2f MB/s",
+		float64(current.ops-last.ops)/seconds, float64(current.bytes-last.bytes)/seconds/mb)
+}
+
+// alignedBlock returns the buffer of the size whose address is aligned for O_DIRECT
+func alignedBlock(size int) []byte {
+	buf := make([]byte, size+directAlignment)
+	offset := 0
+	if remainder := int(uintptr(unsafe.Pointer(&buf[0])) & (directAlignment - 1)); remainder != 0 {
+		offset = directAlignment - remainder
+	}
+	return buf[offset : offset+size]
+}
@@ -19,9 +19,13 @@ package main
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
@@ -58,9 +62,10 @@ func Test_startBurnIO_startFailed(t *testing.T) {
 		actualCommands = append(actualCommands, fmt.Sprintf("%s %s", script, args))
 		return spec.ReturnFail(spec.Code[spec.CommandNotFound], "nohup command not found")
 	}
-	expectedCommands := []string{fmt.Sprintf(`nohup %s --directory /home/admin --size 1024 --read=true --write=true --nohup=true > %s 2>&1 &`, burnBin, logFile)}
+	expectedCommands := []string{fmt.Sprintf(`nohup %s --directory /home/admin --size 1024 --read=true --write=true `+
+		`--pattern rand --block-size 4 --iodepth 8 --rw-mix 70 --fsync-every 0 --nohup=true > %s 2>&1 &`, burnBin, logFile)}
 
-	startBurnIO(as.directory, as.size, as.read, as.write)
+	startBurnIO(as.directory, as.size, as.read, as.write, ioOptions{pattern: randPattern, blockSize: 4, iodepth: 8, rwMix: 70})
 	if exitCode != 1 {
 		t.Errorf("unexpected result: %d, expected result: %d", exitCode, 1)
 	}
@@ -69,6 +74,65 @@ func Test_startBurnIO_startFailed(t *testing.T) {
 	}
 }
 
+func Test_startBurnIO_prefill(t *testing.T) {
+	var exitCode int
+	bin.ExitFunc = func(code int) {
+		exitCode = code
+	}
+	stopBurnIOFunc = func(directory string, read, write bool) {}
+	tests := []struct {
+		name     string
+		log      string
+		exitCode int
+		message  string
+	}{
+		{"prefilled", prefilledMessage + ", 8 blocks of 10240K", 0, "success, the iops"},
+		{"prefill failed", bin.ErrPrefix + " create the file for reading failed, no space left on device", 1, "no space left"},
+		{"prefilling", "", 0, "the load begins after the read file is created"},
+	}
+	prefillTimeout = 0
+	defer func() { prefillTimeout = 30 * time.Second }()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockChannel := channel.NewMockLocalChannel().(*channel.MockLocalChannel)
+			mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+				pattern := strings.Split(args, `"`)
+				if script == "grep" && len(pattern) > 1 && strings.Contains(tt.log, pattern[1]) {
+					return spec.ReturnSuccess(tt.log)
+				}
+				if script == "grep" {
+					return spec.ReturnFail(spec.Code[spec.ServerError], "")
+				}
+				return spec.ReturnSuccess("")
+			}
+			cl = mockChannel
+			startBurnIO("/home/admin", "10", true, false, ioOptions{pattern: seqPattern, iodepth: 1})
+			if exitCode != tt.exitCode || !strings.Contains(bin.ExitMessageForTesting, tt.message) {
+				t.Errorf("unexpected result: %d, %s, expected: %d, %s", exitCode, bin.ExitMessageForTesting, tt.exitCode, tt.message)
+			}
+		})
+	}
+}
+
+func Test_getFileBlocks(t *testing.T) {
+	tests := []struct {
+		blockSize int64
+		iodepth   int
+		expected  int64
+	}{
+		// 8 blocks of the default 10M, instead of 100 blocks
+		{10 * mb, 1, 8},
+		{10 * mb, 16, 128},
+		// not less than 64M
+		{4096, 32, 16384},
+	}
+	for _, tt := range tests {
+		if blocks := getFileBlocks(tt.blockSize, tt.iodepth); blocks != tt.expected {
+			t.Errorf("unexpected blocks of %d and iodepth %d: %d, expected: %d", tt.blockSize, tt.iodepth, blocks, tt.expected)
+		}
+	}
+}
+
 func Test_stopBurnIO(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -90,3 +154,56 @@ func Test_stopBurnIO(t *testing.T) {
 		})
 	}
 }
+
+func Test_getBlockSize(t *testing.T) {
+	tests := []struct {
+		size     string
+		options  ioOptions
+		expected int64
+		wantErr  bool
+	}{
+		{size: "10", expected: 10 * mb},
+		{size: "10", options: ioOptions{blockSize: 16}, expected: 16 * 1024},
+		{size: "10", options: ioOptions{blockSize: 6}, wantErr: true},
+		{size: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		blockSize, err := getBlockSize(tt.size, tt.options)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("getBlockSize(%s, %+v) err = %v, wantErr %v", tt.size, tt.options, err, tt.wantErr)
+			continue
+		}
+		if blockSize != tt.expected {
+			t.Errorf("getBlockSize(%s, %+v) = %d, expected %d", tt.size, tt.options, blockSize, tt.expected)
+		}
+	}
+}
+
+func Test_burnIO(t *testing.T) {
+	directory, err := ioutil.TempDir("", "burnio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	minFileSize = mb
+	defer func() { minFileSize = 64 * mb }()
+
+	for _, pattern := range []string{seqPattern, randPattern} {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		err := burnIO(ctx, directory, "1", true, true, ioOptions{pattern: pattern, blockSize: 4, iodepth: 4, rwMix: 50, fsyncEvery: 16})
+		cancel()
+		if err != nil {
+			t.Fatalf("burnIO() with the %s pattern err, %v", pattern, err)
+		}
+	}
+	info, err := os.Stat(path.Join(directory, readFile))
+	if err != nil || info.Size() != 256*4096 {
+		t.Errorf("unexpected read file: %v, %v, expected the size %d", info, err, 256*4096)
+	}
+	if info, err := os.Stat(path.Join(directory, writeFile)); err != nil || info.Size() > minFileSize {
+		t.Errorf("unexpected write file: %v, %v, expected the size not bigger than %d", info, err, minFileSize)
+	}
+	if err := burnIO(context.Background(), directory, "1", true, false, ioOptions{pattern: "zigzag"}); err == nil {
+		t.Errorf("expected err for the illegal pattern")
+	}
+}
@@ -18,20 +18,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"math"
 	"os"
 	"path"
+	"runtime/debug"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 	"github.com/chaosblade-io/chaosblade-spec-go/util"
-	"github.com/containerd/cgroups"
-	v1 "github.com/containerd/cgroups/stats/v1"
 	"github.com/shirou/gopsutil/mem"
 	"github.com/sirupsen/logrus"
 
@@ -39,8 +40,6 @@ import (
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
 )
 
-const PageCounterMax uint64 = 9223372036854770000
-
 // 128K
 type Block [32 * 1024]int32
 
@@ -58,8 +57,12 @@ func init() {
 
 var (
 	burnMemStart, burnMemStop, burnMemNohup, includeBufferCache, isHost bool
+	memLock                                                             bool
 	memPercent, memReserve, memRate                                     int
+	holdTime, releaseRate, cycles                                       int
 	burnMemMode                                                         string
+	cgroupPath                                                          string
+	targetPid                                                           int
 )
 var calculateMemSize func(int, int) (int64, int64, error)
 
@@ -73,9 +76,15 @@ func main() {
 	flag.IntVar(&memRate, "rate", 100, "burn memory rate, unit is M/S, only support for ram mode")
 	flag.StringVar(&burnMemMode, "mode", "cache", "burn memory mode, cache or ram")
 	flag.BoolVar(&isHost, "isHost", false, "run burn mem isHost")
+	flag.IntVar(&holdTime, "hold", 0, "seconds to hold the memory after it reaches the target, only used with --release-rate")
+	flag.IntVar(&releaseRate, "release-rate", 0, "release memory rate after holding, unit is M/S, the memory is burned, held and released by cycles if it's set")
+	flag.IntVar(&cycles, "cycles", 0, "the cycles of burning, holding and releasing memory, 0 means until destroyed")
+	flag.StringVar(&cgroupPath, "cgroup-path", "", "the memory cgroup to burn memory in")
+	flag.IntVar(&targetPid, "target-pid", 0, "burn memory in the memory cgroup of the process")
+	flag.BoolVar(&memLock, "lock", false, "lock the burned memory by mlock, so it is never swapped out, only support for ram mode")
 	bin.ParseFlagAndInitLog()
 
-	if isHost {
+	if isHost && !isTargetCgroup() {
 		calculateMemSize = calculateMemSizeFromHost
 	} else {
 		calculateMemSize = calculateMemSizeFromCont
@@ -88,7 +97,10 @@ func main() {
 			bin.PrintErrAndExit(errs)
 		}
 	} else if burnMemNohup {
-		if burnMemMode == "cache" {
+		joinCgroup()
+		if releaseRate > 0 {
+			burnMemByCycles()
+		} else if burnMemMode == "cache" {
 			burnMemWithCache()
 		} else if burnMemMode == "ram" {
 			burnMemWithRam()
@@ -110,6 +122,7 @@ func burnMemWithRam() {
 	var cache = make(map[int][]Block, 1)
 	var count = 1
 	cache[count] = make([]Block, 0)
+	var locked = &lockedHolder{}
 	if memRate <= 0 {
 		memRate = 100
 	}
@@ -119,15 +132,17 @@ func burnMemWithRam() {
 			stopBurnMemFunc()
 			bin.PrintErrAndExit(err.Error())
 		}
-		fillMem := expectMem
 		if expectMem > 0 {
-			if expectMem > int64(memRate) {
-				fillMem = int64(memRate)
-			} else {
-				fillMem = expectMem / 10
-				if fillMem == 0 {
-					continue
+			fillMem := nextFillSize(expectMem)
+			if fillMem == 0 {
+				continue
+			}
+			if memLock {
+				if err := locked.grow(fillMem); err != nil {
+					stopBurnMemFunc()
+					bin.PrintErrAndExit(err.Error())
 				}
+				continue
 			}
 			fillSize := int(8 * fillMem)
 			buf := cache[count]
@@ -144,6 +159,14 @@ func burnMemWithRam() {
 	}
 }
 
+// nextFillSize returns the memory to burn in the next second, it is limited by the rate and slows down near the target
+func nextFillSize(expectMem int64) int64 {
+	if expectMem > int64(memRate) {
+		return int64(memRate)
+	}
+	return expectMem / 10
+}
+
 func burnMemWithCache() {
 	filePath := path.Join(path.Join(util.GetProgramPath(), dirName), fileName)
 	tick := time.Tick(time.Second)
@@ -169,6 +192,185 @@ func burnMemWithCache() {
 	}
 }
 
+// memHolder holds the burned memory, which is grown and released by the cycles, the unit is MB
+type memHolder interface {
+	grow(size int64) error
+	release(size int64) error
+	size() int64
+}
+
+// ramHolder holds the memory by the chunks of 1MB in the heap
+type ramHolder struct {
+	chunks [][]Block
+}
+
+func (h *ramHolder) grow(size int64) error {
+	for i := int64(0); i < size; i++ {
+		chunk := make([]Block, 8)
+		// touch every page, so the memory is really allocated
+		for j := range chunk {
+			for k := 0; k < len(chunk[j]); k += 1024 {
+				chunk[j][k] = 1
+			}
+		}
+		h.chunks = append(h.chunks, chunk)
+	}
+	return nil
+}
+
+func (h *ramHolder) release(size int64) error {
+	if size > h.size() {
+		size = h.size()
+	}
+	left := len(h.chunks) - int(size)
+	for i := left; i < len(h.chunks); i++ {
+		h.chunks[i] = nil
+	}
+	h.chunks = h.chunks[:left]
+	// return the freed memory to the os at once
+	debug.FreeOSMemory()
+	return nil
+}
+
+func (h *ramHolder) size() int64 {
+	return int64(len(h.chunks))
+}
+
+// lockedHolder holds the memory by the chunks of 1MB mapped out of the heap and locked by mlock, so the pages are
+// never swapped out, and they are unmapped at once when released
+type lockedHolder struct {
+	chunks [][]byte
+}
+
+func (h *lockedHolder) grow(size int64) error {
+	for i := int64(0); i < size; i++ {
+		chunk, err := syscall.Mmap(-1, 0, 1024*1024, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+		if err != nil {
+			return fmt.Errorf("map the memory failed, %v", err)
+		}
+		// mlock populates all the pages of the chunk
+		if err := syscall.Mlock(chunk); err != nil {
+			syscall.Munmap(chunk)
+			return fmt.Errorf("lock the memory failed, check the RLIMIT_MEMLOCK limit or the CAP_IPC_LOCK capability, %v", err)
+		}
+		h.chunks = append(h.chunks, chunk)
+	}
+	return nil
+}
+
+func (h *lockedHolder) release(size int64) error {
+	for ; size > 0 && len(h.chunks) > 0; size-- {
+		last := len(h.chunks) - 1
+		if err := syscall.Munmap(h.chunks[last]); err != nil {
+			return err
+		}
+		h.chunks = h.chunks[:last]
+	}
+	return nil
+}
+
+func (h *lockedHolder) size() int64 {
+	return int64(len(h.chunks))
+}
+
+// cacheHolder holds the memory by the files in the tmpfs
+type cacheHolder struct {
+	dir   string
+	files []string
+	sizes []int64
+}
+
+func (h *cacheHolder) grow(size int64) error {
+	filePath := path.Join(h.dir, fmt.Sprintf("%s%d", fileName, fileCount))
+	response := cl.Run(context.Background(), "dd", fmt.Sprintf("if=/dev/zero of=%s bs=1M count=%d", filePath, size))
+	if !response.Success {
+		return errors.New(response.Error())
+	}
+	fileCount++
+	h.files = append(h.files, filePath)
+	h.sizes = append(h.sizes, size)
+	return nil
+}
+
+// release truncates the last files, which frees the pages of the tmpfs
+func (h *cacheHolder) release(size int64) error {
+	for size > 0 && len(h.files) > 0 {
+		last := len(h.files) - 1
+		if h.sizes[last] > size {
+			h.sizes[last] -= size
+			return os.Truncate(h.files[last], h.sizes[last]*1024*1024)
+		}
+		if err := os.Remove(h.files[last]); err != nil {
+			return err
+		}
+		size -= h.sizes[last]
+		h.files, h.sizes = h.files[:last], h.sizes[:last]
+	}
+	return nil
+}
+
+func (h *cacheHolder) size() int64 {
+	var total int64
+	for _, size := range h.sizes {
+		total += size
+	}
+	return total
+}
+
+// cycleInterval is the interval of growing or releasing the memory by the rates
+var cycleInterval = time.Second
+
+// burnMemByCycles burns the memory to the target, holds it, releases it, and repeats by the cycles
+func burnMemByCycles() {
+	var holder memHolder = &ramHolder{}
+	if burnMemMode == "cache" {
+		holder = &cacheHolder{dir: path.Join(util.GetProgramPath(), dirName)}
+	} else if memLock {
+		holder = &lockedHolder{}
+	}
+	if err := runCycles(holder); err != nil {
+		stopBurnMemFunc()
+		bin.PrintErrAndExit(err.Error())
+	}
+}
+
+// runCycles returns after the cycles, it never returns if the cycles is 0
+func runCycles(holder memHolder) error {
+	if memRate <= 0 {
+		memRate = 100
+	}
+	for cycle := 1; cycles == 0 || cycle <= cycles; cycle++ {
+		// the target is calculated at the beginning of each cycle, so the burned memory is not affected by itself
+		_, expectMem, err := calculateMemSize(memPercent, memReserve)
+		if err != nil {
+			return err
+		}
+		logrus.Debugf("cycle: %d, expect mem: %d", cycle, expectMem)
+		if expectMem <= 0 {
+			time.Sleep(cycleInterval)
+		}
+		for filled := int64(0); filled < expectMem; {
+			fillMem := expectMem - filled
+			if fillMem > int64(memRate) {
+				fillMem = int64(memRate)
+			}
+			if err := holder.grow(fillMem); err != nil {
+				return err
+			}
+			filled += fillMem
+			time.Sleep(cycleInterval)
+		}
+		time.Sleep(time.Duration(holdTime) * time.Second)
+		for holder.size() > 0 {
+			if err := holder.release(int64(releaseRate)); err != nil {
+				return err
+			}
+			time.Sleep(cycleInterval)
+		}
+	}
+	return nil
+}
+
 var burnMemBin = exec.BurnMemBin
 
 var cl = channel.NewLocalChannel()
@@ -177,8 +379,43 @@ var stopBurnMemFunc = stopBurnMem
 
 var runBurnMemFunc = runBurnMem
 
+// isTargetCgroup returns true if the memory is burned in the cgroup of the --cgroup-path or --target-pid flag
+func isTargetCgroup() bool {
+	return cgroupPath != "" || targetPid > 0
+}
+
+// getCgroup returns the target memory cgroup, or the memory cgroup of the burning process
+func getCgroup() (*bin.Cgroup, error) {
+	if isTargetCgroup() {
+		return bin.GetMemoryCgroup(cgroupPath, targetPid)
+	}
+	return bin.GetMemoryCgroup("", os.Getpid())
+}
+
+// joinCgroup moves the burning process into the target memory cgroup, so the memory is charged to it
+func joinCgroup() {
+	if !isTargetCgroup() {
+		return
+	}
+	cgroup, err := getCgroup()
+	if err != nil {
+		bin.PrintErrAndExit(fmt.Sprintf("get the memory cgroup failed, %v", err))
+	}
+	if err := cgroup.AddProcess(os.Getpid()); err != nil {
+		bin.PrintErrAndExit(err.Error())
+	}
+}
+
 func startBurnMem() {
 	ctx := context.Background()
+	if memLock && burnMemMode != "ram" {
+		bin.PrintErrAndExit("the lock flag only supports the ram mode")
+	}
+	if isTargetCgroup() {
+		if _, err := getCgroup(); err != nil {
+			bin.PrintErrAndExit(fmt.Sprintf("get the memory cgroup failed, %v", err))
+		}
+	}
 	if burnMemMode == "cache" {
 		flPath := path.Join(util.GetProgramPath(), dirName)
 		if _, err := os.Stat(flPath); err != nil {
@@ -198,6 +435,18 @@ func startBurnMem() {
 func runBurnMem(ctx context.Context, memPercent, memReserve, memRate int, burnMemMode string, includeBufferCache bool, isHost bool) {
 	args := fmt.Sprintf(`%s --nohup --mem-percent %d --reserve %d --rate %d --mode %s --include-buffer-cache=%t --isHost=%t`,
 		path.Join(util.GetProgramPath(), burnMemBin), memPercent, memReserve, memRate, burnMemMode, includeBufferCache, isHost)
+	if releaseRate > 0 {
+		args = fmt.Sprintf("%s --hold %d --release-rate %d --cycles %d", args, holdTime, releaseRate, cycles)
+	}
+	if cgroupPath != "" {
+		args = fmt.Sprintf("%s --cgroup-path %s", args, cgroupPath)
+	}
+	if targetPid > 0 {
+		args = fmt.Sprintf("%s --target-pid %d", args, targetPid)
+	}
+	if memLock {
+		args = fmt.Sprintf("%s --lock", args)
+	}
 	args = fmt.Sprintf(`%s > /dev/null 2>&1 &`, args)
 
 	response := cl.Run(ctx, "nohup", args)
@@ -255,11 +504,12 @@ func calculateMemSizeFromCont(percent, reserve int) (int64, int64, error) {
 
 	total := int64(0)
 	available := int64(0)
+	used := int64(0)
 	memoryStat, err := getMemoryStatsByCGroup()
 	if err != nil {
 		logrus.Infof("get memory stats by cgroup failed, used proc memory, %v", err)
 	}
-	if memoryStat == nil || memoryStat.Usage.Limit >= PageCounterMax {
+	if memoryStat == nil || memoryStat.Unlimited() {
 		//no limit
 		virtualMemory, err := mem.VirtualMemory()
 		if err != nil {
@@ -271,8 +521,8 @@ func calculateMemSizeFromCont(percent, reserve int) (int64, int64, error) {
 			available = available + int64(virtualMemory.Buffers+virtualMemory.Cached)
 		}
 	} else {
-		total = int64(memoryStat.Usage.Limit)
-		available = total - int64(memoryStat.Usage.Usage)
+		total = int64(memoryStat.Limit)
+		available = total - int64(memoryStat.Usage)
 		if burnMemMode == "ram" && !includeBufferCache {
 			available = available + int64(memoryStat.Cache)
 		}
@@ -283,11 +533,20 @@ func calculateMemSizeFromCont(percent, reserve int) (int64, int64, error) {
 	} else {
 		reserved = int64(reserve)
 	}
-	//container mode mem calculation
-
-	activeAnon := int64(memoryStat.ActiveAnon)
-	inactiveAnon := int64(memoryStat.InactiveAnon)
-	used := int64(activeAnon + inactiveAnon)
+	//container mode mem calculation, the anonymous memory of the cgroup is used, or the memory of the host without it
+	if memoryStat != nil {
+		used = int64(memoryStat.ActiveAnon + memoryStat.InactiveAnon)
+		// the pages locked by mlock are moved to the unevictable lru and not counted in the anon lru
+		if memLock {
+			used += int64(memoryStat.Unevictable)
+		}
+	} else {
+		virtualMemory, err := mem.VirtualMemory()
+		if err != nil {
+			return 0, 0, err
+		}
+		used = int64(virtualMemory.Total - virtualMemory.Available)
+	}
 	expectSize := (total*int64(percent)/100 - used) / 1024 / 1024
 	logger.Printf("total: %d, used: %d, percent: %d, expectSize: %d",
 		total/1024/1024, used/1024/1024, percent, expectSize)
@@ -303,12 +562,12 @@ func calculateMemSizeFromHost(percent, reserve int) (int64, int64, error) {
 	total := int64(0)
 	available := int64(0)
 	//memoryStat, err := getMemoryStatsByCGroup()
-	var memoryStat *v1.MemoryStat
+	var memoryStat *bin.MemoryStat
 
 	//if err != nil {
 	//	logrus.Infof("get memory stats by cgroup failed, used proc memory, %v", err)
 	//}
-	if memoryStat == nil || memoryStat.Usage.Limit >= PageCounterMax {
+	if memoryStat == nil || memoryStat.Unlimited() {
 		//no limit
 		virtualMemory, err := mem.VirtualMemory()
 		if err != nil {
@@ -320,8 +579,8 @@ func calculateMemSizeFromHost(percent, reserve int) (int64, int64, error) {
 			available = available + int64(virtualMemory.Buffers+virtualMemory.Cached)
 		}
 	} else {
-		total = int64(memoryStat.Usage.Limit)
-		available = total - int64(memoryStat.Usage.Usage)
+		total = int64(memoryStat.Limit)
+		available = total - int64(memoryStat.Usage)
 		if burnMemMode == "ram" && !includeBufferCache {
 			available = available + int64(memoryStat.Cache)
 		}
@@ -353,14 +612,16 @@ func calculateMemSizeFromHost(percent, reserve int) (int64, int64, error) {
 	return total / 1024 / 1024, expectSize, nil
 }
 
-func getMemoryStatsByCGroup() (*v1.MemoryStat, error) {
-	cgroup, err := cgroups.Load(cgroups.V1, cgroups.StaticPath("/"))
+// getMemoryStatsByCGroup reads the memory stats of the target cgroup or the cgroup which the burning process is in,
+// both cgroup v1 and the unified hierarchy of v2 are supported
+func getMemoryStatsByCGroup() (*bin.MemoryStat, error) {
+	cgroup, err := getCgroup()
 	if err != nil {
 		return nil, fmt.Errorf("load cgroup error, %v", err)
 	}
-	stats, err := cgroup.Stat(cgroups.IgnoreNotExist)
+	stats, err := cgroup.MemoryStat()
 	if err != nil {
 		return nil, fmt.Errorf("load cgroup stat error, %v", err)
 	}
-	return stats.Memory, nil
+	return stats, nil
 }
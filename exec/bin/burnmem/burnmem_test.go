@@ -19,15 +19,19 @@ package main
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 	"github.com/chaosblade-io/chaosblade-spec-go/util"
 
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
 )
 
 func Test_startBurnMem(t *testing.T) {
@@ -37,8 +41,10 @@ func Test_startBurnMem(t *testing.T) {
 		exitCode = code
 	}
 
-	runBurnMemFunc = func(context.Context, int, int, int, string) {
+	runBurnMemFunc = func(context.Context, int, int, int, string, bool, bool) {
 	}
+	burnMemMode = "cache"
+	defer func() { burnMemMode = "" }()
 
 	stopBurnMemFunc = func() (bool, string) {
 		return true, ""
@@ -87,12 +93,12 @@ func Test_runBurnMem_failed(t *testing.T) {
 		actualCommands = append(actualCommands, fmt.Sprintf("%s %s", script, args))
 		return spec.ReturnFail(spec.Code[spec.CommandNotFound], "nohup command not found")
 	}
-	expectedCommands := []string{fmt.Sprintf(`nohup %s --nohup --mem-percent 50 > /dev/null 2>&1 &`, burnBin)}
+	expectedCommands := []string{fmt.Sprintf(`nohup %s --nohup --mem-percent 50 --reserve 0 --rate 0 --mode  --include-buffer-cache=false --isHost=false > /dev/null 2>&1 &`, burnBin)}
 	stopBurnMemFunc = func() (bool, string) {
 		return true, ""
 	}
 
-	runBurnMem(context.Background(), as.memPercent, as.memReserve, as.memRate, as.burnMemMode)
+	runBurnMem(context.Background(), as.memPercent, as.memReserve, as.memRate, as.burnMemMode, false, false)
 	if exitCode != 1 {
 		t.Errorf("unexpected result: %d, expected result: %d", exitCode, 1)
 	}
@@ -114,3 +120,209 @@ func Test_stopBurnMem(t *testing.T) {
 		})
 	}
 }
+
+// newFakeMemoryCgroup creates the fake proc and cgroup2 trees, the current process is in the cgroup /pod1
+func newFakeMemoryCgroup(t *testing.T, files map[string]string) func() {
+	root, clean := bintest.NewRoot(t)
+	files[fmt.Sprintf("proc/%d/cgroup", os.Getpid())] = "0::/pod1\n"
+	files["proc/self/mountinfo"] = fmt.Sprintf("35 25 0:31 / %s/unified rw,nosuid - cgroup2 cgroup2 rw\n", root)
+	files["unified/cgroup.controllers"] = "cpu memory\n"
+	bintest.WriteFiles(t, root, files)
+	restore := bintest.Replace(&bin.ProcPath, path.Join(root, "proc"))
+	return func() {
+		restore()
+		clean()
+	}
+}
+
+func Test_calculateMemSizeFromCont_v2(t *testing.T) {
+	clean := newFakeMemoryCgroup(t, map[string]string{
+		"unified/pod1/memory.current": "314572800\n",
+		"unified/pod1/memory.max":     "1073741824\n",
+		"unified/pod1/memory.stat":    "anon 209715200\nfile 104857600\nactive_anon 157286400\ninactive_anon 52428800\n",
+	})
+	defer clean()
+
+	total, expectSize, err := calculateMemSizeFromCont(80, 0)
+	if err != nil {
+		t.Fatalf("calculate memory size err, %v", err)
+	}
+	// 80% of the 1024M limit minus the 200M anonymous memory
+	if total != 1024 || expectSize != 619 {
+		t.Errorf("unexpected total: %d, expect size: %d, expected: 1024, 619", total, expectSize)
+	}
+}
+
+func Test_calculateMemSizeFromCont_target(t *testing.T) {
+	clean := newFakeMemoryCgroup(t, map[string]string{
+		"unified/pod1/memory.current": "314572800\n",
+		"unified/pod1/memory.max":     "max\n",
+		"unified/pod1/memory.stat":    "anon 209715200\nfile 104857600\nactive_anon 157286400\ninactive_anon 52428800\n",
+		"unified/pod2/cgroup.procs":   "",
+		"unified/pod2/memory.current": "104857600\n",
+		"unified/pod2/memory.max":     "536870912\n",
+		"unified/pod2/memory.stat":    "anon 104857600\nfile 0\nactive_anon 104857600\ninactive_anon 0\n",
+	})
+	defer clean()
+	cgroupPath = "/pod2"
+	defer func() { cgroupPath = "" }()
+
+	total, expectSize, err := calculateMemSizeFromCont(90, 0)
+	if err != nil {
+		t.Fatalf("calculate memory size err, %v", err)
+	}
+	// 90% of the 512M limit of the target cgroup minus its 100M anonymous memory
+	if total != 512 || expectSize != 360 {
+		t.Errorf("unexpected total: %d, expect size: %d, expected: 512, 360", total, expectSize)
+	}
+}
+
+func Test_runBurnMem_target(t *testing.T) {
+	burnBin := path.Join(util.GetProgramPath(), "chaos_burnmem")
+	targetPid = 1234
+	defer func() { targetPid = 0 }()
+	cl = channel.NewMockLocalChannel()
+	mockChannel := cl.(*channel.MockLocalChannel)
+	actualCommands := make([]string, 0)
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		actualCommands = append(actualCommands, fmt.Sprintf("%s %s", script, args))
+		return spec.ReturnSuccess("")
+	}
+	mockChannel.GetPidsByProcessNameFunc = func(processName string, ctx context.Context) ([]string, error) {
+		return []string{"4321"}, nil
+	}
+	expectedCommands := []string{fmt.Sprintf(`nohup %s --nohup --mem-percent 90 --reserve 0 --rate 100 --mode ram --include-buffer-cache=false --isHost=false --target-pid 1234 > /dev/null 2>&1 &`, burnBin)}
+
+	runBurnMem(context.Background(), 90, 0, 100, "ram", false, false)
+	if !reflect.DeepEqual(expectedCommands, actualCommands) {
+		t.Errorf("unexpected commands: %+v, expected commands: %+v", actualCommands, expectedCommands)
+	}
+}
+
+func Test_getMemoryStatsByCGroup_unlimited(t *testing.T) {
+	clean := newFakeMemoryCgroup(t, map[string]string{
+		"unified/pod1/memory.current": "314572800\n",
+		"unified/pod1/memory.max":     "max\n",
+		"unified/pod1/memory.stat":    "anon 209715200\nfile 104857600\nactive_anon 157286400\ninactive_anon 52428800\n",
+	})
+	defer clean()
+
+	stat, err := getMemoryStatsByCGroup()
+	if err != nil {
+		t.Fatalf("get memory stats err, %v", err)
+	}
+	if !stat.Unlimited() || stat.Usage != 314572800 {
+		t.Errorf("unexpected memory stat: %+v", stat)
+	}
+}
+
+// recordHolder records the max size and the sizes after releasing
+type recordHolder struct {
+	ramHolder
+	maxSize  int64
+	released []int64
+}
+
+func (h *recordHolder) grow(size int64) error {
+	h.ramHolder.grow(size)
+	if h.size() > h.maxSize {
+		h.maxSize = h.size()
+	}
+	return nil
+}
+
+func (h *recordHolder) release(size int64) error {
+	h.ramHolder.release(size)
+	h.released = append(h.released, h.size())
+	return nil
+}
+
+func Test_runCycles(t *testing.T) {
+	calculateMemSize = func(int, int) (int64, int64, error) {
+		return 1024, 5, nil
+	}
+	cycleInterval = time.Millisecond
+	memRate, holdTime, releaseRate, cycles = 2, 0, 2, 2
+	defer func() { memRate, releaseRate, cycles = 0, 0, 0 }()
+
+	holder := &recordHolder{}
+	if err := runCycles(holder); err != nil {
+		t.Fatalf("run cycles err, %v", err)
+	}
+	expected := []int64{3, 1, 0, 3, 1, 0}
+	if holder.maxSize != 5 || !reflect.DeepEqual(holder.released, expected) {
+		t.Errorf("unexpected max size: %d, released: %v, expected: 5, %v", holder.maxSize, holder.released, expected)
+	}
+}
+
+func Test_cacheHolder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaos-burnmem")
+	if err != nil {
+		t.Fatalf("create temp dir err, %v", err)
+	}
+	defer os.RemoveAll(dir)
+	cl = channel.NewLocalChannel()
+
+	holder := &cacheHolder{dir: dir}
+	for _, size := range []int64{2, 3} {
+		if err := holder.grow(size); err != nil {
+			t.Fatalf("grow err, %v", err)
+		}
+	}
+	if err := holder.release(4); err != nil {
+		t.Fatalf("release err, %v", err)
+	}
+	info, err := os.Stat(holder.files[0])
+	if holder.size() != 1 || len(holder.files) != 1 || err != nil || info.Size() != 1024*1024 {
+		t.Errorf("unexpected holder: %+v, file: %v, %v", holder, info, err)
+	}
+}
+
+func Test_lockedHolder(t *testing.T) {
+	holder := &lockedHolder{}
+	if err := holder.grow(3); err != nil {
+		t.Fatalf("grow err, %v", err)
+	}
+	if holder.size() != 3 || len(holder.chunks[0]) != 1024*1024 {
+		t.Errorf("unexpected holder size: %d, expected: 3", holder.size())
+	}
+	if err := holder.release(2); err != nil || holder.size() != 1 {
+		t.Errorf("unexpected holder size after release: %d, %v, expected: 1", holder.size(), err)
+	}
+	if err := holder.release(5); err != nil || holder.size() != 0 {
+		t.Errorf("unexpected holder size after release: %d, %v, expected: 0", holder.size(), err)
+	}
+}
+
+func Test_calculateMemSizeFromCont_lock(t *testing.T) {
+	stat := "anon %[1]d\nfile 0\nactive_anon 104857600\ninactive_anon 0\nunevictable %[2]d\n"
+	clean := newFakeMemoryCgroup(t, map[string]string{
+		"unified/pod1/memory.current": "104857600\n",
+		"unified/pod1/memory.max":     "1073741824\n",
+		"unified/pod1/memory.stat":    fmt.Sprintf(stat, 104857600, 0),
+	})
+	defer clean()
+	statFile := path.Join(path.Dir(bin.ProcPath), "unified/pod1/memory.stat")
+	memLock, memRate = true, 100
+	defer func() { memLock, memRate = false, 0 }()
+
+	// the locked memory is only in the unevictable lru, the burning must stop at 50% of the 1024M limit
+	locked := int64(0)
+	for i := 0; i < 100; i++ {
+		_, expectMem, err := calculateMemSizeFromCont(50, 0)
+		if err != nil {
+			t.Fatalf("calculate memory size err, %v", err)
+		}
+		if expectMem <= 0 {
+			break
+		}
+		locked += nextFillSize(expectMem)
+		content := fmt.Sprintf(stat, 104857600+locked*1024*1024, locked*1024*1024)
+		if err := ioutil.WriteFile(statFile, []byte(content), 0644); err != nil {
+			t.Fatalf("write memory.stat err, %v", err)
+		}
+	}
+	if locked > 412 || locked < 400 {
+		t.Errorf("unexpected locked memory: %d, expected: about 412", locked)
+	}
+}
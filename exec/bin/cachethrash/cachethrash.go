@@ -0,0 +1,294 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var thrashPattern, thrashCpuList string
+var thrashWorkingSet, thrashThreads int
+var thrashStart, thrashStop, thrashNohup bool
+
+func main() {
+	flag.IntVar(&thrashWorkingSet, "working-set", 0, "the total size of the buffers, unit is MB, 0 means twice the last level cache")
+	flag.IntVar(&thrashThreads, "threads", 0, "the number of threads, 0 means the count of the cpu list or all the cpus")
+	flag.StringVar(&thrashPattern, "pattern", "stream", "the access pattern, stream or random")
+	flag.StringVar(&thrashCpuList, "cpu-list", "", "CPUs in which the threads run (1,3)")
+	flag.BoolVar(&thrashStart, "start", false, "start cache thrash")
+	flag.BoolVar(&thrashStop, "stop", false, "stop cache thrash")
+	flag.BoolVar(&thrashNohup, "nohup", false, "nohup to run cache thrash")
+	bin.ParseFlagAndInitLog()
+
+	if thrashStart {
+		startThrash(thrashWorkingSet, thrashThreads, thrashPattern, thrashCpuList)
+	} else if thrashStop {
+		if success, errs := stopThrash(); !success {
+			bin.PrintErrAndExit(errs)
+		}
+	} else if thrashNohup {
+		config, err := newThrashConfig(thrashWorkingSet, thrashThreads, thrashPattern, thrashCpuList)
+		if err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+			return
+		}
+		if err := runThrash(context.Background(), config); err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+		}
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+var cacheThrashBin = exec.CacheThrashBin
+
+var cl = channel.NewLocalChannel()
+
+var thrashLogFile = util.GetNohupOutput(util.Bin, "chaos_cachethrash.log")
+
+// cacheSysPath is the cache information of the first cpu in sysfs
+var cacheSysPath = "/sys/devices/system/cpu/cpu0/cache"
+
+const (
+	// lineSize is the common cache line size, the buffers are accessed once every line
+	lineSize  = 64
+	lineWords = lineSize / 8
+	mb        = 1024 * 1024
+)
+
+// thrashConfig is the resolved settings of the threads, which is also reported on start
+type thrashConfig struct {
+	WorkingSet     int64  `json:"working_set"`
+	LastLevelCache int64  `json:"last_level_cache"`
+	Threads        int    `json:"threads"`
+	Pattern        string `json:"pattern"`
+	Cores          []int  `json:"cores,omitempty"`
+}
+
+func newThrashConfig(workingSet, threads int, pattern, cpuList string) (*thrashConfig, error) {
+	if pattern != "stream" && pattern != "random" {
+		return nil, fmt.Errorf("illegal pattern %s, it must be stream or random", pattern)
+	}
+	config := &thrashConfig{Pattern: pattern, Threads: threads, Cores: make([]int, 0)}
+	if cpuList != "" {
+		for _, c := range strings.Split(cpuList, ",") {
+			core, err := strconv.Atoi(c)
+			if err != nil {
+				return nil, fmt.Errorf("illegal cpu core %s", c)
+			}
+			config.Cores = append(config.Cores, core)
+		}
+	}
+	if config.Threads <= 0 {
+		config.Threads = len(config.Cores)
+		if config.Threads == 0 {
+			config.Threads = runtime.NumCPU()
+		}
+	}
+	config.LastLevelCache, _ = getLastLevelCache()
+	config.WorkingSet = int64(workingSet) * mb
+	if config.WorkingSet <= 0 {
+		config.WorkingSet = 2 * config.LastLevelCache
+		if config.WorkingSet <= 0 {
+			config.WorkingSet = 64 * mb
+		}
+	}
+	return config, nil
+}
+
+// getLastLevelCache returns the size of the highest level cache of the first cpu
+func getLastLevelCache() (int64, error) {
+	dirs, err := filepath.Glob(path.Join(cacheSysPath, "index*"))
+	if err != nil {
+		return 0, err
+	}
+	var maxLevel, size int64
+	for _, dir := range dirs {
+		levelBytes, err := ioutil.ReadFile(path.Join(dir, "level"))
+		if err != nil {
+			continue
+		}
+		sizeBytes, err := ioutil.ReadFile(path.Join(dir, "size"))
+		if err != nil {
+			continue
+		}
+		level, err := strconv.ParseInt(strings.TrimSpace(string(levelBytes)), 10, 64)
+		if err != nil || level < maxLevel {
+			continue
+		}
+		if s, err := parseCacheSize(strings.TrimSpace(string(sizeBytes))); err == nil {
+			maxLevel, size = level, s
+		}
+	}
+	if size == 0 {
+		return 0, fmt.Errorf("cache size not found in %s", cacheSysPath)
+	}
+	return size, nil
+}
+
+// parseCacheSize parses the size in sysfs, for example 32K or 2M
+func parseCacheSize(size string) (int64, error) {
+	unit := int64(1)
+	switch {
+	case strings.HasSuffix(size, "K"):
+		unit = 1024
+	case strings.HasSuffix(size, "M"):
+		unit = mb
+	}
+	value, err := strconv.ParseInt(strings.TrimRight(size, "KM"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("illegal cache size %s", size)
+	}
+	return value * unit, nil
+}
+
+func startThrash(workingSet, threads int, pattern, cpuList string) {
+	config, err := newThrashConfig(workingSet, threads, pattern, cpuList)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	ctx := context.Background()
+	args := fmt.Sprintf("%s --nohup --working-set %d --threads %d --pattern %s",
+		path.Join(util.GetProgramPath(), cacheThrashBin), config.WorkingSet/mb, config.Threads, pattern)
+	if cpuList != "" {
+		args = fmt.Sprintf("%s --cpu-list %s", args, cpuList)
+	}
+	response := cl.Run(ctx, "nohup", fmt.Sprintf("%s > %s 2>&1 &", args, thrashLogFile))
+	if !response.Success {
+		bin.PrintErrAndExit(response.Err)
+		return
+	}
+	// check
+	time.Sleep(time.Second)
+	response = cl.Run(ctx, "grep", fmt.Sprintf("%s %s", bin.ErrPrefix, thrashLogFile))
+	if response.Success {
+		errMsg := strings.TrimSpace(response.Result.(string))
+		if errMsg != "" {
+			stopThrash()
+			bin.PrintErrAndExit(errMsg)
+			return
+		}
+	}
+	bytes, _ := json.Marshal(config)
+	bin.PrintOutputAndExit(string(bytes))
+}
+
+// stopThrash kills the thrash process like stopping burn cpu
+func stopThrash() (success bool, errs string) {
+	ctx := context.WithValue(context.Background(), channel.ProcessKey, "nohup")
+	pids, _ := cl.GetPidsByProcessName(cacheThrashBin, ctx)
+	if len(pids) > 0 {
+		response := cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
+		if !response.Success {
+			return false, response.Err
+		}
+	}
+	cl.Run(ctx, "rm", fmt.Sprintf("-rf %s", thrashLogFile))
+	return true, errs
+}
+
+// runThrash runs the threads until the context is done, it returns the first error of setting up the threads
+func runThrash(ctx context.Context, config *thrashConfig) error {
+	runtime.GOMAXPROCS(config.Threads + 1)
+	lines := config.WorkingSet / lineSize / int64(config.Threads)
+	if lines < 2 {
+		lines = 2
+	}
+	errs := make(chan error, config.Threads)
+	for i := 0; i < config.Threads; i++ {
+		var cores []int
+		if len(config.Cores) > 0 {
+			cores = []int{config.Cores[i%len(config.Cores)]}
+		}
+		go func() {
+			if len(cores) > 0 {
+				runtime.LockOSThread()
+				if err := bin.SetAffinity(0, cores); err != nil {
+					errs <- err
+					return
+				}
+			}
+			buffer := newBuffer(lines, config.Pattern == "random")
+			for ctx.Err() == nil {
+				if config.Pattern == "random" {
+					chase(buffer)
+				} else {
+					stream(buffer)
+				}
+			}
+		}()
+	}
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// newBuffer allocates the buffer of the lines and touches all the pages. For the random pattern, the first word
+// of each line is the index of the next line in a random cycle through all the lines.
+func newBuffer(lines int64, random bool) []uint64 {
+	buffer := make([]uint64, lines*lineWords)
+	if !random {
+		for i := int64(0); i < lines; i++ {
+			buffer[i*lineWords] = uint64(i)
+		}
+		return buffer
+	}
+	order := rand.New(rand.NewSource(time.Now().UnixNano())).Perm(int(lines))
+	for i := range order {
+		next := order[(i+1)%len(order)]
+		buffer[int64(order[i])*lineWords] = uint64(next)
+	}
+	return buffer
+}
+
+// stream reads and writes every line of the buffer in order
+func stream(buffer []uint64) {
+	for i := 0; i < len(buffer); i += lineWords {
+		buffer[i+1]++
+	}
+}
+
+// chase follows the random cycle through all the lines and writes each line, every access depends on the previous one
+func chase(buffer []uint64) {
+	lines := uint64(len(buffer) / lineWords)
+	index := uint64(0)
+	for i := uint64(0); i < lines; i++ {
+		buffer[index*lineWords+1]++
+		index = buffer[index*lineWords]
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"path"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
+)
+
+func Test_getLastLevelCache(t *testing.T) {
+	dir, clean := bintest.NewRoot(t)
+	defer clean()
+	caches := map[string][2]string{
+		"index0": {"1", "48K"},
+		"index1": {"1", "32K"},
+		"index2": {"2", "2048K"},
+		"index3": {"3", "32M"},
+	}
+	for index, cache := range caches {
+		bintest.WriteFiles(t, dir, map[string]string{index + "/level": cache[0] + "\n", index + "/size": cache[1] + "\n"})
+	}
+	defer bintest.Replace(&cacheSysPath, dir)()
+
+	size, err := getLastLevelCache()
+	if err != nil || size != 32*mb {
+		t.Errorf("unexpected last level cache: %d, %v", size, err)
+	}
+	config, err := newThrashConfig(0, 0, "stream", "0,3")
+	if err != nil {
+		t.Fatalf("new config err, %v", err)
+	}
+	if config.WorkingSet != 64*mb || config.Threads != 2 || len(config.Cores) != 2 {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	cacheSysPath = path.Join(dir, "not-exist")
+	config, _ = newThrashConfig(0, 0, "random", "")
+	if config.WorkingSet != 64*mb || config.Threads != runtime.NumCPU() {
+		t.Errorf("unexpected config without the cache information: %+v", config)
+	}
+}
+
+func Test_newThrashConfig_failed(t *testing.T) {
+	if _, err := newThrashConfig(1, 1, "seq", ""); err == nil {
+		t.Errorf("expected err for the illegal pattern")
+	}
+	if _, err := newThrashConfig(1, 1, "stream", "0-3"); err == nil {
+		t.Errorf("expected err for the illegal cpu list")
+	}
+}
+
+func Test_newBuffer_random(t *testing.T) {
+	lines := int64(1000)
+	buffer := newBuffer(lines, true)
+	// the random order is a cycle through all the lines
+	visited := make(map[uint64]bool)
+	index := uint64(0)
+	for i := int64(0); i < lines; i++ {
+		if visited[index] {
+			t.Fatalf("the line %d is visited twice", index)
+		}
+		visited[index] = true
+		index = buffer[index*lineWords]
+	}
+	if index != 0 || len(visited) != int(lines) {
+		t.Errorf("unexpected cycle, visited: %d, last index: %d", len(visited), index)
+	}
+	chase(buffer)
+	stream(buffer)
+	for i := int64(0); i < lines; i++ {
+		if buffer[i*lineWords+1] != 2 {
+			t.Fatalf("unexpected access count of the line %d: %d", i, buffer[i*lineWords+1])
+		}
+	}
+}
+
+func Test_runThrash(t *testing.T) {
+	for _, pattern := range []string{"stream", "random"} {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		config := &thrashConfig{WorkingSet: 4 * mb, Threads: 2, Pattern: pattern, Cores: []int{0}}
+		if err := runThrash(ctx, config); err != nil {
+			t.Errorf("run %s thrash err, %v", pattern, err)
+		}
+		cancel()
+	}
+	config := &thrashConfig{WorkingSet: mb, Threads: 1, Pattern: "stream", Cores: []int{-1}}
+	if err := runThrash(context.Background(), config); err == nil {
+		t.Errorf("expected err for the illegal core")
+	}
+}
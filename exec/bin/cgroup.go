@@ -0,0 +1,495 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcPath is the mount point of procfs, it is replaced by the fake proc tree in tests
+var ProcPath = "/proc"
+
+const (
+	CgroupV1 = 1
+	CgroupV2 = 2
+)
+
+// Cgroup is the cgroup of a workload, which contains the directories of the controllers
+type Cgroup struct {
+	Version int
+	// Dirs is the absolute directory of each controller, all the controllers share the same directory in v2
+	Dirs map[string]string
+}
+
+// cgroupMounts is the mount points of the cgroup hierarchies
+type cgroupMounts struct {
+	v1 map[string]string
+	v2 string
+	// the roots of the mounts, which are not / if the cgroups of a container are bind mounted into it
+	v1Roots map[string]string
+	v2Root  string
+}
+
+func getCgroupMounts() (*cgroupMounts, error) {
+	file, err := os.Open(path.Join(ProcPath, "self/mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	mounts := &cgroupMounts{v1: make(map[string]string), v1Roots: make(map[string]string)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 33 32 0:29 / /sys/fs/cgroup/cpu rw,relatime - cgroup cgroup rw,cpu
+		fields := strings.Fields(scanner.Text())
+		separator := -1
+		for i, field := range fields {
+			if field == "-" {
+				separator = i
+				break
+			}
+		}
+		if separator < 5 || len(fields) < separator+4 {
+			continue
+		}
+		mountRoot, mountPoint := fields[3], fields[4]
+		switch fields[separator+1] {
+		case "cgroup2":
+			mounts.v2, mounts.v2Root = mountPoint, mountRoot
+		case "cgroup":
+			for _, option := range strings.Split(fields[separator+3], ",") {
+				mounts.v1[option], mounts.v1Roots[option] = mountPoint, mountRoot
+			}
+		}
+	}
+	return mounts, scanner.Err()
+}
+
+// v2Enabled returns true if the controllers are all enabled in the cgroup2 hierarchy
+func (m *cgroupMounts) v2Enabled(controllers []string) bool {
+	if m.v2 == "" {
+		return false
+	}
+	bytes, err := ioutil.ReadFile(path.Join(m.v2, "cgroup.controllers"))
+	if err != nil {
+		return false
+	}
+	enabled := strings.Fields(string(bytes))
+	for _, controller := range controllers {
+		found := false
+		for _, e := range enabled {
+			if e == controller {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *cgroupMounts) v1Enabled(controllers []string) bool {
+	for _, controller := range controllers {
+		if _, ok := m.v1[controller]; !ok {
+			return false
+		}
+	}
+	return len(controllers) > 0
+}
+
+// GetCgroupByPid returns the cgroup of the process with the controllers, the v1 hierarchies are preferred
+// if the controllers are bound to them in hybrid mode
+func GetCgroupByPid(pid int, controllers ...string) (*Cgroup, error) {
+	mounts, err := getCgroupMounts()
+	if err != nil {
+		return nil, fmt.Errorf("get cgroup mounts failed, %v", err)
+	}
+	bytes, err := ioutil.ReadFile(path.Join(ProcPath, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return nil, fmt.Errorf("get cgroup of %d failed, %v", pid, err)
+	}
+	v1Paths := make(map[string]string)
+	var v2Path string
+	for _, line := range strings.Split(strings.TrimSpace(string(bytes)), "\n") {
+		// 4:cpu,cpuacct:/docker/1234 or 0::/kubepods/pod1234
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			v2Path = parts[2]
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			v1Paths[controller] = parts[2]
+		}
+	}
+	if mounts.v1Enabled(controllers) {
+		cgroup := &Cgroup{Version: CgroupV1, Dirs: make(map[string]string)}
+		for _, controller := range controllers {
+			cgroupPath, ok := v1Paths[controller]
+			if !ok {
+				return nil, fmt.Errorf("%s controller not found in the cgroup of %d", controller, pid)
+			}
+			cgroup.Dirs[controller] = path.Join(mounts.v1[controller], trimMountRoot(mounts.v1Roots[controller], cgroupPath))
+		}
+		return cgroup, nil
+	}
+	if v2Path != "" && mounts.v2Enabled(controllers) {
+		return newCgroupV2(path.Join(mounts.v2, trimMountRoot(mounts.v2Root, v2Path)), controllers), nil
+	}
+	return nil, fmt.Errorf("%v controllers not found in the cgroup of %d", controllers, pid)
+}
+
+// GetCgroupByPath returns the cgroup with the controllers by the path, the path is either the absolute directory
+// of the cgroup, for example /sys/fs/cgroup/cpu/docker/1234, or the path relative to the cgroup hierarchy,
+// for example /docker/1234
+func GetCgroupByPath(cgroupPath string, controllers ...string) (*Cgroup, error) {
+	mounts, err := getCgroupMounts()
+	if err != nil {
+		return nil, fmt.Errorf("get cgroup mounts failed, %v", err)
+	}
+	cgroupPath = filepath.Clean(cgroupPath)
+	if mounts.v2 != "" && isSubPath(mounts.v2, cgroupPath) {
+		if !mounts.v2Enabled(controllers) {
+			return nil, fmt.Errorf("%v controllers are not enabled in %s", controllers, mounts.v2)
+		}
+		return checkCgroup(newCgroupV2(cgroupPath, controllers))
+	}
+	relativePath := cgroupPath
+	for _, mountPoint := range mounts.v1 {
+		if isSubPath(mountPoint, cgroupPath) {
+			relativePath, _ = filepath.Rel(mountPoint, cgroupPath)
+			relativePath = "/" + relativePath
+			break
+		}
+	}
+	if mounts.v1Enabled(controllers) {
+		cgroup := &Cgroup{Version: CgroupV1, Dirs: make(map[string]string)}
+		for _, controller := range controllers {
+			cgroup.Dirs[controller] = path.Join(mounts.v1[controller], relativePath)
+		}
+		return checkCgroup(cgroup)
+	}
+	if mounts.v2Enabled(controllers) {
+		return checkCgroup(newCgroupV2(path.Join(mounts.v2, relativePath), controllers))
+	}
+	return nil, fmt.Errorf("%v controllers not found for %s", controllers, cgroupPath)
+}
+
+// trimMountRoot returns the path relative to the root of the mount, for example the cgroup /docker/1234 is
+// mounted at /sys/fs/cgroup/memory in the container, whose root is /docker/1234
+func trimMountRoot(mountRoot, cgroupPath string) string {
+	if mountRoot == "" || mountRoot == "/" || !isSubPath(mountRoot, cgroupPath) {
+		return cgroupPath
+	}
+	return "/" + strings.TrimPrefix(strings.TrimPrefix(cgroupPath, mountRoot), "/")
+}
+
+func isSubPath(parent, child string) bool {
+	return child == parent || strings.HasPrefix(child, strings.TrimSuffix(parent, "/")+"/")
+}
+
+func newCgroupV2(dir string, controllers []string) *Cgroup {
+	cgroup := &Cgroup{Version: CgroupV2, Dirs: make(map[string]string)}
+	for _, controller := range controllers {
+		cgroup.Dirs[controller] = dir
+	}
+	return cgroup
+}
+
+func checkCgroup(cgroup *Cgroup) (*Cgroup, error) {
+	for _, dir := range cgroup.Dirs {
+		if _, err := os.Stat(path.Join(dir, "cgroup.procs")); err != nil {
+			return nil, fmt.Errorf("illegal cgroup directory %s, %v", dir, err)
+		}
+	}
+	return cgroup, nil
+}
+
+// GetCgroup returns the cgroup by the path if it's not empty, otherwise by the pid
+func GetCgroup(cgroupPath string, pid int, controllers ...string) (*Cgroup, error) {
+	if cgroupPath != "" {
+		return GetCgroupByPath(cgroupPath, controllers...)
+	}
+	return GetCgroupByPid(pid, controllers...)
+}
+
+// AddProcess moves the process into the cgroup of all the controllers
+func (c *Cgroup) AddProcess(pid int) error {
+	added := make(map[string]bool)
+	for _, dir := range c.Dirs {
+		if added[dir] {
+			continue
+		}
+		if err := ioutil.WriteFile(path.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+			return fmt.Errorf("add %d to cgroup %s failed, %v", pid, dir, err)
+		}
+		added[dir] = true
+	}
+	return nil
+}
+
+// ReadFile returns the trimmed content of the file of the controller
+func (c *Cgroup) ReadFile(controller, name string) (string, error) {
+	dir, ok := c.Dirs[controller]
+	if !ok {
+		return "", fmt.Errorf("%s controller not found in the cgroup", controller)
+	}
+	bytes, err := ioutil.ReadFile(path.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(bytes)), nil
+}
+
+// WriteFile writes the value to the file of the controller
+func (c *Cgroup) WriteFile(controller, name, value string) error {
+	dir, ok := c.Dirs[controller]
+	if !ok {
+		return fmt.Errorf("%s controller not found in the cgroup", controller)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, name), []byte(value), 0644); err != nil {
+		return fmt.Errorf("write %s to %s failed, %v", value, path.Join(dir, name), err)
+	}
+	return nil
+}
+
+// ReadKeyedFile returns the value of the key in the flat keyed file, for example cpu.stat or memory.stat
+func (c *Cgroup) ReadKeyedFile(controller, name, key string) (uint64, error) {
+	content, err := c.ReadFile(controller, name)
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("%s not found in %s", key, name)
+}
+
+// CpuControllers are the controllers needed by the cpu usage and quota, cpuacct is only used in v1
+func CpuControllers(version int) []string {
+	if version == CgroupV2 {
+		return []string{"cpu"}
+	}
+	return []string{"cpu", "cpuacct"}
+}
+
+// GetCpuCgroup returns the cgroup with the cpu controllers by the path or the pid
+func GetCpuCgroup(cgroupPath string, pid int) (*Cgroup, error) {
+	cgroup, err := GetCgroup(cgroupPath, pid, CpuControllers(CgroupV1)...)
+	if err == nil {
+		return cgroup, nil
+	}
+	if cgroup, v2Err := GetCgroup(cgroupPath, pid, CpuControllers(CgroupV2)...); v2Err == nil && cgroup.Version == CgroupV2 {
+		return cgroup, nil
+	}
+	return nil, err
+}
+
+// CpuUsage returns the total cpu time used by the cgroup
+func (c *Cgroup) CpuUsage() (time.Duration, error) {
+	if c.Version == CgroupV2 {
+		usage, err := c.ReadKeyedFile("cpu", "cpu.stat", "usage_usec")
+		return time.Duration(usage) * time.Microsecond, err
+	}
+	content, err := c.ReadFile("cpuacct", "cpuacct.usage")
+	if err != nil {
+		return 0, err
+	}
+	usage, err := strconv.ParseUint(content, 10, 64)
+	return time.Duration(usage), err
+}
+
+// CpuQuota returns the quota and the period of the cgroup, the quota is -1 if it is unlimited
+func (c *Cgroup) CpuQuota() (quota int64, period uint64, err error) {
+	if c.Version == CgroupV2 {
+		// max 100000 or 50000 100000
+		content, err := c.ReadFile("cpu", "cpu.max")
+		if err != nil {
+			return 0, 0, err
+		}
+		fields := strings.Fields(content)
+		if len(fields) != 2 {
+			return 0, 0, fmt.Errorf("illegal cpu.max %s", content)
+		}
+		if period, err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+			return 0, 0, err
+		}
+		if fields[0] == "max" {
+			return -1, period, nil
+		}
+		quota, err = strconv.ParseInt(fields[0], 10, 64)
+		return quota, period, err
+	}
+	content, err := c.ReadFile("cpu", "cpu.cfs_quota_us")
+	if err != nil {
+		return 0, 0, err
+	}
+	if quota, err = strconv.ParseInt(content, 10, 64); err != nil {
+		return 0, 0, err
+	}
+	content, err = c.ReadFile("cpu", "cpu.cfs_period_us")
+	if err != nil {
+		return 0, 0, err
+	}
+	period, err = strconv.ParseUint(content, 10, 64)
+	return quota, period, err
+}
+
+// SetCpuQuota sets the quota and the period of the cgroup, the quota is -1 means unlimited
+func (c *Cgroup) SetCpuQuota(quota int64, period uint64) error {
+	if c.Version == CgroupV2 {
+		value := "max"
+		if quota > 0 {
+			value = strconv.FormatInt(quota, 10)
+		}
+		return c.WriteFile("cpu", "cpu.max", fmt.Sprintf("%s %d", value, period))
+	}
+	if err := c.WriteFile("cpu", "cpu.cfs_period_us", strconv.FormatUint(period, 10)); err != nil {
+		return err
+	}
+	return c.WriteFile("cpu", "cpu.cfs_quota_us", strconv.FormatInt(quota, 10))
+}
+
+// CpuQuotaCores returns the cpu cores the cgroup can use, the numCpu is returned if the quota is unlimited
+func (c *Cgroup) CpuQuotaCores(numCpu int) float64 {
+	quota, period, err := c.CpuQuota()
+	if err != nil || quota <= 0 || period == 0 {
+		return float64(numCpu)
+	}
+	cores := float64(quota) / float64(period)
+	if cores > float64(numCpu) {
+		return float64(numCpu)
+	}
+	return cores
+}
+
+// CpuPercent returns the cpu usage of the cgroup in the interval, relative to the cores it can use
+func (c *Cgroup) CpuPercent(interval time.Duration, numCpu int) (float64, error) {
+	startUsage, err := c.CpuUsage()
+	if err != nil {
+		return 0, err
+	}
+	startTime := time.Now()
+	time.Sleep(interval)
+	endUsage, err := c.CpuUsage()
+	if err != nil {
+		return 0, err
+	}
+	elapsed := time.Since(startTime)
+	cores := c.CpuQuotaCores(numCpu)
+	if elapsed <= 0 || cores <= 0 || endUsage < startUsage {
+		return 0, nil
+	}
+	return float64(endUsage-startUsage) / float64(elapsed) / cores * 100, nil
+}
+
+// Root returns the root cgroup of the hierarchies which the cgroup belongs to
+func (c *Cgroup) Root() (*Cgroup, error) {
+	mounts, err := getCgroupMounts()
+	if err != nil {
+		return nil, fmt.Errorf("get cgroup mounts failed, %v", err)
+	}
+	root := &Cgroup{Version: c.Version, Dirs: make(map[string]string)}
+	for controller := range c.Dirs {
+		mountPoint := mounts.v2
+		if c.Version == CgroupV1 {
+			mountPoint = mounts.v1[controller]
+		}
+		if mountPoint == "" {
+			return nil, fmt.Errorf("%s controller is not mounted", controller)
+		}
+		root.Dirs[controller] = mountPoint
+	}
+	return root, nil
+}
+
+// IsRoot returns true if the cgroup is the root of all its hierarchies, which cannot be limited
+func (c *Cgroup) IsRoot() bool {
+	root, err := c.Root()
+	if err != nil {
+		return false
+	}
+	for controller, dir := range c.Dirs {
+		if filepath.Clean(dir) != filepath.Clean(root.Dirs[controller]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Path returns the path of the cgroup in the hierarchy of the controller, as shown in /proc/<pid>/cgroup and
+// the kernel log, for example /docker/1234
+func (c *Cgroup) Path(controller string) (string, error) {
+	mounts, err := getCgroupMounts()
+	if err != nil {
+		return "", fmt.Errorf("get cgroup mounts failed, %v", err)
+	}
+	mountPoint, mountRoot := mounts.v2, mounts.v2Root
+	if c.Version == CgroupV1 {
+		mountPoint, mountRoot = mounts.v1[controller], mounts.v1Roots[controller]
+	}
+	dir, ok := c.Dirs[controller]
+	if !ok || mountPoint == "" || !isSubPath(mountPoint, dir) {
+		return "", fmt.Errorf("%s controller is not mounted for the cgroup %v", controller, c.Dirs)
+	}
+	relativePath, _ := filepath.Rel(mountPoint, dir)
+	if mountRoot == "" {
+		mountRoot = "/"
+	}
+	return path.Join(mountRoot, relativePath), nil
+}
+
+// NewChild creates the child cgroup with the name, the controllers are enabled for the children in v2
+func (c *Cgroup) NewChild(name string) (*Cgroup, error) {
+	child := &Cgroup{Version: c.Version, Dirs: make(map[string]string)}
+	for controller, dir := range c.Dirs {
+		if c.Version == CgroupV2 {
+			// the controller may be enabled already, so the error is ignored and checked by the files of the child
+			ioutil.WriteFile(path.Join(dir, "cgroup.subtree_control"), []byte("+"+controller), 0644)
+		}
+		childDir := path.Join(dir, name)
+		if err := os.Mkdir(childDir, 0755); err != nil && !os.IsExist(err) {
+			return nil, fmt.Errorf("create cgroup %s failed, %v", childDir, err)
+		}
+		child.Dirs[controller] = childDir
+	}
+	return child, nil
+}
+
+// Remove removes the directories of the cgroup, the processes in it must be moved out before
+func (c *Cgroup) Remove() error {
+	for _, dir := range c.Dirs {
+		if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove cgroup %s failed, %v", dir, err)
+		}
+	}
+	return nil
+}
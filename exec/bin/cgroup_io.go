@@ -0,0 +1,137 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IoLimit is the io throttling of a device in a cgroup, 0 means unlimited
+type IoLimit struct {
+	ReadBps   uint64 `json:"rbps"`
+	WriteBps  uint64 `json:"wbps"`
+	ReadIops  uint64 `json:"riops"`
+	WriteIops uint64 `json:"wiops"`
+}
+
+// ioLimitKeys are the keys in io.max of v2, and the suffixes of the blkio.throttle files of v1
+var ioLimitKeys = []struct {
+	v2, v1 string
+}{
+	{"rbps", "read_bps_device"},
+	{"wbps", "write_bps_device"},
+	{"riops", "read_iops_device"},
+	{"wiops", "write_iops_device"},
+}
+
+func (l *IoLimit) values() []*uint64 {
+	return []*uint64{&l.ReadBps, &l.WriteBps, &l.ReadIops, &l.WriteIops}
+}
+
+// IoController returns the io controller of the cgroup version, blkio in v1 and io in v2
+func IoController(version int) string {
+	if version == CgroupV2 {
+		return "io"
+	}
+	return "blkio"
+}
+
+// GetIoCgroup returns the cgroup with the io controller by the path or the pid
+func GetIoCgroup(cgroupPath string, pid int) (*Cgroup, error) {
+	cgroup, err := GetCgroup(cgroupPath, pid, IoController(CgroupV1))
+	if err == nil {
+		return cgroup, nil
+	}
+	if cgroup, v2Err := GetCgroup(cgroupPath, pid, IoController(CgroupV2)); v2Err == nil && cgroup.Version == CgroupV2 {
+		return cgroup, nil
+	}
+	return nil, err
+}
+
+// IoLimit returns the io throttling of the device in the cgroup, which is read from io.max in v2,
+// or from blkio.throttle.read_bps_device and the other throttle files in v1
+func (c *Cgroup) IoLimit(device string) (*IoLimit, error) {
+	limit := &IoLimit{}
+	values := limit.values()
+	if c.Version == CgroupV2 {
+		// 8:0 rbps=1048576 wbps=max riops=max wiops=max
+		content, err := c.ReadFile("io", "io.max")
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(content, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 || fields[0] != device {
+				continue
+			}
+			for _, field := range fields[1:] {
+				pair := strings.SplitN(field, "=", 2)
+				if len(pair) != 2 || pair[1] == "max" {
+					continue
+				}
+				for i, key := range ioLimitKeys {
+					if key.v2 == pair[0] {
+						if *values[i], err = strconv.ParseUint(pair[1], 10, 64); err != nil {
+							return nil, fmt.Errorf("illegal io.max %s, %v", line, err)
+						}
+					}
+				}
+			}
+		}
+		return limit, nil
+	}
+	for i, key := range ioLimitKeys {
+		// 8:0 1048576
+		content, err := c.ReadFile("blkio", "blkio.throttle."+key.v1)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(content, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && fields[0] == device {
+				if *values[i], err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+					return nil, fmt.Errorf("illegal blkio.throttle.%s %s, %v", key.v1, line, err)
+				}
+			}
+		}
+	}
+	return limit, nil
+}
+
+// SetIoLimit sets the io throttling of the device in the cgroup, the zero values remove the limits
+func (c *Cgroup) SetIoLimit(device string, limit *IoLimit) error {
+	values := limit.values()
+	if c.Version == CgroupV2 {
+		settings := []string{device}
+		for i, key := range ioLimitKeys {
+			value := "max"
+			if *values[i] > 0 {
+				value = strconv.FormatUint(*values[i], 10)
+			}
+			settings = append(settings, fmt.Sprintf("%s=%s", key.v2, value))
+		}
+		return c.WriteFile("io", "io.max", strings.Join(settings, " "))
+	}
+	for i, key := range ioLimitKeys {
+		if err := c.WriteFile("blkio", "blkio.throttle."+key.v1, fmt.Sprintf("%s %d", device, *values[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
+)
+
+func Test_IoLimit_v1(t *testing.T) {
+	root, clean := newFakeCgroupfs(t, "")
+	defer clean()
+	mountinfo := fmt.Sprintf("36 25 0:35 / %s/blkio rw,nosuid - cgroup cgroup rw,blkio\n", root)
+	bintest.WriteFiles(t, root, map[string]string{
+		"proc/self/mountinfo":                               mountinfo,
+		"proc/100/cgroup":                                   "6:blkio:/docker/abc\n",
+		"blkio/docker/abc/cgroup.procs":                     "",
+		"blkio/docker/abc/blkio.throttle.read_bps_device":   "8:0 1048576\n7:0 2097152\n",
+		"blkio/docker/abc/blkio.throttle.write_bps_device":  "",
+		"blkio/docker/abc/blkio.throttle.read_iops_device":  "",
+		"blkio/docker/abc/blkio.throttle.write_iops_device": "7:0 100\n",
+	})
+
+	cgroup, err := GetIoCgroup("", 100)
+	if err != nil {
+		t.Fatalf("get io cgroup err, %v", err)
+	}
+	limit, err := cgroup.IoLimit("7:0")
+	if err != nil {
+		t.Fatalf("get io limit err, %v", err)
+	}
+	if expected := (IoLimit{ReadBps: 2097152, WriteIops: 100}); *limit != expected {
+		t.Errorf("unexpected io limit: %+v, expected: %+v", *limit, expected)
+	}
+	if err := cgroup.SetIoLimit("7:0", &IoLimit{WriteBps: 1048576}); err != nil {
+		t.Fatalf("set io limit err, %v", err)
+	}
+	expected := map[string]string{
+		"read_bps_device":   "7:0 0",
+		"write_bps_device":  "7:0 1048576",
+		"read_iops_device":  "7:0 0",
+		"write_iops_device": "7:0 0",
+	}
+	for name, value := range expected {
+		bytes, _ := ioutil.ReadFile(path.Join(root, "blkio/docker/abc/blkio.throttle."+name))
+		if string(bytes) != value {
+			t.Errorf("unexpected blkio.throttle.%s: %s, expected: %s", name, bytes, value)
+		}
+	}
+}
+
+func Test_IoLimit_v2(t *testing.T) {
+	root, clean := newFakeCgroupfs(t, "cpu io memory")
+	defer clean()
+	mountinfo := fmt.Sprintf("35 25 0:31 / %s/unified rw,nosuid - cgroup2 cgroup2 rw\n", root)
+	bintest.WriteFiles(t, root, map[string]string{
+		"proc/self/mountinfo":          mountinfo,
+		"unified/kubepods/pod1/io.max": "8:0 rbps=max wbps=max riops=200 wiops=max\n7:0 rbps=1048576 wbps=max riops=max wiops=50\n",
+	})
+
+	cgroup, err := GetIoCgroup("", 100)
+	if err != nil {
+		t.Fatalf("get io cgroup err, %v", err)
+	}
+	if cgroup.Version != CgroupV2 {
+		t.Fatalf("unexpected cgroup version %d", cgroup.Version)
+	}
+	limit, err := cgroup.IoLimit("7:0")
+	if err != nil {
+		t.Fatalf("get io limit err, %v", err)
+	}
+	if expected := (IoLimit{ReadBps: 1048576, WriteIops: 50}); *limit != expected {
+		t.Errorf("unexpected io limit: %+v, expected: %+v", *limit, expected)
+	}
+	if limit, _ := cgroup.IoLimit("253:0"); *limit != (IoLimit{}) {
+		t.Errorf("unexpected io limit of the unlimited device: %+v", *limit)
+	}
+	if err := cgroup.SetIoLimit("7:0", &IoLimit{ReadIops: 10}); err != nil {
+		t.Fatalf("set io limit err, %v", err)
+	}
+	bytes, _ := ioutil.ReadFile(path.Join(root, "unified/kubepods/pod1/io.max"))
+	if expected := "7:0 rbps=max wbps=max riops=10 wiops=max"; string(bytes) != expected {
+		t.Errorf("unexpected io.max: %s, expected: %s", bytes, expected)
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// MemoryStat is the memory usage of a cgroup, the unit is byte
+type MemoryStat struct {
+	Usage uint64
+	// Limit is math.MaxUint64 if the memory of the cgroup is unlimited
+	Limit        uint64
+	Cache        uint64
+	ActiveAnon   uint64
+	InactiveAnon uint64
+	// Unevictable is the memory on the unevictable lru, for example the pages locked by mlock, which is not
+	// counted in ActiveAnon and InactiveAnon
+	Unevictable uint64
+}
+
+// GetMemoryCgroup returns the cgroup with the memory controller by the path or the pid
+func GetMemoryCgroup(cgroupPath string, pid int) (*Cgroup, error) {
+	return GetCgroup(cgroupPath, pid, "memory")
+}
+
+// MemoryStat reads the usage and the limit from memory.usage_in_bytes and memory.limit_in_bytes in v1,
+// or from memory.current and memory.max in v2, and the details from memory.stat
+func (c *Cgroup) MemoryStat() (*MemoryStat, error) {
+	usageFile, limitFile, cacheKey := "memory.usage_in_bytes", "memory.limit_in_bytes", "cache"
+	if c.Version == CgroupV2 {
+		usageFile, limitFile, cacheKey = "memory.current", "memory.max", "file"
+	}
+	stat := &MemoryStat{}
+	var err error
+	if stat.Usage, err = c.readMemoryValue(usageFile); err != nil {
+		return nil, err
+	}
+	if stat.Limit, err = c.readMemoryValue(limitFile); err != nil {
+		return nil, err
+	}
+	if stat.Cache, err = c.ReadKeyedFile("memory", "memory.stat", cacheKey); err != nil {
+		return nil, err
+	}
+	if stat.ActiveAnon, err = c.ReadKeyedFile("memory", "memory.stat", "active_anon"); err != nil {
+		return nil, err
+	}
+	if stat.InactiveAnon, err = c.ReadKeyedFile("memory", "memory.stat", "inactive_anon"); err != nil {
+		return nil, err
+	}
+	// unevictable is missing in the kernels without the unevictable lru, 0 is used for them
+	stat.Unevictable, _ = c.ReadKeyedFile("memory", "memory.stat", "unevictable")
+	return stat, nil
+}
+
+// readMemoryValue reads the bytes in the memory file, max means unlimited in v2
+func (c *Cgroup) readMemoryValue(name string) (uint64, error) {
+	content, err := c.ReadFile("memory", name)
+	if err != nil {
+		return 0, err
+	}
+	if content == "max" {
+		return math.MaxUint64, nil
+	}
+	return strconv.ParseUint(content, 10, 64)
+}
+
+// pageCounterMax is the limit of the unlimited memory cgroup in v1, which is rounded down to the page size
+const pageCounterMax uint64 = 9223372036854770000
+
+// Unlimited returns true if the memory of the cgroup is not limited
+func (s *MemoryStat) Unlimited() bool {
+	return s.Limit >= pageCounterMax
+}
+
+// OomKillCount returns the number of the processes killed by the oom killer in the cgroup, which is read from
+// memory.events in v2, or memory.oom_control in v1 since linux 4.13
+func (c *Cgroup) OomKillCount() (uint64, error) {
+	if c.Version == CgroupV2 {
+		return c.ReadKeyedFile("memory", "memory.events", "oom_kill")
+	}
+	return c.ReadKeyedFile("memory", "memory.oom_control", "oom_kill")
+}
+
+// MemoryHigh returns the memory.high of the cgroup, which is only supported in v2
+func (c *Cgroup) MemoryHigh() (uint64, error) {
+	if c.Version != CgroupV2 {
+		return 0, fmt.Errorf("memory.high is only supported in cgroup v2")
+	}
+	return c.readMemoryValue("memory.high")
+}
+
+// SetMemoryHigh sets the memory.high of the cgroup, math.MaxUint64 means max
+func (c *Cgroup) SetMemoryHigh(value uint64) error {
+	if c.Version != CgroupV2 {
+		return fmt.Errorf("memory.high is only supported in cgroup v2")
+	}
+	if value == math.MaxUint64 {
+		return c.WriteFile("memory", "memory.high", "max")
+	}
+	return c.WriteFile("memory", "memory.high", strconv.FormatUint(value, 10))
+}
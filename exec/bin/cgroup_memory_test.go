@@ -0,0 +1,124 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
+)
+
+func Test_MemoryStat_v1(t *testing.T) {
+	root, clean := newFakeCgroupfs(t, "")
+	defer clean()
+	bintest.WriteFiles(t, root, map[string]string{
+		"memory/docker/abc/cgroup.procs":          "",
+		"memory/docker/abc/memory.usage_in_bytes": "314572800\n",
+		"memory/docker/abc/memory.limit_in_bytes": "1073741824\n",
+		"memory/docker/abc/memory.stat":           "cache 104857600\nrss 209715200\nactive_anon 157286400\ninactive_anon 52428800\nunevictable 4194304\n",
+	})
+
+	cgroup, err := GetMemoryCgroup("", 100)
+	if err != nil {
+		t.Fatalf("get memory cgroup err, %v", err)
+	}
+	stat, err := cgroup.MemoryStat()
+	if err != nil {
+		t.Fatalf("get memory stat err, %v", err)
+	}
+	expected := MemoryStat{Usage: 314572800, Limit: 1073741824, Cache: 104857600, ActiveAnon: 157286400, InactiveAnon: 52428800, Unevictable: 4194304}
+	if *stat != expected {
+		t.Errorf("unexpected memory stat: %+v, expected: %+v", *stat, expected)
+	}
+}
+
+func Test_MemoryStat_v2(t *testing.T) {
+	root, clean := newFakeCgroupfs(t, "cpu memory")
+	defer clean()
+	mountinfo := fmt.Sprintf("35 25 0:31 / %s/unified rw,nosuid - cgroup2 cgroup2 rw\n", root)
+	bintest.WriteFiles(t, root, map[string]string{
+		"proc/self/mountinfo":                  mountinfo,
+		"unified/kubepods/pod1/memory.current": "536870912\n",
+		"unified/kubepods/pod1/memory.max":     "max\n",
+		"unified/kubepods/pod1/memory.stat":    "anon 402653184\nfile 134217728\nactive_anon 268435456\ninactive_anon 134217728\n",
+		"unified/kubepods/pod2/cgroup.procs":   "",
+		"unified/kubepods/pod2/memory.current": "1048576\n",
+		"unified/kubepods/pod2/memory.max":     "268435456\n",
+		"unified/kubepods/pod2/memory.stat":    "anon 1048576\nfile 0\nactive_anon 1048576\ninactive_anon 0\n",
+	})
+
+	cgroup, err := GetMemoryCgroup("", 100)
+	if err != nil {
+		t.Fatalf("get memory cgroup err, %v", err)
+	}
+	stat, err := cgroup.MemoryStat()
+	if err != nil {
+		t.Fatalf("get memory stat err, %v", err)
+	}
+	expected := MemoryStat{Usage: 536870912, Limit: math.MaxUint64, Cache: 134217728, ActiveAnon: 268435456, InactiveAnon: 134217728}
+	if *stat != expected {
+		t.Errorf("unexpected memory stat: %+v, expected: %+v", *stat, expected)
+	}
+
+	cgroup, err = GetMemoryCgroup("/kubepods/pod2", 0)
+	if err != nil {
+		t.Fatalf("get memory cgroup by path err, %v", err)
+	}
+	if stat, err = cgroup.MemoryStat(); err != nil || stat.Limit != 268435456 || stat.Usage != 1048576 {
+		t.Errorf("unexpected memory stat: %+v, %v", stat, err)
+	}
+}
+
+func Test_GetMemoryCgroup_container(t *testing.T) {
+	root, clean := newFakeCgroupfs(t, "")
+	defer clean()
+	// the cgroup of the container is bind mounted at the root of the hierarchy
+	mountinfo := fmt.Sprintf("34 25 0:30 /docker/abc %s/memory rw,nosuid - cgroup cgroup rw,memory\n", root)
+	bintest.WriteFiles(t, root, map[string]string{
+		"proc/self/mountinfo":          mountinfo,
+		"memory/cgroup.procs":          "",
+		"memory/memory.limit_in_bytes": "1073741824\n",
+	})
+
+	cgroup, err := GetMemoryCgroup("", 100)
+	if err != nil {
+		t.Fatalf("get memory cgroup err, %v", err)
+	}
+	if cgroup.Dirs["memory"] != path.Join(root, "memory") {
+		t.Errorf("unexpected memory cgroup: %+v, expected: %s", cgroup.Dirs, path.Join(root, "memory"))
+	}
+}
+
+func Test_MemoryStat_failed(t *testing.T) {
+	root, clean := newFakeCgroupfs(t, "")
+	defer clean()
+	bintest.WriteFiles(t, root, map[string]string{
+		"memory/docker/abc/cgroup.procs":          "",
+		"memory/docker/abc/memory.usage_in_bytes": "314572800\n",
+	})
+
+	cgroup, err := GetMemoryCgroup("", 100)
+	if err != nil {
+		t.Fatalf("get memory cgroup err, %v", err)
+	}
+	if _, err := cgroup.MemoryStat(); err == nil {
+		t.Errorf("expected err for the memory limit not exists")
+	}
+}
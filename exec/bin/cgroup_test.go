@@ -0,0 +1,176 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
+)
+
+// newFakeCgroupfs creates the fake proc and cgroup trees for the process 100, the v1 cpu and cpuacct controllers
+// are mounted at <root>/cpu,cpuacct and the cgroup2 is mounted at <root>/unified
+func newFakeCgroupfs(t *testing.T, v2Controllers string) (string, func()) {
+	root, clean := bintest.NewRoot(t)
+	mountinfo := fmt.Sprintf(`25 20 0:22 / /sys rw,nosuid - sysfs sysfs rw
+33 25 0:29 / %[1]s/cpu,cpuacct rw,nosuid,nodev,noexec,relatime shared:7 - cgroup cgroup rw,cpu,cpuacct
+34 25 0:30 / %[1]s/memory rw,nosuid,nodev,noexec,relatime shared:8 - cgroup cgroup rw,memory
+35 25 0:31 / %[1]s/unified rw,nosuid,nodev,noexec,relatime shared:9 - cgroup2 cgroup2 rw
+`, root)
+	files := map[string]string{
+		"proc/self/mountinfo":                      mountinfo,
+		"proc/100/cgroup":                          "5:cpu,cpuacct:/docker/abc\n4:memory:/docker/abc\n0::/kubepods/pod1\n",
+		"cpu,cpuacct/docker/abc/cgroup.procs":      "",
+		"cpu,cpuacct/docker/abc/cpu.cfs_quota_us":  "150000\n",
+		"cpu,cpuacct/docker/abc/cpu.cfs_period_us": "100000\n",
+		"cpu,cpuacct/docker/abc/cpuacct.usage":     "2000000000\n",
+		"unified/cgroup.controllers":               v2Controllers,
+		"unified/kubepods/pod1/cgroup.procs":       "",
+		"unified/kubepods/pod1/cpu.max":            "50000 100000\n",
+		"unified/kubepods/pod1/cpu.stat":           "usage_usec 3000000\nuser_usec 2000000\nsystem_usec 1000000\n",
+	}
+	bintest.WriteFiles(t, root, files)
+	restore := bintest.Replace(&ProcPath, path.Join(root, "proc"))
+	return root, func() {
+		restore()
+		clean()
+	}
+}
+
+func Test_GetCpuCgroup_v1(t *testing.T) {
+	root, clean := newFakeCgroupfs(t, "")
+	defer clean()
+
+	expected := map[string]string{
+		"cpu":     path.Join(root, "cpu,cpuacct/docker/abc"),
+		"cpuacct": path.Join(root, "cpu,cpuacct/docker/abc"),
+	}
+	for _, cgroupPath := range []string{"", "/docker/abc", path.Join(root, "cpu,cpuacct/docker/abc")} {
+		cgroup, err := GetCpuCgroup(cgroupPath, 100)
+		if err != nil {
+			t.Fatalf("get cgroup by `%s` err, %v", cgroupPath, err)
+		}
+		if cgroup.Version != CgroupV1 || !reflect.DeepEqual(cgroup.Dirs, expected) {
+			t.Errorf("unexpected cgroup by `%s`: %+v, expected dirs: %v", cgroupPath, cgroup, expected)
+		}
+	}
+
+	cgroup, _ := GetCpuCgroup("", 100)
+	quota, period, err := cgroup.CpuQuota()
+	if err != nil || quota != 150000 || period != 100000 {
+		t.Errorf("unexpected quota: %d %d %v", quota, period, err)
+	}
+	if cores := cgroup.CpuQuotaCores(4); cores != 1.5 {
+		t.Errorf("unexpected cores: %f, expected: 1.5", cores)
+	}
+	if usage, err := cgroup.CpuUsage(); err != nil || usage != 2*time.Second {
+		t.Errorf("unexpected usage: %v %v", usage, err)
+	}
+	if err := cgroup.AddProcess(200); err != nil {
+		t.Fatalf("add process err, %v", err)
+	}
+	if procs, _ := cgroup.ReadFile("cpu", "cgroup.procs"); procs != "200" {
+		t.Errorf("unexpected cgroup.procs: %s", procs)
+	}
+}
+
+func Test_GetCpuCgroup_v2(t *testing.T) {
+	root, clean := newFakeCgroupfs(t, "cpuset cpu io memory pids")
+	defer clean()
+	// only the cgroup2 hierarchy is left
+	os.RemoveAll(path.Join(root, "cpu,cpuacct"))
+	mountinfo := fmt.Sprintf("35 25 0:31 / %s/unified rw,nosuid - cgroup2 cgroup2 rw\n", root)
+	ioutil.WriteFile(path.Join(root, "proc/self/mountinfo"), []byte(mountinfo), 0644)
+
+	for _, cgroupPath := range []string{"", "/kubepods/pod1", path.Join(root, "unified/kubepods/pod1")} {
+		cgroup, err := GetCpuCgroup(cgroupPath, 100)
+		if err != nil {
+			t.Fatalf("get cgroup by `%s` err, %v", cgroupPath, err)
+		}
+		if cgroup.Version != CgroupV2 || cgroup.Dirs["cpu"] != path.Join(root, "unified/kubepods/pod1") {
+			t.Errorf("unexpected cgroup by `%s`: %+v", cgroupPath, cgroup)
+		}
+	}
+
+	cgroup, _ := GetCpuCgroup("", 100)
+	if cores := cgroup.CpuQuotaCores(4); cores != 0.5 {
+		t.Errorf("unexpected cores: %f, expected: 0.5", cores)
+	}
+	if usage, err := cgroup.CpuUsage(); err != nil || usage != 3*time.Second {
+		t.Errorf("unexpected usage: %v %v", usage, err)
+	}
+	if err := cgroup.SetCpuQuota(-1, 100000); err != nil {
+		t.Fatalf("set quota err, %v", err)
+	}
+	if cores := cgroup.CpuQuotaCores(4); cores != 4 {
+		t.Errorf("unexpected cores: %f, expected: 4 for the unlimited quota", cores)
+	}
+}
+
+func Test_GetCpuCgroup_failed(t *testing.T) {
+	_, clean := newFakeCgroupfs(t, "")
+	defer clean()
+
+	if _, err := GetCpuCgroup("/docker/not-exist", 100); err == nil {
+		t.Errorf("expected err for the cgroup not exists")
+	}
+	if _, err := GetCpuCgroup("", 101); err == nil {
+		t.Errorf("expected err for the process not exists")
+	}
+	if _, err := GetCgroupByPid(100, "blkio"); err == nil {
+		t.Errorf("expected err for the controller not mounted")
+	}
+}
+
+func Test_Cgroup_NewChild(t *testing.T) {
+	root, clean := newFakeCgroupfs(t, "cpu memory")
+	defer clean()
+	os.RemoveAll(path.Join(root, "cpu,cpuacct"))
+	mountinfo := fmt.Sprintf("35 25 0:31 / %s/unified rw,nosuid - cgroup2 cgroup2 rw\n", root)
+	ioutil.WriteFile(path.Join(root, "proc/self/mountinfo"), []byte(mountinfo), 0644)
+
+	cgroup, err := GetCgroupByPid(100, "cpu")
+	if err != nil {
+		t.Fatalf("get cgroup err, %v", err)
+	}
+	if cgroup.IsRoot() {
+		t.Errorf("unexpected root cgroup: %v", cgroup.Dirs)
+	}
+	rootCgroup, err := cgroup.Root()
+	if err != nil || !rootCgroup.IsRoot() || rootCgroup.Dirs["cpu"] != path.Join(root, "unified") {
+		t.Fatalf("unexpected root cgroup: %+v, %v", rootCgroup, err)
+	}
+	child, err := rootCgroup.NewChild("chaos-test")
+	if err != nil {
+		t.Fatalf("create child err, %v", err)
+	}
+	if subtree, _ := rootCgroup.ReadFile("cpu", "cgroup.subtree_control"); subtree != "+cpu" {
+		t.Errorf("unexpected subtree control: %s", subtree)
+	}
+	if err := child.Remove(); err != nil {
+		t.Errorf("remove child err, %v", err)
+	}
+	if _, err := os.Stat(path.Join(root, "unified/chaos-test")); !os.IsNotExist(err) {
+		t.Errorf("the child is not removed, %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/sirupsen/logrus"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var mtuInterface, mtuDisableOffload string
+var mtuValue int
+var mtuStart, mtuStop bool
+
+func main() {
+	flag.StringVar(&mtuInterface, "interface", "", "network interface")
+	flag.IntVar(&mtuValue, "mtu", 0, "the mtu of the interface, 0 means not changed")
+	flag.StringVar(&mtuDisableOffload, "disable-offload", "", "the offload features to disable, for example: gso,tso,gro")
+	flag.BoolVar(&mtuStart, "start", false, "start change mtu")
+	flag.BoolVar(&mtuStop, "stop", false, "recover mtu")
+	bin.ParseFlagAndInitLog()
+
+	if mtuInterface == "" {
+		bin.PrintErrAndExit("less --interface flag")
+		return
+	}
+	if mtuStart {
+		startChangeMtu(mtuInterface, mtuValue, mtuDisableOffload)
+	} else if mtuStop {
+		recoverMtu(mtuInterface)
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+var cl = channel.NewLocalChannel()
+
+// interfaceState is the original values of the interface, which are recorded in the backup file
+type interfaceState struct {
+	Mtu      int             `json:"mtu"`
+	Offloads map[string]bool `json:"offloads"`
+}
+
+func getBackupFile(netInterface string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_changemtu_%s.bak", netInterface))
+}
+
+func startChangeMtu(netInterface string, mtu int, disableOffload string) {
+	backupFile := getBackupFile(netInterface)
+	if util.IsExist(backupFile) {
+		bin.PrintErrAndExit(fmt.Sprintf("the mtu experiment is running on %s, the backup file %s exists", netInterface, backupFile))
+		return
+	}
+	iface, err := net.InterfaceByName(netInterface)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	original := &interfaceState{Mtu: iface.MTU, Offloads: make(map[string]bool)}
+	offloads := make([]string, 0)
+	for _, offload := range strings.Split(disableOffload, ",") {
+		if offload == "" {
+			continue
+		}
+		enabled, err := getOffload(netInterface, offload)
+		if err != nil {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+		original.Offloads[offload] = enabled
+		offloads = append(offloads, offload)
+	}
+	bytes, err := json.Marshal(original)
+	if err == nil {
+		err = ioutil.WriteFile(backupFile, bytes, 0644)
+	}
+	if err != nil {
+		bin.PrintErrAndExit(fmt.Sprintf("backup the original values of %s failed, %v", netInterface, err))
+		return
+	}
+	ctx := context.Background()
+	if mtu > 0 {
+		response := cl.Run(ctx, "ip", fmt.Sprintf("link set dev %s mtu %d", netInterface, mtu))
+		if !response.Success {
+			restore(netInterface, original)
+			os.Remove(backupFile)
+			bin.PrintErrAndExit(response.Err)
+			return
+		}
+	}
+	for _, offload := range offloads {
+		if err := setOffload(netInterface, offload, false); err != nil {
+			restore(netInterface, original)
+			os.Remove(backupFile)
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+	}
+	bin.PrintOutputAndExit("success")
+}
+
+func recoverMtu(netInterface string) {
+	backupFile := getBackupFile(netInterface)
+	bytes, err := ioutil.ReadFile(backupFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			bin.PrintOutputAndExit("nothing to do")
+			return
+		}
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	var original interfaceState
+	if err := json.Unmarshal(bytes, &original); err != nil {
+		bin.PrintErrAndExit(fmt.Sprintf("illegal backup file %s, %v", backupFile, err))
+		return
+	}
+	if err := restore(netInterface, &original); err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	os.Remove(backupFile)
+	bin.PrintOutputAndExit("success")
+}
+
+// restore sets the original mtu and offloads, and returns the last error
+func restore(netInterface string, original *interfaceState) error {
+	var lastErr error
+	response := cl.Run(context.Background(), "ip", fmt.Sprintf("link set dev %s mtu %d", netInterface, original.Mtu))
+	if !response.Success {
+		logrus.Warnf("restore the mtu of %s failed, %s", netInterface, response.Err)
+		lastErr = errors.New(response.Err)
+	}
+	for offload, enabled := range original.Offloads {
+		if err := setOffload(netInterface, offload, enabled); err != nil {
+			logrus.Warnf("restore %s of %s failed, %v", offload, netInterface, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+const siocEthtool = 0x8946
+
+// the get and set commands of the offloads in linux/ethtool.h
+var ethtoolCommands = map[string][2]uint32{
+	"tso": {0x0000001e, 0x0000001f},
+	"gso": {0x00000023, 0x00000024},
+	"gro": {0x0000002b, 0x0000002c},
+}
+
+type ethtoolValue struct {
+	cmd  uint32
+	data uint32
+}
+
+type ifreq struct {
+	name [syscall.IFNAMSIZ]byte
+	data uintptr
+	_    [16]byte
+}
+
+func ethtool(netInterface string, value *ethtoolValue) error {
+	if len(netInterface) >= syscall.IFNAMSIZ {
+		return fmt.Errorf("illegal interface name %s", netInterface)
+	}
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+	req := &ifreq{data: uintptr(unsafe.Pointer(value))}
+	copy(req.name[:], netInterface)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), siocEthtool, uintptr(unsafe.Pointer(req)))
+	runtime.KeepAlive(value)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func getOffload(netInterface, offload string) (bool, error) {
+	commands, ok := ethtoolCommands[offload]
+	if !ok {
+		return false, fmt.Errorf("unsupported offload %s", offload)
+	}
+	value := &ethtoolValue{cmd: commands[0]}
+	if err := ethtool(netInterface, value); err != nil {
+		return false, fmt.Errorf("get %s of %s failed, %v", offload, netInterface, err)
+	}
+	return value.data != 0, nil
+}
+
+func setOffload(netInterface, offload string, enabled bool) error {
+	commands, ok := ethtoolCommands[offload]
+	if !ok {
+		return fmt.Errorf("unsupported offload %s", offload)
+	}
+	value := &ethtoolValue{cmd: commands[1]}
+	if enabled {
+		value.data = 1
+	}
+	if err := ethtool(netInterface, value); err != nil {
+		return fmt.Errorf("set %s of %s to %t failed, %v", offload, netInterface, enabled, err)
+	}
+	return nil
+}
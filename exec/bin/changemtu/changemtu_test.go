@@ -0,0 +1,122 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+const testInterface = "chaosmtu0"
+
+// setupVeth creates a veth pair which supports changing the mtu and offloads
+func setupVeth(t *testing.T) func() {
+	if os.Geteuid() != 0 {
+		t.Skip("must be root to create veth")
+	}
+	if _, err := exec.LookPath("ip"); err != nil {
+		t.Skip("ip command not found")
+	}
+	exec.Command("ip", "link", "del", testInterface).Run()
+	if output, err := exec.Command("ip", "link", "add", testInterface, "type", "veth", "peer", "name", "chaosmtu1").CombinedOutput(); err != nil {
+		t.Skipf("create veth failed, %v, %s", err, output)
+	}
+	return func() {
+		exec.Command("ip", "link", "del", testInterface).Run()
+	}
+}
+
+func getMtu(t *testing.T) int {
+	iface, err := net.InterfaceByName(testInterface)
+	if err != nil {
+		t.Fatalf("get interface err, %v", err)
+	}
+	return iface.MTU
+}
+
+func Test_offload(t *testing.T) {
+	defer setupVeth(t)()
+	for _, offload := range []string{"gso", "tso", "gro"} {
+		for _, enabled := range []bool{false, true} {
+			if err := setOffload(testInterface, offload, enabled); err != nil {
+				t.Fatalf("set offload err, %v", err)
+			}
+			actual, err := getOffload(testInterface, offload)
+			if err != nil {
+				t.Fatalf("get offload err, %v", err)
+			}
+			if actual != enabled {
+				t.Errorf("unexpected %s: %t, expected: %t", offload, actual, enabled)
+			}
+		}
+	}
+}
+
+func Test_startChangeMtu_and_recoverMtu(t *testing.T) {
+	defer setupVeth(t)()
+	var exitCode int
+	bin.ExitFunc = func(code int) {
+		exitCode = code
+	}
+	cl = channel.NewLocalChannel()
+	setOffload(testInterface, "gso", true)
+	setOffload(testInterface, "gro", false)
+	originalMtu := getMtu(t)
+
+	startChangeMtu(testInterface, 1280, "gso,gro")
+	if exitCode != 0 {
+		t.Fatalf("unexpected result: %d, expected result: %d, %s", exitCode, 0, bin.ExitMessageForTesting)
+	}
+	defer os.Remove(getBackupFile(testInterface))
+	if mtu := getMtu(t); mtu != 1280 {
+		t.Errorf("unexpected mtu: %d, expected: %d", mtu, 1280)
+	}
+	if gso, _ := getOffload(testInterface, "gso"); gso {
+		t.Errorf("unexpected gso: %t, expected: false", gso)
+	}
+
+	// the experiment is running on the interface
+	startChangeMtu(testInterface, 1000, "")
+	if exitCode != 1 {
+		t.Errorf("unexpected result: %d, expected result: %d", exitCode, 1)
+	}
+
+	exitCode = -1
+	recoverMtu(testInterface)
+	if exitCode != 0 {
+		t.Fatalf("unexpected result: %d, expected result: %d, %s", exitCode, 0, bin.ExitMessageForTesting)
+	}
+	if mtu := getMtu(t); mtu != originalMtu {
+		t.Errorf("unexpected mtu: %d, expected: %d", mtu, originalMtu)
+	}
+	if gso, _ := getOffload(testInterface, "gso"); !gso {
+		t.Errorf("unexpected gso: %t, expected: true", gso)
+	}
+	if gro, _ := getOffload(testInterface, "gro"); gro {
+		t.Errorf("unexpected gro: %t, expected: false", gro)
+	}
+	if backupFile := getBackupFile(testInterface); util.IsExist(backupFile) {
+		t.Errorf("the backup file %s should be removed", backupFile)
+	}
+}
@@ -0,0 +1,707 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/sirupsen/logrus"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var ctMode, ctProtocol, ctLocalPort, ctRemotePort, ctExcludePort, ctDestinationIp, ctExcludeIp string
+var ctPercent, ctInterval int
+var ctStart, ctStop, ctNohup bool
+
+func main() {
+	flag.StringVar(&ctMode, "mode", "", "fill or flush")
+	flag.StringVar(&ctProtocol, "protocol", "", "the protocol of the entries, tcp or udp")
+	flag.StringVar(&ctLocalPort, "local-port", "", "the local ports, for example: 80,8000-8080")
+	flag.StringVar(&ctRemotePort, "remote-port", "", "the remote ports, for example: 80,8000-8080")
+	flag.StringVar(&ctExcludePort, "exclude-port", "", "the excluded local ports, for example: 22,8000")
+	flag.StringVar(&ctDestinationIp, "destination-ip", "", "the remote ips, for example: 10.0.0.1,10.0.1.0/24")
+	flag.StringVar(&ctExcludeIp, "exclude-ip", "", "the excluded remote ips, for example: 10.0.0.1,10.0.1.0/24")
+	flag.IntVar(&ctPercent, "percent", 90, "the percent of nf_conntrack_max to fill")
+	flag.IntVar(&ctInterval, "interval", 0, "the interval of flushing, unit is second, 0 means flushing once")
+	flag.BoolVar(&ctStart, "start", false, "start conntrack experiment")
+	flag.BoolVar(&ctStop, "stop", false, "stop conntrack experiment")
+	flag.BoolVar(&ctNohup, "nohup", false, "nohup to run conntrack experiment")
+	bin.ParseFlagAndInitLog()
+
+	if ctMode != exec.ConntrackFillMode && ctMode != exec.ConntrackFlushMode {
+		bin.PrintErrAndExit("illegal --mode flag, it must be fill or flush")
+		return
+	}
+	if ctStart {
+		startConntrack(ctMode)
+	} else if ctStop {
+		stopConntrack(ctMode)
+	} else if ctNohup {
+		var err error
+		if ctMode == exec.ConntrackFillMode {
+			err = runFill(context.Background())
+		} else {
+			err = runFlush(context.Background(), time.Duration(ctInterval)*time.Second)
+		}
+		if err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+		}
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+var cl = channel.NewLocalChannel()
+
+var conntrackBin = exec.ConntrackBin
+
+var conntrackLogFile = util.GetNohupOutput(util.Bin, "chaos_conntrack.log")
+
+// conntrackProcPath is the directory of nf_conntrack_max and nf_conntrack_count
+var conntrackProcPath = "/proc/sys/net/netfilter"
+
+// refreshInterval is the interval of resending the packets of the flows, it must be less than the conntrack timeouts
+var refreshInterval = 10 * time.Second
+
+func startConntrack(mode string) {
+	ctx := context.Background()
+	if ctInterval < 0 {
+		bin.PrintErrAndExit(fmt.Sprintf("illegal --interval %d, it must be a non-negative integer", ctInterval))
+		return
+	}
+	if mode == exec.ConntrackFillMode {
+		if _, _, err := getConntrackUsage(); err != nil {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+	} else if ctInterval == 0 {
+		filter, err := newFilter(ctProtocol, ctLocalPort, ctRemotePort, ctExcludePort, ctDestinationIp, ctExcludeIp)
+		if err != nil {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+		count, err := flush(filter)
+		if err != nil {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+		bin.PrintOutputAndExit(fmt.Sprintf("%d entries deleted", count))
+		return
+	}
+	response := cl.Run(ctx, "nohup", fmt.Sprintf("%s --mode %s --nohup=true %s > %s 2>&1 &",
+		path.Join(util.GetProgramPath(), conntrackBin), mode, getFilterArgs(), conntrackLogFile))
+	if !response.Success {
+		bin.PrintErrAndExit(response.Err)
+		return
+	}
+	// check
+	time.Sleep(time.Second)
+	response = cl.Run(ctx, "grep", fmt.Sprintf("%s %s", bin.ErrPrefix, conntrackLogFile))
+	if response.Success {
+		errMsg := strings.TrimSpace(response.Result.(string))
+		if errMsg != "" {
+			stopConntrack(mode)
+			bin.PrintErrAndExit(errMsg)
+			return
+		}
+	}
+	bin.PrintOutputAndExit("success")
+}
+
+func getFilterArgs() string {
+	args := fmt.Sprintf("--percent %d --interval %d", ctPercent, ctInterval)
+	flags := []struct {
+		name, value string
+	}{
+		{"protocol", ctProtocol},
+		{"local-port", ctLocalPort},
+		{"remote-port", ctRemotePort},
+		{"exclude-port", ctExcludePort},
+		{"destination-ip", ctDestinationIp},
+		{"exclude-ip", ctExcludeIp},
+	}
+	for _, f := range flags {
+		if f.value != "" {
+			args = fmt.Sprintf("%s --%s %s", args, f.name, f.value)
+		}
+	}
+	return args
+}
+
+func stopConntrack(mode string) {
+	ctx := context.WithValue(context.Background(), channel.ProcessKey, conntrackBin)
+	pids, err := cl.GetPidsByProcessName(fmt.Sprintf("mode %s --nohup", mode), ctx)
+	if err != nil {
+		logrus.Warnf("get %s pid failed, %v", conntrackBin, err)
+	}
+	if len(pids) > 0 {
+		cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
+	}
+	if mode == exec.ConntrackFillMode {
+		deleteRstDropRules(ctx)
+	}
+	cl.Run(ctx, "rm", fmt.Sprintf("-rf %s*", conntrackLogFile))
+}
+
+// rstDropComment is the comment of the rule dropping the rst packets of the tcp flows in fill mode
+const rstDropComment = "chaosblade-conntrack-fill"
+
+// getRstDropRule returns the rule dropping the rst packets sent by the local kernel to the destinations when they
+// reply the syn packets, no socket owns the flows, so the entries are closed by the rst without the rule
+func getRstDropRule(destinationIps []*net.IPNet, sourcePorts, remotePorts []int) string {
+	dsts := make([]string, 0, len(destinationIps))
+	for _, n := range destinationIps {
+		dsts = append(dsts, n.String())
+	}
+	return fmt.Sprintf("OUTPUT -p tcp -d %s --sport %d:%d --dport %d:%d --tcp-flags RST RST -m comment --comment %s -j DROP",
+		strings.Join(dsts, ","), minPort(sourcePorts), maxPort(sourcePorts), minPort(remotePorts), maxPort(remotePorts), rstDropComment)
+}
+
+func minPort(ports []int) int {
+	min := ports[0]
+	for _, port := range ports {
+		if port < min {
+			min = port
+		}
+	}
+	return min
+}
+
+func maxPort(ports []int) int {
+	max := ports[0]
+	for _, port := range ports {
+		if port > max {
+			max = port
+		}
+	}
+	return max
+}
+
+// deleteRstDropRules deletes all the rules with the comment of the fill mode, iptables -S prints the rules
+// with the comment quoted or not
+func deleteRstDropRules(ctx context.Context) {
+	response := cl.Run(ctx, "iptables", "-S OUTPUT")
+	if !response.Success {
+		logrus.Warnf("list the rules of OUTPUT failed, %s", response.Err)
+		return
+	}
+	for _, rule := range strings.Split(response.Result.(string), "\n") {
+		rule = strings.TrimSpace(rule)
+		if !strings.HasPrefix(rule, "-A ") || !strings.Contains(strings.Replace(rule, `"`, "", -1), "--comment "+rstDropComment+" ") {
+			continue
+		}
+		if response := cl.Run(ctx, "iptables", fmt.Sprintf("-D %s", strings.TrimPrefix(rule, "-A "))); !response.Success {
+			logrus.Warnf("delete the rule %s failed, %s", rule, response.Err)
+		}
+	}
+}
+
+// getConntrackUsage returns the nf_conntrack_count and nf_conntrack_max
+func getConntrackUsage() (int, int, error) {
+	values := make([]int, 0, 2)
+	for _, name := range []string{"nf_conntrack_count", "nf_conntrack_max"} {
+		bytes, err := ioutil.ReadFile(path.Join(conntrackProcPath, name))
+		if err != nil {
+			return 0, 0, fmt.Errorf("read %s failed, the nf_conntrack module may not be loaded, %v", name, err)
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(string(bytes)))
+		if err != nil {
+			return 0, 0, fmt.Errorf("illegal %s value, %v", name, err)
+		}
+		values = append(values, value)
+	}
+	return values[0], values[1], nil
+}
+
+// parsePorts expands the ports flag, for example 80,8000-8080
+func parsePorts(name, value string) (map[int]bool, error) {
+	ports := make(map[int]bool)
+	if value == "" {
+		return ports, nil
+	}
+	values, err := util.ParseIntegerListToStringSlice(name, value)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		port, _ := strconv.Atoi(v)
+		if port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("illegal port %d in %s", port, name)
+		}
+		ports[port] = true
+	}
+	return ports, nil
+}
+
+// parseNets parses the comma separated ips or cidrs
+func parseNets(name, value string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0)
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("illegal ip %s in %s", v, name)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("illegal cidr %s in %s, %v", v, name, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func containsIp(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// entry is the original tuple of a conntrack entry
+type entry struct {
+	protocol string
+	protoNum int
+	src, dst net.IP
+	sport    int
+	dport    int
+	// icmpType, icmpCode and icmpId identify the icmp entries instead of the ports
+	icmpType, icmpCode, icmpId int
+	zone                       int
+}
+
+// filter matches the conntrack entries by the local and remote side of the flows
+type filter struct {
+	protocol       string
+	localPorts     map[int]bool
+	remotePorts    map[int]bool
+	excludePorts   map[int]bool
+	destinationIps []*net.IPNet
+	excludeIps     []*net.IPNet
+	isLocalIp      func(ip net.IP) bool
+}
+
+func newFilter(protocol, localPort, remotePort, excludePort, destinationIp, excludeIp string) (*filter, error) {
+	f := &filter{protocol: protocol, isLocalIp: isLocalIp}
+	var err error
+	if f.localPorts, err = parsePorts("local-port", localPort); err != nil {
+		return nil, err
+	}
+	if f.remotePorts, err = parsePorts("remote-port", remotePort); err != nil {
+		return nil, err
+	}
+	if f.excludePorts, err = parsePorts("exclude-port", excludePort); err != nil {
+		return nil, err
+	}
+	if f.destinationIps, err = parseNets("destination-ip", destinationIp); err != nil {
+		return nil, err
+	}
+	if f.excludeIps, err = parseNets("exclude-ip", excludeIp); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func isLocalIp(ip net.IP) bool {
+	if ip.IsLoopback() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns true if the entry matches all the conditions. The side of the entry whose ip is
+// not local is the remote side, the source is the local side of the forwarded flows.
+func (f *filter) match(e *entry) bool {
+	if f.protocol != "" && f.protocol != e.protocol {
+		return false
+	}
+	localPort, remoteIp, remotePort := e.sport, e.dst, e.dport
+	if !f.isLocalIp(e.src) && f.isLocalIp(e.dst) {
+		localPort, remoteIp, remotePort = e.dport, e.src, e.sport
+	}
+	if len(f.localPorts) > 0 && !f.localPorts[localPort] {
+		return false
+	}
+	if len(f.remotePorts) > 0 && !f.remotePorts[remotePort] {
+		return false
+	}
+	if f.excludePorts[localPort] {
+		return false
+	}
+	if len(f.destinationIps) > 0 && !containsIp(f.destinationIps, remoteIp) {
+		return false
+	}
+	return !containsIp(f.excludeIps, remoteIp)
+}
+
+// conntrackTable lists the conntrack entries, and deletes them and returns the count of the entries deleted,
+// the entries expired or deleted by others are ignored
+type conntrackTable interface {
+	List() ([]*entry, error)
+	Delete(entries []*entry) (int, error)
+	Close() error
+}
+
+// openTable opens the table over netlink, it's replaced by the fake one in tests
+var openTable = openNetlinkTable
+
+// flush deletes the entries matched, and returns the count of the entries deleted. The entries are dumped and
+// deleted over one netlink socket, because running conntrack -D for each entry forks too many processes on a large table.
+func flush(f *filter) (int, error) {
+	table, err := openTable()
+	if err != nil {
+		return 0, err
+	}
+	defer table.Close()
+	entries, err := table.List()
+	if err != nil {
+		return 0, err
+	}
+	matched := make([]*entry, 0)
+	for _, e := range entries {
+		if f.match(e) {
+			matched = append(matched, e)
+		}
+	}
+	if len(matched) == 0 {
+		return 0, nil
+	}
+	return table.Delete(matched)
+}
+
+// runFlush flushes the entries matched on the interval until the process is killed
+func runFlush(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("illegal interval %v, it must be positive to flush repeatedly", interval)
+	}
+	f, err := newFilter(ctProtocol, ctLocalPort, ctRemotePort, ctExcludePort, ctDestinationIp, ctExcludeIp)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		count, err := flush(f)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("%d conntrack entries deleted", count)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// maxFillIps is the max count of the ips expanded from the cidrs in fill mode
+const maxFillIps = 65536
+
+// maxUdpSockets limits the count of the sockets used by udp flows
+const maxUdpSockets = 512
+
+// getFillIps expands the destination ips to ipv4 addresses
+func getFillIps(destinationIp string) ([]net.IP, error) {
+	nets, err := parseNets("destination-ip", destinationIp)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0)
+	for _, n := range nets {
+		if n.IP.To4() == nil {
+			return nil, fmt.Errorf("only ipv4 is supported in fill mode, %s", n)
+		}
+		for ip := n.IP.To4().Mask(n.Mask); n.Contains(ip) && len(ips) < maxFillIps; ip = nextIp(ip) {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("less --destination-ip flag")
+	}
+	return ips, nil
+}
+
+func nextIp(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+func sortedPorts(ports map[int]bool, excludePorts map[int]bool, defaultStart, defaultEnd int) []int {
+	result := make([]int, 0)
+	for port := 1; port <= 65535; port++ {
+		if len(ports) == 0 && (port < defaultStart || port > defaultEnd) {
+			continue
+		}
+		if len(ports) > 0 && !ports[port] {
+			continue
+		}
+		if !excludePorts[port] {
+			result = append(result, port)
+		}
+	}
+	return result
+}
+
+// runFill creates flows until the conntrack table reaches the percent, and refreshes them until the process is killed
+func runFill(ctx context.Context) error {
+	ips, err := getFillIps(ctDestinationIp)
+	if err != nil {
+		return err
+	}
+	excludeIps, err := parseNets("exclude-ip", ctExcludeIp)
+	if err != nil {
+		return err
+	}
+	targetIps := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if !containsIp(excludeIps, ip) {
+			targetIps = append(targetIps, ip)
+		}
+	}
+	remotePorts, err := parsePorts("remote-port", ctRemotePort)
+	if err != nil {
+		return err
+	}
+	localPorts, err := parsePorts("local-port", ctLocalPort)
+	if err != nil {
+		return err
+	}
+	excludePorts, err := parsePorts("exclude-port", ctExcludePort)
+	if err != nil {
+		return err
+	}
+	ports := sortedPorts(remotePorts, nil, 1024, 65535)
+	if len(targetIps) == 0 || len(ports) == 0 {
+		return fmt.Errorf("no flow can be created by the destination ips and the remote ports")
+	}
+	count, max, err := getConntrackUsage()
+	if err != nil {
+		return err
+	}
+	flows := max*ctPercent/100 - count
+	if flows <= 0 {
+		return fmt.Errorf("the conntrack table usage %d/%d already reaches %d%%", count, max, ctPercent)
+	}
+
+	var sender flowSender
+	var sources int
+	if ctProtocol == "tcp" {
+		sourcePorts := sortedPorts(localPorts, excludePorts, 10000, 30000)
+		if len(sourcePorts) == 0 {
+			return fmt.Errorf("no source port can be used")
+		}
+		destinationIps, _ := parseNets("destination-ip", ctDestinationIp)
+		rule := getRstDropRule(destinationIps, sourcePorts, ports)
+		if response := cl.Run(ctx, "iptables", "-I "+rule); !response.Success {
+			return fmt.Errorf("add the rule dropping the rst packets failed, %s", response.Err)
+		}
+		defer cl.Run(ctx, "iptables", "-D "+rule)
+		rand.Shuffle(len(sourcePorts), func(i, j int) {
+			sourcePorts[i], sourcePorts[j] = sourcePorts[j], sourcePorts[i]
+		})
+		sender, err = newTcpSynSender(sourcePorts)
+		sources = len(sourcePorts)
+	} else {
+		sender, sources = &udpSender{}, maxUdpSockets
+	}
+	if err != nil {
+		return err
+	}
+	defer sender.close()
+	if capacity := sources * len(targetIps) * len(ports); flows > capacity {
+		logrus.Warnf("only %d flows can be created by the flags, %d flows are expected", capacity, flows)
+		flows = capacity
+	}
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		sent := sendFlows(sender, targetIps, ports, flows)
+		if count, max, err := getConntrackUsage(); err == nil {
+			logrus.Infof("%d flows sent, the conntrack table usage is %d/%d", sent, count, max)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// flowSender sends the first packet of a flow, the source is the index of the source port used
+type flowSender interface {
+	send(source int, ip net.IP, port int) error
+	close()
+}
+
+// sendFlows sends a packet for each flow, the destinations are used up before the next source
+func sendFlows(sender flowSender, ips []net.IP, ports []int, flows int) int {
+	sent := 0
+	for source := 0; sent < flows; source++ {
+		for _, ip := range ips {
+			for _, port := range ports {
+				if sent >= flows {
+					return sent
+				}
+				// the packets are dropped if the table is full, keep sending to take the slots released
+				if err := sender.send(source, ip, port); err != nil {
+					logrus.Debugf("send packet to %s:%d failed, %v", ip, port, err)
+				}
+				sent++
+			}
+		}
+	}
+	return sent
+}
+
+type udpSender struct {
+	conns []*net.UDPConn
+}
+
+func (s *udpSender) send(source int, ip net.IP, port int) error {
+	for len(s.conns) <= source {
+		conn, err := net.ListenUDP("udp4", nil)
+		if err != nil {
+			return err
+		}
+		s.conns = append(s.conns, conn)
+	}
+	_, err := s.conns[source].WriteToUDP([]byte("chaosblade"), &net.UDPAddr{IP: ip, Port: port})
+	return err
+}
+
+func (s *udpSender) close() {
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// tcpSynSender sends tcp syn packets by a raw socket, the flows stay half-open because there is no socket
+// to complete the handshake, and the rst packets replied by the local kernel are dropped by the rule of getRstDropRule
+type tcpSynSender struct {
+	fd          int
+	sourcePorts []int
+	sourceIps   map[string]net.IP
+}
+
+func newTcpSynSender(sourcePorts []int) (*tcpSynSender, error) {
+	if len(sourcePorts) == 0 {
+		return nil, fmt.Errorf("no source port can be used")
+	}
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_RAW, syscall.IPPROTO_TCP)
+	if err != nil {
+		return nil, fmt.Errorf("create raw socket failed, %v", err)
+	}
+	return &tcpSynSender{fd: fd, sourcePorts: sourcePorts, sourceIps: make(map[string]net.IP)}, nil
+}
+
+func (s *tcpSynSender) send(source int, ip net.IP, port int) error {
+	src, ok := s.sourceIps[ip.String()]
+	if !ok {
+		// the source ip is selected by the route to the destination
+		conn, err := net.Dial("udp4", net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+		if err != nil {
+			return err
+		}
+		src = conn.LocalAddr().(*net.UDPAddr).IP.To4()
+		conn.Close()
+		s.sourceIps[ip.String()] = src
+	}
+	addr := &syscall.SockaddrInet4{}
+	copy(addr.Addr[:], ip.To4())
+	return syscall.Sendto(s.fd, tcpSynPacket(src, ip.To4(), s.sourcePorts[source], port), 0, addr)
+}
+
+func (s *tcpSynSender) close() {
+	syscall.Close(s.fd)
+}
+
+// tcpSynPacket builds the tcp header of a syn packet with the checksum
+func tcpSynPacket(src, dst net.IP, sport, dport int) []byte {
+	packet := make([]byte, 20)
+	binary.BigEndian.PutUint16(packet[0:], uint16(sport))
+	binary.BigEndian.PutUint16(packet[2:], uint16(dport))
+	binary.BigEndian.PutUint32(packet[4:], rand.Uint32())
+	// data offset is 5 words, and the flag is syn
+	packet[12] = 5 << 4
+	packet[13] = 0x02
+	binary.BigEndian.PutUint16(packet[14:], 65535)
+	binary.BigEndian.PutUint16(packet[16:], tcpChecksum(src, dst, packet))
+	return packet
+}
+
+func tcpChecksum(src, dst net.IP, segment []byte) uint16 {
+	pseudo := make([]byte, 0, 12+len(segment))
+	pseudo = append(pseudo, src.To4()...)
+	pseudo = append(pseudo, dst.To4()...)
+	pseudo = append(pseudo, 0, syscall.IPPROTO_TCP, byte(len(segment)>>8), byte(len(segment)))
+	pseudo = append(pseudo, segment...)
+	var sum uint32
+	for i := 0; i+1 < len(pseudo); i += 2 {
+		sum += uint32(pseudo[i])<<8 | uint32(pseudo[i+1])
+	}
+	if len(pseudo)%2 == 1 {
+		sum += uint32(pseudo[len(pseudo)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
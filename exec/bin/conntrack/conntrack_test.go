@@ -0,0 +1,247 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
+)
+
+// testEntries returns the entries of a tcp flow out, a tcp flow in, a udp flow out and an icmp flow out
+func testEntries() []*entry {
+	return []*entry{
+		{protocol: "tcp", protoNum: 6, src: net.ParseIP("192.168.1.10"), dst: net.ParseIP("10.0.0.2"), sport: 51234, dport: 3306},
+		{protocol: "tcp", protoNum: 6, src: net.ParseIP("10.0.0.3"), dst: net.ParseIP("192.168.1.10"), sport: 40000, dport: 8080},
+		{protocol: "udp", protoNum: 17, src: net.ParseIP("192.168.1.10"), dst: net.ParseIP("10.0.1.5"), sport: 53000, dport: 53},
+		{protocol: "icmp", protoNum: 1, src: net.ParseIP("192.168.1.10"), dst: net.ParseIP("10.0.0.2"), icmpType: 8, icmpId: 1},
+	}
+}
+
+func isTestLocalIp(ip net.IP) bool {
+	return ip.Equal(net.ParseIP("192.168.1.10"))
+}
+
+func Test_filter_match(t *testing.T) {
+	tests := []struct {
+		name                                                            string
+		protocol, localPort, remotePort, excludePort, destIp, excludeIp string
+		expected                                                        []int
+	}{
+		{"all", "", "", "", "", "", "", []int{0, 1, 2, 3}},
+		{"protocol", "udp", "", "", "", "", "", []int{2}},
+		{"local port of the inbound flow", "", "8000-8080", "", "", "", "", []int{1}},
+		{"remote port", "", "", "53,3306", "", "", "", []int{0, 2}},
+		{"exclude port", "tcp", "", "", "51234", "", "", []int{1}},
+		{"destination ip", "", "", "", "", "10.0.0.0/24", "", []int{0, 1, 3}},
+		{"exclude ip", "", "", "", "", "10.0.0.0/16", "10.0.0.3", []int{0, 2, 3}},
+	}
+	entries := testEntries()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newFilter(tt.protocol, tt.localPort, tt.remotePort, tt.excludePort, tt.destIp, tt.excludeIp)
+			if err != nil {
+				t.Fatalf("create filter err, %v", err)
+			}
+			f.isLocalIp = isTestLocalIp
+			matched := make([]int, 0)
+			for i, e := range entries {
+				if f.match(e) {
+					matched = append(matched, i)
+				}
+			}
+			if !reflect.DeepEqual(matched, tt.expected) {
+				t.Errorf("unexpected matched entries: %v, expected: %v", matched, tt.expected)
+			}
+		})
+	}
+}
+
+// fakeTable lists the entries and records the entries deleted instead of sending them over netlink
+type fakeTable struct {
+	entries []*entry
+	deleted []*entry
+}
+
+func (d *fakeTable) List() ([]*entry, error) {
+	return d.entries, nil
+}
+
+func (d *fakeTable) Delete(entries []*entry) (int, error) {
+	d.deleted = append(d.deleted, entries...)
+	return len(entries), nil
+}
+
+func (d *fakeTable) Close() error {
+	return nil
+}
+
+func Test_flush(t *testing.T) {
+	entries := testEntries()
+	table := &fakeTable{entries: entries}
+	openTable = func() (conntrackTable, error) {
+		return table, nil
+	}
+	defer func() { openTable = openNetlinkTable }()
+	f, _ := newFilter("", "", "", "", "10.0.0.2", "")
+	f.isLocalIp = isTestLocalIp
+	count, err := flush(f)
+	if err != nil {
+		t.Fatalf("flush err, %v", err)
+	}
+	expected := []*entry{entries[0], entries[3]}
+	if count != 2 || !reflect.DeepEqual(table.deleted, expected) {
+		t.Errorf("unexpected deleted entries: %+v, count: %d, expected: %+v", table.deleted, count, expected)
+	}
+}
+
+func Test_getConntrackUsage(t *testing.T) {
+	dir, clean := bintest.NewRoot(t)
+	defer clean()
+	defer bintest.Replace(&conntrackProcPath, dir)()
+	if _, _, err := getConntrackUsage(); err == nil || !strings.Contains(err.Error(), "nf_conntrack module") {
+		t.Errorf("unexpected err: %v", err)
+	}
+	bintest.WriteFiles(t, dir, map[string]string{"nf_conntrack_count": "100\n", "nf_conntrack_max": "262144\n"})
+	count, max, err := getConntrackUsage()
+	if err != nil || count != 100 || max != 262144 {
+		t.Errorf("unexpected usage: %d/%d, err: %v", count, max, err)
+	}
+}
+
+func Test_getFillIps(t *testing.T) {
+	ips, err := getFillIps("10.0.0.1,10.0.1.0/30")
+	if err != nil {
+		t.Fatalf("get ips err, %v", err)
+	}
+	expected := []string{"10.0.0.1", "10.0.1.0", "10.0.1.1", "10.0.1.2", "10.0.1.3"}
+	actual := make([]string, 0)
+	for _, ip := range ips {
+		actual = append(actual, ip.String())
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected ips: %v, expected: %v", actual, expected)
+	}
+	if _, err := getFillIps("fd00::1"); err == nil {
+		t.Errorf("expected err for ipv6 address")
+	}
+}
+
+func Test_sendFlows_udp(t *testing.T) {
+	conns := make([]*net.UDPConn, 0)
+	ports := make([]int, 0)
+	for i := 0; i < 2; i++ {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatalf("listen err, %v", err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+		ports = append(ports, conn.LocalAddr().(*net.UDPAddr).Port)
+	}
+	sender := &udpSender{}
+	defer sender.close()
+	// 2 destinations, so 3 flows use 2 source ports
+	if sent := sendFlows(sender, []net.IP{net.IPv4(127, 0, 0, 1)}, ports, 3); sent != 3 {
+		t.Errorf("unexpected sent flows: %d, expected: %d", sent, 3)
+	}
+	if len(sender.conns) != 2 {
+		t.Errorf("unexpected sockets count: %d, expected: %d", len(sender.conns), 2)
+	}
+	buf := make([]byte, 64)
+	sources := make(map[string]bool)
+	for i, expected := range []int{2, 1} {
+		for j := 0; j < expected; j++ {
+			_, addr, err := conns[i].ReadFromUDP(buf)
+			if err != nil {
+				t.Fatalf("read err, %v", err)
+			}
+			sources[addr.String()] = true
+		}
+	}
+	if len(sources) != 2 {
+		t.Errorf("unexpected sources: %v, expected 2 sources", sources)
+	}
+}
+
+func Test_tcpSynPacket(t *testing.T) {
+	src, dst := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")
+	packet := tcpSynPacket(src, dst, 12345, 80)
+	if packet[13] != 0x02 || packet[12]>>4 != 5 {
+		t.Errorf("unexpected tcp header: %v", packet)
+	}
+	// the checksum of the segment with checksum is zero
+	if sum := tcpChecksum(src, dst, packet); sum != 0 {
+		t.Errorf("unexpected checksum: %x, expected: 0", sum)
+	}
+}
+
+func Test_getRstDropRule(t *testing.T) {
+	nets, _ := parseNets("destination-ip", "10.0.0.2,10.0.1.0/24")
+	rule := getRstDropRule(nets, []int{20000, 10000, 30000}, []int{80, 443})
+	expected := "OUTPUT -p tcp -d 10.0.0.2/32,10.0.1.0/24 --sport 10000:30000 --dport 80:443 --tcp-flags RST RST " +
+		"-m comment --comment chaosblade-conntrack-fill -j DROP"
+	if rule != expected {
+		t.Errorf("unexpected rule: %s, expected: %s", rule, expected)
+	}
+}
+
+func Test_stopConntrack_fill(t *testing.T) {
+	mockChannel := channel.NewMockLocalChannel().(*channel.MockLocalChannel)
+	commands := make([]string, 0)
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		if args == "-S OUTPUT" {
+			return spec.ReturnSuccess(`-P OUTPUT ACCEPT
+-A OUTPUT -d 10.0.0.2/32 -p tcp -m tcp --sport 10000:30000 --dport 80 --tcp-flags RST RST -m comment --comment "chaosblade-conntrack-fill" -j DROP
+-A OUTPUT -d 10.0.0.3/32 -p tcp -m tcp --tcp-flags RST RST -j DROP
+`)
+		}
+		if script == "iptables" {
+			commands = append(commands, fmt.Sprintf("%s %s", script, args))
+		}
+		return spec.ReturnSuccess("")
+	}
+	mockChannel.GetPidsByProcessNameFunc = func(processName string, ctx context.Context) ([]string, error) {
+		return []string{}, nil
+	}
+	cl = mockChannel
+	defer func() { cl = channel.NewLocalChannel() }()
+
+	stopConntrack(exec.ConntrackFillMode)
+	expected := []string{`iptables -D OUTPUT -d 10.0.0.2/32 -p tcp -m tcp --sport 10000:30000 --dport 80 --tcp-flags RST RST -m comment --comment "chaosblade-conntrack-fill" -j DROP`}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("unexpected commands: %v, expected: %v", commands, expected)
+	}
+}
+
+func Test_runFlush_illegalInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := runFlush(context.Background(), interval); err == nil {
+			t.Errorf("expected err for the interval %v", interval)
+		}
+	}
+}
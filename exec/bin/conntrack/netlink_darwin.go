@@ -16,12 +16,8 @@
 
 package main
 
-import "github.com/containerd/cgroups"
+import "fmt"
 
-type CgroupMock struct {
-	cgroups.Cgroup
-}
-
-func (cgroupMock *CgroupMock) Add(process cgroups.Process) error {
-	return nil
+func openNetlinkTable() (conntrackTable, error) {
+	return nil, fmt.Errorf("the conntrack table over netlink is only supported on linux")
 }
@@ -0,0 +1,358 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+)
+
+// the constants of ctnetlink in linux/netfilter/nfnetlink.h and nfnetlink_conntrack.h
+const (
+	nfnlSubsysCtnetlink = 1
+	ipctnlMsgCtGet      = 1
+	ipctnlMsgCtDelete   = 2
+
+	ctaTupleOrig  = 1
+	ctaZone       = 18
+	ctaTupleIp    = 1
+	ctaTupleProto = 2
+
+	ctaIpV4Src = 1
+	ctaIpV4Dst = 2
+	ctaIpV6Src = 3
+	ctaIpV6Dst = 4
+
+	ctaProtoNum        = 1
+	ctaProtoSrcPort    = 2
+	ctaProtoDstPort    = 3
+	ctaProtoIcmpId     = 4
+	ctaProtoIcmpType   = 5
+	ctaProtoIcmpCode   = 6
+	ctaProtoIcmpv6Id   = 7
+	ctaProtoIcmpv6Type = 8
+	ctaProtoIcmpv6Code = 9
+
+	nlaFNested = 0x8000
+)
+
+// deleteBatchSize is the count of the delete messages sent in one write
+const deleteBatchSize = 128
+
+// ackTimeout is the timeout of waiting for the acks of a batch or the messages of a dump
+const ackTimeout = 5
+
+// nativeEndian is the byte order of the netlink headers, the values of the attributes are in the network order
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	value := uint16(1)
+	if *(*byte)(unsafe.Pointer(&value)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// netlinkTable lists and deletes the conntrack entries over a ctnetlink socket
+type netlinkTable struct {
+	fd  int
+	seq uint32
+}
+
+func openNetlinkTable() (conntrackTable, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW, syscall.NETLINK_NETFILTER)
+	if err != nil {
+		return nil, fmt.Errorf("create netfilter netlink socket failed, %v", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("bind netfilter netlink socket failed, %v", err)
+	}
+	timeout := syscall.Timeval{Sec: ackTimeout}
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return &netlinkTable{fd: fd}, nil
+}
+
+// List dumps the entries of all the families by IPCTNL_MSG_CT_GET, the entries which cannot be parsed are skipped
+func (d *netlinkTable) List() ([]*entry, error) {
+	d.seq++
+	seq := d.seq
+	if err := syscall.Sendto(d.fd, buildMessage(ipctnlMsgCtGet, syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP, seq, syscall.AF_UNSPEC, nil),
+		0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("send the conntrack dump message failed, %v", err)
+	}
+	entries := make([]*entry, 0)
+	received := make([]byte, syscall.Getpagesize()*16)
+	for {
+		n, _, err := syscall.Recvfrom(d.fd, received, 0)
+		if err != nil {
+			return nil, fmt.Errorf("receive the conntrack entries failed, %v", err)
+		}
+		messages, err := syscall.ParseNetlinkMessage(received[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, message := range messages {
+			if message.Header.Seq != seq {
+				continue
+			}
+			switch message.Header.Type {
+			case syscall.NLMSG_DONE:
+				return entries, nil
+			case syscall.NLMSG_ERROR:
+				if len(message.Data) >= 4 {
+					if errno := syscall.Errno(-int32(nativeEndian.Uint32(message.Data[:4]))); errno != 0 {
+						return nil, fmt.Errorf("dump the conntrack entries failed, %v", errno)
+					}
+				}
+				return entries, nil
+			}
+			e, err := parseEntryMessage(message.Data)
+			if err != nil {
+				logrus.Debugf("skip the conntrack message, %v", err)
+				continue
+			}
+			entries = append(entries, e)
+		}
+	}
+}
+
+func (d *netlinkTable) Delete(entries []*entry) (int, error) {
+	count := 0
+	for start := 0; start < len(entries); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		deleted, err := d.deleteBatch(entries[start:end])
+		count += deleted
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// deleteBatch writes the delete messages of the entries at once, then reads the acks of them
+func (d *netlinkTable) deleteBatch(entries []*entry) (int, error) {
+	buffer := make([]byte, 0)
+	pending := make(map[uint32]bool)
+	for _, e := range entries {
+		d.seq++
+		message, err := buildDeleteMessage(e, d.seq)
+		if err != nil {
+			logrus.Debugf("skip the conntrack entry %+v, %v", e, err)
+			continue
+		}
+		buffer = append(buffer, message...)
+		pending[d.seq] = true
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	if err := syscall.Sendto(d.fd, buffer, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return 0, fmt.Errorf("send the conntrack delete messages failed, %v", err)
+	}
+	count := 0
+	received := make([]byte, syscall.Getpagesize()*16)
+	for len(pending) > 0 {
+		n, _, err := syscall.Recvfrom(d.fd, received, 0)
+		if err != nil {
+			return count, fmt.Errorf("receive the acks of the conntrack delete messages failed, %v", err)
+		}
+		messages, err := syscall.ParseNetlinkMessage(received[:n])
+		if err != nil {
+			return count, err
+		}
+		for _, message := range messages {
+			if message.Header.Type != syscall.NLMSG_ERROR || !pending[message.Header.Seq] || len(message.Data) < 4 {
+				continue
+			}
+			delete(pending, message.Header.Seq)
+			errno := syscall.Errno(-int32(nativeEndian.Uint32(message.Data[:4])))
+			if errno == 0 {
+				count++
+			} else if errno != syscall.ENOENT {
+				// the entry not found is expired or deleted by others
+				logrus.Debugf("delete conntrack entry failed, %v", errno)
+			}
+		}
+	}
+	return count, nil
+}
+
+func (d *netlinkTable) Close() error {
+	return syscall.Close(d.fd)
+}
+
+// buildDeleteMessage returns the IPCTNL_MSG_CT_DELETE message of the original tuple of the entry. The tuple must be
+// complete, because the message without a tuple flushes the whole table.
+func buildDeleteMessage(e *entry, seq uint32) ([]byte, error) {
+	family, src, dst := syscall.AF_INET, e.src.To4(), e.dst.To4()
+	srcType, dstType := uint16(ctaIpV4Src), uint16(ctaIpV4Dst)
+	if src == nil || dst == nil {
+		family, src, dst = syscall.AF_INET6, e.src.To16(), e.dst.To16()
+		srcType, dstType = ctaIpV6Src, ctaIpV6Dst
+	}
+	if src == nil || dst == nil {
+		return nil, fmt.Errorf("illegal ip")
+	}
+	if e.protoNum <= 0 || e.protoNum > 255 {
+		return nil, fmt.Errorf("illegal protocol number %d", e.protoNum)
+	}
+	proto := [][]byte{netlinkAttr(ctaProtoNum, []byte{byte(e.protoNum)})}
+	switch e.protoNum {
+	case syscall.IPPROTO_ICMP:
+		proto = append(proto, netlinkAttr(ctaProtoIcmpId, bigEndian16(e.icmpId)),
+			netlinkAttr(ctaProtoIcmpType, []byte{byte(e.icmpType)}), netlinkAttr(ctaProtoIcmpCode, []byte{byte(e.icmpCode)}))
+	case syscall.IPPROTO_ICMPV6:
+		proto = append(proto, netlinkAttr(ctaProtoIcmpv6Id, bigEndian16(e.icmpId)),
+			netlinkAttr(ctaProtoIcmpv6Type, []byte{byte(e.icmpType)}), netlinkAttr(ctaProtoIcmpv6Code, []byte{byte(e.icmpCode)}))
+	default:
+		if e.sport > 0 || e.dport > 0 {
+			proto = append(proto, netlinkAttr(ctaProtoSrcPort, bigEndian16(e.sport)),
+				netlinkAttr(ctaProtoDstPort, bigEndian16(e.dport)))
+		}
+	}
+	attrs := nestedAttr(ctaTupleOrig,
+		nestedAttr(ctaTupleIp, netlinkAttr(srcType, src), netlinkAttr(dstType, dst)),
+		nestedAttr(ctaTupleProto, proto...))
+	if e.zone > 0 {
+		attrs = append(attrs, netlinkAttr(ctaZone, bigEndian16(e.zone))...)
+	}
+	return buildMessage(ipctnlMsgCtDelete, syscall.NLM_F_REQUEST|syscall.NLM_F_ACK, seq, family, attrs), nil
+}
+
+// buildMessage returns the ctnetlink message of the type with the attributes
+func buildMessage(msgType, flags uint16, seq uint32, family int, attrs []byte) []byte {
+	// nfgenmsg: the family, the version NFNETLINK_V0 and the resource id
+	payload := append([]byte{byte(family), 0, 0, 0}, attrs...)
+	header := make([]byte, syscall.NLMSG_HDRLEN)
+	nativeEndian.PutUint32(header[0:4], uint32(len(header)+len(payload)))
+	nativeEndian.PutUint16(header[4:6], nfnlSubsysCtnetlink<<8|msgType)
+	nativeEndian.PutUint16(header[6:8], flags)
+	nativeEndian.PutUint32(header[8:12], seq)
+	return append(header, payload...)
+}
+
+// protocolNames is the names of the protocols shown by conntrack -L
+var protocolNames = map[int]string{
+	syscall.IPPROTO_ICMP:    "icmp",
+	syscall.IPPROTO_TCP:     "tcp",
+	syscall.IPPROTO_UDP:     "udp",
+	syscall.IPPROTO_DCCP:    "dccp",
+	syscall.IPPROTO_GRE:     "gre",
+	syscall.IPPROTO_ICMPV6:  "icmpv6",
+	syscall.IPPROTO_SCTP:    "sctp",
+	syscall.IPPROTO_UDPLITE: "udplite",
+}
+
+// parseEntryMessage parses the original tuple and the zone of the entry from the payload of the dumped message
+func parseEntryMessage(data []byte) (*entry, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("the message is too short")
+	}
+	attrs := parseAttrs(data[4:])
+	e := &entry{}
+	if zone, ok := attrs[ctaZone]; ok && len(zone) >= 2 {
+		e.zone = int(binary.BigEndian.Uint16(zone))
+	}
+	tuple := parseAttrs(attrs[ctaTupleOrig])
+	ips := parseAttrs(tuple[ctaTupleIp])
+	if src, ok := ips[ctaIpV4Src]; ok {
+		e.src, e.dst = net.IP(src).To16(), net.IP(ips[ctaIpV4Dst]).To16()
+	} else {
+		// the attributes refer to the receive buffer, which is reused
+		e.src, e.dst = append(net.IP(nil), ips[ctaIpV6Src]...), append(net.IP(nil), ips[ctaIpV6Dst]...)
+	}
+	if len(e.src) != net.IPv6len || len(e.dst) != net.IPv6len {
+		return nil, fmt.Errorf("the original tuple has no ip")
+	}
+	proto := parseAttrs(tuple[ctaTupleProto])
+	if num := proto[ctaProtoNum]; len(num) == 1 {
+		e.protoNum = int(num[0])
+	} else {
+		return nil, fmt.Errorf("the original tuple has no protocol")
+	}
+	e.protocol = protocolNames[e.protoNum]
+	if e.protocol == "" {
+		e.protocol = "unknown"
+	}
+	idType, typeType, codeType := uint16(ctaProtoIcmpId), uint16(ctaProtoIcmpType), uint16(ctaProtoIcmpCode)
+	if e.protoNum == syscall.IPPROTO_ICMPV6 {
+		idType, typeType, codeType = ctaProtoIcmpv6Id, ctaProtoIcmpv6Type, ctaProtoIcmpv6Code
+	}
+	for attrType, value := range proto {
+		switch {
+		case attrType == ctaProtoSrcPort && len(value) == 2:
+			e.sport = int(binary.BigEndian.Uint16(value))
+		case attrType == ctaProtoDstPort && len(value) == 2:
+			e.dport = int(binary.BigEndian.Uint16(value))
+		case attrType == idType && len(value) == 2:
+			e.icmpId = int(binary.BigEndian.Uint16(value))
+		case attrType == typeType && len(value) == 1:
+			e.icmpType = int(value[0])
+		case attrType == codeType && len(value) == 1:
+			e.icmpCode = int(value[0])
+		}
+	}
+	return e, nil
+}
+
+// parseAttrs returns the values of the attributes by the types without the nested flag
+func parseAttrs(data []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	for len(data) >= syscall.SizeofRtAttr {
+		length := int(nativeEndian.Uint16(data[0:2]))
+		if length < syscall.SizeofRtAttr || length > len(data) {
+			break
+		}
+		attrs[nativeEndian.Uint16(data[2:4])&^nlaFNested] = data[syscall.SizeofRtAttr:length]
+		aligned := (length + syscall.NLA_ALIGNTO - 1) &^ (syscall.NLA_ALIGNTO - 1)
+		if aligned > len(data) {
+			break
+		}
+		data = data[aligned:]
+	}
+	return attrs
+}
+
+func netlinkAttr(attrType uint16, data []byte) []byte {
+	length := syscall.SizeofRtAttr + len(data)
+	attr := make([]byte, (length+syscall.NLA_ALIGNTO-1)&^(syscall.NLA_ALIGNTO-1))
+	nativeEndian.PutUint16(attr[0:2], uint16(length))
+	nativeEndian.PutUint16(attr[2:4], attrType)
+	copy(attr[syscall.SizeofRtAttr:], data)
+	return attr
+}
+
+func nestedAttr(attrType uint16, attrs ...[]byte) []byte {
+	return netlinkAttr(attrType|nlaFNested, bytes.Join(attrs, nil))
+}
+
+func bigEndian16(value int) []byte {
+	data := make([]byte, 2)
+	binary.BigEndian.PutUint16(data, uint16(value))
+	return data
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func Test_buildDeleteMessage(t *testing.T) {
+	e := &entry{protoNum: syscall.IPPROTO_TCP, src: net.ParseIP("192.168.1.10"), dst: net.ParseIP("10.0.0.2"), sport: 51234, dport: 3306}
+	message, err := buildDeleteMessage(e, 7)
+	if err != nil {
+		t.Fatalf("build message err, %v", err)
+	}
+	messages, err := syscall.ParseNetlinkMessage(message)
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("parse message err, %v", err)
+	}
+	header := messages[0].Header
+	if header.Type != nfnlSubsysCtnetlink<<8|ipctnlMsgCtDelete || header.Seq != 7 || header.Flags != syscall.NLM_F_REQUEST|syscall.NLM_F_ACK {
+		t.Errorf("unexpected header: %+v", header)
+	}
+	if messages[0].Data[0] != syscall.AF_INET {
+		t.Errorf("unexpected family: %d", messages[0].Data[0])
+	}
+	// the attributes follow the nfgenmsg
+	expected := netlinkAttr(ctaTupleOrig|nlaFNested, append(
+		netlinkAttr(ctaTupleIp|nlaFNested, append(netlinkAttr(ctaIpV4Src, []byte{192, 168, 1, 10}), netlinkAttr(ctaIpV4Dst, []byte{10, 0, 0, 2})...)),
+		netlinkAttr(ctaTupleProto|nlaFNested, append(append(netlinkAttr(ctaProtoNum, []byte{6}),
+			netlinkAttr(ctaProtoSrcPort, []byte{0xc8, 0x22})...), netlinkAttr(ctaProtoDstPort, []byte{0x0c, 0xea})...))...))
+	if !reflect.DeepEqual(messages[0].Data[4:], expected) {
+		t.Errorf("unexpected attributes: %v, expected: %v", messages[0].Data[4:], expected)
+	}
+
+	icmp := &entry{protoNum: syscall.IPPROTO_ICMPV6, src: net.ParseIP("fd00::1"), dst: net.ParseIP("fd00::2"), icmpType: 128, icmpId: 1}
+	if message, err = buildDeleteMessage(icmp, 8); err != nil || message[syscall.NLMSG_HDRLEN] != syscall.AF_INET6 {
+		t.Errorf("unexpected icmpv6 message: %v, %v", message, err)
+	}
+	// the message without a complete tuple flushes the whole table, so it's never built
+	if _, err := buildDeleteMessage(&entry{src: net.ParseIP("10.0.0.1"), dst: net.ParseIP("10.0.0.2")}, 9); err == nil {
+		t.Errorf("expected err for the entry without the protocol number")
+	}
+}
+
+func Test_parseEntryMessage(t *testing.T) {
+	tests := []*entry{
+		{protocol: "tcp", protoNum: syscall.IPPROTO_TCP, src: net.ParseIP("192.168.1.10"), dst: net.ParseIP("10.0.0.2"), sport: 51234, dport: 3306, zone: 2},
+		{protocol: "icmpv6", protoNum: syscall.IPPROTO_ICMPV6, src: net.ParseIP("fd00::1"), dst: net.ParseIP("fd00::2"), icmpType: 128, icmpId: 1},
+	}
+	for _, expected := range tests {
+		// the dumped entry has the same original tuple as the delete message
+		message, err := buildDeleteMessage(expected, 1)
+		if err != nil {
+			t.Fatalf("build message err, %v", err)
+		}
+		e, err := parseEntryMessage(message[syscall.NLMSG_HDRLEN:])
+		if err != nil {
+			t.Fatalf("parse message err, %v", err)
+		}
+		if !reflect.DeepEqual(e, expected) {
+			t.Errorf("unexpected entry: %+v, expected: %+v", e, expected)
+		}
+	}
+	if _, err := parseEntryMessage([]byte{syscall.AF_INET, 0, 0, 0}); err == nil {
+		t.Errorf("expected err for the message without the original tuple")
+	}
+}
+
+func Test_netlinkTable_List(t *testing.T) {
+	table, err := openNetlinkTable()
+	if err != nil {
+		t.Skipf("open the netlink table err, %v", err)
+	}
+	defer table.Close()
+	if _, err := table.List(); err != nil {
+		t.Errorf("list the conntrack entries err, %v", err)
+	}
+}
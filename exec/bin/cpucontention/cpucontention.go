@@ -0,0 +1,274 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/sirupsen/logrus"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var contentionPolicy, contentionCpuList string
+var contentionThreads, contentionBusyTime, contentionPriority, contentionNice int
+var contentionStart, contentionStop, contentionNohup bool
+
+func main() {
+	flag.IntVar(&contentionThreads, "threads", runtime.NumCPU()*4, "the number of runnable threads")
+	flag.IntVar(&contentionBusyTime, "busy-time", 100, "the busy time of each cycle, unit is microsecond")
+	flag.StringVar(&contentionPolicy, "policy", "normal", "the scheduling policy, normal, fifo or rr")
+	flag.IntVar(&contentionPriority, "priority", 1, "the real-time priority of the fifo or rr policy")
+	flag.IntVar(&contentionNice, "nice", 0, "the nice value of the normal policy")
+	flag.StringVar(&contentionCpuList, "cpu-list", "", "CPUs in which the threads run (1,3)")
+	flag.BoolVar(&contentionStart, "start", false, "start cpu contention")
+	flag.BoolVar(&contentionStop, "stop", false, "stop cpu contention")
+	flag.BoolVar(&contentionNohup, "nohup", false, "nohup to run cpu contention")
+	bin.ParseFlagAndInitLog()
+
+	if contentionStart {
+		startContention(contentionThreads, contentionBusyTime, contentionPolicy, contentionPriority, contentionNice, contentionCpuList)
+	} else if contentionStop {
+		if success, errs := stopContention(); !success {
+			bin.PrintErrAndExit(errs)
+		}
+	} else if contentionNohup {
+		policy, err := getPolicy(contentionPolicy)
+		if err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+			return
+		}
+		cores, err := getCores(contentionCpuList)
+		if err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+			return
+		}
+		if err := checkRealtime(policy, contentionPriority, cores); err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+			return
+		}
+		if err := runContention(context.Background(), contentionThreads, time.Duration(contentionBusyTime)*time.Microsecond,
+			policy, contentionPriority, contentionNice, cores); err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+		}
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+var cpuContentionBin = exec.CpuContentionBin
+
+var cl = channel.NewLocalChannel()
+
+var contentionLogFile = util.GetNohupOutput(util.Bin, "chaos_cpucontention.log")
+
+// reportInterval is the interval to measure the context switches after the threads start
+var reportInterval = time.Second
+
+func getPolicy(policy string) (int, error) {
+	switch policy {
+	case "", "normal":
+		return bin.SchedNormal, nil
+	case "fifo":
+		return bin.SchedFifo, nil
+	case "rr":
+		return bin.SchedRR, nil
+	}
+	return 0, fmt.Errorf("illegal policy %s, it must be normal, fifo or rr", policy)
+}
+
+func getCores(cpuList string) ([]int, error) {
+	cores := make([]int, 0)
+	if cpuList == "" {
+		return cores, nil
+	}
+	for _, c := range strings.Split(cpuList, ",") {
+		core, err := strconv.Atoi(c)
+		if err != nil {
+			return nil, fmt.Errorf("illegal cpu core %s", c)
+		}
+		cores = append(cores, core)
+	}
+	return cores, nil
+}
+
+// checkRealtime makes sure the real-time threads leave at least one core and the kernel threads of irq to the others,
+// the busy spinning threads starve everything with a lower priority on their cores
+func checkRealtime(policy, priority int, cores []int) error {
+	if policy == bin.SchedNormal {
+		return nil
+	}
+	if priority < 1 || priority > exec.MaxContentionPriority {
+		return fmt.Errorf("illegal priority %d, it must be in [1, %d]", priority, exec.MaxContentionPriority)
+	}
+	if len(cores) == 0 {
+		return fmt.Errorf("the cpu-list flag is required by the real-time policy")
+	}
+	distinct := make(map[int]bool, len(cores))
+	for _, core := range cores {
+		distinct[core] = true
+	}
+	if len(distinct) >= runtime.NumCPU() {
+		return fmt.Errorf("the cpu-list %v covers all the cpus, the real-time threads must leave one cpu at least", cores)
+	}
+	return nil
+}
+
+func startContention(threads, busyTime int, policy string, priority, nice int, cpuList string) {
+	ctx := context.Background()
+	args := fmt.Sprintf("%s --nohup --threads %d --busy-time %d --policy %s --priority %d --nice %d",
+		path.Join(util.GetProgramPath(), cpuContentionBin), threads, busyTime, policy, priority, nice)
+	if cpuList != "" {
+		args = fmt.Sprintf("%s --cpu-list %s", args, cpuList)
+	}
+	response := cl.Run(ctx, "nohup", fmt.Sprintf("%s > %s 2>&1 &", args, contentionLogFile))
+	if !response.Success {
+		bin.PrintErrAndExit(response.Err)
+		return
+	}
+	// check
+	time.Sleep(time.Second)
+	response = cl.Run(ctx, "grep", fmt.Sprintf("%s %s", bin.ErrPrefix, contentionLogFile))
+	if response.Success {
+		errMsg := strings.TrimSpace(response.Result.(string))
+		if errMsg != "" {
+			stopContention()
+			bin.PrintErrAndExit(errMsg)
+			return
+		}
+	}
+	report, err := measureContention(threads, reportInterval)
+	if err != nil {
+		logrus.Warnf("measure the contention failed, %v", err)
+		bin.PrintOutputAndExit("success")
+		return
+	}
+	bytes, _ := json.Marshal(report)
+	bin.PrintOutputAndExit(string(bytes))
+}
+
+// stopContention kills the contention process like stopping burn cpu
+func stopContention() (success bool, errs string) {
+	ctx := context.WithValue(context.Background(), channel.ProcessKey, "nohup")
+	pids, _ := cl.GetPidsByProcessName(cpuContentionBin, ctx)
+	if len(pids) > 0 {
+		response := cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
+		if !response.Success {
+			return false, response.Err
+		}
+	}
+	cl.Run(ctx, "rm", fmt.Sprintf("-rf %s", contentionLogFile))
+	return true, errs
+}
+
+// contentionReport is the achieved contention of the system
+type contentionReport struct {
+	Threads         int        `json:"threads"`
+	LoadAvg         [3]float64 `json:"loadavg"`
+	ProcsRunning    uint64     `json:"procs_running"`
+	ContextSwitches float64    `json:"context_switches_per_sec"`
+}
+
+// measureContention returns the load average, the runnable threads and the context switches per second in the interval
+func measureContention(threads int, interval time.Duration) (*contentionReport, error) {
+	prev, err := bin.ReadProcStatValue("ctxt")
+	if err != nil {
+		return nil, err
+	}
+	startTime := time.Now()
+	time.Sleep(interval)
+	cur, err := bin.ReadProcStatValue("ctxt")
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(startTime)
+	report := &contentionReport{Threads: threads}
+	if report.ProcsRunning, err = bin.ReadProcStatValue("procs_running"); err != nil {
+		return nil, err
+	}
+	if report.LoadAvg, err = bin.ReadLoadAvg(); err != nil {
+		return nil, err
+	}
+	if cur > prev {
+		report.ContextSwitches = math.Round(float64(cur-prev)/elapsed.Seconds()*100) / 100
+	}
+	return report, nil
+}
+
+// runContention runs the threads until the context is done, it returns the first error of setting up the threads
+func runContention(ctx context.Context, threads int, busy time.Duration, policy, priority, nice int, cores []int) error {
+	// all the threads must hold a processor to be runnable at the same time
+	runtime.GOMAXPROCS(threads + 1)
+	errs := make(chan error, threads)
+	for i := 0; i < threads; i++ {
+		go contend(ctx, busy, policy, priority, nice, cores, errs)
+	}
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// contend locks the goroutine to a thread with the scheduling settings, and loops busy and yield cycles
+func contend(ctx context.Context, busy time.Duration, policy, priority, nice int, cores []int, errs chan<- error) {
+	runtime.LockOSThread()
+	if len(cores) > 0 {
+		if err := bin.SetAffinity(0, cores); err != nil {
+			errs <- err
+			return
+		}
+	}
+	if policy != bin.SchedNormal {
+		if err := bin.SetScheduler(policy, priority); err != nil {
+			errs <- err
+			return
+		}
+	} else if nice != 0 {
+		if err := bin.SetNice(nice); err != nil {
+			errs <- err
+			return
+		}
+	}
+	for i := 0; ; i++ {
+		// check the context sometimes, it is too expensive for every cycle
+		if i%1000 == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+		}
+		startTime := time.Now()
+		for time.Since(startTime) < busy {
+		}
+		bin.Yield()
+	}
+}
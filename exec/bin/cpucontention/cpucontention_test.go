@@ -0,0 +1,148 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+func Test_runContention(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   int
+		priority int
+		nice     int
+		cores    []int
+	}{
+		{"normal", bin.SchedNormal, 0, 0, nil},
+		{"nice", bin.SchedNormal, 0, 5, []int{0}},
+		{"rr", bin.SchedRR, 1, 0, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev, err := bin.ReadProcStatValue("ctxt")
+			if err != nil {
+				t.Skipf("read context switches err, %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+			defer cancel()
+			if err := runContention(ctx, 4, 50*time.Microsecond, tt.policy, tt.priority, tt.nice, tt.cores); err != nil {
+				if tt.policy != bin.SchedNormal {
+					t.Skipf("the real-time policy is not permitted, %v", err)
+				}
+				t.Fatalf("run contention err, %v", err)
+			}
+			cur, _ := bin.ReadProcStatValue("ctxt")
+			// every thread yields thousands of times
+			if cur-prev < 100 {
+				t.Errorf("unexpected context switches: %d", cur-prev)
+			}
+		})
+	}
+}
+
+func Test_runContention_failed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// the real-time priority must be in [1, 99]
+	if err := runContention(ctx, 2, time.Microsecond, bin.SchedFifo, 0, 0, nil); err == nil {
+		t.Errorf("expected err for the illegal priority")
+	}
+}
+
+func Test_measureContention(t *testing.T) {
+	report, err := measureContention(8, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("measure contention err, %v", err)
+	}
+	if report.Threads != 8 || report.ProcsRunning == 0 || report.ContextSwitches <= 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func Test_getPolicyAndCores(t *testing.T) {
+	for policy, expected := range map[string]int{"": bin.SchedNormal, "normal": bin.SchedNormal, "fifo": bin.SchedFifo, "rr": bin.SchedRR} {
+		if got, err := getPolicy(policy); err != nil || got != expected {
+			t.Errorf("unexpected policy of `%s`: %d, %v", policy, got, err)
+		}
+	}
+	if _, err := getPolicy("batch"); err == nil {
+		t.Errorf("expected err for the illegal policy")
+	}
+	if cores, err := getCores("0,3"); err != nil || !reflect.DeepEqual(cores, []int{0, 3}) {
+		t.Errorf("unexpected cores: %v, %v", cores, err)
+	}
+	if _, err := getCores("0-3"); err == nil {
+		t.Errorf("expected err for the cpu range")
+	}
+}
+
+func Test_checkRealtime(t *testing.T) {
+	if err := checkRealtime(bin.SchedNormal, 0, nil); err != nil {
+		t.Errorf("unexpected err for the normal policy, %v", err)
+	}
+	if err := checkRealtime(bin.SchedFifo, 1, nil); err == nil {
+		t.Errorf("expected err for the real-time policy without cpu-list")
+	}
+	if err := checkRealtime(bin.SchedRR, 99, []int{0}); err == nil {
+		t.Errorf("expected err for the priority above the irq threads")
+	}
+	all := make([]int, 0)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		all = append(all, i, i)
+	}
+	if err := checkRealtime(bin.SchedFifo, 1, all); err == nil {
+		t.Errorf("expected err for the cpu-list covering all the cpus")
+	}
+	if runtime.NumCPU() > 1 {
+		if err := checkRealtime(bin.SchedFifo, 10, []int{0}); err != nil {
+			t.Errorf("unexpected err for one cpu, %v", err)
+		}
+	}
+}
+
+func Test_stopContention(t *testing.T) {
+	cl = channel.NewMockLocalChannel()
+	defer func() { cl = channel.NewLocalChannel() }()
+	mockChannel := cl.(*channel.MockLocalChannel)
+	mockChannel.GetPidsByProcessNameFunc = func(processName string, ctx context.Context) ([]string, error) {
+		return []string{"100", "101"}, nil
+	}
+	actualCommands := make([]string, 0)
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		actualCommands = append(actualCommands, fmt.Sprintf("%s %s", script, args))
+		return spec.ReturnSuccess("")
+	}
+	expectedCommands := []string{"kill -9 100 101", fmt.Sprintf("rm -rf %s", contentionLogFile)}
+
+	if success, errs := stopContention(); !success {
+		t.Fatalf("stop contention err, %s", errs)
+	}
+	if !reflect.DeepEqual(expectedCommands, actualCommands) {
+		t.Errorf("unexpected commands: %+v, expected commands: %+v", actualCommands, expectedCommands)
+	}
+}
@@ -0,0 +1,340 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/sirupsen/logrus"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var faultUid, faultPath, faultTarget string
+var faultReadDelay, faultWriteDelay, faultUpInterval, faultDownInterval int
+var faultDropWrites, faultErrorWrites, faultStart, faultStop bool
+
+func main() {
+	flag.StringVar(&faultUid, "uid", "", "the uid of the experiment")
+	flag.StringVar(&faultPath, "path", "/", "the path on the device-mapper device")
+	flag.StringVar(&faultTarget, "target", "", "the device-mapper target to swap in, delay or flakey")
+	flag.IntVar(&faultReadDelay, "read-delay", 0, "the delay of the reads, unit is ms")
+	flag.IntVar(&faultWriteDelay, "write-delay", 0, "the delay of the writes, unit is ms")
+	flag.IntVar(&faultUpInterval, "up-interval", 0, "the seconds the device works in each cycle")
+	flag.IntVar(&faultDownInterval, "down-interval", 1, "the seconds the device fails in each cycle")
+	flag.BoolVar(&faultDropWrites, "drop-writes", false, "drop the writes silently when the device is down")
+	flag.BoolVar(&faultErrorWrites, "error-writes", false, "fail the writes only when the device is down")
+	flag.BoolVar(&faultStart, "start", false, "swap in the target")
+	flag.BoolVar(&faultStop, "stop", false, "restore the original table")
+	bin.ParseFlagAndInitLog()
+
+	if faultUid == "" {
+		bin.PrintErrAndExit("less --uid flag")
+		return
+	}
+	if faultStart {
+		params, err := buildTargetParams(faultTarget, faultReadDelay, faultWriteDelay,
+			faultUpInterval, faultDownInterval, faultDropWrites, faultErrorWrites)
+		if err != nil {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+		startFault(faultUid, faultPath, faultTarget, params)
+	} else if faultStop {
+		stopFault(faultUid)
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+const (
+	delayTarget  = "delay"
+	flakeyTarget = "flakey"
+)
+
+var cl = channel.NewLocalChannel()
+
+// faultState is the original table of the device-mapper device, which is recorded in the backup file
+type faultState struct {
+	Name string `json:"name"`
+	// Inner is the device created by the original table, which the delay or flakey target is stacked on
+	Inner string `json:"inner"`
+	Table string `json:"table"`
+}
+
+func getBackupFile(uid string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_diskfault_%s.bak", uid))
+}
+
+func getTableFile(uid string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_diskfault_%s.table", uid))
+}
+
+// buildTargetParams returns the parameters of the target after the underlying device and its offset,
+// <read_ms> <device> 0 <write_ms> for delay, and <up> <down> [<features>] for flakey
+func buildTargetParams(target string, readDelay, writeDelay, upInterval, downInterval int,
+	dropWrites, errorWrites bool) (func(device string) string, error) {
+	switch target {
+	case delayTarget:
+		if readDelay < 0 || writeDelay < 0 || readDelay+writeDelay == 0 {
+			return nil, fmt.Errorf("illegal --read-delay %d and --write-delay %d, one of them must be positive", readDelay, writeDelay)
+		}
+		return func(device string) string {
+			return fmt.Sprintf("%d %s 0 %d", readDelay, device, writeDelay)
+		}, nil
+	case flakeyTarget:
+		if upInterval < 0 || downInterval <= 0 {
+			return nil, fmt.Errorf("illegal --up-interval %d and --down-interval %d, the down interval must be positive", upInterval, downInterval)
+		}
+		if dropWrites && errorWrites {
+			return nil, fmt.Errorf("--drop-writes and --error-writes cannot be specified at the same time")
+		}
+		features := "0"
+		if dropWrites {
+			features = "1 drop_writes"
+		} else if errorWrites {
+			features = "1 error_writes"
+		}
+		return func(string) string {
+			return fmt.Sprintf("%d %d %s", upInterval, downInterval, features)
+		}, nil
+	}
+	return nil, fmt.Errorf("illegal --target %s, it must be delay or flakey", target)
+}
+
+// getSectors returns the total sectors of the table, which is the end of the last segment
+func getSectors(table string) (uint64, error) {
+	var sectors uint64
+	for _, line := range strings.Split(strings.TrimSpace(table), "\n") {
+		// 0 2097152 linear 7:0 0
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return 0, fmt.Errorf("illegal table line %s", line)
+		}
+		start, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("illegal table line %s, %v", line, err)
+		}
+		length, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("illegal table line %s, %v", line, err)
+		}
+		if start+length > sectors {
+			sectors = start + length
+		}
+	}
+	if sectors == 0 {
+		return 0, fmt.Errorf("empty table")
+	}
+	return sectors, nil
+}
+
+// innerPrefix is the prefix of the inner devices created by the experiments
+const innerPrefix = "chaos_"
+
+// checkTable returns error if the table cannot be copied to the inner device safely. Only linear and striped are
+// supported, for example the key of crypt is masked in the table, and thin or snapshot maps the metadata twice.
+// The device already stacked on an inner device is refused too, the inner devices cannot be removed out of order.
+func checkTable(name, table string) error {
+	if strings.HasPrefix(name, innerPrefix) {
+		return fmt.Errorf("%s is the inner device of another experiment", name)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(table), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return fmt.Errorf("illegal table line %s", line)
+		}
+		var devices []string
+		switch fields[2] {
+		case "linear":
+			// 0 2097152 linear 7:0 0
+			if len(fields) != 5 {
+				return fmt.Errorf("illegal table line %s", line)
+			}
+			devices = []string{fields[3]}
+		case "striped":
+			// 0 4194304 striped 2 128 7:0 0 7:1 0
+			for i := 5; i+1 < len(fields); i += 2 {
+				devices = append(devices, fields[i])
+			}
+			if len(devices) == 0 {
+				return fmt.Errorf("illegal table line %s", line)
+			}
+		default:
+			return fmt.Errorf("the %s target of %s is not supported, only linear and striped are supported", fields[2], name)
+		}
+		for _, device := range devices {
+			deviceName := path.Base(device)
+			if strings.Contains(device, ":") {
+				// the device is not a device-mapper device if the name is not found
+				deviceName, _ = bin.GetDeviceMapperName(device)
+			}
+			if strings.HasPrefix(deviceName, innerPrefix) {
+				return fmt.Errorf("%s is stacked on the inner device %s of another experiment", name, deviceName)
+			}
+		}
+	}
+	return nil
+}
+
+func dmsetup(args string) (string, error) {
+	response := cl.Run(context.Background(), "dmsetup", args)
+	if !response.Success {
+		return "", fmt.Errorf("dmsetup %s failed, %s", args, response.Err)
+	}
+	if result, ok := response.Result.(string); ok {
+		return result, nil
+	}
+	return "", nil
+}
+
+func startFault(uid, p, target string, params func(device string) string) {
+	backupFile := getBackupFile(uid)
+	if util.IsExist(backupFile) {
+		bin.PrintErrAndExit(fmt.Sprintf("the disk %s experiment %s is running, the backup file %s exists", target, uid, backupFile))
+		return
+	}
+	if !cl.IsCommandAvailable("dmsetup") {
+		bin.PrintErrAndExit("dmsetup command not found")
+		return
+	}
+	device, err := bin.GetMountDevice(p)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	name, err := bin.GetDeviceMapperName(device)
+	if err != nil {
+		bin.PrintErrAndExit(fmt.Sprintf("%v, the path must be on a device-mapper device, for example a dm-linear over a loop device", err))
+		return
+	}
+	table, err := dmsetup(fmt.Sprintf("table %s", name))
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	if err := checkTable(name, table); err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	sectors, err := getSectors(table)
+	if err != nil {
+		bin.PrintErrAndExit(fmt.Sprintf("get the sectors of %s failed, %v", name, err))
+		return
+	}
+	state := &faultState{Name: name, Inner: innerPrefix + uid, Table: strings.TrimSpace(table)}
+	bytes, _ := json.Marshal(state)
+	if err := ioutil.WriteFile(backupFile, bytes, 0644); err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	faultTable := fmt.Sprintf("0 %d %s /dev/mapper/%s 0 %s", sectors, target, state.Inner, params("/dev/mapper/"+state.Inner))
+	if err := swapTable(uid, state, faultTable); err != nil {
+		if restoreErr := restoreTable(uid, state); restoreErr != nil {
+			logrus.Warnf("restore the table of %s failed, %v", name, restoreErr)
+		} else {
+			os.Remove(backupFile)
+		}
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	bin.PrintOutputAndExit(fmt.Sprintf("swap in the table of %s: %s", name, faultTable))
+}
+
+// swapTable creates the inner device by the original table, and replaces the table of the device by the fault table
+func swapTable(uid string, state *faultState, faultTable string) error {
+	tableFile := getTableFile(uid)
+	defer os.Remove(tableFile)
+	if err := ioutil.WriteFile(tableFile, []byte(state.Table+"\n"), 0644); err != nil {
+		return err
+	}
+	if _, err := dmsetup(fmt.Sprintf("create %s < %s", state.Inner, tableFile)); err != nil {
+		return err
+	}
+	if _, err := dmsetup(fmt.Sprintf(`load %s --table "%s"`, state.Name, faultTable)); err != nil {
+		return err
+	}
+	if _, err := dmsetup(fmt.Sprintf("suspend %s", state.Name)); err != nil {
+		dmsetup(fmt.Sprintf("clear %s", state.Name))
+		return err
+	}
+	// the inactive fault table becomes live when resumed
+	if _, err := dmsetup(fmt.Sprintf("resume %s", state.Name)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// restoreTable loads the original table to the device, and removes the inner device
+func restoreTable(uid string, state *faultState) error {
+	tableFile := getTableFile(uid)
+	defer os.Remove(tableFile)
+	if err := ioutil.WriteFile(tableFile, []byte(state.Table+"\n"), 0644); err != nil {
+		return err
+	}
+	if _, err := dmsetup(fmt.Sprintf("load %s < %s", state.Name, tableFile)); err != nil {
+		return err
+	}
+	if _, err := dmsetup(fmt.Sprintf("suspend %s", state.Name)); err != nil {
+		dmsetup(fmt.Sprintf("clear %s", state.Name))
+		return err
+	}
+	if _, err := dmsetup(fmt.Sprintf("resume %s", state.Name)); err != nil {
+		return err
+	}
+	// the inner device may not exist if the experiment failed to start
+	if _, err := dmsetup(fmt.Sprintf("info %s", state.Inner)); err == nil {
+		if _, err := dmsetup(fmt.Sprintf("remove %s", state.Inner)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func stopFault(uid string) {
+	backupFile := getBackupFile(uid)
+	bytes, err := ioutil.ReadFile(backupFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			bin.PrintOutputAndExit("nothing to do")
+			return
+		}
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	var state faultState
+	if err := json.Unmarshal(bytes, &state); err != nil || state.Name == "" || state.Table == "" {
+		bin.PrintErrAndExit(fmt.Sprintf("illegal backup file %s, %v", backupFile, err))
+		return
+	}
+	if err := restoreTable(uid, &state); err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	os.Remove(backupFile)
+	bin.PrintOutputAndExit(fmt.Sprintf("restore the table of %s", state.Name))
+}
@@ -0,0 +1,197 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
+)
+
+func Test_buildTargetParams(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		readDelay   int
+		writeDelay  int
+		up          int
+		down        int
+		dropWrites  bool
+		errorWrites bool
+		expected    string
+		wantErr     bool
+	}{
+		{name: "delay reads", target: delayTarget, readDelay: 100, expected: "100 /dev/mapper/inner 0 0"},
+		{name: "delay writes", target: delayTarget, readDelay: 10, writeDelay: 500, expected: "10 /dev/mapper/inner 0 500"},
+		{name: "no delay", target: delayTarget, wantErr: true},
+		{name: "always fail", target: flakeyTarget, down: 1, expected: "0 1 0"},
+		{name: "drop writes", target: flakeyTarget, up: 30, down: 10, dropWrites: true, expected: "30 10 1 drop_writes"},
+		{name: "error writes", target: flakeyTarget, up: 30, down: 10, errorWrites: true, expected: "30 10 1 error_writes"},
+		{name: "drop and error writes", target: flakeyTarget, down: 10, dropWrites: true, errorWrites: true, wantErr: true},
+		{name: "illegal target", target: "linear", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := buildTargetParams(tt.target, tt.readDelay, tt.writeDelay, tt.up, tt.down, tt.dropWrites, tt.errorWrites)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildTargetParams() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && params("/dev/mapper/inner") != tt.expected {
+				t.Errorf("buildTargetParams() = %s, expected %s", params("/dev/mapper/inner"), tt.expected)
+			}
+		})
+	}
+}
+
+func Test_getSectors(t *testing.T) {
+	sectors, err := getSectors("0 2097152 linear 7:0 0\n2097152 1048576 linear 7:1 2048\n")
+	if err != nil || sectors != 3145728 {
+		t.Errorf("getSectors() = %d, %v, expected 3145728", sectors, err)
+	}
+	if _, err := getSectors(""); err == nil {
+		t.Errorf("expected err for the empty table")
+	}
+}
+
+func Test_checkTable(t *testing.T) {
+	root, clean := bintest.NewRoot(t)
+	defer clean()
+	bintest.WriteFiles(t, root, map[string]string{
+		"sys/dev/block/252:1/dm/name": "chaos_diskfault-1\n",
+		"sys/dev/block/252:2/dm/name": "data\n",
+	})
+	defer bintest.Replace(&bin.SysPath, path.Join(root, "sys"))()
+
+	tests := []struct {
+		name    string
+		device  string
+		table   string
+		wantErr bool
+	}{
+		{name: "linear", device: "data", table: "0 2097152 linear 7:0 0\n2097152 1048576 linear 252:2 2048\n"},
+		{name: "striped", device: "data", table: "0 4194304 striped 2 128 7:0 0 7:1 0\n"},
+		{name: "crypt", device: "data", table: "0 2097152 crypt aes-xts-plain64 :64:logon:cryptsetup:key 0 7:0 4096\n", wantErr: true},
+		{name: "thin", device: "data", table: "0 2097152 thin 252:3 1\n", wantErr: true},
+		{name: "delay", device: "data", table: "0 2097152 delay 252:1 0 100 252:1 0 0\n", wantErr: true},
+		{name: "stacked on inner device", device: "data", table: "0 2097152 linear 252:1 0\n", wantErr: true},
+		{name: "striped on inner device", device: "data", table: "0 4194304 striped 2 128 7:0 0 252:1 0\n", wantErr: true},
+		{name: "inner device", device: "chaos_diskfault-1", table: "0 2097152 linear 7:0 0\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkTable(tt.device, tt.table); (err != nil) != tt.wantErr {
+				t.Errorf("checkTable() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_startAndStopFault(t *testing.T) {
+	root, clean := bintest.NewRoot(t)
+	defer clean()
+	bintest.WriteFiles(t, root, map[string]string{
+		"proc/self/mountinfo":         "21 20 252:0 / /data rw,relatime - ext4 /dev/mapper/data rw\n",
+		"sys/dev/block/252:0/dm/name": "data\n",
+	})
+	defer bintest.Replace(&bin.ProcPath, path.Join(root, "proc"))()
+	defer bintest.Replace(&bin.SysPath, path.Join(root, "sys"))()
+	var exitCode int
+	bin.ExitFunc = func(code int) {
+		exitCode = code
+	}
+
+	uid := "diskfault-test"
+	defer os.Remove(getBackupFile(uid))
+	commands := make([]string, 0)
+	tables := make([]string, 0)
+	mockChannel := channel.NewMockLocalChannel().(*channel.MockLocalChannel)
+	mockChannel.IsCommandAvailableFunc = func(commandName string) bool { return true }
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		commands = append(commands, fmt.Sprintf("%s %s", script, strings.Split(args, " < ")[0]))
+		if strings.Contains(args, " < ") {
+			bytes, _ := ioutil.ReadFile(strings.Split(args, " < ")[1])
+			tables = append(tables, string(bytes))
+		}
+		if args == "table data" {
+			return spec.ReturnSuccess("0 2097152 linear 7:0 0\n")
+		}
+		return spec.ReturnSuccess("")
+	}
+	cl = mockChannel
+
+	params, _ := buildTargetParams(delayTarget, 100, 200, 0, 0, false, false)
+	startFault(uid, "/data/mysql", delayTarget, params)
+	if exitCode != 0 {
+		t.Fatalf("start fault err, %s", bin.ExitMessageForTesting)
+	}
+	expected := []string{
+		"dmsetup table data",
+		"dmsetup create chaos_diskfault-test",
+		`dmsetup load data --table "0 2097152 delay /dev/mapper/chaos_diskfault-test 0 100 /dev/mapper/chaos_diskfault-test 0 200"`,
+		"dmsetup suspend data",
+		"dmsetup resume data",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("unexpected commands: %v, expected: %v", commands, expected)
+	}
+	if !util.IsExist(getBackupFile(uid)) {
+		t.Errorf("expected the backup file %s", getBackupFile(uid))
+	}
+
+	commands = commands[:0]
+	stopFault(uid)
+	if exitCode != 0 {
+		t.Fatalf("stop fault err, %s", bin.ExitMessageForTesting)
+	}
+	expected = []string{
+		"dmsetup load data",
+		"dmsetup suspend data",
+		"dmsetup resume data",
+		"dmsetup info chaos_diskfault-test",
+		"dmsetup remove chaos_diskfault-test",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("unexpected commands: %v, expected: %v", commands, expected)
+	}
+	for _, table := range tables {
+		if table != "0 2097152 linear 7:0 0\n" {
+			t.Errorf("unexpected table: %s, expected the original table", table)
+		}
+	}
+	if util.IsExist(getBackupFile(uid)) {
+		t.Errorf("expected the backup file %s removed", getBackupFile(uid))
+	}
+
+	// the path not on a device-mapper device
+	bintest.WriteFiles(t, root, map[string]string{"proc/self/mountinfo": "21 20 7:0 / /data rw,relatime - ext4 /dev/loop0 rw\n"})
+	startFault(uid, "/data", delayTarget, params)
+	if exitCode != 1 || !strings.Contains(bin.ExitMessageForTesting, "not a device-mapper device") {
+		t.Errorf("unexpected result: %d, %s, expected the err of the device-mapper device", exitCode, bin.ExitMessageForTesting)
+	}
+}
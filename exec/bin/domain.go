@@ -0,0 +1,109 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LookupIP is used to resolve the domains, it is replaced in tests
+var LookupIP = net.LookupIP
+
+// ResolveDomains resolves the comma separated domains to the sorted A and AAAA records
+func ResolveDomains(domains string) ([]string, error) {
+	ipSet := make(map[string]bool)
+	for _, domain := range strings.Split(domains, ",") {
+		domain = strings.TrimSpace(domain)
+		if domain == "" {
+			continue
+		}
+		ips, err := LookupIP(domain)
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s failed, %v", domain, err)
+		}
+		for _, ip := range ips {
+			ipSet[ip.String()] = true
+		}
+	}
+	if len(ipSet) == 0 {
+		return nil, fmt.Errorf("no ip is resolved for %s", domains)
+	}
+	ips := make([]string, 0, len(ipSet))
+	for ip := range ipSet {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips, nil
+}
+
+// DiffIps returns the ips which are added to and removed from the current ips
+func DiffIps(current, latest []string) (added, removed []string) {
+	currentSet := make(map[string]bool, len(current))
+	for _, ip := range current {
+		currentSet[ip] = true
+	}
+	latestSet := make(map[string]bool, len(latest))
+	for _, ip := range latest {
+		latestSet[ip] = true
+		if !currentSet[ip] {
+			added = append(added, ip)
+		}
+	}
+	for _, ip := range current {
+		if !latestSet[ip] {
+			removed = append(removed, ip)
+		}
+	}
+	return added, removed
+}
+
+// SuperviseDomains re-resolves the domains on the interval until the context is done, and invokes the update
+// function when the ips change. The current ips are kept if the domains can't be resolved temporarily.
+func SuperviseDomains(ctx context.Context, domains string, interval time.Duration, current []string,
+	update func(added, removed []string) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		latest, err := ResolveDomains(domains)
+		if err != nil {
+			logrus.Warnf("re-resolve %s failed, keep the current ips %v, %v", domains, current, err)
+			continue
+		}
+		added, removed := DiffIps(current, latest)
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		logrus.Infof("the ips of %s changed, added: %v, removed: %v", domains, added, removed)
+		if err := update(added, removed); err != nil {
+			logrus.Warnf("update the rules for %s failed, %v", domains, err)
+			continue
+		}
+		current = latest
+	}
+}
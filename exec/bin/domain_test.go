@@ -0,0 +1,61 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func Test_ResolveDomains(t *testing.T) {
+	LookupIP = func(host string) ([]net.IP, error) {
+		switch host {
+		case "www.example.com":
+			return []net.IP{net.ParseIP("10.0.0.2"), net.ParseIP("2001:db8::1")}, nil
+		case "api.example.com":
+			return []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}, nil
+		}
+		return nil, fmt.Errorf("no such host")
+	}
+	defer func() {
+		LookupIP = net.LookupIP
+	}()
+	ips, err := ResolveDomains("www.example.com,api.example.com")
+	if err != nil {
+		t.Fatalf("resolve err, %v", err)
+	}
+	expected := []string{"10.0.0.1", "10.0.0.2", "2001:db8::1"}
+	if !reflect.DeepEqual(ips, expected) {
+		t.Errorf("unexpected ips: %v, expected: %v", ips, expected)
+	}
+	if _, err := ResolveDomains("www.example.com,unknown.example.com"); err == nil {
+		t.Errorf("expected err for the unknown domain")
+	}
+}
+
+func Test_DiffIps(t *testing.T) {
+	added, removed := DiffIps([]string{"10.0.0.1", "10.0.0.2"}, []string{"10.0.0.2", "10.0.0.3"})
+	if !reflect.DeepEqual(added, []string{"10.0.0.3"}) || !reflect.DeepEqual(removed, []string{"10.0.0.1"}) {
+		t.Errorf("unexpected added: %v, removed: %v", added, removed)
+	}
+	added, removed = DiffIps([]string{"10.0.0.1"}, []string{"10.0.0.1"})
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("unexpected added: %v, removed: %v", added, removed)
+	}
+}
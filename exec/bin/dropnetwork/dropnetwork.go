@@ -20,16 +20,23 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/sirupsen/logrus"
 
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
 )
 
 var dropSourceIp, dropDestinationIp, dropSourcePort, dropDestinationPort, dropStringPattern, dropNetworkTraffic string
-var dropNetStart, dropNetStop bool
+var dropDestinationDomain, dropDomainIps string
+var dropResolveInterval int
+var dropNetStart, dropNetStop, dropNohup bool
 
 func main() {
 	flag.StringVar(&dropSourceIp, "source-ip", "", "source ip")
@@ -38,13 +45,32 @@ func main() {
 	flag.StringVar(&dropDestinationPort, "destination-port", "", "destination port")
 	flag.StringVar(&dropStringPattern, "string-pattern", "", "string pattern")
 	flag.StringVar(&dropNetworkTraffic, "network-traffic", "", "network traffic")
+	flag.StringVar(&dropDestinationDomain, "destination-domain", "", "destination domain")
+	flag.StringVar(&dropDomainIps, "domain-ips", "", "the ips of the destination domain which the rules are added for, used by the supervisor")
+	flag.IntVar(&dropResolveInterval, "resolve-interval", 30, "the interval of re-resolving the destination domain, unit is second")
+	flag.BoolVar(&dropNohup, "nohup", false, "nohup to supervise the destination domain")
 	flag.BoolVar(&dropNetStart, "start", false, "start drop")
 	flag.BoolVar(&dropNetStop, "stop", false, "stop drop")
 	bin.ParseFlagAndInitLog()
 
+	if dropNohup {
+		superviseDomainDrop(dropDestinationDomain, dropDomainIps, dropSourcePort, dropDestinationPort, dropStringPattern,
+			dropNetworkTraffic, dropResolveInterval)
+		return
+	}
 	if dropNetStart == dropNetStop {
 		bin.PrintErrAndExit("must add --start or --stop flag")
 	}
+	if dropDestinationDomain != "" {
+		if dropNetStart {
+			startDomainDrop(dropSourceIp, dropDestinationIp, dropSourcePort, dropDestinationPort, dropStringPattern,
+				dropNetworkTraffic, dropDestinationDomain)
+		} else {
+			stopDomainDrop(dropDestinationDomain)
+			bin.PrintOutputAndExit("success")
+		}
+		return
+	}
 	if dropNetStart {
 		startDropNet(dropSourceIp, dropDestinationIp, dropSourcePort, dropDestinationPort, dropStringPattern, dropNetworkTraffic)
 	} else if dropNetStop {
@@ -73,60 +99,16 @@ func handleDropSpecifyPort(sourceIp string, destinationIp string, sourcePort str
 	}
 
 	var response *spec.Response
-	netFlows := []string{"INPUT", "OUTPUT"}
-	if networkTraffic == "in" {
-		netFlows = []string{"INPUT"}
-	}
-	if networkTraffic == "out" {
-		netFlows = []string{"OUTPUT"}
-	}
-	for _, netFlow := range netFlows {
-		tcpArgs := fmt.Sprintf("-A %s -p tcp", netFlow)
-		udpArgs := fmt.Sprintf("-A %s -p udp", netFlow)
-		if sourceIp != "" {
-			tcpArgs = fmt.Sprintf("%s -s %s", tcpArgs, sourceIp)
-			udpArgs = fmt.Sprintf("%s -s %s", udpArgs, sourceIp)
-		}
-		if destinationIp != "" {
-			tcpArgs = fmt.Sprintf("%s -d %s", tcpArgs, destinationIp)
-			udpArgs = fmt.Sprintf("%s -d %s", udpArgs, destinationIp)
-		}
-		if sourcePort != "" {
-			if strings.Contains(sourcePort, ",") {
-				tcpArgs = fmt.Sprintf("%s -m multiport --sports %s", tcpArgs, sourcePort)
-				udpArgs = fmt.Sprintf("%s -m multiport --sports %s", udpArgs, sourcePort)
-			} else {
-				tcpArgs = fmt.Sprintf("%s --sport %s", tcpArgs, sourcePort)
-				udpArgs = fmt.Sprintf("%s --sport %s", udpArgs, sourcePort)
+	for _, netFlow := range getNetFlows(networkTraffic) {
+		for _, protocol := range []string{"tcp", "udp"} {
+			rule := getDropRule(netFlow, protocol, sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, "")
+			response = cl.Run(ctx, "iptables", fmt.Sprintf(`-A %s`, rule))
+			if !response.Success {
+				stopDropNetFunc(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic)
+				bin.PrintErrAndExit(response.Err)
+				return
 			}
 		}
-		if destinationPort != "" {
-			if strings.Contains(destinationPort, ",") {
-				tcpArgs = fmt.Sprintf("%s -m multiport --dports %s", tcpArgs, destinationPort)
-				udpArgs = fmt.Sprintf("%s -m multiport --dports %s", udpArgs, destinationPort)
-			} else {
-				tcpArgs = fmt.Sprintf("%s --dport %s", tcpArgs, destinationPort)
-				udpArgs = fmt.Sprintf("%s --dport %s", udpArgs, destinationPort)
-			}
-		}
-		if stringPattern != "" {
-			tcpArgs = fmt.Sprintf("%s -m string --string %s --algo bm", tcpArgs, stringPattern)
-			udpArgs = fmt.Sprintf("%s -m string --string %s --algo bm", udpArgs, stringPattern)
-		}
-		tcpArgs = fmt.Sprintf("%s -j DROP", tcpArgs)
-		udpArgs = fmt.Sprintf("%s -j DROP", udpArgs)
-		response = cl.Run(ctx, "iptables", fmt.Sprintf(`%s`, tcpArgs))
-		if !response.Success {
-			stopDropNetFunc(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic)
-			bin.PrintErrAndExit(response.Err)
-			return
-		}
-		response = cl.Run(ctx, "iptables", fmt.Sprintf(`%s`, udpArgs))
-		if !response.Success {
-			stopDropNetFunc(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic)
-			bin.PrintErrAndExit(response.Err)
-			return
-		}
 	}
 	bin.PrintOutputAndExit(response.Result.(string))
 }
@@ -138,6 +120,20 @@ func stopDropNet(sourceIp, destinationIp, sourcePort, destinationPort, stringPat
 
 	ctx := context.Background()
 	var response *spec.Response
+	for _, netFlow := range getNetFlows(networkTraffic) {
+		for _, protocol := range []string{"tcp", "udp"} {
+			rule := getDropRule(netFlow, protocol, sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, "")
+			response = cl.Run(ctx, "iptables", fmt.Sprintf(`-D %s`, rule))
+			if !response.Success {
+				bin.PrintErrAndExit(response.Err)
+				return
+			}
+		}
+	}
+	bin.PrintOutputAndExit(response.Result.(string))
+}
+
+func getNetFlows(networkTraffic string) []string {
 	netFlows := []string{"INPUT", "OUTPUT"}
 	if networkTraffic == "in" {
 		netFlows = []string{"INPUT"}
@@ -145,51 +141,188 @@ func stopDropNet(sourceIp, destinationIp, sourcePort, destinationPort, stringPat
 	if networkTraffic == "out" {
 		netFlows = []string{"OUTPUT"}
 	}
-	for _, netFlow := range netFlows {
-		tcpArgs := fmt.Sprintf("-D %s -p tcp", netFlow)
-		udpArgs := fmt.Sprintf("-D %s -p udp", netFlow)
-		if sourceIp != "" {
-			tcpArgs = fmt.Sprintf("%s -s %s", tcpArgs, sourceIp)
-			udpArgs = fmt.Sprintf("%s -s %s", udpArgs, sourceIp)
-		}
-		if destinationIp != "" {
-			tcpArgs = fmt.Sprintf("%s -d %s", tcpArgs, destinationIp)
-			udpArgs = fmt.Sprintf("%s -d %s", udpArgs, destinationIp)
-		}
-		if sourcePort != "" {
-			if strings.Contains(sourcePort, ",") {
-				tcpArgs = fmt.Sprintf("%s -m multiport --sports %s", tcpArgs, sourcePort)
-				udpArgs = fmt.Sprintf("%s -m multiport --sports %s", udpArgs, sourcePort)
-			} else {
-				tcpArgs = fmt.Sprintf("%s --sport %s", tcpArgs, sourcePort)
-				udpArgs = fmt.Sprintf("%s --sport %s", udpArgs, sourcePort)
-			}
+	return netFlows
+}
+
+// getDropRule returns the rule without the command, for example: OUTPUT -p tcp -d 10.0.0.1 --dport 80 -j DROP
+func getDropRule(netFlow, protocol, sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, comment string) string {
+	args := fmt.Sprintf("%s -p %s", netFlow, protocol)
+	if sourceIp != "" {
+		args = fmt.Sprintf("%s -s %s", args, sourceIp)
+	}
+	if destinationIp != "" {
+		args = fmt.Sprintf("%s -d %s", args, destinationIp)
+	}
+	if sourcePort != "" {
+		if strings.Contains(sourcePort, ",") {
+			args = fmt.Sprintf("%s -m multiport --sports %s", args, sourcePort)
+		} else {
+			args = fmt.Sprintf("%s --sport %s", args, sourcePort)
 		}
-		if destinationPort != "" {
-			if strings.Contains(destinationPort, ",") {
-				tcpArgs = fmt.Sprintf("%s -m multiport --dports %s", tcpArgs, destinationPort)
-				udpArgs = fmt.Sprintf("%s -m multiport --dports %s", udpArgs, destinationPort)
-			} else {
-				tcpArgs = fmt.Sprintf("%s --dport %s", tcpArgs, destinationPort)
-				udpArgs = fmt.Sprintf("%s --dport %s", udpArgs, destinationPort)
-			}
+	}
+	if destinationPort != "" {
+		if strings.Contains(destinationPort, ",") {
+			args = fmt.Sprintf("%s -m multiport --dports %s", args, destinationPort)
+		} else {
+			args = fmt.Sprintf("%s --dport %s", args, destinationPort)
+		}
+	}
+	if stringPattern != "" {
+		args = fmt.Sprintf("%s -m string --string %s --algo bm", args, stringPattern)
+	}
+	if comment != "" {
+		args = fmt.Sprintf("%s -m comment --comment %s", args, comment)
+	}
+	return fmt.Sprintf("%s -j DROP", args)
+}
+
+// getDomainComment returns the comment of the rules for the domain, the rules are deleted by the comment
+func getDomainComment(destDomain string) string {
+	return fmt.Sprintf("chaosblade-domain-%s", destDomain)
+}
+
+// runDomainDrop adds or deletes the rules for the domain ip, the ip is the remote side of the traffic
+func runDomainDrop(ctx context.Context, operation, ip, sourcePort, destinationPort, stringPattern, networkTraffic, destDomain string) *spec.Response {
+	command := "iptables"
+	if strings.Contains(ip, ":") {
+		command = "ip6tables"
+	}
+	var response *spec.Response
+	for _, netFlow := range getNetFlows(networkTraffic) {
+		sourceIp, destinationIp := "", ip
+		if netFlow == "INPUT" {
+			sourceIp, destinationIp = ip, ""
 		}
-		if stringPattern != "" {
-			tcpArgs = fmt.Sprintf("%s -m string --string %s --algo bm", tcpArgs, stringPattern)
-			udpArgs = fmt.Sprintf("%s -m string --string %s --algo bm", udpArgs, stringPattern)
+		for _, protocol := range []string{"tcp", "udp"} {
+			rule := getDropRule(netFlow, protocol, sourceIp, destinationIp, sourcePort, destinationPort, stringPattern,
+				getDomainComment(destDomain))
+			response = cl.Run(ctx, command, fmt.Sprintf("%s %s", operation, rule))
+			if !response.Success {
+				return response
+			}
 		}
-		tcpArgs = fmt.Sprintf("%s -j DROP", tcpArgs)
-		udpArgs = fmt.Sprintf("%s -j DROP", udpArgs)
-		response = cl.Run(ctx, "iptables", fmt.Sprintf(`%s`, tcpArgs))
+	}
+	return response
+}
+
+// startDomainDrop adds the rules for the resolved domain ips, and starts the supervisor to follow the changes
+func startDomainDrop(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic, destDomain string) {
+	if sourceIp != "" || destinationIp != "" {
+		bin.PrintErrAndExit("--destination-domain can't be used with --source-ip or --destination-ip")
+		return
+	}
+	if !cl.IsCommandAvailable("iptables") {
+		bin.PrintErrAndExit(spec.ResponseErr[spec.CommandIptablesNotFound].Err)
+		return
+	}
+	ips, err := bin.ResolveDomains(destDomain)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	ctx := context.Background()
+	for _, ip := range ips {
+		response := runDomainDrop(ctx, "-A", ip, sourcePort, destinationPort, stringPattern, networkTraffic, destDomain)
 		if !response.Success {
+			stopDomainDrop(destDomain)
 			bin.PrintErrAndExit(response.Err)
 			return
 		}
-		response = cl.Run(ctx, "iptables", fmt.Sprintf(`%s`, udpArgs))
+	}
+	args := fmt.Sprintf("%s --destination-domain %s --nohup=true --domain-ips %s --resolve-interval %d --debug=%t",
+		path.Join(util.GetProgramPath(), exec.DropNetworkBin), destDomain, strings.Join(ips, ","), dropResolveInterval, util.Debug)
+	flags := []struct {
+		name, value string
+	}{
+		{"source-port", sourcePort},
+		{"destination-port", destinationPort},
+		{"string-pattern", stringPattern},
+		{"network-traffic", networkTraffic},
+	}
+	for _, f := range flags {
+		if f.value != "" {
+			args = fmt.Sprintf("%s --%s %s", args, f.name, f.value)
+		}
+	}
+	response := cl.Run(ctx, "nohup", fmt.Sprintf("%s > %s 2>&1 &", args, domainLogFile))
+	if !response.Success {
+		stopDomainDrop(destDomain)
+		bin.PrintErrAndExit(response.Err)
+		return
+	}
+	bin.PrintOutputAndExit("success")
+}
+
+var domainLogFile = util.GetNohupOutput(util.Bin, "chaos_dropnetwork_domain.log")
+
+// superviseDomainDrop re-resolves the domain, and adds or deletes the rules when the ips change
+func superviseDomainDrop(destDomain, domainIps, sourcePort, destinationPort, stringPattern, networkTraffic string, interval int) {
+	if destDomain == "" || interval <= 0 {
+		bin.PrintAndExitWithErrPrefix("less --destination-domain or illegal --resolve-interval flag")
+		return
+	}
+	ctx := context.Background()
+	ips := make([]string, 0)
+	for _, ip := range strings.Split(domainIps, ",") {
+		if ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	bin.SuperviseDomains(ctx, destDomain, time.Duration(interval)*time.Second, ips, func(added, removed []string) error {
+		for _, ip := range removed {
+			if response := runDomainDrop(ctx, "-D", ip, sourcePort, destinationPort, stringPattern, networkTraffic, destDomain); !response.Success {
+				logrus.Warnf("delete the rules of %s failed, %s", ip, response.Err)
+			}
+		}
+		for _, ip := range added {
+			if response := runDomainDrop(ctx, "-A", ip, sourcePort, destinationPort, stringPattern, networkTraffic, destDomain); !response.Success {
+				return fmt.Errorf("add the rules of %s failed, %s", ip, response.Err)
+			}
+		}
+		return nil
+	})
+}
+
+// stopDomainDrop kills the supervisor, and deletes all the rules with the comment of the domain
+func stopDomainDrop(destDomain string) {
+	ctx := context.WithValue(context.Background(), channel.ProcessKey, exec.DropNetworkBin)
+	pids, err := cl.GetPidsByProcessName(fmt.Sprintf("destination-domain %s --nohup", destDomain), ctx)
+	if err != nil {
+		logrus.Warnf("get the supervisor of %s failed, %v", destDomain, err)
+	}
+	if len(pids) > 0 {
+		cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
+	}
+	comment := getDomainComment(destDomain)
+	for _, command := range []string{"iptables", "ip6tables"} {
+		if !cl.IsCommandAvailable(command) {
+			continue
+		}
+		response := cl.Run(ctx, command, "-S")
 		if !response.Success {
-			bin.PrintErrAndExit(response.Err)
-			return
+			logrus.Warnf("list the rules by %s failed, %s", command, response.Err)
+			continue
+		}
+		for _, rule := range strings.Split(response.Result.(string), "\n") {
+			rule = strings.TrimSpace(rule)
+			if !strings.HasPrefix(rule, "-A ") || getRuleComment(rule) != comment {
+				continue
+			}
+			if response := cl.Run(ctx, command, fmt.Sprintf("-D %s", strings.TrimPrefix(rule, "-A "))); !response.Success {
+				logrus.Warnf("delete the rule %s failed, %s", rule, response.Err)
+			}
 		}
 	}
-	bin.PrintOutputAndExit(response.Result.(string))
+}
+
+// getRuleComment returns the comment of the rule printed by iptables -S, the quotes are stripped, iptables quotes
+// the comment with the characters other than letters, digits, underscores and hyphens
+func getRuleComment(rule string) string {
+	fields := strings.Fields(rule)
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "--comment" {
+			return strings.Trim(fields[i+1], `"`)
+		}
+	}
+	return ""
 }
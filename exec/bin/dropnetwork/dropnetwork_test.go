@@ -18,6 +18,8 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
@@ -97,3 +99,56 @@ func Test_handleDropSpecifyPort(t *testing.T) {
 		}
 	}
 }
+
+func Test_runDomainDrop(t *testing.T) {
+	cl = channel.NewMockLocalChannel()
+	mockChannel := cl.(*channel.MockLocalChannel)
+	actualCommands := make([]string, 0)
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		actualCommands = append(actualCommands, fmt.Sprintf("%s %s", script, args))
+		return spec.ReturnSuccess("")
+	}
+	runDomainDrop(context.Background(), "-A", "10.0.0.2", "", "443", "", "", "www.example.com")
+	runDomainDrop(context.Background(), "-D", "2001:db8::1", "", "", "", "out", "www.example.com")
+	expectedCommands := []string{
+		"iptables -A INPUT -p tcp -s 10.0.0.2 --dport 443 -m comment --comment chaosblade-domain-www.example.com -j DROP",
+		"iptables -A INPUT -p udp -s 10.0.0.2 --dport 443 -m comment --comment chaosblade-domain-www.example.com -j DROP",
+		"iptables -A OUTPUT -p tcp -d 10.0.0.2 --dport 443 -m comment --comment chaosblade-domain-www.example.com -j DROP",
+		"iptables -A OUTPUT -p udp -d 10.0.0.2 --dport 443 -m comment --comment chaosblade-domain-www.example.com -j DROP",
+		"ip6tables -D OUTPUT -p tcp -d 2001:db8::1 -m comment --comment chaosblade-domain-www.example.com -j DROP",
+		"ip6tables -D OUTPUT -p udp -d 2001:db8::1 -m comment --comment chaosblade-domain-www.example.com -j DROP",
+	}
+	if !reflect.DeepEqual(actualCommands, expectedCommands) {
+		t.Errorf("unexpected commands: %+v, expected commands: %+v", actualCommands, expectedCommands)
+	}
+}
+
+func Test_stopDomainDrop(t *testing.T) {
+	cl = channel.NewMockLocalChannel()
+	mockChannel := cl.(*channel.MockLocalChannel)
+	actualCommands := make([]string, 0)
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		if args == "-S" {
+			if script == "ip6tables" {
+				return spec.ReturnSuccess("-P INPUT ACCEPT\n")
+			}
+			return spec.ReturnSuccess(`-P INPUT ACCEPT
+-A INPUT -s 10.0.0.2/32 -p tcp -m comment --comment "chaosblade-domain-www.example.com" -j DROP
+-A OUTPUT -d 10.0.0.2/32 -p tcp -m comment --comment "chaosblade-domain-www.example.com.cn" -j DROP
+-A OUTPUT -d 10.0.0.3/32 -p tcp -j DROP
+`)
+		}
+		actualCommands = append(actualCommands, fmt.Sprintf("%s %s", script, args))
+		return spec.ReturnSuccess("")
+	}
+	mockChannel.IsCommandAvailableFunc = func(commandName string) bool {
+		return true
+	}
+	stopDomainDrop("www.example.com")
+	expectedCommands := []string{
+		`iptables -D INPUT -s 10.0.0.2/32 -p tcp -m comment --comment "chaosblade-domain-www.example.com" -j DROP`,
+	}
+	if !reflect.DeepEqual(actualCommands, expectedCommands) {
+		t.Errorf("unexpected commands: %+v, expected commands: %+v", actualCommands, expectedCommands)
+	}
+}
@@ -21,11 +21,16 @@ import (
 	"flag"
 	"fmt"
 	"math"
+	"net/url"
 	"os"
 	"path"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
@@ -37,8 +42,9 @@ import (
 )
 
 var fillDataFile = "chaos_filldisk.log.dat"
-var fillDiskSize, fillDiskDirectory, fillDiskPercent, reserveDiskSize string
-var fillDiskStart, fillDiskStop, fillDiskRetainHandle, fillDiskRetainNohup bool
+var fillDiskSize, fillDiskDirectory, fillDiskPercent, reserveDiskSize, fillDiskMode, fillDiskCount, fillDiskDataFile string
+var fillDiskStart, fillDiskStop, fillDiskRetainHandle, fillDiskRetainNohup, fillDiskGrowNohup bool
+var fillDiskRate, fillDiskDuration int
 
 const diskFillErrorMessage = "No space left on device"
 
@@ -51,19 +57,40 @@ func main() {
 	flag.BoolVar(&fillDiskStop, "stop", false, "stop fill or not")
 	flag.BoolVar(&fillDiskRetainHandle, "retain-handle", false, "whether to retain the big file handle")
 	flag.BoolVar(&fillDiskRetainNohup, "retain-nohup", false, "whether to read the big file in the background")
+	flag.IntVar(&fillDiskRate, "rate", 0, "the growth rate of the file, unit is M/s")
+	flag.IntVar(&fillDiskDuration, "duration", 0, "the seconds to grow the file to the size")
+	flag.BoolVar(&fillDiskGrowNohup, "grow-nohup", false, "grow the file in the background")
+	flag.StringVar(&fillDiskDataFile, "data-file", "", "the data file to grow in the background")
+	flag.StringVar(&fillDiskMode, "mode", "bytes", "fill the bytes or the inodes, bytes or inodes")
+	flag.StringVar(&fillDiskCount, "count", "", "the number of the inodes to create in the inodes mode")
 	bin.ParseFlagAndInitLog()
 
+	if fillDiskGrowNohup {
+		size, _ := strconv.Atoi(fillDiskSize)
+		if err := growFile(context.Background(), fillDiskDataFile, size, fillDiskRate); err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+		}
+		return
+	}
 	if fillDiskStart == fillDiskStop {
 		bin.PrintErrAndExit("must specify start or stop operation")
 	}
 
+	if fillDiskStart && fillDiskMode == "inodes" {
+		err, result := startFillInodes(fillDiskDirectory, fillDiskCount, fillDiskPercent)
+		if err != nil {
+			bin.PrintErrAndExit(err.Error())
+		}
+		bin.PrintOutputAndExit(result)
+	}
 	if fillDiskStart {
 		if fillDiskRetainHandle && fillDiskRetainNohup {
 			if err := retainFileHandle(); err != nil {
 				bin.PrintErrAndExit(err.Error())
 			}
 		}
-		err, result := startFill(fillDiskDirectory, fillDiskSize, fillDiskPercent, reserveDiskSize, fillDiskRetainHandle)
+		err, result := startFill(fillDiskDirectory, fillDiskSize, fillDiskPercent, reserveDiskSize, fillDiskRetainHandle,
+			fillDiskRate, fillDiskDuration)
 		if err != nil {
 			bin.PrintErrAndExit(err.Error())
 		}
@@ -90,7 +117,7 @@ func retainFileHandle() error {
 
 var cl = channel.NewLocalChannel()
 
-func startFill(directory, size, percent, reserve string, retainHandle bool) (error, string) {
+func startFill(directory, size, percent, reserve string, retainHandle bool, rate, duration int) (error, string) {
 	ctx := context.TODO()
 	if directory == "" {
 		return fmt.Errorf("--directory flag value is empty"), ""
@@ -104,11 +131,14 @@ func startFill(directory, size, percent, reserve string, retainHandle bool) (err
 		return fmt.Errorf("calculate size err, %v", err), ""
 	}
 	var response *spec.Response
+	if rate > 0 || duration > 0 {
+		response = fillDiskGradually(ctx, directory, size, rate, duration)
+	}
 	// Some normal filesystems (ext4, xfs, btrfs and ocfs2) tack quick works
-	if cl.IsCommandAvailable("fallocate") {
+	if response == nil && cl.IsCommandAvailable("fallocate") {
 		response = fillDiskByFallocate(ctx, size, dataFile)
 	}
-	if response == nil || !response.Success {
+	if response == nil || (!response.Success && rate <= 0 && duration <= 0) {
 		// If execute fallocate command failed, use dd command to retry.
 		response = fillDiskByDD(ctx, dataFile, directory, size)
 	}
@@ -219,8 +249,8 @@ func stopFill(directory string) error {
 	if directory == "" {
 		return fmt.Errorf("--directory flag value is empty")
 	}
-	// kill dd or fallocate process
-	pids, _ := cl.GetPidsByProcessName(fillDataFile, ctx)
+	// kill dd or fallocate process, the processes growing the files are killed by their directories below
+	pids, _ := cl.GetPidsByProcessName(fillDataFile, context.WithValue(ctx, channel.ExcludeProcessKey, "--grow-nohup"))
 	if pids != nil || len(pids) >= 0 {
 		cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
 	}
@@ -230,6 +260,15 @@ func stopFill(directory string) error {
 	if pids != nil || len(pids) >= 0 {
 		cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
 	}
+	if err := removeInodes(path.Join(directory, fillInodeDir)); err != nil {
+		return err
+	}
+	// kill the process growing the file of the directory only
+	pids, _ = cl.GetPidsByProcessName(getGrowKey(directory), ctx)
+	if len(pids) > 0 {
+		cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
+	}
+	os.Remove(getGrowLogFile(directory))
 	fileName := path.Join(directory, fillDataFile)
 	if util.IsExist(fileName) {
 		response := cl.Run(ctx, "rm", fmt.Sprintf(`-rf %s`, fileName))
@@ -239,3 +278,261 @@ func stopFill(directory string) error {
 	}
 	return nil
 }
+
+// getGrowKey returns the flags identifying the process growing the data file of the directory. The full path of the
+// data file is matched, so the directories with the same prefix, such as /home and /home2, are not matched.
+func getGrowKey(directory string) string {
+	return fmt.Sprintf("--grow-nohup --data-file %s", path.Join(directory, fillDataFile))
+}
+
+// getGrowLogFile returns the log of the process growing the data file, each directory has its own log
+func getGrowLogFile(directory string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_filldisk_grow_%s.log", url.PathEscape(path.Clean(directory))))
+}
+
+// fillDiskGradually starts the process which grows the data file to the size at the rate in the background,
+// the rate is calculated by the duration if it is not specified
+func fillDiskGradually(ctx context.Context, directory, size string, rate, duration int) *spec.Response {
+	total, err := strconv.Atoi(size)
+	if err != nil {
+		return spec.ReturnFail(spec.Code[spec.IllegalParameters], fmt.Sprintf("the size %s is illegal, %v", size, err))
+	}
+	if rate <= 0 {
+		rate = int(math.Ceil(float64(total) / float64(duration)))
+	}
+	logFile := getGrowLogFile(directory)
+	args := fmt.Sprintf("%s %s --size %d --rate %d > %s 2>&1 &",
+		path.Join(util.GetProgramPath(), fillDiskBin), getGrowKey(directory), total, rate, logFile)
+	response := cl.Run(ctx, "nohup", args)
+	if !response.Success {
+		return response
+	}
+	// check
+	time.Sleep(time.Second)
+	response = cl.Run(ctx, "grep", fmt.Sprintf("%s %s", bin.ErrPrefix, logFile))
+	if response.Success {
+		errMsg := strings.TrimSpace(response.Result.(string))
+		if errMsg != "" {
+			return spec.ReturnFail(spec.Code[spec.ExecCommandError], errMsg)
+		}
+	}
+	return spec.ReturnSuccess(fmt.Sprintf("grow %s to %dM at %dM/s", path.Join(directory, fillDataFile), total, rate))
+}
+
+// growFile appends the rate MB to the file every second until the size is reached, the disk full stops it without error
+func growFile(ctx context.Context, dataFile string, size, rate int) error {
+	if size <= 0 || rate <= 0 {
+		return fmt.Errorf("the size %d and the rate %d must be positive", size, rate)
+	}
+	file, err := os.OpenFile(dataFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	chunk := make([]byte, 1024*1024)
+	ticker := time.NewTicker(growInterval)
+	defer ticker.Stop()
+	for written := 0; written < size; {
+		for i := 0; i < rate && written < size; i++ {
+			if _, err := file.Write(chunk); err != nil {
+				if isNoSpaceError(err) {
+					logrus.Infof("stop growing %s at %dM because of %s", dataFile, written, diskFillErrorMessage)
+					return nil
+				}
+				return err
+			}
+			written++
+		}
+		// flush the data, so the disk usage grows as the file
+		if err := file.Sync(); err != nil && !isNoSpaceError(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// growInterval is the interval of growing the file by the rate, it is replaced in tests
+var growInterval = time.Second
+
+// fillInodeDir is the root of the sharded directory tree which holds the empty files in the inodes mode
+const fillInodeDir = "chaos_filldisk_inodes"
+
+// filesPerShard keeps the shard directories small, the lookups in a huge directory are slow on most filesystems
+var filesPerShard = 1000
+
+// inodeWorkers is the number of the goroutines which create or remove the shards in parallel
+var inodeWorkers = runtime.NumCPU() * 4
+
+func startFillInodes(directory, count, percent string) (error, string) {
+	if directory == "" {
+		return fmt.Errorf("--directory flag value is empty"), ""
+	}
+	if count == "" && percent == "" {
+		return fmt.Errorf("less --count or --percent flag"), ""
+	}
+	inodes, err := calculateInodes(directory, count, percent)
+	if err != nil {
+		return fmt.Errorf("calculate inodes err, %v", err), ""
+	}
+	root := path.Join(directory, fillInodeDir)
+	if util.IsExist(root) {
+		return fmt.Errorf("the directory %s exists, the experiment is running", root), ""
+	}
+	created, err := fillInodes(root, inodes)
+	if err != nil {
+		if err := removeInodes(root); err != nil {
+			logrus.Warningf("failed to remove %s when starting failed, %v", root, err)
+		}
+		return err, ""
+	}
+	if created < inodes {
+		return nil, fmt.Sprintf("success because of %s, create %d inodes in %s", diskFillErrorMessage, created, root)
+	}
+	return nil, fmt.Sprintf("create %d inodes in %s", created, root)
+}
+
+// calculateInodes returns the number of the inodes which should be created
+func calculateInodes(directory, count, percent string) (int64, error) {
+	if percent == "" {
+		c, err := strconv.ParseInt(count, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		if c <= 0 {
+			return 0, fmt.Errorf("the count %d must be positive", c)
+		}
+		return c, nil
+	}
+	p, err := strconv.Atoi(percent)
+	if err != nil {
+		return 0, err
+	}
+	stat := getSysStatFunc(directory)
+	if stat.Files == 0 {
+		// btrfs and some other filesystems allocate the inodes dynamically
+		return 0, fmt.Errorf("the filesystem of %s has no fixed number of inodes", directory)
+	}
+	total := int64(stat.Files)
+	used := total - int64(stat.Ffree)
+	expected := total * int64(p) / 100
+	if used >= expected {
+		return 0, fmt.Errorf("the inodes have been used %.2f, large than expected", float64(used)/float64(total))
+	}
+	return expected - used, nil
+}
+
+// fillInodes creates the shard directories and the empty files in them until the inodes are created,
+// the directories are counted as the inodes too. The disk full error stops the filling without error
+func fillInodes(root string, inodes int64) (int64, error) {
+	if err := os.Mkdir(root, 0755); err != nil {
+		return 0, err
+	}
+	// the root directory is the first inode
+	inodes--
+	shards := make(chan int64)
+	// stopped is set when the disk is full or creating failed, the remaining shards are skipped
+	var created, stopped int64 = 1, 0
+	var once sync.Once
+	var firstErr error
+	var wg sync.WaitGroup
+	for i := 0; i < inodeWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for shard := range shards {
+				n, err := fillShard(root, shard, inodes)
+				atomic.AddInt64(&created, n)
+				if err == nil {
+					continue
+				}
+				atomic.StoreInt64(&stopped, 1)
+				if !isNoSpaceError(err) {
+					once.Do(func() { firstErr = err })
+				}
+			}
+		}()
+	}
+	shardInodes := int64(filesPerShard) + 1
+	for shard := int64(0); shard*shardInodes < inodes; shard++ {
+		if atomic.LoadInt64(&stopped) == 1 {
+			break
+		}
+		shards <- shard
+	}
+	close(shards)
+	wg.Wait()
+	return created, firstErr
+}
+
+// fillShard creates the directory of the shard and the files in it, returns the number of the inodes created
+func fillShard(root string, shard, inodes int64) (int64, error) {
+	shardInodes := int64(filesPerShard) + 1
+	n := inodes - shard*shardInodes
+	if n > shardInodes {
+		n = shardInodes
+	}
+	dir := path.Join(root, strconv.FormatInt(shard, 10))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return 0, err
+	}
+	for i := int64(1); i < n; i++ {
+		f, err := os.OpenFile(path.Join(dir, strconv.FormatInt(i, 10)), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			return i, err
+		}
+		f.Close()
+	}
+	return n, nil
+}
+
+func isNoSpaceError(err error) bool {
+	if pathErr, ok := err.(*os.PathError); ok {
+		err = pathErr.Err
+	}
+	return err == syscall.ENOSPC || err == syscall.EDQUOT
+}
+
+// removeInodes removes the shard directories in parallel, then the root of them
+func removeInodes(root string) error {
+	dir, err := os.Open(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	names, err := dir.Readdirnames(-1)
+	dir.Close()
+	if err != nil {
+		return err
+	}
+	shards := make(chan string)
+	var once sync.Once
+	var firstErr error
+	var wg sync.WaitGroup
+	for i := 0; i < inodeWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for shard := range shards {
+				if err := os.RemoveAll(shard); err != nil {
+					once.Do(func() { firstErr = err })
+				}
+			}
+		}()
+	}
+	for _, name := range names {
+		shards <- path.Join(root, name)
+	}
+	close(shards)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return os.RemoveAll(root)
+}
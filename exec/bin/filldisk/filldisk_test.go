@@ -19,11 +19,16 @@ package main
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"math"
+	"os"
+	"path"
 	"reflect"
 	"strconv"
+	"strings"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
@@ -56,7 +61,7 @@ func Test_startFill(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err, result := startFill(tt.args.directory, tt.args.size, tt.args.percent, tt.args.reserve, tt.args.retainHandle)
+			err, result := startFill(tt.args.directory, tt.args.size, tt.args.percent, tt.args.reserve, tt.args.retainHandle, 0, 0)
 			if !reflect.DeepEqual(err, tt.err) {
 				t.Errorf("startFill() got = %v, want %v", err, tt.err)
 			}
@@ -218,3 +223,115 @@ func Test_stopFill(t *testing.T) {
 		t.Errorf("stopFill() error, does not kill necessary process")
 	}
 }
+
+func Test_calculateInodes(t *testing.T) {
+	getSysStatFunc = func(directory string) *syscall.Statfs_t {
+		return &syscall.Statfs_t{
+			Files: 1000000,
+			Ffree: 800000,
+		}
+	}
+	tests := []struct {
+		name    string
+		count   string
+		percent string
+		want    int64
+		wantErr bool
+	}{
+		{name: "count is 5000", count: "5000", want: 5000},
+		{name: "percent is 90", percent: "90", want: 700000},
+		{name: "percent is 20 not more than used", percent: "20", wantErr: true},
+		{name: "count is illegal", count: "-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calculateInodes("/", tt.count, tt.percent)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("calculateInodes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("calculateInodes() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_fillInodes(t *testing.T) {
+	directory, err := ioutil.TempDir("", "filldisk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	filesPerShard = 10
+	defer func() { filesPerShard = 1000 }()
+
+	root := path.Join(directory, fillInodeDir)
+	created, err := fillInodes(root, 35)
+	if err != nil {
+		t.Fatalf("fillInodes() error = %v", err)
+	}
+	if created != 35 {
+		t.Errorf("fillInodes() got = %d, want 35", created)
+	}
+	// the root directory and the shards of 11, 11, 11 and 1 inodes, including the shard directories
+	shards, _ := ioutil.ReadDir(root)
+	if len(shards) != 4 {
+		t.Errorf("fillInodes() got %d shards, want 4", len(shards))
+	}
+	if files, _ := ioutil.ReadDir(path.Join(root, "3")); len(files) != 0 {
+		t.Errorf("fillInodes() got %d files in the last shard, want 0", len(files))
+	}
+	if err := removeInodes(root); err != nil {
+		t.Errorf("removeInodes() error = %v", err)
+	}
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Errorf("removeInodes() the directory %s is not removed", root)
+	}
+}
+
+func Test_growFile(t *testing.T) {
+	directory, err := ioutil.TempDir("", "filldisk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	growInterval = 10 * time.Millisecond
+	defer func() { growInterval = time.Second }()
+
+	dataFile := path.Join(directory, fillDataFile)
+	if err := growFile(context.Background(), dataFile, 5, 2); err != nil {
+		t.Fatalf("growFile() error = %v", err)
+	}
+	if info, _ := os.Stat(dataFile); info == nil || info.Size() != 5*1024*1024 {
+		t.Errorf("growFile() got the file %v, want 5M", info)
+	}
+
+	// the file stops growing when the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := growFile(ctx, dataFile, 5, 2); err != nil {
+		t.Fatalf("growFile() error = %v", err)
+	}
+	if info, _ := os.Stat(dataFile); info == nil || info.Size() != 2*1024*1024 {
+		t.Errorf("growFile() got the file %v, want 2M", info)
+	}
+}
+
+func Test_getGrowKey(t *testing.T) {
+	cmdline := func(directory string) string {
+		return fmt.Sprintf("/opt/chaosblade/bin/chaos_filldisk %s --size 100 --rate 10", getGrowKey(directory))
+	}
+	key := strings.TrimSpace(getGrowKey("/home"))
+	if !strings.Contains(cmdline("/home"), key) || !strings.Contains(cmdline("/home/"), key) {
+		t.Errorf("the key %s does not match the process of /home", key)
+	}
+	for _, directory := range []string{"/home2", "/data/home"} {
+		if strings.Contains(cmdline(directory), key) {
+			t.Errorf("the key %s matches the process of %s", key, directory)
+		}
+	}
+	if getGrowLogFile("/home") == getGrowLogFile("/home2") || getGrowLogFile("/data/home") == getGrowLogFile("/data_home") {
+		t.Errorf("the directories share the log file %s", getGrowLogFile("/home"))
+	}
+}
@@ -0,0 +1,431 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/sirupsen/logrus"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+const (
+	Abort  = "abort"
+	Delay  = "delay"
+	Modify = "modify"
+)
+
+var proxyPort, proxyUpstream, faultType string
+var matchMethod, matchPath, matchHeader, matchHost string
+var setHeader, removeHeader, replacedBody string
+var matchPercent, statusCode, delayTime, delayOffset int
+var proxyStart, proxyStop, proxyNohup, abortReset, replaceBody bool
+
+func main() {
+	flag.StringVar(&proxyPort, "port", "", "the port the proxy listens on")
+	flag.StringVar(&proxyUpstream, "upstream", "", "the upstream address")
+	flag.StringVar(&faultType, "type", "", "http experiment type, value is abort|delay|modify, required")
+	flag.StringVar(&matchMethod, "method", "", "request methods to match, separated by commas")
+	flag.StringVar(&matchPath, "path", "", "regular expression of the request path to match")
+	flag.StringVar(&matchHeader, "header", "", "request headers to match, for example X-User:test")
+	flag.StringVar(&matchHost, "host", "", "request host to match")
+	flag.IntVar(&matchPercent, "percent", 100, "percent of the matched requests to inject")
+	flag.IntVar(&statusCode, "code", 0, "http status code")
+	flag.BoolVar(&abortReset, "reset", false, "close the connection after sending part of the response")
+	flag.IntVar(&delayTime, "time", 0, "delay time, ms")
+	flag.IntVar(&delayOffset, "offset", 0, "delay offset, ms")
+	flag.StringVar(&setHeader, "set-header", "", "response headers to set, for example Cache-Control:no-cache")
+	flag.StringVar(&removeHeader, "remove-header", "", "response headers to remove, separated by commas")
+	flag.BoolVar(&replaceBody, "replace-body", false, "replace the response body by the body flag")
+	flag.StringVar(&replacedBody, "body", "", "the response body")
+	flag.BoolVar(&proxyStart, "start", false, "start proxy")
+	flag.BoolVar(&proxyStop, "stop", false, "stop proxy")
+	flag.BoolVar(&proxyNohup, "nohup", false, "nohup to run proxy")
+	bin.ParseFlagAndInitLog()
+
+	if proxyPort == "" {
+		bin.PrintErrAndExit("less --port flag")
+	}
+	if proxyStart {
+		startProxy(proxyPort)
+	} else if proxyStop {
+		stopProxy(proxyPort)
+	} else if proxyNohup {
+		runProxy(proxyPort)
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+var cl = channel.NewLocalChannel()
+
+var httpProxyBin = exec.HttpProxyBin
+
+var proxyLogFile = util.GetNohupOutput(util.Bin, "chaos_httpproxy.log")
+
+// startProxy checks the flags and runs the proxy in background by nohup
+func startProxy(port string) {
+	if _, _, _, err := createProxyConfig(); err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	ctx := context.Background()
+	args := fmt.Sprintf("%s --port %s --nohup=true --type %s --upstream %s --percent %d --code %d --reset=%t --time %d --offset %d",
+		path.Join(util.GetProgramPath(), httpProxyBin), port, faultType, exec.ShellQuote(proxyUpstream), matchPercent, statusCode, abortReset,
+		delayTime, delayOffset)
+	if matchMethod != "" {
+		args = fmt.Sprintf("%s --method %s", args, exec.ShellQuote(matchMethod))
+	}
+	if matchPath != "" {
+		args = fmt.Sprintf("%s --path %s", args, exec.ShellQuote(matchPath))
+	}
+	if matchHeader != "" {
+		args = fmt.Sprintf("%s --header %s", args, exec.ShellQuote(matchHeader))
+	}
+	if matchHost != "" {
+		args = fmt.Sprintf("%s --host %s", args, exec.ShellQuote(matchHost))
+	}
+	if setHeader != "" {
+		args = fmt.Sprintf("%s --set-header %s", args, exec.ShellQuote(setHeader))
+	}
+	if removeHeader != "" {
+		args = fmt.Sprintf("%s --remove-header %s", args, exec.ShellQuote(removeHeader))
+	}
+	if replaceBody {
+		args = fmt.Sprintf("%s --replace-body --body %s", args, exec.ShellQuote(replacedBody))
+	}
+	response := cl.Run(ctx, "nohup", fmt.Sprintf("%s --debug=%t > %s 2>&1 &", args, util.Debug, proxyLogFile))
+	if !response.Success {
+		bin.PrintErrAndExit(response.Err)
+		return
+	}
+	// check
+	time.Sleep(time.Second)
+	response = cl.Run(ctx, "grep", fmt.Sprintf("%s %s", bin.ErrPrefix, proxyLogFile))
+	if response.Success {
+		errMsg := strings.TrimSpace(response.Result.(string))
+		if errMsg != "" {
+			stopProxy(port)
+			bin.PrintErrAndExit(errMsg)
+			return
+		}
+	}
+	bin.PrintOutputAndExit("success")
+}
+
+// runProxy listens on the port and serves until the process is killed
+func runProxy(port string) {
+	rand.Seed(time.Now().UnixNano())
+	upstream, m, f, err := createProxyConfig()
+	if err != nil {
+		bin.PrintAndExitWithErrPrefix(err.Error())
+		return
+	}
+	err = http.ListenAndServe(fmt.Sprintf(":%s", port), newProxyHandler(upstream, m, f))
+	if err != nil {
+		bin.PrintAndExitWithErrPrefix(err.Error())
+	}
+}
+
+func stopProxy(port string) {
+	ctx := context.WithValue(context.Background(), channel.ProcessKey, httpProxyBin)
+	pids, err := cl.GetPidsByProcessName(fmt.Sprintf("port %s --nohup", port), ctx)
+	if err != nil {
+		logrus.Warnf("get %s pid failed, %v", httpProxyBin, err)
+	}
+	if len(pids) > 0 {
+		cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
+	}
+}
+
+func createProxyConfig() (*url.URL, *matcher, *fault, error) {
+	upstream, err := parseUpstream(proxyUpstream)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	m, err := newMatcher(matchMethod, matchPath, matchHeader, matchHost, matchPercent)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	f := &fault{
+		kind:       faultType,
+		code:       statusCode,
+		reset:      abortReset,
+		delay:      time.Duration(delayTime) * time.Millisecond,
+		offset:     time.Duration(delayOffset) * time.Millisecond,
+		setHeaders: map[string]string{},
+	}
+	switch faultType {
+	case Abort:
+		if f.code == 0 {
+			f.code = http.StatusServiceUnavailable
+		}
+	case Delay:
+		if f.delay <= 0 {
+			return nil, nil, nil, fmt.Errorf("--time value must be a positive integer")
+		}
+	case Modify:
+		f.setHeaders, err = parseHeaders(setHeader)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if removeHeader != "" {
+			f.removeHeaders = strings.Split(removeHeader, ",")
+		}
+		f.replaceBody = replaceBody
+		f.body = replacedBody
+	default:
+		return nil, nil, nil, fmt.Errorf("unsupported type for http experiments")
+	}
+	return upstream, m, f, nil
+}
+
+func parseUpstream(upstream string) (*url.URL, error) {
+	if upstream == "" {
+		return nil, fmt.Errorf("less --upstream flag")
+	}
+	if !strings.Contains(upstream, "://") {
+		upstream = fmt.Sprintf("http://%s", upstream)
+	}
+	u, err := url.Parse(upstream)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("illegal upstream: %s", upstream)
+	}
+	return u, nil
+}
+
+// parseHeaders parses the value like key1:value1,key2:value2
+func parseHeaders(value string) (map[string]string, error) {
+	headers := make(map[string]string, 0)
+	if value == "" {
+		return headers, nil
+	}
+	for _, kv := range strings.Split(value, ",") {
+		idx := strings.Index(kv, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("illegal header: %s, the format is key:value", kv)
+		}
+		headers[http.CanonicalHeaderKey(strings.TrimSpace(kv[:idx]))] = strings.TrimSpace(kv[idx+1:])
+	}
+	return headers, nil
+}
+
+// matcher decides whether the fault is injected into the request
+type matcher struct {
+	methods map[string]bool
+	path    *regexp.Regexp
+	headers map[string]string
+	host    string
+	percent int
+}
+
+func newMatcher(method, urlPath, header, host string, percent int) (*matcher, error) {
+	if percent < 0 || percent > 100 {
+		return nil, fmt.Errorf("--percent value must be in [0, 100]")
+	}
+	m := &matcher{
+		methods: make(map[string]bool, 0),
+		host:    strings.ToLower(host),
+		percent: percent,
+	}
+	if method != "" {
+		for _, md := range strings.Split(method, ",") {
+			m.methods[strings.ToUpper(strings.TrimSpace(md))] = true
+		}
+	}
+	if urlPath != "" {
+		r, err := regexp.Compile(urlPath)
+		if err != nil {
+			return nil, fmt.Errorf("illegal path regular expression: %s, %v", urlPath, err)
+		}
+		m.path = r
+	}
+	headers, err := parseHeaders(header)
+	if err != nil {
+		return nil, err
+	}
+	m.headers = headers
+	return m, nil
+}
+
+func (m *matcher) match(r *http.Request) bool {
+	if len(m.methods) > 0 && !m.methods[r.Method] {
+		return false
+	}
+	if m.path != nil && !m.path.MatchString(r.URL.Path) {
+		return false
+	}
+	for k, v := range m.headers {
+		if r.Header.Get(k) != v {
+			return false
+		}
+	}
+	if m.host != "" {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if strings.ToLower(host) != m.host {
+			return false
+		}
+	}
+	return m.percent >= 100 || rand.Intn(100) < m.percent
+}
+
+// fault describes what to do with the matched requests
+type fault struct {
+	kind          string
+	code          int
+	reset         bool
+	delay         time.Duration
+	offset        time.Duration
+	setHeaders    map[string]string
+	removeHeaders []string
+	replaceBody   bool
+	body          string
+}
+
+func (f *fault) modifyResponse(resp *http.Response) error {
+	if f.code > 0 {
+		resp.StatusCode = f.code
+		resp.Status = fmt.Sprintf("%d %s", f.code, http.StatusText(f.code))
+	}
+	for _, k := range f.removeHeaders {
+		resp.Header.Del(strings.TrimSpace(k))
+	}
+	for k, v := range f.setHeaders {
+		resp.Header.Set(k, v)
+	}
+	if f.replaceBody {
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(strings.NewReader(f.body))
+		resp.ContentLength = int64(len(f.body))
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Set("Content-Length", strconv.Itoa(len(f.body)))
+	}
+	return nil
+}
+
+func (f *fault) delayTime() time.Duration {
+	if f.offset <= 0 {
+		return f.delay
+	}
+	d := f.delay + time.Duration(rand.Int63n(int64(2*f.offset))) - f.offset
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// newProxyHandler returns a reverse proxy to the upstream which injects the fault into the matched requests
+func newProxyHandler(upstream *url.URL, m *matcher, f *fault) http.Handler {
+	proxy := httputil.NewSingleHostReverseProxy(upstream)
+	modifyProxy := httputil.NewSingleHostReverseProxy(upstream)
+	modifyProxy.ModifyResponse = f.modifyResponse
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.match(r) {
+			proxy.ServeHTTP(w, r)
+			return
+		}
+		logrus.Debugf("inject %s into %s %s", f.kind, r.Method, r.URL.Path)
+		switch f.kind {
+		case Abort:
+			if !f.reset {
+				http.Error(w, http.StatusText(f.code), f.code)
+				return
+			}
+			tw := &truncateWriter{ResponseWriter: w}
+			proxy.ServeHTTP(tw, r)
+			// close the connection without finishing the body
+			panic(http.ErrAbortHandler)
+		case Delay:
+			select {
+			case <-time.After(f.delayTime()):
+			case <-r.Context().Done():
+				return
+			}
+			proxy.ServeHTTP(w, r)
+		case Modify:
+			modifyProxy.ServeHTTP(w, r)
+		default:
+			proxy.ServeHTTP(w, r)
+		}
+	})
+}
+
+var errTruncated = errors.New("response body is truncated")
+
+// streamTruncateLimit is the bytes of the body written before it's cut off, if the length of the body is unknown,
+// such as the chunked or streamed responses
+const streamTruncateLimit = 4096
+
+// truncateWriter writes the headers and the first half of the body, then refuses to write
+type truncateWriter struct {
+	http.ResponseWriter
+	limit   int64
+	written int64
+}
+
+func (t *truncateWriter) WriteHeader(code int) {
+	t.limit = streamTruncateLimit
+	if length, err := strconv.ParseInt(t.Header().Get("Content-Length"), 10, 64); err == nil {
+		t.limit = length / 2
+	}
+	t.ResponseWriter.WriteHeader(code)
+	t.flush()
+}
+
+func (t *truncateWriter) Write(b []byte) (int, error) {
+	remain := t.limit - t.written
+	if remain <= 0 {
+		return 0, errTruncated
+	}
+	if int64(len(b)) > remain {
+		b = b[:remain]
+	}
+	n, err := t.ResponseWriter.Write(b)
+	t.written += int64(n)
+	t.flush()
+	if err == nil && t.written >= t.limit {
+		err = errTruncated
+	}
+	return n, err
+}
+
+func (t *truncateWriter) flush() {
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
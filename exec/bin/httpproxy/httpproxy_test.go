@@ -0,0 +1,238 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const upstreamBody = "hello chaosblade, this is the upstream body"
+
+func newUpstream() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream", "true")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(upstreamBody))
+	}))
+}
+
+func newProxy(t *testing.T, upstream *httptest.Server, m *matcher, f *fault) *httptest.Server {
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatalf("parse upstream url err, %v", err)
+	}
+	return httptest.NewServer(newProxyHandler(u, m, f))
+}
+
+func mustMatcher(t *testing.T, method, urlPath, header, host string) *matcher {
+	m, err := newMatcher(method, urlPath, header, host, 100)
+	if err != nil {
+		t.Fatalf("create matcher err, %v", err)
+	}
+	return m
+}
+
+func Test_abort(t *testing.T) {
+	upstream := newUpstream()
+	defer upstream.Close()
+	proxy := newProxy(t, upstream, mustMatcher(t, "", "^/api/", "", ""), &fault{kind: Abort, code: 503})
+	defer proxy.Close()
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/api/users", http.StatusServiceUnavailable},
+		{"/health", http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(proxy.URL + tt.path)
+		if err != nil {
+			t.Fatalf("request %s err, %v", tt.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.code {
+			t.Errorf("unexpected code for %s: %d, expected: %d", tt.path, resp.StatusCode, tt.code)
+		}
+	}
+}
+
+func Test_abortReset(t *testing.T) {
+	upstream := newUpstream()
+	defer upstream.Close()
+	proxy := newProxy(t, upstream, mustMatcher(t, "", "", "", ""), &fault{kind: Abort, reset: true})
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL + "/download")
+	if err != nil {
+		t.Fatalf("request err, %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err == nil {
+		t.Errorf("expected the body to be aborted, but got the whole body: %s", body)
+	}
+	if len(body) >= len(upstreamBody) {
+		t.Errorf("unexpected body length: %d, expected less than %d", len(body), len(upstreamBody))
+	}
+}
+
+func Test_abortReset_chunked(t *testing.T) {
+	chunk := strings.Repeat("x", streamTruncateLimit)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the flushed chunks are sent without Content-Length
+		for i := 0; i < 3; i++ {
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer upstream.Close()
+	proxy := newProxy(t, upstream, mustMatcher(t, "", "", "", ""), &fault{kind: Abort, reset: true})
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL + "/stream")
+	if err != nil {
+		t.Fatalf("request err, %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err == nil {
+		t.Errorf("expected the body to be aborted, but got the whole body of %d bytes", len(body))
+	}
+	if len(body) != streamTruncateLimit {
+		t.Errorf("unexpected body length: %d, expected: %d", len(body), streamTruncateLimit)
+	}
+}
+
+func Test_delay(t *testing.T) {
+	upstream := newUpstream()
+	defer upstream.Close()
+	proxy := newProxy(t, upstream, mustMatcher(t, "GET", "", "", ""), &fault{kind: Delay, delay: 200 * time.Millisecond})
+	defer proxy.Close()
+
+	start := time.Now()
+	resp, err := http.Get(proxy.URL)
+	if err != nil {
+		t.Fatalf("request err, %v", err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("unexpected elapsed time: %v, expected at least 200ms", elapsed)
+	}
+
+	start = time.Now()
+	resp, err = http.Post(proxy.URL, "text/plain", strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("request err, %v", err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+		t.Errorf("unexpected elapsed time: %v, POST request must not be delayed", elapsed)
+	}
+}
+
+func Test_modify(t *testing.T) {
+	upstream := newUpstream()
+	defer upstream.Close()
+	f := &fault{
+		kind:          Modify,
+		code:          http.StatusTooManyRequests,
+		setHeaders:    map[string]string{"Retry-After": "30"},
+		removeHeaders: []string{"X-Upstream"},
+		replaceBody:   true,
+		body:          "too many requests",
+	}
+	proxy := newProxy(t, upstream, mustMatcher(t, "", "", "X-User:test", ""), f)
+	defer proxy.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, proxy.URL, nil)
+	req.Header.Set("X-User", "test")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request err, %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("unexpected code: %d, expected: %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+	if resp.Header.Get("Retry-After") != "30" || resp.Header.Get("X-Upstream") != "" {
+		t.Errorf("unexpected headers: %v", resp.Header)
+	}
+	if string(body) != f.body {
+		t.Errorf("unexpected body: %s, expected: %s", body, f.body)
+	}
+
+	// the request without the header is not modified
+	resp, err = http.Get(proxy.URL)
+	if err != nil {
+		t.Fatalf("request err, %v", err)
+	}
+	body, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != upstreamBody {
+		t.Errorf("unexpected response: %d %s", resp.StatusCode, body)
+	}
+}
+
+func Test_matcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		header  string
+		host    string
+		request func() *http.Request
+		expect  bool
+	}{
+		{"method matched", "GET,post", "", "", "", func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/", nil)
+		}, true},
+		{"method not matched", "GET", "", "", "", func() *http.Request {
+			return httptest.NewRequest(http.MethodPut, "/", nil)
+		}, false},
+		{"path matched", "", "^/api/v[0-9]+/users$", "", "", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+		}, true},
+		{"host matched", "", "", "", "www.example.com", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "http://www.example.com:8080/", nil)
+		}, true},
+		{"host not matched", "", "", "", "www.example.com", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "http://api.example.com/", nil)
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mustMatcher(t, tt.method, tt.path, tt.header, tt.host)
+			if got := m.match(tt.request()); got != tt.expect {
+				t.Errorf("unexpected result: %t, expected: %t", got, tt.expect)
+			}
+		})
+	}
+}
+
+func Test_parseHeaders_failed(t *testing.T) {
+	if _, err := parseHeaders("X-User"); err == nil {
+		t.Errorf("expected err for the header without value")
+	}
+}
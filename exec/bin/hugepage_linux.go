@@ -0,0 +1,35 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"math/bits"
+	"syscall"
+)
+
+// mapHugeShift is MAP_HUGE_SHIFT, the log2 of the hugepage size is encoded in the mmap flags above it
+const mapHugeShift = 26
+
+// MmapHugepages maps the anonymous memory of the length by MAP_HUGETLB, the hugepages are reserved by the kernel
+// at once, and the pageSize in bytes selects the size of the hugepages, 0 means the default size
+func MmapHugepages(length, pageSize int) ([]byte, error) {
+	flags := syscall.MAP_ANON | syscall.MAP_PRIVATE | syscall.MAP_HUGETLB
+	if pageSize > 0 {
+		flags |= bits.TrailingZeros(uint(pageSize)) << mapHugeShift
+	}
+	return syscall.Mmap(-1, 0, length, syscall.PROT_READ|syscall.PROT_WRITE, flags)
+}
@@ -0,0 +1,250 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var hugepageUid, hugepageDirectory string
+var hugepageCount, hugepagePercent, hugepageSize int
+var hugepageStart, hugepageStop, hugepageNohup bool
+
+func main() {
+	flag.StringVar(&hugepageUid, "uid", "", "the uid of the experiment")
+	flag.IntVar(&hugepageCount, "count", 0, "the number of the hugepages to reserve")
+	flag.IntVar(&hugepagePercent, "percent", 100, "the percent of the free hugepages to reserve")
+	flag.IntVar(&hugepageSize, "page-size", 0, "the size of the hugepages, unit is KB, 0 means the default size")
+	flag.StringVar(&hugepageDirectory, "directory", "", "the mount point of a hugetlbfs")
+	flag.BoolVar(&hugepageStart, "start", false, "reserve the hugepages")
+	flag.BoolVar(&hugepageStop, "stop", false, "release the hugepages")
+	flag.BoolVar(&hugepageNohup, "nohup", false, "nohup to hold the hugepages")
+	bin.ParseFlagAndInitLog()
+
+	if hugepageUid == "" {
+		bin.PrintErrAndExit("less --uid flag")
+		return
+	}
+	if hugepageStart {
+		startHugepage(hugepageUid, hugepageDirectory, hugepageCount, hugepagePercent, hugepageSize)
+	} else if hugepageStop {
+		if err := stopHugepage(hugepageUid, hugepageDirectory); err != nil {
+			bin.PrintErrAndExit(err.Error())
+		}
+	} else if hugepageNohup {
+		if err := holdHugepages(context.Background(), hugepageCount, hugepageSize); err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+		}
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+var memHugepageBin = exec.MemHugepageBin
+
+var cl = channel.NewLocalChannel()
+
+// hugepageSysPath is the hugepage pools in sysfs, it is replaced in tests
+var hugepageSysPath = "/sys/kernel/mm/hugepages"
+
+// hugetlbfsMagic is the filesystem type of hugetlbfs in statfs
+const hugetlbfsMagic = 0x958458f6
+
+func getLogFile(uid string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_memhugepage_%s.log", uid))
+}
+
+func getHugepageFile(uid, directory string) string {
+	return path.Join(directory, fmt.Sprintf("chaos_hugepage_%s", uid))
+}
+
+// getDefaultPageSize returns the Hugepagesize in /proc/meminfo, unit is KB
+func getDefaultPageSize() (int, error) {
+	file, err := os.Open(path.Join(bin.ProcPath, "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "Hugepagesize:" {
+			return strconv.Atoi(fields[1])
+		}
+	}
+	return 0, fmt.Errorf("Hugepagesize not found in meminfo, the hugepages are not supported")
+}
+
+// getFreeHugepages returns the number of the free hugepages of the size in KB
+func getFreeHugepages(pageSize int) (int, error) {
+	bytes, err := ioutil.ReadFile(path.Join(hugepageSysPath, fmt.Sprintf("hugepages-%dkB", pageSize), "free_hugepages"))
+	if err != nil {
+		return 0, fmt.Errorf("the %dKB hugepages are not supported, %v", pageSize, err)
+	}
+	return strconv.Atoi(strings.TrimSpace(string(bytes)))
+}
+
+// calculatePages returns the number of the hugepages to reserve by the count, or the percent of the free hugepages
+func calculatePages(pageSize, count, percent int) (int, error) {
+	free, err := getFreeHugepages(pageSize)
+	if err != nil {
+		return 0, err
+	}
+	pages := count
+	if pages <= 0 {
+		pages = free * percent / 100
+	}
+	if pages <= 0 {
+		return 0, fmt.Errorf("no free %dKB hugepages to reserve, the vm.nr_hugepages may be 0", pageSize)
+	}
+	if pages > free {
+		return 0, fmt.Errorf("the count %d is more than the %d free %dKB hugepages", pages, free, pageSize)
+	}
+	return pages, nil
+}
+
+func startHugepage(uid, directory string, count, percent, pageSize int) {
+	var err error
+	if directory != "" {
+		// the size of the hugepages is decided by the mount option of the hugetlbfs
+		var stat syscall.Statfs_t
+		if err := syscall.Statfs(directory, &stat); err != nil {
+			bin.PrintErrAndExit(fmt.Sprintf("get the filesystem of %s failed, %v", directory, err))
+			return
+		}
+		if int64(stat.Type) != hugetlbfsMagic {
+			bin.PrintErrAndExit(fmt.Sprintf("%s is not a hugetlbfs", directory))
+			return
+		}
+		pageSize = int(stat.Bsize / 1024)
+	} else if pageSize <= 0 {
+		if pageSize, err = getDefaultPageSize(); err != nil {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+	}
+	pages, err := calculatePages(pageSize, count, percent)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	if directory != "" {
+		file := getHugepageFile(uid, directory)
+		if err := reserveByFile(file, pages, pageSize); err != nil {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+		bin.PrintOutputAndExit(fmt.Sprintf("reserve %d hugepages of %dKB by the file %s", pages, pageSize, file))
+		return
+	}
+
+	ctx := context.Background()
+	logFile := getLogFile(uid)
+	args := fmt.Sprintf("%s --nohup --uid %s --count %d --page-size %d",
+		path.Join(util.GetProgramPath(), memHugepageBin), uid, pages, pageSize)
+	response := cl.Run(ctx, "nohup", fmt.Sprintf("%s > %s 2>&1 &", args, logFile))
+	if !response.Success {
+		bin.PrintErrAndExit(response.Err)
+		return
+	}
+	// check
+	time.Sleep(time.Second)
+	response = cl.Run(ctx, "grep", fmt.Sprintf("%s %s", bin.ErrPrefix, logFile))
+	if response.Success {
+		errMsg := strings.TrimSpace(response.Result.(string))
+		if errMsg != "" {
+			stopHugepage(uid, directory)
+			bin.PrintErrAndExit(errMsg)
+			return
+		}
+	}
+	bin.PrintOutputAndExit(fmt.Sprintf("reserve %d hugepages of %dKB by MAP_HUGETLB", pages, pageSize))
+}
+
+// reserveByFile allocates the hugepages to the file in the hugetlbfs, which are held by the file until it is removed
+func reserveByFile(file string, pages, pageSize int) error {
+	if util.IsExist(file) {
+		return fmt.Errorf("the hugepage file %s exists, the experiment is running", file)
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// the hugepages are reserved when mapped, and allocated to the file when touched
+	region, err := syscall.Mmap(int(f.Fd()), 0, pages*pageSize*1024, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		os.Remove(file)
+		return fmt.Errorf("map the hugepages failed, %v", err)
+	}
+	touchPages(region, pageSize*1024)
+	return syscall.Munmap(region)
+}
+
+// holdHugepages maps the hugepages by MAP_HUGETLB and holds them until the context is done
+func holdHugepages(ctx context.Context, pages, pageSize int) error {
+	region, err := bin.MmapHugepages(pages*pageSize*1024, pageSize*1024)
+	if err != nil {
+		return fmt.Errorf("map %d hugepages of %dKB failed, %v", pages, pageSize, err)
+	}
+	defer syscall.Munmap(region)
+	touchPages(region, pageSize*1024)
+	<-ctx.Done()
+	return nil
+}
+
+// touchPages writes every page of the region, so the pages are really allocated
+func touchPages(region []byte, pageSize int) {
+	for i := 0; i < len(region); i += pageSize {
+		region[i] = 1
+	}
+}
+
+// stopHugepage removes the hugepage file or kills the nohup process, which releases the hugepages
+func stopHugepage(uid, directory string) error {
+	if directory != "" {
+		if err := os.Remove(getHugepageFile(uid, directory)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	ctx := context.WithValue(context.Background(), channel.ProcessKey, fmt.Sprintf("--nohup --uid %s", uid))
+	pids, _ := cl.GetPidsByProcessName(memHugepageBin, ctx)
+	if len(pids) > 0 {
+		response := cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
+		if !response.Success {
+			return fmt.Errorf("kill the hugepage process failed, %s", response.Err)
+		}
+	}
+	os.Remove(getLogFile(uid))
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
+)
+
+func Test_calculatePages(t *testing.T) {
+	dir, clean := bintest.NewRoot(t)
+	defer clean()
+	bintest.WriteFiles(t, dir, map[string]string{
+		"hugepages-2048kB/free_hugepages":    "10\n",
+		"hugepages-1048576kB/free_hugepages": "0\n",
+	})
+	defer bintest.Replace(&hugepageSysPath, dir)()
+
+	tests := []struct {
+		pageSize int
+		count    int
+		percent  int
+		pages    int
+		success  bool
+	}{
+		{2048, 4, 100, 4, true},
+		{2048, 0, 55, 5, true},
+		{2048, 0, 100, 10, true},
+		{2048, 11, 100, 0, false},
+		{1048576, 0, 100, 0, false},
+		{4096, 1, 100, 0, false},
+	}
+	for _, tt := range tests {
+		pages, err := calculatePages(tt.pageSize, tt.count, tt.percent)
+		if pages != tt.pages || (err == nil) != tt.success {
+			t.Errorf("unexpected result of %+v: %d, %v", tt, pages, err)
+		}
+	}
+}
+
+func Test_getDefaultPageSize(t *testing.T) {
+	dir, clean := bintest.NewRoot(t)
+	defer clean()
+	bintest.WriteFiles(t, dir, map[string]string{"meminfo": "MemTotal:        6158152 kB\nHugePages_Free:        0\nHugepagesize:       2048 kB\n"})
+	defer bintest.Replace(&bin.ProcPath, dir)()
+
+	if size, err := getDefaultPageSize(); err != nil || size != 2048 {
+		t.Errorf("unexpected page size: %d, %v, expected: 2048", size, err)
+	}
+}
+
+func Test_stopHugepage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaos-hugetlbfs")
+	if err != nil {
+		t.Fatalf("create temp dir err, %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := getHugepageFile("test", dir)
+	ioutil.WriteFile(file, []byte{}, 0600)
+
+	cl = channel.NewMockLocalChannel()
+	mockChannel := cl.(*channel.MockLocalChannel)
+	mockChannel.GetPidsByProcessNameFunc = func(processName string, ctx context.Context) ([]string, error) {
+		return []string{}, nil
+	}
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		t.Errorf("unexpected command: %s %s", script, args)
+		return spec.ReturnSuccess("")
+	}
+	if err := stopHugepage("test", dir); err != nil {
+		t.Fatalf("stop hugepage err, %v", err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("expected the hugepage file removed, %v", err)
+	}
+}
@@ -0,0 +1,390 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/sirupsen/logrus"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var oomUid, oomCgroupPath, oomBurnerScoreAdj, oomVictimScoreAdj string
+var oomTargetPid, oomRate int
+var oomStart, oomStop, oomNohup bool
+
+func main() {
+	flag.StringVar(&oomUid, "uid", "", "the uid of the experiment")
+	flag.StringVar(&oomCgroupPath, "cgroup-path", "", "the memory cgroup to trigger the oom killer in")
+	flag.IntVar(&oomTargetPid, "target-pid", 0, "trigger the oom killer in the memory cgroup of the process")
+	flag.IntVar(&oomRate, "rate", 100, "the rate of allocating memory, unit is M/S")
+	flag.StringVar(&oomBurnerScoreAdj, "burner-score-adj", "", "the oom_score_adj of the burning process")
+	flag.StringVar(&oomVictimScoreAdj, "victim-score-adj", "", "the oom_score_adj of the target process")
+	flag.BoolVar(&oomStart, "start", false, "start the oom experiment")
+	flag.BoolVar(&oomStop, "stop", false, "stop the oom experiment")
+	flag.BoolVar(&oomNohup, "nohup", false, "nohup to run the burning process")
+	bin.ParseFlagAndInitLog()
+
+	if oomUid == "" {
+		bin.PrintErrAndExit("less --uid flag")
+		return
+	}
+	if oomStart {
+		startOom(oomUid, oomCgroupPath, oomTargetPid, oomRate, oomBurnerScoreAdj, oomVictimScoreAdj)
+	} else if oomStop {
+		stopOom(oomUid)
+	} else if oomNohup {
+		if err := burnOom(oomCgroupPath, oomTargetPid, oomRate, oomBurnerScoreAdj); err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+		}
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+var memOomBin = exec.MemOomBin
+
+var cl = channel.NewLocalChannel()
+
+// kmsgPath is the kernel log device, which the oom killed processes are read from
+var kmsgPath = "/dev/kmsg"
+
+// oomState is the state before the experiment, which is recorded in the backup file
+type oomState struct {
+	Version int               `json:"version"`
+	Dirs    map[string]string `json:"dirs"`
+	// OomKill is the number of the oom killed processes in the cgroup before the experiment
+	OomKill uint64 `json:"oom_kill"`
+	// KmsgSeq is the sequence number of the last kernel log, -1 if the kernel log cannot be read
+	KmsgSeq int64 `json:"kmsg_seq"`
+	// Memcg is the path of the cgroup in the memory hierarchy, which the kernel log shows as oom_memcg
+	Memcg string `json:"memcg"`
+	// VictimPid and VictimScoreAdj are the target process and its original oom_score_adj
+	VictimPid      int `json:"victim_pid,omitempty"`
+	VictimScoreAdj int `json:"victim_score_adj,omitempty"`
+}
+
+// oomReport is the oom killed processes in the experiment
+type oomReport struct {
+	OomKill uint64          `json:"oom_kill"`
+	Killed  []killedProcess `json:"killed"`
+}
+
+type killedProcess struct {
+	Pid  int    `json:"pid"`
+	Name string `json:"name"`
+}
+
+func getBackupFile(uid string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_memoom_%s.bak", uid))
+}
+
+func getLogFile(uid string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_memoom_%s.log", uid))
+}
+
+// getLimitedCgroup returns the memory cgroup of the target, which must be limited
+func getLimitedCgroup(cgroupPath string, targetPid int) (*bin.Cgroup, error) {
+	cgroup, err := bin.GetMemoryCgroup(cgroupPath, targetPid)
+	if err != nil {
+		return nil, fmt.Errorf("get the memory cgroup failed, %v", err)
+	}
+	stat, err := cgroup.MemoryStat()
+	if err != nil {
+		return nil, fmt.Errorf("get the memory stat of %v failed, %v", cgroup.Dirs, err)
+	}
+	if stat.Unlimited() {
+		return nil, fmt.Errorf("the memory of the cgroup %s is unlimited, the oom killer cannot be triggered by it", cgroup.Dirs["memory"])
+	}
+	return cgroup, nil
+}
+
+func startOom(uid, cgroupPath string, targetPid, rate int, burnerScoreAdj, victimScoreAdj string) {
+	backupFile := getBackupFile(uid)
+	if util.IsExist(backupFile) {
+		bin.PrintErrAndExit(fmt.Sprintf("the oom experiment %s is running, the backup file %s exists", uid, backupFile))
+		return
+	}
+	cgroup, err := getLimitedCgroup(cgroupPath, targetPid)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	state := &oomState{Version: cgroup.Version, Dirs: cgroup.Dirs, KmsgSeq: -1}
+	if state.OomKill, err = cgroup.OomKillCount(); err != nil {
+		logrus.Warnf("get the oom kill count failed, %v", err)
+	}
+	if state.Memcg, err = cgroup.Path("memory"); err != nil {
+		logrus.Warnf("get the path of the memory cgroup failed, the oom killed processes cannot be reported, %v", err)
+	} else if _, seq, err := readKilledProcesses(-1, state.Memcg); err == nil {
+		state.KmsgSeq = seq
+	} else {
+		logrus.Warnf("read the kernel log failed, the oom killed processes cannot be reported, %v", err)
+	}
+	if victimScoreAdj != "" {
+		adj, err := strconv.Atoi(victimScoreAdj)
+		if err != nil {
+			bin.PrintErrAndExit(fmt.Sprintf("illegal victim-score-adj %s", victimScoreAdj))
+			return
+		}
+		if state.VictimScoreAdj, err = getOomScoreAdj(targetPid); err != nil {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+		state.VictimPid = targetPid
+		if err := setOomScoreAdj(targetPid, adj); err != nil {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+	}
+	// restore and remove the backup file if the burning process fails to start
+	fail := func(errMsg string) {
+		restoreOom(uid, state)
+		os.Remove(backupFile)
+		bin.PrintErrAndExit(errMsg)
+	}
+	bytes, _ := json.Marshal(state)
+	if err := ioutil.WriteFile(backupFile, bytes, 0644); err != nil {
+		fail(err.Error())
+		return
+	}
+
+	ctx := context.Background()
+	logFile := getLogFile(uid)
+	args := fmt.Sprintf("%s --nohup --uid %s --rate %d", path.Join(util.GetProgramPath(), memOomBin), uid, rate)
+	if cgroupPath != "" {
+		args = fmt.Sprintf("%s --cgroup-path %s", args, cgroupPath)
+	} else {
+		args = fmt.Sprintf("%s --target-pid %d", args, targetPid)
+	}
+	if burnerScoreAdj != "" {
+		args = fmt.Sprintf("%s --burner-score-adj %s", args, burnerScoreAdj)
+	}
+	response := cl.Run(ctx, "nohup", fmt.Sprintf("%s > %s 2>&1 &", args, logFile))
+	if !response.Success {
+		fail(response.Err)
+		return
+	}
+	// check
+	time.Sleep(time.Second)
+	response = cl.Run(ctx, "grep", fmt.Sprintf("%s %s", bin.ErrPrefix, logFile))
+	if response.Success {
+		errMsg := strings.TrimSpace(response.Result.(string))
+		if errMsg != "" {
+			fail(errMsg)
+			return
+		}
+	}
+	bin.PrintOutputAndExit(fmt.Sprintf("allocate memory at %dM/S in the cgroup %s", rate, cgroup.Dirs["memory"]))
+}
+
+// burnOom joins the memory cgroup and allocates memory until the process is killed
+func burnOom(cgroupPath string, targetPid, rate int, burnerScoreAdj string) error {
+	cgroup, err := getLimitedCgroup(cgroupPath, targetPid)
+	if err != nil {
+		return err
+	}
+	if burnerScoreAdj != "" {
+		adj, err := strconv.Atoi(burnerScoreAdj)
+		if err != nil {
+			return fmt.Errorf("illegal burner-score-adj %s", burnerScoreAdj)
+		}
+		if err := setOomScoreAdj(os.Getpid(), adj); err != nil {
+			return err
+		}
+	}
+	if err := cgroup.AddProcess(os.Getpid()); err != nil {
+		return err
+	}
+	// the memory is never released, so the garbage collector is useless
+	debug.SetGCPercent(-1)
+	chunks := make([][]byte, 0)
+	for range time.Tick(time.Second) {
+		for i := 0; i < rate; i++ {
+			chunk := make([]byte, 1024*1024)
+			// touch every page, so the memory is really charged to the cgroup
+			for j := 0; j < len(chunk); j += 4096 {
+				chunk[j] = 1
+			}
+			chunks = append(chunks, chunk)
+		}
+		logrus.Debugf("allocated %dM", len(chunks))
+	}
+	return nil
+}
+
+func stopOom(uid string) {
+	backupFile := getBackupFile(uid)
+	bytes, err := ioutil.ReadFile(backupFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			bin.PrintOutputAndExit("nothing to do")
+			return
+		}
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	var state oomState
+	if err := json.Unmarshal(bytes, &state); err != nil {
+		bin.PrintErrAndExit(fmt.Sprintf("illegal backup file %s, %v", backupFile, err))
+		return
+	}
+	report := &oomReport{Killed: make([]killedProcess, 0)}
+	if count, err := (&bin.Cgroup{Version: state.Version, Dirs: state.Dirs}).OomKillCount(); err == nil && count > state.OomKill {
+		report.OomKill = count - state.OomKill
+	}
+	if state.KmsgSeq >= 0 {
+		if killed, _, err := readKilledProcesses(state.KmsgSeq, state.Memcg); err == nil {
+			report.Killed = killed
+		} else {
+			logrus.Warnf("read the kernel log failed, %v", err)
+		}
+	}
+	if err := restoreOom(uid, &state); err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	os.Remove(backupFile)
+	bytes, _ = json.Marshal(report)
+	bin.PrintOutputAndExit(string(bytes))
+}
+
+// restoreOom kills the burning process and restores the oom_score_adj of the victim, the backup file is kept
+func restoreOom(uid string, state *oomState) error {
+	ctx := context.WithValue(context.Background(), channel.ProcessKey, fmt.Sprintf("--nohup --uid %s", uid))
+	pids, _ := cl.GetPidsByProcessName(memOomBin, ctx)
+	if len(pids) > 0 {
+		response := cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
+		if !response.Success {
+			return fmt.Errorf("kill the burning process failed, %s", response.Err)
+		}
+	}
+	os.Remove(getLogFile(uid))
+	if state.VictimPid > 0 {
+		// the victim may be killed already
+		if err := setOomScoreAdj(state.VictimPid, state.VictimScoreAdj); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func getOomScoreAdj(pid int) (int, error) {
+	bytes, err := ioutil.ReadFile(path.Join(bin.ProcPath, strconv.Itoa(pid), "oom_score_adj"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(bytes)))
+}
+
+func setOomScoreAdj(pid, adj int) error {
+	return ioutil.WriteFile(path.Join(bin.ProcPath, strconv.Itoa(pid), "oom_score_adj"), []byte(strconv.Itoa(adj)), 0644)
+}
+
+// killedProcessRegexp matches the kernel log of the oom killer, for example
+// Memory cgroup out of memory: Killed process 1234 (java) total-vm:...
+var killedProcessRegexp = regexp.MustCompile(`Killed process (\d+) \(([^)]*)\)`)
+
+// oomMemcgRegexp matches the kernel log before the killed process, which shows the cgroup out of memory, for example
+// oom-kill:constraint=CONSTRAINT_MEMCG,nodemask=(null),cpuset=/,mems_allowed=0,oom_memcg=/pod1,task_memcg=/pod1/c1,...
+var oomMemcgRegexp = regexp.MustCompile(`oom-kill:.*oom_memcg=([^,]*)`)
+
+// readKilledProcesses reads the kernel log records after the sequence number, and returns the processes killed by
+// the oom of the memcg and the sequence number of the last record
+func readKilledProcesses(afterSeq int64, memcg string) ([]killedProcess, int64, error) {
+	// the device is read by the syscalls, because the runtime poller blocks the nonblocking reads of it
+	fd, err := syscall.Open(kmsgPath, syscall.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, afterSeq, fmt.Errorf("open %s failed, %v", kmsgPath, err)
+	}
+	defer syscall.Close(fd)
+	killed := make([]killedProcess, 0)
+	lastSeq := afterSeq
+	buf := make([]byte, 8192)
+	pending := ""
+	// oomMemcg is the memcg of the last oom-kill record, the other cgroups and the global oom are skipped
+	oomMemcg := ""
+	for {
+		// each read returns one record from /dev/kmsg, or many lines from a regular file
+		n, err := syscall.Read(fd, buf)
+		if err == syscall.EPIPE || err == syscall.EINTR {
+			// the record is overwritten in the ring buffer
+			continue
+		}
+		if err == syscall.EAGAIN || (err == nil && n == 0) {
+			break
+		}
+		if err != nil {
+			return nil, lastSeq, fmt.Errorf("read %s failed, %v", kmsgPath, err)
+		}
+		lines := strings.Split(pending+string(buf[:n]), "\n")
+		pending = lines[len(lines)-1]
+		for _, line := range lines[:len(lines)-1] {
+			seq, message, ok := parseKmsg(line)
+			if !ok || seq <= afterSeq {
+				continue
+			}
+			lastSeq = seq
+			if match := oomMemcgRegexp.FindStringSubmatch(message); match != nil {
+				oomMemcg = match[1]
+				continue
+			}
+			if match := killedProcessRegexp.FindStringSubmatch(message); match != nil {
+				if oomMemcg == memcg {
+					pid, _ := strconv.Atoi(match[1])
+					killed = append(killed, killedProcess{Pid: pid, Name: match[2]})
+				}
+				oomMemcg = ""
+			}
+		}
+	}
+	return killed, lastSeq, nil
+}
+
+// parseKmsg returns the sequence number and the message of the record, for example
+// 6,1234,5678901,-;Memory cgroup out of memory: Killed process ...
+func parseKmsg(line string) (int64, string, bool) {
+	semicolon := strings.Index(line, ";")
+	if semicolon < 0 {
+		return 0, "", false
+	}
+	fields := strings.Split(line[:semicolon], ",")
+	if len(fields) < 2 {
+		return 0, "", false
+	}
+	seq, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return seq, line[semicolon+1:], true
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
+)
+
+func Test_parseKmsg(t *testing.T) {
+	tests := []struct {
+		line    string
+		seq     int64
+		message string
+		ok      bool
+	}{
+		{"6,1234,5678901,-;Memory cgroup out of memory: Killed process 100 (java)", 1234, "Memory cgroup out of memory: Killed process 100 (java)", true},
+		{"4,12,100,c;a;b", 12, "a;b", true},
+		{" SUBSYSTEM=memory", 0, "", false},
+		{"6,abc,100,-;message", 0, "", false},
+	}
+	for _, tt := range tests {
+		seq, message, ok := parseKmsg(tt.line)
+		if seq != tt.seq || message != tt.message || ok != tt.ok {
+			t.Errorf("unexpected result of %s: %d, %s, %t, expected: %d, %s, %t", tt.line, seq, message, ok, tt.seq, tt.message, tt.ok)
+		}
+	}
+}
+
+func Test_readKilledProcesses(t *testing.T) {
+	file, err := ioutil.TempFile("", "kmsg")
+	if err != nil {
+		t.Fatalf("create temp file err, %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`6,9,90,-;oom-kill:constraint=CONSTRAINT_MEMCG,nodemask=(null),cpuset=/,mems_allowed=0,oom_memcg=/pod1,task_memcg=/pod1,task=java,pid=100,uid=0
+6,10,100,-;Memory cgroup out of memory: Killed process 100 (java) total-vm:1000kB
+6,11,200,-;oom_reaper: reaped process 100 (java)
+ SUBSYSTEM=memory
+6,12,250,-;oom-kill:constraint=CONSTRAINT_MEMCG,nodemask=(null),cpuset=/,mems_allowed=0,oom_memcg=/pod1,task_memcg=/pod1/c1,task=nginx,pid=200,uid=0
+6,13,300,-;Memory cgroup out of memory: Killed process 200 (nginx: worker) total-vm:1000kB
+6,14,350,-;oom-kill:constraint=CONSTRAINT_MEMCG,nodemask=(null),cpuset=/,mems_allowed=0,oom_memcg=/pod2,task_memcg=/pod2,task=redis,pid=250,uid=0
+6,15,360,-;Memory cgroup out of memory: Killed process 250 (redis) total-vm:1000kB
+6,16,370,-;oom-kill:constraint=CONSTRAINT_NONE,nodemask=(null),cpuset=/,mems_allowed=0,global_oom,task_memcg=/pod1,task=python,pid=300,uid=0
+3,17,400,-;Out of memory: Killed process 300 (python) total-vm:1000kB
+`)
+	file.Close()
+	defer func(origin string) { kmsgPath = origin }(kmsgPath)
+	kmsgPath = file.Name()
+
+	// only the processes killed by the oom of /pod1 after the sequence number 10
+	killed, lastSeq, err := readKilledProcesses(10, "/pod1")
+	if err != nil {
+		t.Fatalf("read killed processes err, %v", err)
+	}
+	expected := []killedProcess{{Pid: 200, Name: "nginx: worker"}}
+	if !reflect.DeepEqual(killed, expected) || lastSeq != 17 {
+		t.Errorf("unexpected result: %+v, %d, expected: %+v, 17", killed, lastSeq, expected)
+	}
+}
+
+func Test_getLimitedCgroup(t *testing.T) {
+	root, clean := bintest.NewRoot(t)
+	defer clean()
+	files := map[string]string{
+		"proc/self/mountinfo":         fmt.Sprintf("35 25 0:31 / %s/unified rw,nosuid - cgroup2 cgroup2 rw\n", root),
+		"unified/cgroup.controllers":  "cpu memory\n",
+		"unified/pod1/cgroup.procs":   "",
+		"unified/pod1/memory.current": "1048576\n",
+		"unified/pod1/memory.max":     "max\n",
+		"unified/pod1/memory.stat":    "anon 1048576\nfile 0\nactive_anon 1048576\ninactive_anon 0\n",
+		"unified/pod2/cgroup.procs":   "",
+		"unified/pod2/memory.current": "1048576\n",
+		"unified/pod2/memory.max":     "268435456\n",
+		"unified/pod2/memory.stat":    "anon 1048576\nfile 0\nactive_anon 1048576\ninactive_anon 0\n",
+	}
+	bintest.WriteFiles(t, root, files)
+	defer bintest.Replace(&bin.ProcPath, path.Join(root, "proc"))()
+
+	if _, err := getLimitedCgroup("/pod1", 0); err == nil {
+		t.Errorf("expected err for the unlimited cgroup")
+	}
+	cgroup, err := getLimitedCgroup("/pod2", 0)
+	if err != nil {
+		t.Fatalf("get limited cgroup err, %v", err)
+	}
+	if cgroup.Dirs["memory"] != path.Join(root, "unified/pod2") {
+		t.Errorf("unexpected cgroup: %+v, expected: %s", cgroup.Dirs, path.Join(root, "unified/pod2"))
+	}
+	if memcg, err := cgroup.Path("memory"); err != nil || memcg != "/pod2" {
+		t.Errorf("unexpected path of the cgroup: %s, %v, expected: /pod2", memcg, err)
+	}
+}
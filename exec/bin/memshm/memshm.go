@@ -0,0 +1,131 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var shmUid, shmDirectory string
+var shmSize, shmPercent int
+var shmStart, shmStop bool
+
+func main() {
+	flag.StringVar(&shmUid, "uid", "", "the uid of the experiment")
+	flag.StringVar(&shmDirectory, "directory", "/dev/shm", "the mount point of the tmpfs")
+	flag.IntVar(&shmSize, "size", 0, "the size to fill, unit is MB")
+	flag.IntVar(&shmPercent, "percent", 100, "the percent of the tmpfs to fill up to")
+	flag.BoolVar(&shmStart, "start", false, "fill the tmpfs")
+	flag.BoolVar(&shmStop, "stop", false, "remove the filled file")
+	bin.ParseFlagAndInitLog()
+
+	if shmUid == "" {
+		bin.PrintErrAndExit("less --uid flag")
+		return
+	}
+	if shmStart {
+		startShm(shmUid, shmDirectory, shmSize, shmPercent)
+	} else if shmStop {
+		if err := os.Remove(getShmFile(shmUid, shmDirectory)); err != nil && !os.IsNotExist(err) {
+			bin.PrintErrAndExit(err.Error())
+		}
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+const (
+	mb = 1024 * 1024
+	// tmpfsMagic is the filesystem type of tmpfs in statfs
+	tmpfsMagic = 0x01021994
+)
+
+func getShmFile(uid, directory string) string {
+	return path.Join(directory, fmt.Sprintf("chaos_shm_%s", uid))
+}
+
+// calculateFillSize returns the bytes to fill by the size in MB, or up to the percent of the tmpfs
+func calculateFillSize(directory string, size, percent int) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(directory, &stat); err != nil {
+		return 0, fmt.Errorf("get the filesystem of %s failed, %v", directory, err)
+	}
+	if int64(stat.Type) != tmpfsMagic {
+		return 0, fmt.Errorf("%s is not a tmpfs", directory)
+	}
+	total := int64(stat.Blocks) * int64(stat.Bsize)
+	available := int64(stat.Bavail) * int64(stat.Bsize)
+	if size > 0 {
+		if int64(size)*mb > available {
+			return 0, fmt.Errorf("the size %dM is more than the available %dM of %s", size, available/mb, directory)
+		}
+		return int64(size) * mb, nil
+	}
+	fill := total*int64(percent)/100 - (total - available)
+	if fill <= 0 {
+		return 0, fmt.Errorf("the used space of %s is already more than the percent %d", directory, percent)
+	}
+	return fill, nil
+}
+
+func startShm(uid, directory string, size, percent int) {
+	file := getShmFile(uid, directory)
+	if util.IsExist(file) {
+		bin.PrintErrAndExit(fmt.Sprintf("the shm file %s exists, the experiment is running", file))
+		return
+	}
+	fill, err := calculateFillSize(directory, size, percent)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	if err := fillFile(file, fill); err != nil {
+		os.Remove(file)
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	bin.PrintOutputAndExit(fmt.Sprintf("fill %dM in the tmpfs %s", fill/mb, directory))
+}
+
+// fillFile writes the file of the size, the pages of the tmpfs are allocated by writing
+func fillFile(file string, size int64) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	chunk := make([]byte, mb)
+	for size > 0 {
+		n := int64(len(chunk))
+		if size < n {
+			n = size
+		}
+		if _, err := f.Write(chunk[:n]); err != nil {
+			return fmt.Errorf("write the shm file %s failed, %v", file, err)
+		}
+		size -= n
+	}
+	return nil
+}
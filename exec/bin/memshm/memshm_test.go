@@ -0,0 +1,77 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+)
+
+func isTmpfs(directory string) bool {
+	var stat syscall.Statfs_t
+	return syscall.Statfs(directory, &stat) == nil && int64(stat.Type) == tmpfsMagic
+}
+
+func Test_calculateFillSize(t *testing.T) {
+	if !isTmpfs("/dev/shm") {
+		t.Skip("/dev/shm is not a tmpfs")
+	}
+	if size, err := calculateFillSize("/dev/shm", 1, 100); err != nil || size != mb {
+		t.Errorf("unexpected fill size: %d, %v, expected: %d", size, err, mb)
+	}
+	if _, err := calculateFillSize("/dev/shm", 1<<30, 100); err == nil {
+		t.Errorf("expected err for the size more than the available")
+	}
+	if size, err := calculateFillSize("/dev/shm", 0, 100); err != nil || size <= 0 {
+		t.Errorf("unexpected fill size: %d, %v", size, err)
+	}
+}
+
+func Test_calculateFillSize_notTmpfs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaos-shm")
+	if err != nil {
+		t.Fatalf("create temp dir err, %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if isTmpfs(dir) {
+		t.Skipf("%s is a tmpfs", dir)
+	}
+	if _, err := calculateFillSize(dir, 1, 100); err == nil {
+		t.Errorf("expected err for the directory not a tmpfs")
+	}
+}
+
+func Test_fillFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaos-shm")
+	if err != nil {
+		t.Fatalf("create temp dir err, %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "chaos_shm_test")
+	if err := fillFile(file, mb+100); err != nil {
+		t.Fatalf("fill file err, %v", err)
+	}
+	if info, err := os.Stat(file); err != nil || info.Size() != mb+100 {
+		t.Errorf("unexpected file: %+v, %v, expected size: %d", info, err, mb+100)
+	}
+	if err := fillFile(file, 1); err == nil {
+		t.Errorf("expected err for the file exists")
+	}
+}
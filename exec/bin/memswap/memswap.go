@@ -0,0 +1,195 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/sirupsen/logrus"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var swapUid string
+var swapSize, swapRate int
+var swapStorm, swapStart, swapStop, swapNohup bool
+
+func main() {
+	flag.StringVar(&swapUid, "uid", "", "the uid of the experiment")
+	flag.IntVar(&swapSize, "size", 0, "the size of the anonymous memory pushed into swap, unit is MB")
+	flag.IntVar(&swapRate, "rate", 100, "the rate of paging out, unit is M/S")
+	flag.BoolVar(&swapStorm, "storm", false, "swap the memory in and out continuously")
+	flag.BoolVar(&swapStart, "start", false, "start the swap experiment")
+	flag.BoolVar(&swapStop, "stop", false, "stop the swap experiment")
+	flag.BoolVar(&swapNohup, "nohup", false, "nohup to run the swap experiment")
+	bin.ParseFlagAndInitLog()
+
+	if swapUid == "" {
+		bin.PrintErrAndExit("less --uid flag")
+		return
+	}
+	if swapStart {
+		startSwap(swapUid, swapSize, swapRate, swapStorm)
+	} else if swapStop {
+		if err := stopSwap(swapUid); err != nil {
+			bin.PrintErrAndExit(err.Error())
+		}
+	} else if swapNohup {
+		if err := runSwap(context.Background(), swapSize, swapRate, swapStorm); err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+		}
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+var memSwapBin = exec.MemSwapBin
+
+var cl = channel.NewLocalChannel()
+
+// swapMemory returns the swap statistics, it is replaced in tests
+var swapMemory = mem.SwapMemory
+
+// pageOutInterval is the interval of paging out the memory by the rate
+var pageOutInterval = time.Second
+
+const mb = 1024 * 1024
+
+func getLogFile(uid string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_memswap_%s.log", uid))
+}
+
+// checkSwap checks the swap is enabled and has enough free space for the size
+func checkSwap(size int) error {
+	swap, err := swapMemory()
+	if err != nil {
+		return fmt.Errorf("get the swap memory failed, %v", err)
+	}
+	if swap.Total == 0 {
+		return fmt.Errorf("the swap is disabled")
+	}
+	if swap.Free < uint64(size)*mb {
+		return fmt.Errorf("the free swap is %dM, less than the size %dM", swap.Free/mb, size)
+	}
+	return nil
+}
+
+func startSwap(uid string, size, rate int, storm bool) {
+	if size <= 0 || rate <= 0 {
+		bin.PrintErrAndExit("the size and rate flags must be positive integers")
+		return
+	}
+	if err := checkSwap(size); err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	ctx := context.Background()
+	logFile := getLogFile(uid)
+	args := fmt.Sprintf("%s --nohup --uid %s --size %d --rate %d --storm=%t",
+		path.Join(util.GetProgramPath(), memSwapBin), uid, size, rate, storm)
+	response := cl.Run(ctx, "nohup", fmt.Sprintf("%s > %s 2>&1 &", args, logFile))
+	if !response.Success {
+		bin.PrintErrAndExit(response.Err)
+		return
+	}
+	// check
+	time.Sleep(time.Second)
+	response = cl.Run(ctx, "grep", fmt.Sprintf("%s %s", bin.ErrPrefix, logFile))
+	if response.Success {
+		errMsg := strings.TrimSpace(response.Result.(string))
+		if errMsg != "" {
+			stopSwap(uid)
+			bin.PrintErrAndExit(errMsg)
+			return
+		}
+	}
+	bin.PrintOutputAndExit(fmt.Sprintf("page out %dM anonymous memory to swap at %dM/S", size, rate))
+}
+
+// stopSwap kills the nohup process, and the swapped memory is freed with it
+func stopSwap(uid string) error {
+	ctx := context.WithValue(context.Background(), channel.ProcessKey, fmt.Sprintf("--nohup --uid %s", uid))
+	pids, _ := cl.GetPidsByProcessName(memSwapBin, ctx)
+	if len(pids) > 0 {
+		response := cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
+		if !response.Success {
+			return fmt.Errorf("kill the swap process failed, %s", response.Err)
+		}
+	}
+	os.Remove(getLogFile(uid))
+	return nil
+}
+
+// runSwap maps the anonymous memory of the size and pages it out by the rate, then holds it until the context is done.
+// For the storm, the pages are touched and paged out again and again.
+func runSwap(ctx context.Context, size, rate int, storm bool) error {
+	region, err := syscall.Mmap(-1, 0, size*mb, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return fmt.Errorf("map the memory failed, %v", err)
+	}
+	defer syscall.Munmap(region)
+	pageSize := os.Getpagesize()
+	// each word is filled by its offset, so the pages are not stored as the same filled pages by zswap or zram
+	// without writing to the swap device
+	for i := 0; i+8 <= len(region); i += 8 {
+		binary.LittleEndian.PutUint64(region[i:], uint64(i))
+	}
+	ticker := time.NewTicker(pageOutInterval)
+	defer ticker.Stop()
+	for round := 1; ; round++ {
+		for offset := 0; offset < len(region); offset += rate * mb {
+			end := offset + rate*mb
+			if end > len(region) {
+				end = len(region)
+			}
+			if round > 1 {
+				// swap in the pages by reading them
+				var sum byte
+				for i := offset; i < end; i += pageSize {
+					sum += region[i]
+				}
+				logrus.Debugf("read the pages, sum: %d", sum)
+			}
+			if err := bin.PageOut(region[offset:end]); err != nil {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+		logrus.Debugf("round %d, paged out %dM", round, size)
+		if !storm {
+			break
+		}
+	}
+	<-ctx.Done()
+	return nil
+}
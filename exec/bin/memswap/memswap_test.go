@@ -0,0 +1,85 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/shirou/gopsutil/mem"
+)
+
+func Test_checkSwap(t *testing.T) {
+	defer func(origin func() (*mem.SwapMemoryStat, error)) { swapMemory = origin }(swapMemory)
+	tests := []struct {
+		stat    *mem.SwapMemoryStat
+		err     error
+		size    int
+		success bool
+	}{
+		{&mem.SwapMemoryStat{Total: 2048 * mb, Free: 1024 * mb}, nil, 1024, true},
+		{&mem.SwapMemoryStat{Total: 2048 * mb, Free: 512 * mb}, nil, 1024, false},
+		{&mem.SwapMemoryStat{}, nil, 1, false},
+		{nil, errors.New("no such file"), 1, false},
+	}
+	for _, tt := range tests {
+		swapMemory = func() (*mem.SwapMemoryStat, error) {
+			return tt.stat, tt.err
+		}
+		if err := checkSwap(tt.size); (err == nil) != tt.success {
+			t.Errorf("unexpected result of %+v: %v, expected success: %t", tt.stat, err, tt.success)
+		}
+	}
+}
+
+func Test_runSwap(t *testing.T) {
+	defer func(origin time.Duration) { pageOutInterval = origin }(pageOutInterval)
+	pageOutInterval = 10 * time.Millisecond
+	for _, storm := range []bool{false, true} {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		if err := runSwap(ctx, 4, 1, storm); err != nil {
+			t.Errorf("run swap err, storm: %t, %v", storm, err)
+		}
+		cancel()
+	}
+}
+
+func Test_stopSwap(t *testing.T) {
+	cl = channel.NewMockLocalChannel()
+	mockChannel := cl.(*channel.MockLocalChannel)
+	mockChannel.GetPidsByProcessNameFunc = func(processName string, ctx context.Context) ([]string, error) {
+		if processName != memSwapBin || ctx.Value(channel.ProcessKey) != "--nohup --uid test" {
+			t.Errorf("unexpected process: %s, %v", processName, ctx.Value(channel.ProcessKey))
+		}
+		return []string{"100", "101"}, nil
+	}
+	var killArgs string
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		killArgs = args
+		return spec.ReturnSuccess("")
+	}
+	if err := stopSwap("test"); err != nil {
+		t.Fatalf("stop swap err, %v", err)
+	}
+	if killArgs != "-9 100 101" {
+		t.Errorf("unexpected kill args: %s, expected: -9 100 101", killArgs)
+	}
+}
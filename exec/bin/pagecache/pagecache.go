@@ -0,0 +1,303 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/sirupsen/logrus"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var cacheUid, cacheMode, cacheDirectory string
+var cacheInterval, cacheDropLevel, cacheSize int
+var cacheStart, cacheStop, cacheNohup bool
+
+func main() {
+	flag.StringVar(&cacheUid, "uid", "", "the uid of the experiment")
+	flag.StringVar(&cacheMode, "mode", exec.DropPageCacheMode, "drop or thrash the page cache")
+	flag.IntVar(&cacheInterval, "interval", 10, "the seconds between two drops")
+	flag.IntVar(&cacheDropLevel, "drop-level", 1, "the value written to drop_caches, 1, 2 or 3")
+	flag.StringVar(&cacheDirectory, "directory", "", "the directory where the working set file is created, required by the thrash mode")
+	flag.IntVar(&cacheSize, "size", 0, "the size of the working set file, unit is MB, 0 means the total memory bounded by the free space")
+	flag.BoolVar(&cacheStart, "start", false, "start the page cache experiment")
+	flag.BoolVar(&cacheStop, "stop", false, "stop the page cache experiment")
+	flag.BoolVar(&cacheNohup, "nohup", false, "nohup to run the page cache experiment")
+	bin.ParseFlagAndInitLog()
+
+	if cacheUid == "" {
+		bin.PrintErrAndExit("less --uid flag")
+		return
+	}
+	if cacheStart {
+		startPageCache(cacheUid, cacheMode, cacheInterval, cacheDropLevel, cacheDirectory, cacheSize)
+	} else if cacheStop {
+		if err := stopPageCache(cacheUid, cacheDirectory); err != nil {
+			bin.PrintErrAndExit(err.Error())
+		}
+	} else if cacheNohup {
+		config, err := newPageCacheConfig(cacheUid, cacheMode, cacheInterval, cacheDropLevel, cacheDirectory, cacheSize)
+		if err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+			return
+		}
+		if err := runPageCache(context.Background(), config); err != nil {
+			bin.PrintAndExitWithErrPrefix(err.Error())
+		}
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+var pageCacheBin = exec.PageCacheBin
+
+var cl = channel.NewLocalChannel()
+
+// dropCachesPath is the file to drop the caches, it is replaced in tests
+var dropCachesPath = "/proc/sys/vm/drop_caches"
+
+const (
+	mb = 1024 * 1024
+	// tmpfsMagic is the filesystem type of tmpfs in statfs, whose pages are not evicted like the page cache
+	tmpfsMagic = 0x01021994
+	// reservedPercent is the percent of the filesystem left free for the other writers
+	reservedPercent = 10
+)
+
+// pageCacheConfig is the resolved settings of the experiment, which is also reported on start
+type pageCacheConfig struct {
+	Mode      string `json:"mode"`
+	Interval  int    `json:"interval,omitempty"`
+	DropLevel int    `json:"drop_level,omitempty"`
+	File      string `json:"file,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+}
+
+func getWorkingSetFile(uid, directory string) string {
+	return path.Join(directory, fmt.Sprintf("chaos_pagecache_%s.dat", uid))
+}
+
+func getLogFile(uid string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_pagecache_%s.log", uid))
+}
+
+func newPageCacheConfig(uid, mode string, interval, dropLevel int, directory string, size int) (*pageCacheConfig, error) {
+	config := &pageCacheConfig{Mode: mode}
+	switch mode {
+	case exec.DropPageCacheMode:
+		if interval <= 0 {
+			return nil, fmt.Errorf("illegal interval %d, it must be a positive integer", interval)
+		}
+		if dropLevel < 1 || dropLevel > 3 {
+			return nil, fmt.Errorf("illegal drop-level %d, it must be 1, 2 or 3", dropLevel)
+		}
+		config.Interval, config.DropLevel = interval, dropLevel
+	case exec.ThrashPageCacheMode:
+		if directory == "" {
+			return nil, fmt.Errorf("less --directory flag, it is required by the thrash mode")
+		}
+		config.File = getWorkingSetFile(uid, directory)
+		var err error
+		if config.Size, err = getWorkingSetSize(directory, config.File, int64(size)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("illegal mode %s, it must be drop or thrash", mode)
+	}
+	return config, nil
+}
+
+// getWorkingSetSize checks the directory is not a tmpfs, and returns the size of the working set file which leaves
+// the reserved space of the filesystem free. The default size is the total memory bounded by the free space
+func getWorkingSetSize(directory, file string, size int64) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(directory, &stat); err != nil {
+		return 0, fmt.Errorf("get the filesystem of %s failed, %v", directory, err)
+	}
+	if stat.Type == tmpfsMagic {
+		return 0, fmt.Errorf("%s is a tmpfs, the working set file must be on a disk", directory)
+	}
+	available := int64(stat.Bavail) * int64(stat.Bsize)
+	// the existing file of the last run is reused
+	if info, err := os.Stat(file); err == nil {
+		available += info.Size()
+	}
+	free := (available - int64(stat.Blocks)*int64(stat.Bsize)*reservedPercent/100) / mb
+	if size <= 0 {
+		virtualMemory, err := mem.VirtualMemory()
+		if err != nil {
+			return 0, err
+		}
+		size = int64(virtualMemory.Total / mb)
+		if size > free {
+			size = free
+		}
+	}
+	if size <= 0 || size > free {
+		return 0, fmt.Errorf("the available space of %s is %dM, the size %dM must leave %d%% of the filesystem free",
+			directory, available/mb, size, reservedPercent)
+	}
+	return size, nil
+}
+
+func startPageCache(uid, mode string, interval, dropLevel int, directory string, size int) {
+	config, err := newPageCacheConfig(uid, mode, interval, dropLevel, directory, size)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	ctx := context.Background()
+	logFile := getLogFile(uid)
+	args := fmt.Sprintf("%s --nohup --uid %s --mode %s", path.Join(util.GetProgramPath(), pageCacheBin), uid, mode)
+	if mode == exec.DropPageCacheMode {
+		args = fmt.Sprintf("%s --interval %d --drop-level %d", args, config.Interval, config.DropLevel)
+	} else {
+		args = fmt.Sprintf("%s --directory %s --size %d", args, directory, config.Size)
+	}
+	response := cl.Run(ctx, "nohup", fmt.Sprintf("%s > %s 2>&1 &", args, logFile))
+	if !response.Success {
+		bin.PrintErrAndExit(response.Err)
+		return
+	}
+	// check
+	time.Sleep(time.Second)
+	response = cl.Run(ctx, "grep", fmt.Sprintf("%s %s", bin.ErrPrefix, logFile))
+	if response.Success {
+		errMsg := strings.TrimSpace(response.Result.(string))
+		if errMsg != "" {
+			stopPageCache(uid, directory)
+			bin.PrintErrAndExit(errMsg)
+			return
+		}
+	}
+	bytes, _ := json.Marshal(config)
+	bin.PrintOutputAndExit(string(bytes))
+}
+
+// stopPageCache kills the nohup process and removes the working set file
+func stopPageCache(uid, directory string) error {
+	ctx := context.WithValue(context.Background(), channel.ProcessKey, fmt.Sprintf("--nohup --uid %s", uid))
+	pids, _ := cl.GetPidsByProcessName(pageCacheBin, ctx)
+	if len(pids) > 0 {
+		response := cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
+		if !response.Success {
+			return fmt.Errorf("kill the page cache process failed, %s", response.Err)
+		}
+	}
+	os.Remove(getLogFile(uid))
+	if directory == "" {
+		return nil
+	}
+	if err := os.Remove(getWorkingSetFile(uid, directory)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func runPageCache(ctx context.Context, config *pageCacheConfig) error {
+	if config.Mode == exec.DropPageCacheMode {
+		return runDrop(ctx, time.Duration(config.Interval)*time.Second, config.DropLevel)
+	}
+	if err := createWorkingSet(ctx, config.File, config.Size); err != nil {
+		return err
+	}
+	return runThrash(ctx, config.File)
+}
+
+// runDrop drops the caches at once and then every interval until the context is done
+func runDrop(ctx context.Context, interval time.Duration, dropLevel int) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// the dirty pages cannot be dropped, so write them back first
+		syscall.Sync()
+		if err := ioutil.WriteFile(dropCachesPath, []byte(strconv.Itoa(dropLevel)), 0644); err != nil {
+			return fmt.Errorf("drop the caches failed, %v", err)
+		}
+		logrus.Debugf("drop the caches, level: %d", dropLevel)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// createWorkingSet writes the working set file of the size, the file is not sparse so all the pages are cached when read
+func createWorkingSet(ctx context.Context, file string, size int64) error {
+	if info, err := os.Stat(file); err == nil && info.Size() == size*mb {
+		return nil
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	chunk := make([]byte, mb)
+	for i := range chunk {
+		chunk[i] = byte(i)
+	}
+	for i := int64(0); i < size && ctx.Err() == nil; i++ {
+		if _, err := f.Write(chunk); err != nil {
+			return fmt.Errorf("write the working set file %s failed, %v", file, err)
+		}
+	}
+	return nil
+}
+
+// runThrash stream reads the working set file over and over until the context is done
+func runThrash(ctx context.Context, file string) error {
+	buffer := make([]byte, mb)
+	for passes := 1; ctx.Err() == nil; passes++ {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		var total int64
+		for ctx.Err() == nil {
+			n, err := f.Read(buffer)
+			total += int64(n)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return fmt.Errorf("read the working set file %s failed, %v", file, err)
+			}
+		}
+		f.Close()
+		logrus.Debugf("pass %d, read %dM in %v", passes, total/mb, time.Since(start))
+	}
+	return nil
+}
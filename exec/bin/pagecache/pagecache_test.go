@@ -0,0 +1,144 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+func Test_newPageCacheConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaos-pagecache")
+	if err != nil {
+		t.Fatalf("create temp dir err, %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config, err := newPageCacheConfig("test", "thrash", 10, 1, dir, 1)
+	if err != nil {
+		t.Fatalf("new config err, %v", err)
+	}
+	if config.File != path.Join(dir, "chaos_pagecache_test.dat") || config.Size != 1 {
+		t.Errorf("unexpected config: %+v", config)
+	}
+	// the default size is bounded by the free space of the filesystem
+	if config, err := newPageCacheConfig("test", "thrash", 10, 1, dir, 0); err == nil && config.Size <= 0 {
+		t.Errorf("unexpected default size: %+v", config)
+	}
+	if _, err := newPageCacheConfig("test", "thrash", 10, 1, "", 1); err == nil {
+		t.Errorf("expected err for the directory not specified")
+	}
+	tests := []struct {
+		mode      string
+		interval  int
+		dropLevel int
+		size      int
+	}{
+		{"evict", 10, 1, 1},
+		{"drop", 0, 1, 1},
+		{"drop", 10, 4, 1},
+		{"thrash", 10, 1, 1 << 40},
+	}
+	for _, tt := range tests {
+		if _, err := newPageCacheConfig("test", tt.mode, tt.interval, tt.dropLevel, dir, tt.size); err == nil {
+			t.Errorf("expected err for %+v", tt)
+		}
+	}
+}
+
+func Test_runDrop(t *testing.T) {
+	file, err := ioutil.TempFile("", "drop_caches")
+	if err != nil {
+		t.Fatalf("create temp file err, %v", err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+	defer func(origin string) { dropCachesPath = origin }(dropCachesPath)
+	dropCachesPath = file.Name()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := runDrop(ctx, 20*time.Millisecond, 3); err != nil {
+		t.Fatalf("run drop err, %v", err)
+	}
+	if bytes, _ := ioutil.ReadFile(file.Name()); string(bytes) != "3" {
+		t.Errorf("unexpected drop level: %s, expected: 3", string(bytes))
+	}
+
+	dropCachesPath = path.Join(file.Name(), "not-exist")
+	if err := runDrop(context.Background(), time.Second, 1); err == nil {
+		t.Errorf("expected err for the drop_caches not exists")
+	}
+}
+
+func Test_runPageCache_thrash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaos-pagecache")
+	if err != nil {
+		t.Fatalf("create temp dir err, %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	config := &pageCacheConfig{Mode: "thrash", File: path.Join(dir, "chaos_pagecache_test.dat"), Size: 2}
+	if err := runPageCache(ctx, config); err != nil {
+		t.Fatalf("run thrash err, %v", err)
+	}
+	info, err := os.Stat(config.File)
+	if err != nil || info.Size() != 2*mb {
+		t.Errorf("unexpected working set file: %+v, %v, expected size: %d", info, err, 2*mb)
+	}
+}
+
+func Test_stopPageCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaos-pagecache")
+	if err != nil {
+		t.Fatalf("create temp dir err, %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := getWorkingSetFile("test", dir)
+	ioutil.WriteFile(file, []byte("cache"), 0644)
+
+	cl = channel.NewMockLocalChannel()
+	mockChannel := cl.(*channel.MockLocalChannel)
+	mockChannel.GetPidsByProcessNameFunc = func(processName string, ctx context.Context) ([]string, error) {
+		if ctx.Value(channel.ProcessKey) != "--nohup --uid test" {
+			t.Errorf("unexpected process key: %v", ctx.Value(channel.ProcessKey))
+		}
+		return []string{"100"}, nil
+	}
+	var killArgs string
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		killArgs = args
+		return spec.ReturnSuccess("")
+	}
+	if err := stopPageCache("test", dir); err != nil {
+		t.Fatalf("stop page cache err, %v", err)
+	}
+	if killArgs != "-9 100" {
+		t.Errorf("unexpected kill args: %s, expected: -9 100", killArgs)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("expected the working set file removed, %v", err)
+	}
+}
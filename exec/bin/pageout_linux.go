@@ -0,0 +1,36 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// madvPageout is MADV_PAGEOUT since linux 5.4, which reclaims the pages of the range at once
+const madvPageout = 21
+
+// PageOut pages out the memory to swap by madvise MADV_PAGEOUT
+func PageOut(b []byte) error {
+	if err := syscall.Madvise(b, madvPageout); err != nil {
+		if err == syscall.EINVAL {
+			return fmt.Errorf("madvise MADV_PAGEOUT is not supported, it needs linux 5.4 or later, %v", err)
+		}
+		return fmt.Errorf("page out the memory failed, %v", err)
+	}
+	return nil
+}
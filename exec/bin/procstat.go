@@ -0,0 +1,109 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// CpuStat is the cpu time in jiffies from /proc/stat
+type CpuStat struct {
+	Busy  uint64
+	Total uint64
+}
+
+// ReadCpuStats returns the cpu time of all the cpus and each core from /proc/stat, the keys are cpu, cpu0, cpu1...
+func ReadCpuStats() (map[string]CpuStat, error) {
+	bytes, err := ioutil.ReadFile(path.Join(ProcPath, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]CpuStat)
+	for _, line := range strings.Split(string(bytes), "\n") {
+		// cpu0 user nice system idle iowait irq softirq steal guest guest_nice
+		fields := strings.Fields(line)
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		var stat CpuStat
+		// guest and guest_nice are included in user and nice
+		for i := 1; i < len(fields) && i <= 8; i++ {
+			value, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("illegal %s in /proc/stat, %v", fields[0], err)
+			}
+			stat.Total += value
+			// idle and iowait
+			if i != 4 && i != 5 {
+				stat.Busy += value
+			}
+		}
+		stats[fields[0]] = stat
+	}
+	return stats, nil
+}
+
+// CoreName returns the name of the core in /proc/stat
+func CoreName(core int) string {
+	return fmt.Sprintf("cpu%d", core)
+}
+
+// CpuUsagePercent returns the cpu usage between the two stats
+func CpuUsagePercent(prev, cur CpuStat) float64 {
+	if cur.Total <= prev.Total || cur.Busy < prev.Busy {
+		return 0
+	}
+	return float64(cur.Busy-prev.Busy) / float64(cur.Total-prev.Total) * 100
+}
+
+// ReadProcStatValue returns the value of the key in /proc/stat, for example ctxt, procs_running
+func ReadProcStatValue(key string) (uint64, error) {
+	bytes, err := ioutil.ReadFile(path.Join(ProcPath, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(bytes), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("%s not found in /proc/stat", key)
+}
+
+// ReadLoadAvg returns the load average of 1, 5 and 15 minutes from /proc/loadavg
+func ReadLoadAvg() ([3]float64, error) {
+	var loadAvg [3]float64
+	bytes, err := ioutil.ReadFile(path.Join(ProcPath, "loadavg"))
+	if err != nil {
+		return loadAvg, err
+	}
+	fields := strings.Fields(string(bytes))
+	if len(fields) < 3 {
+		return loadAvg, fmt.Errorf("illegal /proc/loadavg: %s", bytes)
+	}
+	for i := range loadAvg {
+		if loadAvg[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return loadAvg, fmt.Errorf("illegal /proc/loadavg: %s", bytes)
+		}
+	}
+	return loadAvg, nil
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
+)
+
+func Test_ReadCpuStats(t *testing.T) {
+	root, clean := bintest.NewRoot(t)
+	defer clean()
+	stat := `cpu  300 0 100 500 100 0 0 0 0 0
+cpu0 200 0 50 200 50 0 0 0 0 0
+cpu1 100 0 50 300 50 0 0 0 0 0
+intr 464744 0 0
+ctxt 1000
+`
+	bintest.WriteFiles(t, root, map[string]string{"stat": stat})
+	defer bintest.Replace(&ProcPath, root)()
+
+	stats, err := ReadCpuStats()
+	if err != nil {
+		t.Fatalf("read cpu stats err, %v", err)
+	}
+	expected := map[string]CpuStat{
+		"cpu":  {Busy: 400, Total: 1000},
+		"cpu0": {Busy: 250, Total: 500},
+		"cpu1": {Busy: 150, Total: 500},
+	}
+	for name, e := range expected {
+		if stats[name] != e {
+			t.Errorf("unexpected stat of %s: %+v, expected: %+v", name, stats[name], e)
+		}
+	}
+	if usage := CpuUsagePercent(CpuStat{Busy: 200, Total: 400}, stats[CoreName(0)]); usage != 50 {
+		t.Errorf("unexpected usage: %f, expected: 50", usage)
+	}
+	if usage := CpuUsagePercent(stats["cpu0"], stats["cpu0"]); usage != 0 {
+		t.Errorf("unexpected usage: %f, expected: 0 without the delta", usage)
+	}
+}
@@ -0,0 +1,288 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var readonlyUid, readonlyPath, readonlyMode string
+var readonlyStart, readonlyStop bool
+
+func main() {
+	flag.StringVar(&readonlyUid, "uid", "", "the uid of the experiment")
+	flag.StringVar(&readonlyPath, "path", "", "the path to make read-only")
+	flag.StringVar(&readonlyMode, "mode", remountMode, "remount the filesystem or bind the directory read-only, remount|bind")
+	flag.BoolVar(&readonlyStart, "start", false, "make the path read-only")
+	flag.BoolVar(&readonlyStop, "stop", false, "restore the mount")
+	bin.ParseFlagAndInitLog()
+
+	if readonlyUid == "" {
+		bin.PrintErrAndExit("less --uid flag")
+		return
+	}
+	if readonlyStart {
+		startReadonly(readonlyUid, readonlyPath, readonlyMode)
+	} else if readonlyStop {
+		stopReadonly(readonlyUid)
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+var cl = channel.NewLocalChannel()
+
+const (
+	remountMode = "remount"
+	bindMode    = "bind"
+)
+
+// readonlyState is the mount made read-only, which is recorded in the backup file
+type readonlyState struct {
+	Mode string `json:"mode"`
+	// MountPoint is the mount point of the filesystem remounted, or the directory bound over itself
+	MountPoint string `json:"mountPoint"`
+}
+
+func getBackupFile(uid string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_readonlydisk_%s.bak", uid))
+}
+
+func startReadonly(uid, directory, mode string) {
+	backupFile := getBackupFile(uid)
+	if util.IsExist(backupFile) {
+		bin.PrintErrAndExit(fmt.Sprintf("the disk readonly experiment %s is running, the backup file %s exists", uid, backupFile))
+		return
+	}
+	if !cl.IsCommandAvailable("mount") {
+		bin.PrintErrAndExit("mount command not found")
+		return
+	}
+	mount, err := bin.GetMountByPath(directory)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	if isReadonly(mount) {
+		bin.PrintErrAndExit(fmt.Sprintf("the mount point %s of %s is read-only already", mount.MountPoint, directory))
+		return
+	}
+	state := &readonlyState{Mode: mode, MountPoint: mount.MountPoint}
+	var args []string
+	switch mode {
+	case remountMode:
+		if err := checkBusy(mount); err != nil {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+		args = []string{fmt.Sprintf("-o remount,ro %s", exec.ShellQuote(mount.MountPoint))}
+	case bindMode:
+		if state.MountPoint, err = realPath(directory); err != nil {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+		args = []string{
+			fmt.Sprintf("--bind %s %s", exec.ShellQuote(state.MountPoint), exec.ShellQuote(state.MountPoint)),
+			fmt.Sprintf("-o remount,bind,ro %s", exec.ShellQuote(state.MountPoint)),
+		}
+	default:
+		bin.PrintErrAndExit(fmt.Sprintf("illegal mode %s, only support remount and bind", mode))
+		return
+	}
+	bytes, _ := json.Marshal(state)
+	if err := ioutil.WriteFile(backupFile, bytes, 0644); err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	for i, arg := range args {
+		response := cl.Run(context.Background(), "mount", arg)
+		if response.Success {
+			continue
+		}
+		if i > 0 {
+			// the directory is bound but not read-only, so the bind mount is removed
+			cl.Run(context.Background(), "umount", exec.ShellQuote(state.MountPoint))
+		}
+		os.Remove(backupFile)
+		if isBusyError(response.Err) {
+			bin.PrintErrAndExit(fmt.Sprintf("the mount point %s is busy, some files are opened for writing, "+
+				"stop the processes writing it or use the bind mode, %s", mount.MountPoint, response.Err))
+			return
+		}
+		bin.PrintErrAndExit(response.Err)
+		return
+	}
+	bin.PrintOutputAndExit(fmt.Sprintf("%s %s read-only", mode, state.MountPoint))
+}
+
+func stopReadonly(uid string) {
+	backupFile := getBackupFile(uid)
+	bytes, err := ioutil.ReadFile(backupFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			bin.PrintOutputAndExit("nothing to do")
+			return
+		}
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	var state readonlyState
+	if err := json.Unmarshal(bytes, &state); err != nil || state.MountPoint == "" {
+		bin.PrintErrAndExit(fmt.Sprintf("illegal backup file %s, %v", backupFile, err))
+		return
+	}
+	response := spec.ReturnSuccess("")
+	switch state.Mode {
+	case remountMode:
+		response = cl.Run(context.Background(), "mount", fmt.Sprintf("-o remount,rw %s", exec.ShellQuote(state.MountPoint)))
+	case bindMode:
+		mount, err := bin.GetMountByPath(state.MountPoint)
+		if err != nil {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+		// the bind mount is removed already, so nothing is unmounted to avoid removing the others
+		if mount.MountPoint == state.MountPoint && isReadonly(mount) {
+			response = cl.Run(context.Background(), "umount", exec.ShellQuote(state.MountPoint))
+		}
+	default:
+		bin.PrintErrAndExit(fmt.Sprintf("illegal mode %s in the backup file %s", state.Mode, backupFile))
+		return
+	}
+	if !response.Success {
+		if isBusyError(response.Err) {
+			bin.PrintErrAndExit(fmt.Sprintf("the mount point %s is busy, stop the processes using it and retry, %s",
+				state.MountPoint, response.Err))
+			return
+		}
+		bin.PrintErrAndExit(response.Err)
+		return
+	}
+	os.Remove(backupFile)
+	bin.PrintOutputAndExit(fmt.Sprintf("restore %s read-write", state.MountPoint))
+}
+
+func isReadonly(mount *bin.Mount) bool {
+	return strings.Split(mount.Options, ",")[0] == "ro"
+}
+
+func isBusyError(err string) bool {
+	return strings.Contains(strings.ToLower(err), "busy")
+}
+
+func realPath(p string) (string, error) {
+	absPath, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(absPath)
+}
+
+// checkBusy returns an error with the processes writing the filesystem, which makes the remount fail
+func checkBusy(mount *bin.Mount) error {
+	mounts, err := bin.GetMounts()
+	if err != nil {
+		return err
+	}
+	// the files are opened on the bind mounts of the filesystem too
+	mountIds := make(map[string]bool)
+	for _, m := range mounts {
+		if m.Device == mount.Device {
+			mountIds[m.ID] = true
+		}
+	}
+	writers := getWriters(mountIds)
+	if len(writers) == 0 {
+		return nil
+	}
+	processes := make([]string, 0, len(writers))
+	for _, pid := range writers {
+		comm, _ := ioutil.ReadFile(path.Join(bin.ProcPath, strconv.Itoa(pid), "comm"))
+		processes = append(processes, fmt.Sprintf("%d(%s)", pid, strings.TrimSpace(string(comm))))
+	}
+	return fmt.Errorf("the mount point %s is busy, the files are opened for writing by the processes %s, "+
+		"stop them or use the bind mode", mount.MountPoint, strings.Join(processes, ", "))
+}
+
+// getWriters returns the processes which open the files for writing on the mounts
+func getWriters(mountIds map[string]bool) []int {
+	dirs, err := ioutil.ReadDir(bin.ProcPath)
+	if err != nil {
+		return nil
+	}
+	writers := make([]int, 0)
+	for _, dir := range dirs {
+		pid, err := strconv.Atoi(dir.Name())
+		if err != nil {
+			continue
+		}
+		fdinfoDir := path.Join(bin.ProcPath, dir.Name(), "fdinfo")
+		fds, err := ioutil.ReadDir(fdinfoDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			bytes, err := ioutil.ReadFile(path.Join(fdinfoDir, fd.Name()))
+			if err != nil {
+				continue
+			}
+			if isWriting(string(bytes), mountIds) {
+				writers = append(writers, pid)
+				break
+			}
+		}
+	}
+	sort.Ints(writers)
+	return writers
+}
+
+// isWriting returns true if the fdinfo is a file opened for writing on the mounts
+func isWriting(fdinfo string, mountIds map[string]bool) bool {
+	var flags int64 = -1
+	var mountId string
+	for _, line := range strings.Split(fdinfo, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "flags:":
+			flags, _ = strconv.ParseInt(fields[1], 8, 64)
+		case "mnt_id:":
+			mountId = fields[1]
+		}
+	}
+	// the access mode is O_WRONLY or O_RDWR
+	return flags&03 != 0 && flags != -1 && mountIds[mountId]
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
+)
+
+func Test_isWriting(t *testing.T) {
+	mountIds := map[string]bool{"21": true}
+	tests := []struct {
+		name     string
+		fdinfo   string
+		expected bool
+	}{
+		{name: "write only", fdinfo: "pos:\t0\nflags:\t0100001\nmnt_id:\t21\n", expected: true},
+		{name: "read write", fdinfo: "pos:\t0\nflags:\t02100002\nmnt_id:\t21\n", expected: true},
+		{name: "read only", fdinfo: "pos:\t0\nflags:\t0100000\nmnt_id:\t21\n", expected: false},
+		{name: "other mount", fdinfo: "pos:\t0\nflags:\t0100002\nmnt_id:\t22\n", expected: false},
+		{name: "no flags", fdinfo: "pos:\t0\nmnt_id:\t21\n", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := isWriting(tt.fdinfo, mountIds); actual != tt.expected {
+				t.Errorf("isWriting() = %t, expected %t", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func Test_startAndStopReadonly(t *testing.T) {
+	root, clean := bintest.NewRoot(t)
+	defer clean()
+	mountinfo := "21 20 8:1 / /data rw,relatime - ext4 /dev/sda1 rw\n" +
+		"22 20 8:1 /app /app rw,relatime - ext4 /dev/sda1 rw\n" +
+		"23 20 8:2 / /log rw,relatime - ext4 /dev/sda2 rw\n"
+	bintest.WriteFiles(t, root, map[string]string{
+		"self/mountinfo": mountinfo,
+		"100/comm":       "mysqld\n",
+		"100/fdinfo/3":   "pos:\t0\nflags:\t0100000\nmnt_id:\t21\n",
+		"100/fdinfo/4":   "pos:\t0\nflags:\t0100001\nmnt_id:\t23\n",
+	})
+	defer bintest.Replace(&bin.ProcPath, root)()
+	var exitCode int
+	bin.ExitFunc = func(code int) {
+		exitCode = code
+	}
+
+	uid := "readonlydisk-test"
+	defer os.Remove(getBackupFile(uid))
+	commands := make([]string, 0)
+	mockChannel := channel.NewMockLocalChannel().(*channel.MockLocalChannel)
+	mockChannel.IsCommandAvailableFunc = func(commandName string) bool { return true }
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		commands = append(commands, fmt.Sprintf("%s %s", script, args))
+		return spec.ReturnSuccess("")
+	}
+	cl = mockChannel
+
+	startReadonly(uid, "/data/mysql", remountMode)
+	if exitCode != 0 {
+		t.Fatalf("start readonly err, %s", bin.ExitMessageForTesting)
+	}
+	stopReadonly(uid)
+	if exitCode != 0 {
+		t.Fatalf("stop readonly err, %s", bin.ExitMessageForTesting)
+	}
+	expected := []string{"mount -o remount,ro '/data'", "mount -o remount,rw '/data'"}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("unexpected commands: %v, expected: %v", commands, expected)
+	}
+	if util.IsExist(getBackupFile(uid)) {
+		t.Errorf("expected the backup file %s removed", getBackupFile(uid))
+	}
+
+	// the file opened for writing on the bind mount of the same filesystem
+	bintest.WriteFiles(t, root, map[string]string{"100/fdinfo/5": "pos:\t0\nflags:\t0100002\nmnt_id:\t22\n"})
+	commands = commands[:0]
+	startReadonly(uid, "/data/mysql", remountMode)
+	if exitCode != 1 || !strings.Contains(bin.ExitMessageForTesting, "100(mysqld)") {
+		t.Errorf("unexpected result: %d, %s, expected the busy err", exitCode, bin.ExitMessageForTesting)
+	}
+	if len(commands) != 0 || util.IsExist(getBackupFile(uid)) {
+		t.Errorf("unexpected commands: %v, expected nothing is mounted", commands)
+	}
+
+	// the read-only filesystem
+	bintest.WriteFiles(t, root, map[string]string{"self/mountinfo": "21 20 8:1 / /data ro,relatime - ext4 /dev/sda1 ro\n"})
+	startReadonly(uid, "/data/mysql", remountMode)
+	if exitCode != 1 || !strings.Contains(bin.ExitMessageForTesting, "read-only already") {
+		t.Errorf("unexpected result: %d, %s, expected the read-only err", exitCode, bin.ExitMessageForTesting)
+	}
+}
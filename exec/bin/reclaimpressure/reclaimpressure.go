@@ -0,0 +1,176 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/sirupsen/logrus"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var reclaimUid, reclaimCgroupPath string
+var reclaimTargetPid, reclaimHigh, reclaimHighPercent int
+var reclaimStart, reclaimStop bool
+
+func main() {
+	flag.StringVar(&reclaimUid, "uid", "", "the uid of the experiment")
+	flag.StringVar(&reclaimCgroupPath, "cgroup-path", "", "the memory cgroup to lower memory.high")
+	flag.IntVar(&reclaimTargetPid, "target-pid", 0, "lower memory.high of the memory cgroup of the process")
+	flag.IntVar(&reclaimHigh, "high", 0, "the memory.high to set, unit is MB")
+	flag.IntVar(&reclaimHighPercent, "high-percent", 80, "the memory.high to set, percent of the current memory usage")
+	flag.BoolVar(&reclaimStart, "start", false, "lower memory.high")
+	flag.BoolVar(&reclaimStop, "stop", false, "restore memory.high")
+	bin.ParseFlagAndInitLog()
+
+	if reclaimUid == "" {
+		bin.PrintErrAndExit("less --uid flag")
+		return
+	}
+	if reclaimStart {
+		startReclaimPressure(reclaimUid, reclaimCgroupPath, reclaimTargetPid, reclaimHigh, reclaimHighPercent)
+	} else if reclaimStop {
+		stopReclaimPressure(reclaimUid)
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+// reclaimState is the original memory.high of the cgroup, which is recorded in the backup file
+type reclaimState struct {
+	Dirs     map[string]string `json:"dirs"`
+	Original uint64            `json:"original"`
+	// HighEvents is the times the cgroup was throttled by memory.high before the experiment
+	HighEvents uint64 `json:"high_events"`
+}
+
+func (r *reclaimState) cgroup() *bin.Cgroup {
+	return &bin.Cgroup{Version: bin.CgroupV2, Dirs: r.Dirs}
+}
+
+func getBackupFile(uid string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_reclaimpressure_%s.bak", uid))
+}
+
+// lowerHigh returns the memory.high in bytes by the high flag, or the percent of the current usage,
+// it must be lower than the original memory.high, otherwise there is no pressure
+func lowerHigh(cgroup *bin.Cgroup, original uint64, high, highPercent int) (uint64, error) {
+	var value uint64
+	if high > 0 {
+		value = uint64(high) * 1024 * 1024
+	} else {
+		if highPercent <= 0 || highPercent > 100 {
+			return 0, fmt.Errorf("illegal --high-percent flag %d, it must be in (0, 100]", highPercent)
+		}
+		stat, err := cgroup.MemoryStat()
+		if err != nil {
+			return 0, err
+		}
+		value = stat.Usage * uint64(highPercent) / 100
+	}
+	if value >= original {
+		return 0, fmt.Errorf("the memory.high %s is not lower than the current memory.high %s", formatHigh(value), formatHigh(original))
+	}
+	return value, nil
+}
+
+func formatHigh(high uint64) string {
+	if high == math.MaxUint64 {
+		return "max"
+	}
+	return fmt.Sprintf("%dM", high/1024/1024)
+}
+
+func startReclaimPressure(uid, cgroupPath string, targetPid, high, highPercent int) {
+	backupFile := getBackupFile(uid)
+	if util.IsExist(backupFile) {
+		bin.PrintErrAndExit(fmt.Sprintf("the reclaim pressure experiment %s is running, the backup file %s exists", uid, backupFile))
+		return
+	}
+	cgroup, err := bin.GetMemoryCgroup(cgroupPath, targetPid)
+	if err != nil {
+		bin.PrintErrAndExit(fmt.Sprintf("get the memory cgroup failed, %v", err))
+		return
+	}
+	state := &reclaimState{Dirs: cgroup.Dirs}
+	if state.Original, err = cgroup.MemoryHigh(); err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	if state.HighEvents, err = cgroup.ReadKeyedFile("memory", "memory.events", "high"); err != nil {
+		logrus.Warnf("read the high events failed, %v", err)
+	}
+	value, err := lowerHigh(cgroup, state.Original, high, highPercent)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	bytes, _ := json.Marshal(state)
+	if err := ioutil.WriteFile(backupFile, bytes, 0644); err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	if err := cgroup.SetMemoryHigh(value); err != nil {
+		os.Remove(backupFile)
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	bin.PrintOutputAndExit(fmt.Sprintf("lower memory.high of %s from %s to %s",
+		cgroup.Dirs["memory"], formatHigh(state.Original), formatHigh(value)))
+}
+
+func stopReclaimPressure(uid string) {
+	backupFile := getBackupFile(uid)
+	bytes, err := ioutil.ReadFile(backupFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			bin.PrintOutputAndExit("nothing to do")
+			return
+		}
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	var state reclaimState
+	if err := json.Unmarshal(bytes, &state); err != nil {
+		bin.PrintErrAndExit(fmt.Sprintf("illegal backup file %s, %v", backupFile, err))
+		return
+	}
+	cgroup := state.cgroup()
+	events, _ := cgroup.ReadKeyedFile("memory", "memory.events", "high")
+	if err := cgroup.SetMemoryHigh(state.Original); err != nil {
+		if _, statErr := os.Stat(state.Dirs["memory"]); !os.IsNotExist(statErr) {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+		// the cgroup is removed with its workload
+		logrus.Warnf("the cgroup %s not exists, %v", state.Dirs["memory"], err)
+	}
+	os.Remove(backupFile)
+	var throttled uint64
+	if events > state.HighEvents {
+		throttled = events - state.HighEvents
+	}
+	bin.PrintOutputAndExit(fmt.Sprintf("restore memory.high of %s to %s, the cgroup was throttled %d times",
+		state.Dirs["memory"], formatHigh(state.Original), throttled))
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
+)
+
+// newFakeCgroupfs creates the fake proc and cgroup trees, the memory controller is mounted at v1 if v1 is true,
+// otherwise it is enabled in the cgroup v2 mounted at unified
+func newFakeCgroupfs(t *testing.T, v1 bool, files map[string]string) (string, func()) {
+	root, clean := bintest.NewRoot(t)
+	files["proc/self/mountinfo"] = fmt.Sprintf("35 25 0:31 / %s/unified rw,nosuid - cgroup2 cgroup2 rw\n", root)
+	files["unified/cgroup.controllers"] = "cpu memory\n"
+	if v1 {
+		files["proc/self/mountinfo"] += fmt.Sprintf("34 25 0:30 / %s/v1 rw,nosuid - cgroup cgroup rw,memory\n", root)
+		files["unified/cgroup.controllers"] = ""
+	}
+	bintest.WriteFiles(t, root, files)
+	restore := bintest.Replace(&bin.ProcPath, path.Join(root, "proc"))
+	return root, func() {
+		restore()
+		clean()
+	}
+}
+
+func Test_startAndStopReclaimPressure(t *testing.T) {
+	root, clean := newFakeCgroupfs(t, false, map[string]string{
+		"unified/pod1/cgroup.procs":   "",
+		"unified/pod1/memory.current": "524288000\n",
+		"unified/pod1/memory.max":     "max\n",
+		"unified/pod1/memory.high":    "max\n",
+		"unified/pod1/memory.stat":    "anon 419430400\nfile 104857600\nactive_anon 419430400\ninactive_anon 0\n",
+		"unified/pod1/memory.events":  "low 0\nhigh 3\nmax 0\noom 0\noom_kill 0\n",
+	})
+	defer clean()
+	var exitCode int
+	bin.ExitFunc = func(code int) {
+		exitCode = code
+	}
+	uid := "reclaim-test"
+	defer os.Remove(getBackupFile(uid))
+	highFile := path.Join(root, "unified/pod1/memory.high")
+
+	startReclaimPressure(uid, "/pod1", 0, 0, 80)
+	if exitCode != 0 {
+		t.Fatalf("start reclaim pressure err, %s", bin.ExitMessageForTesting)
+	}
+	if bytes, _ := ioutil.ReadFile(highFile); string(bytes) != "419430400" {
+		t.Errorf("unexpected memory.high: %s, expected: 419430400", string(bytes))
+	}
+	if !util.IsExist(getBackupFile(uid)) {
+		t.Errorf("expected the backup file %s", getBackupFile(uid))
+	}
+
+	startReclaimPressure(uid, "/pod1", 0, 100, 80)
+	if exitCode != 1 {
+		t.Errorf("expected err for the running experiment")
+	}
+
+	ioutil.WriteFile(path.Join(root, "unified/pod1/memory.events"), []byte("low 0\nhigh 10\nmax 0\noom 0\noom_kill 0\n"), 0644)
+	exitCode = 0
+	stopReclaimPressure(uid)
+	if exitCode != 0 {
+		t.Fatalf("stop reclaim pressure err, %s", bin.ExitMessageForTesting)
+	}
+	if bytes, _ := ioutil.ReadFile(highFile); string(bytes) != "max" {
+		t.Errorf("unexpected memory.high: %s, expected: max", string(bytes))
+	}
+	if !strings.Contains(bin.ExitMessageForTesting, "throttled 7 times") {
+		t.Errorf("unexpected message: %s, expected the throttled times 7", bin.ExitMessageForTesting)
+	}
+	if util.IsExist(getBackupFile(uid)) {
+		t.Errorf("expected the backup file %s removed", getBackupFile(uid))
+	}
+}
+
+func Test_startReclaimPressure_notLower(t *testing.T) {
+	root, clean := newFakeCgroupfs(t, false, map[string]string{
+		"unified/pod1/cgroup.procs":   "",
+		"unified/pod1/memory.current": "524288000\n",
+		"unified/pod1/memory.max":     "max\n",
+		"unified/pod1/memory.high":    "314572800\n",
+		"unified/pod1/memory.stat":    "anon 419430400\nfile 104857600\nactive_anon 419430400\ninactive_anon 0\n",
+	})
+	defer clean()
+	var exitCode int
+	bin.ExitFunc = func(code int) {
+		exitCode = code
+	}
+	uid := "reclaim-test-not-lower"
+	defer os.Remove(getBackupFile(uid))
+
+	// 80 percent of the usage and 400M are both above the current 300M
+	for _, high := range []int{0, 400} {
+		exitCode = 0
+		startReclaimPressure(uid, "/pod1", 0, high, 80)
+		if exitCode != 1 || !strings.Contains(bin.ExitMessageForTesting, "not lower") {
+			t.Errorf("unexpected result: %d, %s, expected the err of not lower", exitCode, bin.ExitMessageForTesting)
+		}
+	}
+	if bytes, _ := ioutil.ReadFile(path.Join(root, "unified/pod1/memory.high")); string(bytes) != "314572800\n" {
+		t.Errorf("unexpected memory.high: %s, expected unchanged", string(bytes))
+	}
+	if util.IsExist(getBackupFile(uid)) {
+		t.Errorf("unexpected backup file %s", getBackupFile(uid))
+	}
+}
+
+func Test_startReclaimPressure_v1(t *testing.T) {
+	_, clean := newFakeCgroupfs(t, true, map[string]string{
+		"v1/pod1/cgroup.procs":          "",
+		"v1/pod1/memory.usage_in_bytes": "524288000\n",
+		"v1/pod1/memory.limit_in_bytes": "1073741824\n",
+	})
+	defer clean()
+	var exitCode int
+	bin.ExitFunc = func(code int) {
+		exitCode = code
+	}
+	uid := "reclaim-test-v1"
+	defer os.Remove(getBackupFile(uid))
+
+	startReclaimPressure(uid, "/pod1", 0, 100, 0)
+	if exitCode != 1 || !strings.Contains(bin.ExitMessageForTesting, "cgroup v2") {
+		t.Errorf("unexpected result: %d, %s, expected the err of cgroup v2", exitCode, bin.ExitMessageForTesting)
+	}
+	if util.IsExist(getBackupFile(uid)) {
+		t.Errorf("unexpected backup file %s", getBackupFile(uid))
+	}
+}
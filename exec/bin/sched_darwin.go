@@ -0,0 +1,47 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+const (
+	SchedNormal = 0
+	SchedFifo   = 1
+	SchedRR     = 2
+)
+
+// SetScheduler only supports the normal policy on darwin
+func SetScheduler(policy, priority int) error {
+	if policy != SchedNormal {
+		return fmt.Errorf("the real-time scheduling policy is not supported on darwin")
+	}
+	return nil
+}
+
+// SetNice sets the nice value of the process, darwin has no nice value for threads
+func SetNice(nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, nice)
+}
+
+// Yield gives up the processor of the calling goroutine
+func Yield() {
+	runtime.Gosched()
+}
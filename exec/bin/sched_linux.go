@@ -0,0 +1,53 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bin
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// The scheduling policies in linux/sched.h
+const (
+	SchedNormal = 0
+	SchedFifo   = 1
+	SchedRR     = 2
+)
+
+// SetScheduler sets the scheduling policy and the real-time priority of the calling thread
+func SetScheduler(policy, priority int) error {
+	param := struct{ priority int32 }{int32(priority)}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETSCHEDULER, 0, uintptr(policy), uintptr(unsafe.Pointer(&param)))
+	if errno != 0 {
+		return fmt.Errorf("set the scheduling policy %d with the priority %d failed, %v", policy, priority, errno)
+	}
+	return nil
+}
+
+// SetNice sets the nice value of the calling thread
+func SetNice(nice int) error {
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, syscall.Gettid(), nice); err != nil {
+		return fmt.Errorf("set the nice value %d failed, %v", nice, err)
+	}
+	return nil
+}
+
+// Yield gives up the cpu of the calling thread to other runnable threads by sched_yield
+func Yield() {
+	syscall.RawSyscall(syscall.SYS_SCHED_YIELD, 0, 0, 0)
+}
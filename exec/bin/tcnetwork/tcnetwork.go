@@ -20,19 +20,25 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 	"github.com/chaosblade-io/chaosblade-spec-go/util"
 	"github.com/sirupsen/logrus"
 
+	"github.com/chaosblade-io/chaosblade-exec-os/exec"
 	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
 )
 
 var tcNetInterface, tcLocalPort, tcRemotePort, tcExcludePort string
 var tcDestinationIp, tcExcludeIp string
+var tcDestinationDomain, tcDomainIps string
+var tcResolveInterval int
+var tcNohup bool
 var netPercent, delayNetTime, delayNetOffset string
 var tcNetStart, tcNetStop, tcForce bool
 var tcIgnorePeerPorts bool
@@ -59,6 +65,10 @@ func main() {
 	flag.StringVar(&tcExcludePort, "exclude-port", "", "exclude ports, for example: 22,23")
 	flag.StringVar(&tcDestinationIp, "destination-ip", "", "destination ip")
 	flag.StringVar(&tcExcludeIp, "exclude-ip", "", "exclude ip")
+	flag.StringVar(&tcDestinationDomain, "destination-domain", "", "destination domain, for example: www.example.com")
+	flag.StringVar(&tcDomainIps, "domain-ips", "", "the ips of the destination domain which the filters are added for, used by the supervisor")
+	flag.IntVar(&tcResolveInterval, "resolve-interval", 30, "the interval of re-resolving the destination domain, unit is second")
+	flag.BoolVar(&tcNohup, "nohup", false, "nohup to supervise the destination domain")
 	flag.BoolVar(&tcNetStart, "start", false, "start delay")
 	flag.BoolVar(&tcNetStop, "stop", false, "stop delay")
 	flag.BoolVar(&tcIgnorePeerPorts, "ignore-peer-port", false, "ignore excluding all ports communicating with this port, generally used when the ss command does not exist")
@@ -96,9 +106,11 @@ func main() {
 		default:
 			bin.PrintErrAndExit("unsupported type for network experiments")
 		}
-		startNet(tcNetInterface, classRule, tcLocalPort, tcRemotePort, tcExcludePort, tcDestinationIp, tcExcludeIp, tcForce)
+		startNet(tcNetInterface, classRule, tcLocalPort, tcRemotePort, tcExcludePort, tcDestinationIp, tcDestinationDomain, tcExcludeIp, tcForce)
 	} else if tcNetStop {
 		stopNet(tcNetInterface)
+	} else if tcNohup {
+		superviseDomainFilters(tcNetInterface, tcLocalPort, tcRemotePort, tcDestinationDomain, tcDomainIps, tcResolveInterval)
 	} else {
 		bin.PrintErrAndExit("less --start or --stop flag")
 	}
@@ -106,7 +118,7 @@ func main() {
 
 var cl = channel.NewLocalChannel()
 
-func startNet(netInterface, classRule, localPort, remotePort, excludePort, destIp, excludeIp string, force bool) {
+func startNet(netInterface, classRule, localPort, remotePort, excludePort, destIp, destDomain, excludeIp string, force bool) {
 	// check device txqueuelen size, if the size is zero, then set the value to 1000
 	response := preHandleTxqueue(netInterface)
 	if !response.Success {
@@ -121,12 +133,20 @@ func startNet(netInterface, classRule, localPort, remotePort, excludePort, destI
 			excludeIp = channelIps
 		}
 	}
+	var domainIps []string
+	if destDomain != "" {
+		domainIps, err = bin.ResolveDomains(destDomain)
+		if err != nil {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+	}
 	if force {
 		stopNet(netInterface)
 	}
 	ctx := context.Background()
 	// Only interface flag
-	if localPort == "" && remotePort == "" && excludePort == "" && destIp == "" && excludeIp == "" {
+	if localPort == "" && remotePort == "" && excludePort == "" && destIp == "" && destDomain == "" && excludeIp == "" {
 		response := cl.Run(ctx, "tc", fmt.Sprintf(`qdisc add dev %s root %s`, netInterface, classRule))
 		if !response.Success {
 			bin.PrintErrAndExit(response.Err)
@@ -146,7 +166,7 @@ func startNet(netInterface, classRule, localPort, remotePort, excludePort, destI
 	}
 
 	// only contains excludePort or excludeIP
-	if localPort == "" && remotePort == "" && destIp == "" {
+	if localPort == "" && remotePort == "" && destIp == "" && destDomain == "" {
 		// Add class rule to 1,2,3 band, exclude port and exclude ip are added to 4 band
 		args := buildNetemToDefaultBandsArgs(netInterface, classRule)
 		excludeFilters := buildExcludeFilterToNewBand(netInterface, excludePorts, excludeIp)
@@ -160,12 +180,162 @@ func startNet(netInterface, classRule, localPort, remotePort, excludePort, destI
 	}
 	destIpRules := getIpRules(destIp)
 	excludeIpRules := getIpRules(excludeIp)
-	// local port or remote port
-	response = executeTargetPortAndIpWithExclude(ctx, cl, netInterface, classRule, localPort, remotePort, destIpRules,
-		excludePorts, excludeIpRules)
+	if destDomain != "" && destIp == "" {
+		// the ports only take effect on the domain ips, which are added by the domain filters
+		response = executeTargetPortAndIpWithExclude(ctx, cl, netInterface, classRule, "", "", destIpRules,
+			excludePorts, excludeIpRules)
+	} else {
+		// local port or remote port
+		response = executeTargetPortAndIpWithExclude(ctx, cl, netInterface, classRule, localPort, remotePort, destIpRules,
+			excludePorts, excludeIpRules)
+	}
+	if destDomain != "" {
+		startDomainFilters(ctx, netInterface, localPort, remotePort, destDomain, domainIps)
+	}
 	bin.PrintOutputAndExit(response.Result.(string))
 }
 
+// domainFilterPrio is the first prio of the domain filters. The filters of each domain ip use a unique prio,
+// so that they can be deleted by the prio when the ip is removed from the domain.
+const domainFilterPrio = 5
+
+// startDomainFilters adds the filters for the resolved domain ips, and starts the supervisor to follow the changes
+func startDomainFilters(ctx context.Context, netInterface, localPort, remotePort, destDomain string, domainIps []string) {
+	for i, ip := range domainIps {
+		response := cl.Run(ctx, "tc", buildDomainFilterArgs(netInterface, localPort, remotePort, ip, domainFilterPrio+i))
+		if !response.Success {
+			stopDLNetFunc(netInterface)
+			bin.PrintErrAndExit(response.Err)
+			return
+		}
+	}
+	args := fmt.Sprintf("%s --interface %s --nohup=true --destination-domain %s --domain-ips %s --resolve-interval %d --debug=%t",
+		path.Join(util.GetProgramPath(), exec.TcNetworkBin), netInterface, destDomain, strings.Join(domainIps, delimiter),
+		tcResolveInterval, util.Debug)
+	if localPort != "" {
+		args = fmt.Sprintf("%s --local-port %s", args, localPort)
+	}
+	if remotePort != "" {
+		args = fmt.Sprintf("%s --remote-port %s", args, remotePort)
+	}
+	response := cl.Run(ctx, "nohup", fmt.Sprintf("%s > %s 2>&1 &", args, domainLogFile))
+	if !response.Success {
+		stopDLNetFunc(netInterface)
+		bin.PrintErrAndExit(response.Err)
+	}
+}
+
+var domainLogFile = util.GetNohupOutput(util.Bin, "chaos_tcnetwork_domain.log")
+
+// buildDomainFilterArgs returns the tc args to add the filters of the ip with the prio
+func buildDomainFilterArgs(netInterface, localPort, remotePort, ip string, prio int) string {
+	protocol, ipMatch, portMatch := "ip", fmt.Sprintf("match ip dst %s", ip), "match ip"
+	if strings.Contains(ip, ":") {
+		protocol, ipMatch, portMatch = "ipv6", fmt.Sprintf("match ip6 dst %s/128", ip), "match ip6"
+	}
+	matches := make([]string, 0)
+	if localPort != "" {
+		for _, port := range strings.Split(localPort, delimiter) {
+			matches = append(matches, fmt.Sprintf("%s %s sport %s 0xffff", ipMatch, portMatch, port))
+		}
+	}
+	if remotePort != "" {
+		for _, port := range strings.Split(remotePort, delimiter) {
+			matches = append(matches, fmt.Sprintf("%s %s dport %s 0xffff", ipMatch, portMatch, port))
+		}
+	}
+	if len(matches) == 0 {
+		matches = append(matches, ipMatch)
+	}
+	filters := make([]string, 0, len(matches))
+	for _, match := range matches {
+		filters = append(filters, fmt.Sprintf("filter add dev %s parent 1: prio %d protocol %s u32 %s flowid 1:4",
+			netInterface, prio, protocol, match))
+	}
+	return strings.Join(filters, " && tc ")
+}
+
+// superviseDomainFilters re-resolves the domain, and adds or removes the filters when the ips change
+func superviseDomainFilters(netInterface, localPort, remotePort, destDomain, domainIps string, interval int) {
+	if destDomain == "" || interval <= 0 {
+		bin.PrintAndExitWithErrPrefix("less --destination-domain or illegal --resolve-interval flag")
+		return
+	}
+	ctx := context.Background()
+	ips := make([]string, 0)
+	prios := make(map[string]int)
+	for _, ip := range strings.Split(domainIps, delimiter) {
+		if ip == "" {
+			continue
+		}
+		prios[ip] = domainFilterPrio + len(ips)
+		ips = append(ips, ip)
+	}
+	bin.SuperviseDomains(ctx, destDomain, time.Duration(interval)*time.Second, ips, func(added, removed []string) error {
+		return updateDomainFilters(ctx, netInterface, localPort, remotePort, prios, added, removed)
+	})
+}
+
+// updateDomainFilters deletes the filters of the removed ips, and adds the filters for the added ips by the unused prios.
+// If adding fails, the filters added by the call are deleted, so the added ips are added again on the next update.
+func updateDomainFilters(ctx context.Context, netInterface, localPort, remotePort string, prios map[string]int,
+	added, removed []string) error {
+	for _, ip := range removed {
+		prio, ok := prios[ip]
+		if !ok {
+			continue
+		}
+		deleteDomainFilters(ctx, netInterface, ip, prio)
+		delete(prios, ip)
+	}
+	for i, ip := range added {
+		prio := nextDomainFilterPrio(prios)
+		response := cl.Run(ctx, "tc", buildDomainFilterArgs(netInterface, localPort, remotePort, ip, prio))
+		if !response.Success {
+			// the filters of the ip are added one by one, some of them may be added
+			deleteDomainFilters(ctx, netInterface, ip, prio)
+			for _, addedIp := range added[:i] {
+				deleteDomainFilters(ctx, netInterface, addedIp, prios[addedIp])
+				delete(prios, addedIp)
+			}
+			return fmt.Errorf("add the filters of %s failed, %s", ip, response.Err)
+		}
+		prios[ip] = prio
+	}
+	return nil
+}
+
+func deleteDomainFilters(ctx context.Context, netInterface, ip string, prio int) {
+	response := cl.Run(ctx, "tc", fmt.Sprintf("filter del dev %s parent 1: prio %d", netInterface, prio))
+	if !response.Success {
+		logrus.Warnf("delete the filters of %s failed, %s", ip, response.Err)
+	}
+}
+
+func nextDomainFilterPrio(prios map[string]int) int {
+	used := make(map[int]bool, len(prios))
+	for _, prio := range prios {
+		used[prio] = true
+	}
+	prio := domainFilterPrio
+	for used[prio] {
+		prio++
+	}
+	return prio
+}
+
+// stopDomainSupervisor kills the supervisor of the interface
+func stopDomainSupervisor(netInterface string) {
+	ctx := context.WithValue(context.Background(), channel.ProcessKey, exec.TcNetworkBin)
+	pids, err := cl.GetPidsByProcessName(fmt.Sprintf("interface %s --nohup", netInterface), ctx)
+	if err != nil {
+		logrus.Warnf("get the supervisor of %s failed, %v", netInterface, err)
+	}
+	if len(pids) > 0 {
+		cl.Run(ctx, "kill", fmt.Sprintf("-9 %s", strings.Join(pids, " ")))
+	}
+}
+
 func getExcludePorts(excludePort string) ([]string, error) {
 	ports := strings.Split(excludePort, delimiter)
 
@@ -379,6 +549,7 @@ func addQdiscForDL(channel spec.Channel, ctx context.Context, netInterface strin
 // stopNet, no need to add os.Exit
 func stopNet(netInterface string) {
 	ctx := context.Background()
+	stopDomainSupervisor(netInterface)
 	cl.Run(ctx, "tc", fmt.Sprintf(`filter del dev %s parent 1: prio 4`, netInterface))
 	cl.Run(ctx, "tc", fmt.Sprintf(`qdisc del dev %s root`, netInterface))
 }
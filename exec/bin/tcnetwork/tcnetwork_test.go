@@ -0,0 +1,106 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+)
+
+func Test_buildDomainFilterArgs(t *testing.T) {
+	tests := []struct {
+		localPort  string
+		remotePort string
+		ip         string
+		expected   string
+	}{
+		{"", "", "10.0.0.2", "filter add dev eth0 parent 1: prio 5 protocol ip u32 match ip dst 10.0.0.2 flowid 1:4"},
+		{"8080", "443", "10.0.0.2", "filter add dev eth0 parent 1: prio 5 protocol ip u32 match ip dst 10.0.0.2 match ip sport 8080 0xffff flowid 1:4 && " +
+			"tc filter add dev eth0 parent 1: prio 5 protocol ip u32 match ip dst 10.0.0.2 match ip dport 443 0xffff flowid 1:4"},
+		{"", "443", "2001:db8::1", "filter add dev eth0 parent 1: prio 5 protocol ipv6 u32 match ip6 dst 2001:db8::1/128 match ip6 dport 443 0xffff flowid 1:4"},
+	}
+	for _, tt := range tests {
+		if actual := buildDomainFilterArgs("eth0", tt.localPort, tt.remotePort, tt.ip, 5); actual != tt.expected {
+			t.Errorf("unexpected args: %s, expected: %s", actual, tt.expected)
+		}
+	}
+}
+
+func Test_updateDomainFilters(t *testing.T) {
+	cl = channel.NewMockLocalChannel()
+	mockChannel := cl.(*channel.MockLocalChannel)
+	actualCommands := make([]string, 0)
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		actualCommands = append(actualCommands, fmt.Sprintf("%s %s", script, args))
+		return spec.ReturnSuccess("")
+	}
+	prios := map[string]int{"10.0.0.1": 5, "10.0.0.2": 6}
+	err := updateDomainFilters(context.Background(), "eth0", "", "", prios, []string{"10.0.0.3", "10.0.0.4"}, []string{"10.0.0.1"})
+	if err != nil {
+		t.Fatalf("update filters err, %v", err)
+	}
+	expectedCommands := []string{
+		"tc filter del dev eth0 parent 1: prio 5",
+		"tc filter add dev eth0 parent 1: prio 5 protocol ip u32 match ip dst 10.0.0.3 flowid 1:4",
+		"tc filter add dev eth0 parent 1: prio 7 protocol ip u32 match ip dst 10.0.0.4 flowid 1:4",
+	}
+	if !reflect.DeepEqual(actualCommands, expectedCommands) {
+		t.Errorf("unexpected commands: %+v, expected commands: %+v", actualCommands, expectedCommands)
+	}
+	expectedPrios := map[string]int{"10.0.0.2": 6, "10.0.0.3": 5, "10.0.0.4": 7}
+	if !reflect.DeepEqual(prios, expectedPrios) {
+		t.Errorf("unexpected prios: %v, expected prios: %v", prios, expectedPrios)
+	}
+}
+
+func Test_updateDomainFilters_addFailed(t *testing.T) {
+	cl = channel.NewMockLocalChannel()
+	mockChannel := cl.(*channel.MockLocalChannel)
+	actualCommands := make([]string, 0)
+	mockChannel.RunFunc = func(ctx context.Context, script, args string) *spec.Response {
+		actualCommands = append(actualCommands, fmt.Sprintf("%s %s", script, args))
+		if strings.Contains(args, "10.0.0.4") {
+			return spec.ReturnFail(spec.Code[spec.ExecCommandError], "RTNETLINK answers: No buffer space available")
+		}
+		return spec.ReturnSuccess("")
+	}
+	prios := map[string]int{"10.0.0.1": 5}
+	err := updateDomainFilters(context.Background(), "eth0", "", "", prios, []string{"10.0.0.3", "10.0.0.4"}, nil)
+	if err == nil {
+		t.Fatalf("expected err for the failed add")
+	}
+	// the filters added by the update are deleted, so the ips are added again by the next update
+	expectedCommands := []string{
+		"tc filter add dev eth0 parent 1: prio 6 protocol ip u32 match ip dst 10.0.0.3 flowid 1:4",
+		"tc filter add dev eth0 parent 1: prio 7 protocol ip u32 match ip dst 10.0.0.4 flowid 1:4",
+		"tc filter del dev eth0 parent 1: prio 7",
+		"tc filter del dev eth0 parent 1: prio 6",
+	}
+	if !reflect.DeepEqual(actualCommands, expectedCommands) {
+		t.Errorf("unexpected commands: %+v, expected commands: %+v", actualCommands, expectedCommands)
+	}
+	expectedPrios := map[string]int{"10.0.0.1": 5}
+	if !reflect.DeepEqual(prios, expectedPrios) {
+		t.Errorf("unexpected prios: %v, expected prios: %v", prios, expectedPrios)
+	}
+}
@@ -0,0 +1,314 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/sirupsen/logrus"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var throttleUid, throttlePid, throttleProcess, throttleProcessCmd string
+var throttleQuotaPercent int
+var throttleIsolate, throttleStart, throttleStop bool
+
+func main() {
+	flag.StringVar(&throttleUid, "uid", "", "the uid of the experiment")
+	flag.StringVar(&throttlePid, "pid", "", "the process ids, separated by commas")
+	flag.StringVar(&throttleProcess, "process", "", "process name")
+	flag.StringVar(&throttleProcessCmd, "process-cmd", "", "process in command")
+	flag.IntVar(&throttleQuotaPercent, "quota-percent", 0, "the cpu quota, percent of one cpu core")
+	flag.BoolVar(&throttleIsolate, "isolate", false, "move the processes into a temporary cgroup")
+	flag.BoolVar(&throttleStart, "start", false, "start throttle cpu")
+	flag.BoolVar(&throttleStop, "stop", false, "recover the cpu quota")
+	bin.ParseFlagAndInitLog()
+
+	if throttleUid == "" {
+		bin.PrintErrAndExit("less --uid flag")
+		return
+	}
+	if throttleStart {
+		startThrottle(throttleUid, throttlePid, throttleProcess, throttleProcessCmd, throttleQuotaPercent, throttleIsolate)
+	} else if throttleStop {
+		stopThrottle(throttleUid)
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+var cl = channel.NewLocalChannel()
+
+// defaultPeriod is the default cfs period in microseconds
+const defaultPeriod = 100000
+
+// throttledCgroup is the original state of a throttled cgroup, which is recorded in the backup file
+type throttledCgroup struct {
+	Version int               `json:"version"`
+	Dirs    map[string]string `json:"dirs"`
+	// Quota and Period are the original values of the cgroup whose quota is lowered in place
+	Quota  int64  `json:"quota,omitempty"`
+	Period uint64 `json:"period,omitempty"`
+	// Pids are the processes moved into the temporary cgroup Dirs from their original cgroups
+	Pids     []int               `json:"pids,omitempty"`
+	Original []map[string]string `json:"original,omitempty"`
+}
+
+func (t *throttledCgroup) cgroup() *bin.Cgroup {
+	return &bin.Cgroup{Version: t.Version, Dirs: t.Dirs}
+}
+
+func (t *throttledCgroup) isolated() bool {
+	return len(t.Pids) > 0
+}
+
+func getBackupFile(uid string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_throttlecpu_%s.bak", uid))
+}
+
+// getPids returns the process ids by the pid list, or matches the processes like process kill
+func getPids(pid, process, processCmd string) ([]int, error) {
+	var pids []string
+	var err error
+	ctx := context.WithValue(context.Background(), channel.ExcludeProcessKey, "blade")
+	if pid != "" {
+		pids = strings.Split(pid, ",")
+	} else if process != "" {
+		pids, err = cl.GetPidsByProcessName(process, ctx)
+	} else if processCmd != "" {
+		pids, err = cl.GetPidsByProcessCmdName(processCmd, ctx)
+	} else {
+		return nil, fmt.Errorf("less --pid, --process or --process-cmd flag")
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := make([]int, 0)
+	for _, p := range util.RemoveDuplicates(pids) {
+		id, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("illegal pid %s", p)
+		}
+		result = append(result, id)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("process not found")
+	}
+	return result, nil
+}
+
+func startThrottle(uid, pid, process, processCmd string, quotaPercent int, isolate bool) {
+	backupFile := getBackupFile(uid)
+	if util.IsExist(backupFile) {
+		bin.PrintErrAndExit(fmt.Sprintf("the throttle experiment %s is running, the backup file %s exists", uid, backupFile))
+		return
+	}
+	if quotaPercent <= 0 {
+		bin.PrintErrAndExit("illegal --quota-percent flag, it must be a positive integer")
+		return
+	}
+	pids, err := getPids(pid, process, processCmd)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	throttled, err := throttle(uid, pids, int64(quotaPercent)*defaultPeriod/100, isolate)
+	if err == nil {
+		var bytes []byte
+		if bytes, err = json.Marshal(throttled); err == nil {
+			err = ioutil.WriteFile(backupFile, bytes, 0644)
+		}
+	}
+	if err != nil {
+		restore(throttled)
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	dirs := make([]string, 0)
+	for _, t := range throttled {
+		dirs = append(dirs, t.Dirs["cpu"])
+	}
+	bin.PrintOutputAndExit(fmt.Sprintf("throttle %v to %.2f cores in %s", pids, float64(quotaPercent)/100, strings.Join(dirs, ",")))
+}
+
+// throttle lowers the quota of the cgroups which the processes run in, the processes in the root cgroup or with the
+// isolate flag are moved into a temporary cgroup, which is not supported on cgroup v2. The quota is only lowered, an error is
+// returned if it is not below the current one. The throttled cgroups are returned even if failed, so they can be restored.
+func throttle(uid string, pids []int, quota int64, isolate bool) ([]*throttledCgroup, error) {
+	throttled := make([]*throttledCgroup, 0)
+	var temporary *throttledCgroup
+	for _, pid := range pids {
+		cgroup, err := bin.GetCgroupByPid(pid, "cpu")
+		if err != nil {
+			return throttled, err
+		}
+		if !isolate && !cgroup.IsRoot() {
+			if containsCgroup(throttled, cgroup) {
+				continue
+			}
+			if err := checkLowered(cgroup, quota); err != nil {
+				return throttled, err
+			}
+			original, period, err := cgroup.CpuQuota()
+			if err != nil {
+				return throttled, err
+			}
+			throttled = append(throttled, &throttledCgroup{Version: cgroup.Version, Dirs: cgroup.Dirs, Quota: original, Period: period})
+			if err := cgroup.SetCpuQuota(quota*int64(period)/defaultPeriod, period); err != nil {
+				return throttled, err
+			}
+			logrus.Infof("throttle the cgroup %v of %d, the original quota is %d/%d", cgroup.Dirs, pid, original, period)
+			continue
+		}
+		if !cgroup.IsRoot() {
+			if cgroup.Version == bin.CgroupV2 {
+				// the v2 controllers share one hierarchy, so the process moved out loses the memory and pids limits too
+				return throttled, fmt.Errorf("the isolate flag is not supported on cgroup v2, moving %d out of the cgroup %s drops its other limits",
+					pid, cgroup.Dirs["cpu"])
+			}
+			if err := checkLowered(cgroup, quota); err != nil {
+				return throttled, err
+			}
+		}
+		if temporary == nil {
+			root, err := cgroup.Root()
+			if err != nil {
+				return throttled, err
+			}
+			child, err := root.NewChild(fmt.Sprintf("chaosblade-throttle-%s", uid))
+			if err != nil {
+				return throttled, err
+			}
+			temporary = &throttledCgroup{Version: child.Version, Dirs: child.Dirs}
+			throttled = append(throttled, temporary)
+			if err := child.SetCpuQuota(quota, defaultPeriod); err != nil {
+				return throttled, err
+			}
+		}
+		if err := temporary.cgroup().AddProcess(pid); err != nil {
+			return throttled, err
+		}
+		temporary.Pids = append(temporary.Pids, pid)
+		temporary.Original = append(temporary.Original, cgroup.Dirs)
+		logrus.Infof("move %d from the cgroup %v to %v", pid, cgroup.Dirs, temporary.Dirs)
+	}
+	return throttled, nil
+}
+
+// checkLowered returns an error if the quota is not below the current quota of the cgroup, the experiment only lowers it
+func checkLowered(cgroup *bin.Cgroup, quota int64) error {
+	original, period, err := cgroup.CpuQuota()
+	if err != nil {
+		return err
+	}
+	if original > 0 && period > 0 && quota*int64(period)/defaultPeriod >= original {
+		return fmt.Errorf("the quota %.2f cores is not below the current quota %.2f cores of the cgroup %s",
+			float64(quota)/defaultPeriod, float64(original)/float64(period), cgroup.Dirs["cpu"])
+	}
+	return nil
+}
+
+func containsCgroup(throttled []*throttledCgroup, cgroup *bin.Cgroup) bool {
+	for _, t := range throttled {
+		if !t.isolated() && t.Dirs["cpu"] == cgroup.Dirs["cpu"] {
+			return true
+		}
+	}
+	return false
+}
+
+func stopThrottle(uid string) {
+	backupFile := getBackupFile(uid)
+	bytes, err := ioutil.ReadFile(backupFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			bin.PrintOutputAndExit("nothing to do")
+			return
+		}
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	var throttled []*throttledCgroup
+	if err := json.Unmarshal(bytes, &throttled); err != nil {
+		bin.PrintErrAndExit(fmt.Sprintf("illegal backup file %s, %v", backupFile, err))
+		return
+	}
+	if err := restore(throttled); err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	os.Remove(backupFile)
+	bin.PrintOutputAndExit("success")
+}
+
+// cgroupExists returns false if a directory of the cgroup is removed, for example with its pod
+func cgroupExists(dirs map[string]string) bool {
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			return false
+		}
+	}
+	return true
+}
+
+// restore sets the original quota, moves the processes back to their original cgroups and removes the temporary
+// cgroup, the processes exited already and the cgroups removed are ignored. It returns the last error.
+func restore(throttled []*throttledCgroup) error {
+	var lastErr error
+	for _, t := range throttled {
+		if !t.isolated() && t.Period > 0 {
+			if !cgroupExists(t.Dirs) {
+				logrus.Warnf("the cgroup %v not exists, skip restoring the quota", t.Dirs)
+				continue
+			}
+			if err := t.cgroup().SetCpuQuota(t.Quota, t.Period); err != nil {
+				logrus.Warnf("restore the quota of %v failed, %v", t.Dirs, err)
+				lastErr = err
+			}
+			continue
+		}
+		for i, pid := range t.Pids {
+			if _, err := os.Stat(fmt.Sprintf("%s/%d", bin.ProcPath, pid)); err != nil {
+				continue
+			}
+			if !cgroupExists(t.Original[i]) {
+				logrus.Warnf("the original cgroup %v of %d not exists, skip moving it back", t.Original[i], pid)
+				continue
+			}
+			original := &bin.Cgroup{Version: t.Version, Dirs: t.Original[i]}
+			if err := original.AddProcess(pid); err != nil {
+				logrus.Warnf("move %d back to %v failed, %v", pid, t.Original[i], err)
+				lastErr = err
+			}
+		}
+		if err := t.cgroup().Remove(); err != nil {
+			logrus.Warnf("remove the temporary cgroup failed, %v", err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
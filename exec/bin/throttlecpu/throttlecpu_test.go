@@ -0,0 +1,200 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+// startSleep starts a process to throttle, it must be killed by the returned function
+func startSleep(t *testing.T) (int, func()) {
+	cmd := exec.Command("sleep", "300")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start sleep err, %v", err)
+	}
+	return cmd.Process.Pid, func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+}
+
+// getCpuCgroup returns the cpu cgroup of the process, the test is skipped if the cgroup cannot be modified
+func getCpuCgroup(t *testing.T, pid int) *bin.Cgroup {
+	if os.Geteuid() != 0 {
+		t.Skip("root is required to modify the cgroups")
+	}
+	cgroup, err := bin.GetCgroupByPid(pid, "cpu")
+	if err != nil {
+		t.Skipf("cpu cgroup not found, %v", err)
+	}
+	if _, _, err := cgroup.CpuQuota(); err != nil {
+		t.Skipf("cpu quota is not supported, %v", err)
+	}
+	return cgroup
+}
+
+func Test_throttle_inPlace(t *testing.T) {
+	pid, kill := startSleep(t)
+	defer kill()
+	root, err := getCpuCgroup(t, pid).Root()
+	if err != nil {
+		t.Fatalf("get root cgroup err, %v", err)
+	}
+	cgroup, err := root.NewChild("chaos-throttle-test")
+	if err != nil {
+		t.Skipf("create cgroup err, %v", err)
+	}
+	defer cgroup.Remove()
+	if err := cgroup.AddProcess(pid); err != nil {
+		t.Fatalf("add process err, %v", err)
+	}
+	defer root.AddProcess(pid)
+	quota, period, _ := cgroup.CpuQuota()
+
+	throttled, err := throttle("test", []int{pid, pid}, 50000, false)
+	if err != nil {
+		t.Fatalf("throttle err, %v", err)
+	}
+	if len(throttled) != 1 || throttled[0].isolated() || throttled[0].Quota != quota {
+		t.Fatalf("unexpected throttled cgroups: %+v", throttled)
+	}
+	if q, p, _ := cgroup.CpuQuota(); q != 50000*int64(p)/defaultPeriod {
+		t.Errorf("unexpected quota: %d/%d, expected: 50%%", q, p)
+	}
+	if err := restore(throttled); err != nil {
+		t.Fatalf("restore err, %v", err)
+	}
+	if q, p, _ := cgroup.CpuQuota(); q != quota || p != period {
+		t.Errorf("unexpected quota: %d/%d, expected: %d/%d", q, p, quota, period)
+	}
+
+	// the cgroup limited to half a core already is not raised
+	if err := cgroup.SetCpuQuota(50000, defaultPeriod); err != nil {
+		t.Fatalf("set quota err, %v", err)
+	}
+	defer cgroup.SetCpuQuota(quota, period)
+	throttled, err = throttle("test", []int{pid}, 200000, false)
+	if err == nil || len(throttled) != 0 {
+		restore(throttled)
+		t.Errorf("expected the err of raising the quota, got %+v", throttled)
+	}
+	if q, _, _ := cgroup.CpuQuota(); q != 50000 {
+		t.Errorf("unexpected quota: %d, expected: 50000", q)
+	}
+}
+
+func Test_throttle_isolate(t *testing.T) {
+	pid, kill := startSleep(t)
+	defer kill()
+	original := getCpuCgroup(t, pid)
+
+	throttled, err := throttle("test", []int{pid}, 150000, true)
+	if err != nil {
+		restore(throttled)
+		t.Fatalf("throttle err, %v", err)
+	}
+	if len(throttled) != 1 || !throttled[0].isolated() {
+		restore(throttled)
+		t.Fatalf("unexpected throttled cgroups: %+v", throttled)
+	}
+	current, _ := bin.GetCgroupByPid(pid, "cpu")
+	if current.Dirs["cpu"] != throttled[0].Dirs["cpu"] || path.Base(current.Dirs["cpu"]) != "chaosblade-throttle-test" {
+		t.Errorf("unexpected cgroup: %v, expected: %v", current.Dirs, throttled[0].Dirs)
+	}
+	if cores := current.CpuQuotaCores(64); cores != 1.5 {
+		t.Errorf("unexpected quota cores: %f, expected: 1.5", cores)
+	}
+	if err := restore(throttled); err != nil {
+		t.Fatalf("restore err, %v", err)
+	}
+	current, _ = bin.GetCgroupByPid(pid, "cpu")
+	if current.Dirs["cpu"] != original.Dirs["cpu"] {
+		t.Errorf("unexpected cgroup after restore: %v, expected: %v", current.Dirs, original.Dirs)
+	}
+	if _, err := os.Stat(throttled[0].Dirs["cpu"]); !os.IsNotExist(err) {
+		t.Errorf("the temporary cgroup is not removed, %v", err)
+	}
+}
+
+func Test_restore_cgroupRemoved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaos-throttlecpu")
+	if err != nil {
+		t.Fatalf("create temp dir err, %v", err)
+	}
+	os.RemoveAll(dir)
+	// the cgroups are removed with the pod, there is nothing to restore
+	throttled := []*throttledCgroup{
+		{Version: bin.CgroupV2, Dirs: map[string]string{"cpu": path.Join(dir, "pod1")}, Quota: -1, Period: 100000},
+		{Version: bin.CgroupV2, Dirs: map[string]string{"cpu": path.Join(dir, "chaosblade-throttle-test")},
+			Pids: []int{os.Getpid()}, Original: []map[string]string{{"cpu": path.Join(dir, "pod2")}}},
+	}
+	if err := restore(throttled); err != nil {
+		t.Errorf("unexpected restore err, %v", err)
+	}
+}
+
+func Test_startAndStopThrottle(t *testing.T) {
+	pid, kill := startSleep(t)
+	defer kill()
+	getCpuCgroup(t, pid)
+	bin.ExitFunc = func(code int) {
+		if code != 0 {
+			t.Errorf("unexpected exit code: %d, %s", code, bin.ExitMessageForTesting)
+		}
+	}
+
+	uid := "start-stop-test"
+	startThrottle(uid, fmt.Sprintf("%d", pid), "", "", 50, false)
+	bytes, err := ioutil.ReadFile(getBackupFile(uid))
+	if err != nil {
+		t.Fatalf("read backup file err, %v", err)
+	}
+	var throttled []*throttledCgroup
+	if err := json.Unmarshal(bytes, &throttled); err != nil || len(throttled) != 1 {
+		t.Fatalf("unexpected backup: %s, %v", bytes, err)
+	}
+	stopThrottle(uid)
+	if _, err := os.Stat(getBackupFile(uid)); !os.IsNotExist(err) {
+		t.Errorf("the backup file is not removed, %v", err)
+	}
+	// stop again does nothing
+	stopThrottle(uid)
+	if bin.ExitMessageForTesting != "nothing to do" {
+		t.Errorf("unexpected message: %s", bin.ExitMessageForTesting)
+	}
+}
+
+func Test_getPids(t *testing.T) {
+	pids, err := getPids("1,1,2", "", "")
+	if err != nil || len(pids) != 2 || pids[0] != 1 || pids[1] != 2 {
+		t.Errorf("unexpected pids: %v, %v", pids, err)
+	}
+	if _, err := getPids("a", "", ""); err == nil {
+		t.Errorf("expected err for the illegal pid")
+	}
+	if _, err := getPids("", "", ""); err == nil {
+		t.Errorf("expected err without the process matcher")
+	}
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+	"github.com/sirupsen/logrus"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+)
+
+var throttleUid, throttlePath, throttleCgroupPath string
+var throttleTargetPid, throttleReadBps, throttleWriteBps, throttleReadIops, throttleWriteIops int
+var throttleStart, throttleStop bool
+
+func main() {
+	flag.StringVar(&throttleUid, "uid", "", "the uid of the experiment")
+	flag.StringVar(&throttlePath, "path", "/", "the path on the disk to throttle")
+	flag.StringVar(&throttleCgroupPath, "cgroup-path", "", "the cgroup to throttle")
+	flag.IntVar(&throttleTargetPid, "target-pid", 0, "throttle the cgroup of the process")
+	flag.IntVar(&throttleReadBps, "read-bps", 0, "the read bytes per second limit, unit is MB")
+	flag.IntVar(&throttleWriteBps, "write-bps", 0, "the write bytes per second limit, unit is MB")
+	flag.IntVar(&throttleReadIops, "read-iops", 0, "the read io operations per second limit")
+	flag.IntVar(&throttleWriteIops, "write-iops", 0, "the write io operations per second limit")
+	flag.BoolVar(&throttleStart, "start", false, "throttle the disk io")
+	flag.BoolVar(&throttleStop, "stop", false, "restore the io limits")
+	bin.ParseFlagAndInitLog()
+
+	if throttleUid == "" {
+		bin.PrintErrAndExit("less --uid flag")
+		return
+	}
+	if throttleStart {
+		limit := &bin.IoLimit{
+			ReadBps:   uint64(throttleReadBps) * mb,
+			WriteBps:  uint64(throttleWriteBps) * mb,
+			ReadIops:  uint64(throttleReadIops),
+			WriteIops: uint64(throttleWriteIops),
+		}
+		startThrottle(throttleUid, throttlePath, throttleCgroupPath, throttleTargetPid, limit)
+	} else if throttleStop {
+		stopThrottle(throttleUid)
+	} else {
+		bin.PrintErrAndExit("less --start or --stop flag")
+	}
+}
+
+const mb = 1024 * 1024
+
+// throttleState is the original io limits of the device in the cgroup, which is recorded in the backup file
+type throttleState struct {
+	Version  int               `json:"version"`
+	Dirs     map[string]string `json:"dirs"`
+	Device   string            `json:"device"`
+	Original *bin.IoLimit      `json:"original"`
+}
+
+func (t *throttleState) cgroup() *bin.Cgroup {
+	return &bin.Cgroup{Version: t.Version, Dirs: t.Dirs}
+}
+
+// removed returns true if a directory of the cgroup not exists
+func (t *throttleState) removed() bool {
+	for _, dir := range t.Dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+func getBackupFile(uid string) string {
+	return util.GetNohupOutput(util.Bin, fmt.Sprintf("chaos_throttledisk_%s.bak", uid))
+}
+
+// mergeLimit returns the limit to set, the limits not specified keep the original values, and the specified ones
+// must be below the original non-zero limits, otherwise the device is not throttled by them
+func mergeLimit(original, limit *bin.IoLimit) (*bin.IoLimit, error) {
+	merged := *limit
+	for _, l := range []struct {
+		name          string
+		value         *uint64
+		originalValue uint64
+	}{
+		{"read bps", &merged.ReadBps, original.ReadBps},
+		{"write bps", &merged.WriteBps, original.WriteBps},
+		{"read iops", &merged.ReadIops, original.ReadIops},
+		{"write iops", &merged.WriteIops, original.WriteIops},
+	} {
+		if *l.value == 0 {
+			*l.value = l.originalValue
+			continue
+		}
+		if l.originalValue > 0 && *l.value >= l.originalValue {
+			return nil, fmt.Errorf("the %s %d is not below the current limit %d", l.name, *l.value, l.originalValue)
+		}
+	}
+	return &merged, nil
+}
+
+func formatLimit(limit *bin.IoLimit) string {
+	format := func(value uint64) string {
+		if value == 0 {
+			return "max"
+		}
+		return fmt.Sprintf("%d", value)
+	}
+	return fmt.Sprintf("rbps=%s wbps=%s riops=%s wiops=%s",
+		format(limit.ReadBps), format(limit.WriteBps), format(limit.ReadIops), format(limit.WriteIops))
+}
+
+func startThrottle(uid, directory, cgroupPath string, targetPid int, limit *bin.IoLimit) {
+	backupFile := getBackupFile(uid)
+	if util.IsExist(backupFile) {
+		bin.PrintErrAndExit(fmt.Sprintf("the disk throttle experiment %s is running, the backup file %s exists", uid, backupFile))
+		return
+	}
+	device, err := bin.GetBlockDevice(directory)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	cgroup, err := bin.GetIoCgroup(cgroupPath, targetPid)
+	if err != nil {
+		bin.PrintErrAndExit(fmt.Sprintf("get the io cgroup failed, %v", err))
+		return
+	}
+	state := &throttleState{Version: cgroup.Version, Dirs: cgroup.Dirs, Device: device}
+	if state.Original, err = cgroup.IoLimit(device); err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	merged, err := mergeLimit(state.Original, limit)
+	if err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	bytes, _ := json.Marshal(state)
+	if err := ioutil.WriteFile(backupFile, bytes, 0644); err != nil {
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	if err := cgroup.SetIoLimit(device, merged); err != nil {
+		// the v1 limits are set one by one, so the original ones are restored
+		cgroup.SetIoLimit(device, state.Original)
+		os.Remove(backupFile)
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	bin.PrintOutputAndExit(fmt.Sprintf("throttle the device %s of %s in %s to %s",
+		device, directory, cgroup.Dirs[bin.IoController(cgroup.Version)], formatLimit(merged)))
+}
+
+func stopThrottle(uid string) {
+	backupFile := getBackupFile(uid)
+	bytes, err := ioutil.ReadFile(backupFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			bin.PrintOutputAndExit("nothing to do")
+			return
+		}
+		bin.PrintErrAndExit(err.Error())
+		return
+	}
+	var state throttleState
+	if err := json.Unmarshal(bytes, &state); err != nil || state.Original == nil {
+		bin.PrintErrAndExit(fmt.Sprintf("illegal backup file %s, %v", backupFile, err))
+		return
+	}
+	if err := state.cgroup().SetIoLimit(state.Device, state.Original); err != nil {
+		if !state.removed() {
+			bin.PrintErrAndExit(err.Error())
+			return
+		}
+		// the cgroup is removed with its workload
+		logrus.Warnf("the cgroup %v not exists, %v", state.Dirs, err)
+	}
+	os.Remove(backupFile)
+	bin.PrintOutputAndExit(fmt.Sprintf("restore the io limits of the device %s to %s", state.Device, formatLimit(state.Original)))
+}
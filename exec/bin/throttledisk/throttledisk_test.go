@@ -0,0 +1,112 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin"
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/bin/bintest"
+)
+
+func Test_startAndStopThrottle(t *testing.T) {
+	root, clean := bintest.NewRoot(t)
+	defer clean()
+	files := map[string]string{
+		"proc/self/mountinfo": fmt.Sprintf("21 20 7:0 / /data rw,relatime - ext4 /dev/loop0 rw\n"+
+			"35 25 0:31 / %s/unified rw,nosuid - cgroup2 cgroup2 rw\n", root),
+		"unified/cgroup.controllers": "cpu io memory\n",
+		"unified/pod1/cgroup.procs":  "",
+		"unified/pod1/io.max":        "7:0 rbps=max wbps=max riops=100 wiops=max\n",
+		"sys/dev/block/7:0/dev":      "7:0\n",
+	}
+	bintest.WriteFiles(t, root, files)
+	defer bintest.Replace(&bin.ProcPath, path.Join(root, "proc"))()
+	defer bintest.Replace(&bin.SysPath, path.Join(root, "sys"))()
+	var exitCode int
+	bin.ExitFunc = func(code int) {
+		exitCode = code
+	}
+	uid := "throttledisk-test"
+	defer os.Remove(getBackupFile(uid))
+	ioMax := path.Join(root, "unified/pod1/io.max")
+
+	startThrottle(uid, "/data/db", "/pod1", 0, &bin.IoLimit{WriteBps: mb})
+	if exitCode != 0 {
+		t.Fatalf("start throttle err, %s", bin.ExitMessageForTesting)
+	}
+	if bytes, _ := ioutil.ReadFile(ioMax); string(bytes) != "7:0 rbps=max wbps=1048576 riops=100 wiops=max" {
+		t.Errorf("unexpected io.max: %s, expected the write bps limited and the read iops kept", string(bytes))
+	}
+
+	startThrottle(uid, "/data/db", "/pod1", 0, &bin.IoLimit{ReadBps: mb})
+	if exitCode != 1 {
+		t.Errorf("expected err for the running experiment")
+	}
+
+	exitCode = 0
+	stopThrottle(uid)
+	if exitCode != 0 {
+		t.Fatalf("stop throttle err, %s", bin.ExitMessageForTesting)
+	}
+	if bytes, _ := ioutil.ReadFile(ioMax); string(bytes) != "7:0 rbps=max wbps=max riops=100 wiops=max" {
+		t.Errorf("unexpected io.max: %s, expected the original limits", string(bytes))
+	}
+	if util.IsExist(getBackupFile(uid)) {
+		t.Errorf("expected the backup file %s removed", getBackupFile(uid))
+	}
+
+	// the read iops is limited to 100 already
+	startThrottle(uid, "/data/db", "/pod1", 0, &bin.IoLimit{WriteBps: mb, ReadIops: 200})
+	if exitCode != 1 || !strings.Contains(bin.ExitMessageForTesting, "not below") {
+		t.Errorf("unexpected result: %d, %s, expected the err of not below", exitCode, bin.ExitMessageForTesting)
+	}
+	if util.IsExist(getBackupFile(uid)) {
+		t.Errorf("unexpected backup file %s", getBackupFile(uid))
+	}
+}
+
+func Test_stopThrottle_cgroupRemoved(t *testing.T) {
+	root, clean := bintest.NewRoot(t)
+	defer clean()
+	var exitCode int
+	bin.ExitFunc = func(code int) {
+		exitCode = code
+	}
+	uid := "throttledisk-removed-test"
+	defer os.Remove(getBackupFile(uid))
+	state := fmt.Sprintf(`{"version":2,"dirs":{"io":"%s/unified/pod1"},"device":"7:0","original":{}}`, root)
+	if err := ioutil.WriteFile(getBackupFile(uid), []byte(state), 0644); err != nil {
+		t.Fatalf("write the backup file err, %v", err)
+	}
+
+	// the cgroup is removed with the pod, the experiment is destroyed without restoring
+	stopThrottle(uid)
+	if exitCode != 0 {
+		t.Fatalf("stop throttle err, %s", bin.ExitMessageForTesting)
+	}
+	if util.IsExist(getBackupFile(uid)) {
+		t.Errorf("expected the backup file %s removed", getBackupFile(uid))
+	}
+}
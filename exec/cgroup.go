@@ -0,0 +1,43 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+// checkCgroupTarget returns a failed response if the cgroup-path and target-pid flags are illegal, they select
+// the cgroup of the cpu, mem, disk and other experiments running in the cgroup of a path or a process
+func checkCgroupTarget(uid, cgroupPath, targetPid string) *spec.Response {
+	if cgroupPath != "" && targetPid != "" {
+		util.Errorf(uid, util.GetRunFuncName(), "cgroup-path and target-pid cannot be specified at the same time")
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "cgroup-path|target-pid"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "cgroup-path|target-pid"))
+	}
+	if targetPid != "" {
+		if pid, err := strconv.Atoi(targetPid); err != nil || pid <= 0 {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: target-pid is illegal, it must be a positive integer", targetPid))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "target-pid"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "target-pid"))
+		}
+	}
+	return nil
+}
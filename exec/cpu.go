@@ -24,7 +24,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
 	"github.com/chaosblade-io/chaosblade-spec-go/util"
 
@@ -33,6 +32,16 @@ import (
 
 const BurnCpuBin = "chaos_burncpu"
 
+// The strategies of burning cpu
+const (
+	// FeedbackStrategy adjusts the busy time by the measured cpu usage, so the total usage reaches the cpu-percent
+	FeedbackStrategy = "feedback"
+	// CgroupQuotaStrategy burns in a cpu cgroup whose quota is the cpu-percent of the cores
+	CgroupQuotaStrategy = "cgroup-quota"
+	// AbsoluteStrategy burns the cpu-percent by itself regardless of the other processes
+	AbsoluteStrategy = "absolute"
+)
+
 type CpuCommandModelSpec struct {
 	spec.BaseExpModelCommandSpec
 }
@@ -44,7 +53,32 @@ func NewCpuCommandModelSpec() spec.ExpModelCommandSpec {
 				&FullLoadActionCommand{
 					spec.BaseExpActionCommandSpec{
 						ActionMatchers: []spec.ExpFlagSpec{},
-						ActionFlags:    []spec.ExpFlagSpec{},
+						ActionFlags: []spec.ExpFlagSpec{
+							&spec.ExpFlag{
+								Name:     "cgroup-path",
+								Desc:     "The cpu cgroup to burn cpu in, for example /sys/fs/cgroup/cpu/docker/<id> or /docker/<id>, the cpu-percent is relative to the quota of the cgroup",
+								Required: false,
+							},
+							&spec.ExpFlag{
+								Name: "strategy",
+								Desc: "The strategy of burning cpu, default value is feedback. feedback: adjust the load by the measured usage so the total usage reaches the cpu-percent; " +
+									"cgroup-quota: burn in a cpu cgroup limited to the cpu-percent of the cores, which is precise regardless of the other processes; " +
+									"absolute: burn the cpu-percent by the process itself, the same as the absolute flag",
+								Required: false,
+							},
+							&spec.ExpFlag{
+								Name: "profile",
+								Desc: "The load profile over time, it overrides the climb-time. Steps: 30:60s,80:120s; " +
+									"sine around the cpu-percent with period and amplitude: sine:60s:20; " +
+									"random spikes to the cpu-percent with period, duty percent and optional base percent: spike:30s:20:10, the spike lasts at least 1s",
+								Required: false,
+							},
+							&spec.ExpFlag{
+								Name:     "target-pid",
+								Desc:     "Burn cpu in the cpu cgroup of the process, the cpu-percent is relative to the quota of the cgroup",
+								Required: false,
+							},
+						},
 						ActionExecutor: &cpuExecutor{},
 						ActionExample: `
 # Create a CPU full load experiment
@@ -56,15 +90,33 @@ blade create cpu load --cpu-percent 60 --cpu-count 2
 # Specifies that the core is full load with index 0, 3, and that the core's index starts at 0
 blade create cpu load --cpu-list 0,3
 
+# Hold the cores 0 and 3 at 50% separately, the requested and achieved load of each core is returned
+blade create cpu load --cpu-list 0,3 --cpu-percent 50
+
 # Specify the core full load of indexes 1-3
 blade create cpu load --cpu-list 1-3
 
 # Specified percentage load
-blade create cpu load --cpu-percent 60`,
+blade create cpu load --cpu-percent 60
+
+# Burn 50% of the cores 0 and 1 precisely by the cgroup quota
+blade create cpu load --cpu-list 0,1 --cpu-percent 50 --strategy cgroup-quota
+
+# Load 60% of the cpu quota of the container which the process 1234 runs in
+blade create cpu load --cpu-percent 60 --target-pid 1234
+
+# Hold 30% for 60s then 80% for 120s, and repeat
+blade create cpu load --profile 30:60s,80:120s
+
+# Oscillate between 30% and 70% every 5 minutes
+blade create cpu load --cpu-percent 50 --profile sine:5m:20`,
 						ActionPrograms:   []string{BurnCpuBin},
 						ActionCategories: []string{category.SystemCpu},
 					},
 				},
+				NewThrottleActionSpec(),
+				NewContentionActionSpec(),
+				NewCacheThrashActionSpec(),
 			},
 			ExpFlags: []spec.ExpFlagSpec{
 				&spec.ExpFlag{
@@ -157,7 +209,14 @@ func (ce *cpuExecutor) Exec(uid string, ctx context.Context, model *spec.ExpMode
 		return spec.ReturnFail(spec.Code[spec.ServerError], "channel is nil")
 	}
 	if _, ok := spec.IsDestroy(ctx); ok {
-		return ce.stop(ctx)
+		// the cpu list is expanded as the start, so the target file recorded by the burner is found
+		var cpuList string
+		if cpuListStr := model.ActionFlags["cpu-list"]; cpuListStr != "" {
+			if cores, err := util.ParseIntegerListToStringSlice("cpu-list", cpuListStr); err == nil {
+				cpuList = strings.Join(cores, ",")
+			}
+		}
+		return ce.stop(ctx, cpuList)
 	}
 	var cpuCount int
 	var cpuList string
@@ -185,10 +244,6 @@ func (ce *cpuExecutor) Exec(uid string, ctx context.Context, model *spec.ExpMode
 
 	cpuListStr := model.ActionFlags["cpu-list"]
 	if cpuListStr != "" {
-		if !channel.NewLocalChannel().IsCommandAvailable("taskset") {
-			return spec.ResponseFailWaitResult(spec.CommandTasksetNotFound, spec.ResponseErr[spec.CommandTasksetNotFound].Err,
-				spec.ResponseErr[spec.CommandTasksetNotFound].ErrInfo)
-		}
 		cores, err := util.ParseIntegerListToStringSlice("cpu-list", cpuListStr)
 		if err != nil {
 			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: cpu-list is illegal", cpuListStr))
@@ -217,7 +272,7 @@ func (ce *cpuExecutor) Exec(uid string, ctx context.Context, model *spec.ExpMode
 		var err error
 		absolute, err = strconv.ParseBool(absolutestr)
 		if err != nil {
-			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: absolute is Bool", absolute))
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: absolute is Bool", absolutestr))
 			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "climb-time"),
 				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "absolute"))
 		}
@@ -239,24 +294,80 @@ func (ce *cpuExecutor) Exec(uid string, ctx context.Context, model *spec.ExpMode
 		}
 	}
 
-	return ce.start(ctx, cpuList, cpuCount, cpuPercent, climbTime, absolute)
+	profile := model.ActionFlags["profile"]
+	if profile != "" {
+		if _, err := ParseCpuProfile(profile, cpuPercent); err != nil {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: profile is illegal, %v", profile, err))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "profile"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "profile"))
+		}
+	}
+
+	strategy := model.ActionFlags["strategy"]
+	if strategy == "" {
+		strategy = FeedbackStrategy
+		if absolute {
+			strategy = AbsoluteStrategy
+		}
+	}
+	if (strategy != FeedbackStrategy && strategy != CgroupQuotaStrategy && strategy != AbsoluteStrategy) ||
+		(absolute && strategy != AbsoluteStrategy) {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: strategy is illegal, it must be feedback, cgroup-quota or absolute, "+
+			"and it must be absolute if the absolute flag is true", strategy))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "strategy"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "strategy"))
+	}
+
+	cgroupPath := model.ActionFlags["cgroup-path"]
+	targetPid := model.ActionFlags["target-pid"]
+	if strategy == CgroupQuotaStrategy && (cgroupPath != "" || targetPid != "") {
+		util.Errorf(uid, util.GetRunFuncName(), "the cgroup-quota strategy burns in its own cgroup, cgroup-path and target-pid cannot be specified")
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "strategy"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "strategy"))
+	}
+	if response := checkCgroupTarget(uid, cgroupPath, targetPid); response != nil {
+		return response
+	}
+	if cpuList != "" && (cgroupPath != "" || targetPid != "") {
+		util.Errorf(uid, util.GetRunFuncName(), "the cores of cpu-list are held at the host percent, cgroup-path and target-pid cannot be specified")
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "cpu-list"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "cpu-list"))
+	}
+
+	return ce.start(ctx, cpuList, cpuCount, cpuPercent, climbTime, strategy, profile, cgroupPath, targetPid)
 }
 
 // start burn cpu
-func (ce *cpuExecutor) start(ctx context.Context, cpuList string, cpuCount int, cpuPercent int, climbTime int, absolute bool) *spec.Response {
+func (ce *cpuExecutor) start(ctx context.Context, cpuList string, cpuCount int, cpuPercent int, climbTime int, strategy string,
+	profile, cgroupPath, targetPid string) *spec.Response {
 	args := fmt.Sprintf("--start --climb-time %d --cpu-count %d --cpu-percent %d --debug=%t", climbTime, cpuCount, cpuPercent, util.Debug)
 	if cpuList != "" {
 		args = fmt.Sprintf("%s --cpu-list %s", args, cpuList)
 	}
-	if absolute {
+	switch strategy {
+	case AbsoluteStrategy:
 		args = fmt.Sprintf("%s --absolute", args)
+	case CgroupQuotaStrategy:
+		args = fmt.Sprintf("%s --strategy %s", args, strategy)
+	}
+	if profile != "" {
+		args = fmt.Sprintf("%s --profile %s", args, profile)
+	}
+	if cgroupPath != "" {
+		args = fmt.Sprintf("%s --cgroup-path %s", args, cgroupPath)
+	}
+	if targetPid != "" {
+		args = fmt.Sprintf("%s --target-pid %s", args, targetPid)
 	}
 
 	return ce.channel.Run(ctx, path.Join(ce.channel.GetScriptPath(), BurnCpuBin), args)
 }
 
-// stop burn cpu
-func (ce *cpuExecutor) stop(ctx context.Context) *spec.Response {
-	return ce.channel.Run(ctx, path.Join(ce.channel.GetScriptPath(), BurnCpuBin),
-		fmt.Sprintf("--stop --debug=%t", util.Debug))
+// stop burn cpu, the cpu list is passed to remove the target file of the experiment
+func (ce *cpuExecutor) stop(ctx context.Context, cpuList string) *spec.Response {
+	args := fmt.Sprintf("--stop --debug=%t", util.Debug)
+	if cpuList != "" {
+		args = fmt.Sprintf("%s --cpu-list %s", args, cpuList)
+	}
+	return ce.channel.Run(ctx, path.Join(ce.channel.GetScriptPath(), BurnCpuBin), args)
 }
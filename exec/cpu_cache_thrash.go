@@ -0,0 +1,149 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const CacheThrashBin = "chaos_cachethrash"
+
+type CacheThrashActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewCacheThrashActionSpec() spec.ExpActionCommandSpec {
+	return &CacheThrashActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "working-set",
+					Desc: "The total size of the buffers to stream over, unit is MB, default value is twice the size of the last level cache",
+				},
+				&spec.ExpFlag{
+					Name: "threads",
+					Desc: "The number of threads, each thread streams over its part of the working set, default value is the count of the cpu-list or all the cpus",
+				},
+				&spec.ExpFlag{
+					Name: "pattern",
+					Desc: "The access pattern, stream or random, default value is stream. " +
+						"The stream pattern saturates the memory bandwidth, the random pattern defeats the prefetcher and misses the cache on every access",
+				},
+			},
+			ActionExecutor: &CacheThrashActionExecutor{},
+			ActionExample: `
+# Stream over twice the size of the last level cache with a thread on every cpu
+blade create cpu cache-thrash
+
+# Randomly access a 256MB working set on the cores 2 and 3
+blade create cpu cache-thrash --working-set 256 --pattern random --cpu-list 2,3`,
+			ActionPrograms:   []string{CacheThrashBin},
+			ActionCategories: []string{category.SystemCpu},
+		},
+	}
+}
+
+func (*CacheThrashActionSpec) Name() string {
+	return "cache-thrash"
+}
+
+func (*CacheThrashActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*CacheThrashActionSpec) ShortDesc() string {
+	return "Thrash the cpu cache and the memory bandwidth"
+}
+
+func (c *CacheThrashActionSpec) LongDesc() string {
+	if c.ActionLongDesc != "" {
+		return c.ActionLongDesc
+	}
+	return "Read and write buffers sized to the last level cache or larger, which evicts the cache lines of the neighbours " +
+		"and consumes the memory bandwidth, to reproduce the noisy neighbour effects that cpu load does not cause"
+}
+
+type CacheThrashActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*CacheThrashActionExecutor) Name() string {
+	return "cache-thrash"
+}
+
+func (cte *CacheThrashActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if cte.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return cte.stop(ctx)
+	}
+	args := fmt.Sprintf("--start --debug=%t", util.Debug)
+	for _, name := range []string{"working-set", "threads"} {
+		valueStr := model.ActionFlags[name]
+		if valueStr == "" {
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		if err != nil || value <= 0 {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal, it must be a positive integer", valueStr, name))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		args = fmt.Sprintf("%s --%s %d", args, name, value)
+	}
+	pattern := model.ActionFlags["pattern"]
+	if pattern == "" {
+		pattern = "stream"
+	}
+	if pattern != "stream" && pattern != "random" {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: pattern is illegal, it must be stream or random", pattern))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "pattern"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "pattern"))
+	}
+	args = fmt.Sprintf("%s --pattern %s", args, pattern)
+	if cpuListStr := model.ActionFlags["cpu-list"]; cpuListStr != "" {
+		cores, err := util.ParseIntegerListToStringSlice("cpu-list", cpuListStr)
+		if err != nil {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: cpu-list is illegal", cpuListStr))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "cpu-list"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "cpu-list"))
+		}
+		args = fmt.Sprintf("%s --cpu-list %s", args, strings.Join(cores, ","))
+	}
+	return cte.channel.Run(ctx, path.Join(cte.channel.GetScriptPath(), CacheThrashBin), args)
+}
+
+func (cte *CacheThrashActionExecutor) stop(ctx context.Context) *spec.Response {
+	return cte.channel.Run(ctx, path.Join(cte.channel.GetScriptPath(), CacheThrashBin),
+		fmt.Sprintf("--stop --debug=%t", util.Debug))
+}
+
+func (cte *CacheThrashActionExecutor) SetChannel(channel spec.Channel) {
+	cte.channel = channel
+}
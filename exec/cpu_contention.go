@@ -0,0 +1,200 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const CpuContentionBin = "chaos_cpucontention"
+
+// MaxContentionPriority keeps the real-time threads below the threaded irq handlers, which run at the priority 50
+const MaxContentionPriority = 49
+
+type ContentionActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewContentionActionSpec() spec.ExpActionCommandSpec {
+	return &ContentionActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "threads",
+					Desc: "The number of runnable threads, default value is 4 times the cpu count",
+				},
+				&spec.ExpFlag{
+					Name: "busy-time",
+					Desc: "The busy time of each cycle before the thread yields, unit is microsecond, default value is 100",
+				},
+				&spec.ExpFlag{
+					Name: "policy",
+					Desc: "The scheduling policy of the threads, normal, fifo or rr, default value is normal. " +
+						"The real-time threads starve the other processes on the cpus, so the cpu-list flag is required and must leave one cpu at least",
+				},
+				&spec.ExpFlag{
+					Name: "priority",
+					Desc: "The real-time priority of the fifo or rr policy, [1, 49], default value is 1",
+				},
+				&spec.ExpFlag{
+					Name: "nice",
+					Desc: "The nice value of the normal policy, [-20, 19], default value is 0",
+				},
+			},
+			ActionExecutor: &ContentionActionExecutor{},
+			ActionExample: `
+# Run 4 runnable threads per cpu, which busy for 100us and yield
+blade create cpu contention
+
+# Run 32 threads with the nice value -10 on the cores 0 and 1
+blade create cpu contention --threads 32 --nice -10 --cpu-list 0,1
+
+# Run 8 real-time threads, the load average and context switches per second are returned
+blade create cpu contention --threads 8 --policy fifo --priority 10 --cpu-list 0`,
+			ActionPrograms:   []string{CpuContentionBin},
+			ActionCategories: []string{category.SystemCpu},
+		},
+	}
+}
+
+func (*ContentionActionSpec) Name() string {
+	return "contention"
+}
+
+func (*ContentionActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*ContentionActionSpec) ShortDesc() string {
+	return "Scheduler contention"
+}
+
+func (c *ContentionActionSpec) LongDesc() string {
+	if c.ActionLongDesc != "" {
+		return c.ActionLongDesc
+	}
+	return "Run many runnable threads doing short busy and yield cycles, so the run queue is long and the context switches storm, " +
+		"which delays the other threads on the cpus even if the cpu usage is not high"
+}
+
+type ContentionActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*ContentionActionExecutor) Name() string {
+	return "contention"
+}
+
+func (cae *ContentionActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if cae.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return cae.stop(ctx)
+	}
+	values := map[string]int{
+		"threads":   runtime.NumCPU() * 4,
+		"busy-time": 100,
+		"priority":  1,
+		"nice":      0,
+	}
+	ranges := map[string][2]int{
+		"threads":   {1, 4096},
+		"busy-time": {1, 1000000},
+		"priority":  {1, MaxContentionPriority},
+		"nice":      {-20, 19},
+	}
+	for _, name := range []string{"threads", "busy-time", "priority", "nice"} {
+		valueStr := model.ActionFlags[name]
+		if valueStr == "" {
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		if err != nil || value < ranges[name][0] || value > ranges[name][1] {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal, it must be in [%d, %d]", valueStr, name, ranges[name][0], ranges[name][1]))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		values[name] = value
+	}
+	policy := model.ActionFlags["policy"]
+	if policy == "" {
+		policy = "normal"
+	}
+	if policy != "normal" && policy != "fifo" && policy != "rr" {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: policy is illegal, it must be normal, fifo or rr", policy))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "policy"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "policy"))
+	}
+	var cpuList string
+	if cpuListStr := model.ActionFlags["cpu-list"]; cpuListStr != "" {
+		cores, err := util.ParseIntegerListToStringSlice("cpu-list", cpuListStr)
+		if err != nil {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: cpu-list is illegal", cpuListStr))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "cpu-list"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "cpu-list"))
+		}
+		cpuList = strings.Join(cores, ",")
+	}
+	if policy != "normal" {
+		if cpuList == "" {
+			util.Errorf(uid, util.GetRunFuncName(), "cpu-list is required by the real-time policy")
+			return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "cpu-list"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "cpu-list"))
+		}
+		distinct := make(map[string]bool)
+		for _, core := range strings.Split(cpuList, ",") {
+			distinct[core] = true
+		}
+		if len(distinct) >= runtime.NumCPU() {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: cpu-list covers all the cpus, the real-time threads must leave one cpu at least", cpuList))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "cpu-list"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "cpu-list"))
+		}
+	}
+	return cae.start(ctx, values["threads"], values["busy-time"], policy, values["priority"], values["nice"], cpuList)
+}
+
+func (cae *ContentionActionExecutor) start(ctx context.Context, threads, busyTime int, policy string, priority, nice int, cpuList string) *spec.Response {
+	args := fmt.Sprintf("--start --threads %d --busy-time %d --policy %s --priority %d --nice %d --debug=%t",
+		threads, busyTime, policy, priority, nice, util.Debug)
+	if cpuList != "" {
+		args = fmt.Sprintf("%s --cpu-list %s", args, cpuList)
+	}
+	return cae.channel.Run(ctx, path.Join(cae.channel.GetScriptPath(), CpuContentionBin), args)
+}
+
+func (cae *ContentionActionExecutor) stop(ctx context.Context) *spec.Response {
+	return cae.channel.Run(ctx, path.Join(cae.channel.GetScriptPath(), CpuContentionBin),
+		fmt.Sprintf("--stop --debug=%t", util.Debug))
+}
+
+func (cae *ContentionActionExecutor) SetChannel(channel spec.Channel) {
+	cae.channel = channel
+}
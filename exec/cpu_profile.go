@@ -0,0 +1,206 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StepProfile  = "step"
+	SineProfile  = "sine"
+	SpikeProfile = "spike"
+)
+
+// CpuProfileTick is the interval the burning process changes the load by the profile
+const CpuProfileTick = time.Second
+
+// CpuProfile returns the target cpu percent at the elapsed time of the experiment
+type CpuProfile interface {
+	Percent(elapsed time.Duration) float64
+}
+
+// ParseCpuProfile parses the profile flag, the formats are:
+//
+//	step:30:60s,80:120s, the prefix is optional, holds 30% for 60s then 80% for 120s and repeats
+//	sine:60s:20, oscillates around the cpu percent with the period 60s and the amplitude 20%
+//	spike:30s:20[:10], rises to the cpu percent for 20% of every 30s at a random moment, otherwise holds 10%,
+//	the spike must be at least a CpuProfileTick
+func ParseCpuProfile(profile string, cpuPercent int) (CpuProfile, error) {
+	fields := strings.Split(profile, ":")
+	switch fields[0] {
+	case SineProfile:
+		return parseSineProfile(fields[1:], cpuPercent)
+	case SpikeProfile:
+		return parseSpikeProfile(fields[1:], cpuPercent)
+	case StepProfile:
+		return parseStepProfile(strings.TrimPrefix(profile, StepProfile+":"))
+	default:
+		return parseStepProfile(profile)
+	}
+}
+
+type profileStep struct {
+	percent  float64
+	duration time.Duration
+}
+
+type stepCpuProfile struct {
+	steps []profileStep
+	total time.Duration
+}
+
+func parseStepProfile(profile string) (CpuProfile, error) {
+	p := &stepCpuProfile{}
+	for _, step := range strings.Split(profile, ",") {
+		fields := strings.Split(strings.TrimSpace(step), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("illegal step `%s`, the format is percent:duration, for example 30:60s", step)
+		}
+		percent, err := parseProfilePercent(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		duration, err := parseProfileDuration(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		p.steps = append(p.steps, profileStep{percent: percent, duration: duration})
+		p.total += duration
+	}
+	return p, nil
+}
+
+func (p *stepCpuProfile) Percent(elapsed time.Duration) float64 {
+	elapsed = elapsed % p.total
+	for _, step := range p.steps {
+		if elapsed < step.duration {
+			return step.percent
+		}
+		elapsed -= step.duration
+	}
+	return p.steps[len(p.steps)-1].percent
+}
+
+type sineCpuProfile struct {
+	base      float64
+	amplitude float64
+	period    time.Duration
+}
+
+func parseSineProfile(fields []string, cpuPercent int) (CpuProfile, error) {
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("illegal sine profile, the format is sine:period:amplitude, for example sine:60s:20")
+	}
+	period, err := parseProfileDuration(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	amplitude, err := parseProfilePercent(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	return &sineCpuProfile{base: float64(cpuPercent), amplitude: amplitude, period: period}, nil
+}
+
+func (p *sineCpuProfile) Percent(elapsed time.Duration) float64 {
+	percent := p.base + p.amplitude*math.Sin(2*math.Pi*float64(elapsed%p.period)/float64(p.period))
+	return math.Max(0, math.Min(100, percent))
+}
+
+type spikeCpuProfile struct {
+	base   float64
+	peak   float64
+	period time.Duration
+	// duty is the ratio of the spike in every period
+	duty   float64
+	cycle  int64
+	offset time.Duration
+	rand   *rand.Rand
+}
+
+func parseSpikeProfile(fields []string, cpuPercent int) (CpuProfile, error) {
+	if len(fields) != 2 && len(fields) != 3 {
+		return nil, fmt.Errorf("illegal spike profile, the format is spike:period:duty[:base], for example spike:30s:20")
+	}
+	period, err := parseProfileDuration(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	duty, err := parseProfilePercent(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	if duty == 0 {
+		return nil, fmt.Errorf("illegal duty `%s`, it must be bigger than 0", fields[1])
+	}
+	// the load is changed by the tick, the shorter spike is skipped or stretched to a tick
+	if spike := time.Duration(float64(period) * duty / 100); spike < CpuProfileTick {
+		return nil, fmt.Errorf("illegal duty `%s`, the spike %v is shorter than %v", fields[1], spike, CpuProfileTick)
+	}
+	var base float64
+	if len(fields) == 3 {
+		if base, err = parseProfilePercent(fields[2]); err != nil {
+			return nil, err
+		}
+	}
+	return &spikeCpuProfile{
+		base:   base,
+		peak:   float64(cpuPercent),
+		period: period,
+		duty:   duty / 100,
+		cycle:  -1,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+func (p *spikeCpuProfile) Percent(elapsed time.Duration) float64 {
+	spike := time.Duration(float64(p.period) * p.duty)
+	// the spike starts at a random tick of each period
+	if cycle := int64(elapsed / p.period); cycle != p.cycle {
+		p.cycle = cycle
+		p.offset = 0
+		if p.period > spike {
+			p.offset = time.Duration(p.rand.Int63n(int64(p.period - spike))).Truncate(CpuProfileTick)
+		}
+	}
+	if inPeriod := elapsed % p.period; inPeriod >= p.offset && inPeriod < p.offset+spike {
+		return p.peak
+	}
+	return p.base
+}
+
+func parseProfilePercent(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("illegal percent `%s`, it must be in [0, 100]", value)
+	}
+	return percent, nil
+}
+
+func parseProfileDuration(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < time.Second {
+		return 0, fmt.Errorf("illegal duration `%s`, it must be at least 1s, for example 60s or 2m", value)
+	}
+	return duration, nil
+}
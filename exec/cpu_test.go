@@ -17,7 +17,9 @@
 package exec
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/util"
 )
@@ -46,3 +48,56 @@ func TestParseCpuList(t *testing.T) {
 		}
 	}
 }
+
+func TestParseCpuProfile(t *testing.T) {
+	tests := []struct {
+		profile string
+		expect  map[time.Duration]float64
+	}{
+		{"30:60s,80:2m", map[time.Duration]float64{0: 30, 59 * time.Second: 30, 60 * time.Second: 80, 179 * time.Second: 80, 180 * time.Second: 30}},
+		{"step:10:1s", map[time.Duration]float64{0: 10, 5 * time.Second: 10}},
+		{"sine:60s:20", map[time.Duration]float64{0: 50, 15 * time.Second: 70, 45 * time.Second: 30}},
+		{"spike:10s:100:10", map[time.Duration]float64{0: 50, 5 * time.Second: 50}},
+	}
+	for _, tt := range tests {
+		profile, err := ParseCpuProfile(tt.profile, 50)
+		if err != nil {
+			t.Fatalf("parse profile `%s` err, %v", tt.profile, err)
+		}
+		for elapsed, expect := range tt.expect {
+			if got := profile.Percent(elapsed); math.Abs(got-expect) > 0.001 {
+				t.Errorf("unexpected percent of `%s` at %v: %f, expected: %f", tt.profile, elapsed, got, expect)
+			}
+		}
+	}
+}
+
+func TestParseCpuProfile_spike(t *testing.T) {
+	profile, err := ParseCpuProfile("spike:10s:20:10", 90)
+	if err != nil {
+		t.Fatalf("parse profile err, %v", err)
+	}
+	// every period spikes for 2s
+	for cycle := 0; cycle < 5; cycle++ {
+		spikes := 0
+		for i := 0; i < 100; i++ {
+			percent := profile.Percent(time.Duration(cycle)*10*time.Second + time.Duration(i)*100*time.Millisecond)
+			if percent == 90 {
+				spikes++
+			} else if percent != 10 {
+				t.Fatalf("unexpected percent: %f", percent)
+			}
+		}
+		if spikes != 20 {
+			t.Errorf("unexpected spikes in cycle %d: %d, expected: 20", cycle, spikes)
+		}
+	}
+}
+
+func TestParseCpuProfile_failed(t *testing.T) {
+	for _, profile := range []string{"30", "30:60", "101:60s", "30:0.5s", "sine:60s", "sine:x:20", "spike:10s:0", "spike:2s:20", "spike:10s:20:10:5", "30:60s,"} {
+		if _, err := ParseCpuProfile(profile, 50); err == nil {
+			t.Errorf("expected err for the profile `%s`", profile)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const ThrottleCpuBin = "chaos_throttlecpu"
+
+type ThrottleActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewThrottleActionSpec() spec.ExpActionCommandSpec {
+	return &ThrottleActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "pid",
+					Desc: "The process ids to throttle, separated by commas",
+				},
+				&spec.ExpFlag{
+					Name: "process",
+					Desc: "Process name",
+				},
+				&spec.ExpFlag{
+					Name: "process-cmd",
+					Desc: "Process name in command",
+				},
+			},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name:     "quota-percent",
+					Desc:     "The cpu quota to throttle to, percent of one cpu core, for example 50 means half a core and 200 means two cores",
+					Required: true,
+				},
+				&spec.ExpFlag{
+					Name:   "isolate",
+					Desc:   "Move the processes into a temporary cgroup with the quota, instead of lowering the quota of their cgroups which other processes may share. It is not supported on cgroup v2, where the processes would lose the other limits of their cgroups",
+					NoArgs: true,
+				},
+			},
+			ActionExecutor: &ThrottleActionExecutor{},
+			ActionExample: `
+# Throttle the cgroup of the java process to half a core
+blade create cpu throttle --process java --quota-percent 50
+
+# Move the process 1234 into a temporary cgroup limited to 1.5 cores
+blade create cpu throttle --pid 1234 --quota-percent 150 --isolate`,
+			ActionPrograms:   []string{ThrottleCpuBin},
+			ActionCategories: []string{category.SystemCpu},
+		},
+	}
+}
+
+func (*ThrottleActionSpec) Name() string {
+	return "throttle"
+}
+
+func (*ThrottleActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*ThrottleActionSpec) ShortDesc() string {
+	return "Throttle the cpu quota of processes"
+}
+
+func (t *ThrottleActionSpec) LongDesc() string {
+	if t.ActionLongDesc != "" {
+		return t.ActionLongDesc
+	}
+	return "Lower the cpu quota of the cgroups which the processes run in, cpu.max in cgroup v2 or cpu.cfs_quota_us in v1, " +
+		"so the processes are throttled by the CFS. The processes in the root cgroup are moved into a temporary cgroup. " +
+		"The original quota is restored when the experiment is destroyed"
+}
+
+type ThrottleActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*ThrottleActionExecutor) Name() string {
+	return "throttle"
+}
+
+func (tae *ThrottleActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if tae.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return tae.stop(ctx, uid)
+	}
+	pid := model.ActionFlags["pid"]
+	process := model.ActionFlags["process"]
+	processCmd := model.ActionFlags["process-cmd"]
+	if pid == "" && process == "" && processCmd == "" {
+		util.Errorf(uid, util.GetRunFuncName(), "less pid, process and process-cmd, less process matcher")
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "pid|process|process-cmd"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "pid|process|process-cmd"))
+	}
+	flags := fmt.Sprintf("--start --uid %s --debug=%t", uid, util.Debug)
+	if pid != "" {
+		for _, p := range strings.Split(pid, ",") {
+			if v, err := strconv.Atoi(p); err != nil || v <= 0 {
+				util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: pid is illegal, it must be positive integers separated by commas", pid))
+				return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "pid"),
+					fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "pid"))
+			}
+		}
+		flags = fmt.Sprintf("%s --pid %s", flags, pid)
+	} else {
+		ctx = context.WithValue(ctx, channel.ExcludeProcessKey, "blade")
+		if response := checkProcessInvalid(uid, process, processCmd, "", ctx); response != nil {
+			return response
+		}
+		if process != "" {
+			flags = fmt.Sprintf(`%s --process "%s"`, flags, process)
+		} else {
+			flags = fmt.Sprintf(`%s --process-cmd "%s"`, flags, processCmd)
+		}
+	}
+	quotaPercentStr := model.ActionFlags["quota-percent"]
+	quotaPercent, err := strconv.Atoi(quotaPercentStr)
+	if err != nil || quotaPercent <= 0 {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: quota-percent is illegal, it must be a positive integer", quotaPercentStr))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "quota-percent"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "quota-percent"))
+	}
+	flags = fmt.Sprintf("%s --quota-percent %d", flags, quotaPercent)
+	if model.ActionFlags["isolate"] == "true" {
+		flags = fmt.Sprintf("%s --isolate", flags)
+	}
+	return tae.channel.Run(ctx, path.Join(tae.channel.GetScriptPath(), ThrottleCpuBin), flags)
+}
+
+func (tae *ThrottleActionExecutor) stop(ctx context.Context, uid string) *spec.Response {
+	return tae.channel.Run(ctx, path.Join(tae.channel.GetScriptPath(), ThrottleCpuBin),
+		fmt.Sprintf("--stop --uid %s --debug=%t", uid, util.Debug))
+}
+
+func (tae *ThrottleActionExecutor) SetChannel(channel spec.Channel) {
+	tae.channel = channel
+}
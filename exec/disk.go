@@ -30,6 +30,10 @@ func NewDiskCommandSpec() spec.ExpModelCommandSpec {
 			ExpActions: []spec.ExpActionCommandSpec{
 				NewFillActionSpec(),
 				NewBurnActionSpec(),
+				NewDiskThrottleActionSpec(),
+				NewDiskLatencyActionSpec(),
+				NewDiskErrorActionSpec(),
+				NewDiskReadonlyActionSpec(),
 			},
 			ExpFlags: []spec.ExpFlagSpec{},
 		},
@@ -45,5 +49,5 @@ func (*DiskCommandSpec) ShortDesc() string {
 }
 
 func (*DiskCommandSpec) LongDesc() string {
-	return "Disk experiment contains fill disk or burn io"
+	return "Disk experiment contains fill disk, burn io, throttle io, the io latency or error of a device-mapper device, or make the filesystem read-only"
 }
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"strconv"
 
 	"github.com/chaosblade-io/chaosblade-spec-go/channel"
 	"github.com/chaosblade-io/chaosblade-spec-go/spec"
@@ -40,12 +41,12 @@ func NewBurnActionSpec() spec.ExpActionCommandSpec {
 			ActionMatchers: []spec.ExpFlagSpec{
 				&spec.ExpFlag{
 					Name:   "read",
-					Desc:   "Burn io by read, it will create a 600M for reading and delete it when destroy it",
+					Desc:   "Burn io by read, it will create a file of 8 blocks for each iodepth or 64M if bigger for reading, the load begins after the file is created, and delete it when destroy it",
 					NoArgs: true,
 				},
 				&spec.ExpFlag{
 					Name:   "write",
-					Desc:   "Burn io by write, it will create a file by value of the size flag, for example the size default value is 10, then it will create a 10M*100=1000M file for writing, and delete it when destroy",
+					Desc:   "Burn io by write, the writes wrap around a file of 8 blocks for each iodepth or 64M if bigger, for example the size default value is 10 and the iodepth is 1, then the file is 10M*8=80M, and delete it when destroy",
 					NoArgs: true,
 				},
 			},
@@ -58,6 +59,26 @@ func NewBurnActionSpec() spec.ExpActionCommandSpec {
 					Name: "path",
 					Desc: "The path of directory where the disk is burning, default value is /",
 				},
+				&spec.ExpFlag{
+					Name: "pattern",
+					Desc: "The access pattern, seq or rand, default value is seq",
+				},
+				&spec.ExpFlag{
+					Name: "block-size",
+					Desc: "The block size of each io, unit is KB, it must be a multiple of 4. The size flag is used if it does not exist",
+				},
+				&spec.ExpFlag{
+					Name: "iodepth",
+					Desc: "The number of the concurrent io, default value is 1",
+				},
+				&spec.ExpFlag{
+					Name: "rw-mix",
+					Desc: "The percent of the reads when burning by read and write, [0, 100], default value is 50",
+				},
+				&spec.ExpFlag{
+					Name: "fsync-every",
+					Desc: "Fsync the write file every the number of writes, default value is 0 which means never",
+				},
 			},
 			ActionExecutor: &BurnIOExecutor{},
 			ActionExample: `
@@ -68,7 +89,13 @@ blade create disk burn --read --path /home
 blade create disk burn --write --path /home
 
 # Read and write IO load scenarios are performed at the same time. Path is not specified. The default is /
-blade create disk burn --read --write`,
+blade create disk burn --read --write
+
+# 4KB random io by 16 concurrent io, 70% of the io are reads
+blade create disk burn --read --write --pattern rand --block-size 4 --iodepth 16 --rw-mix 70
+
+# Sequential 1MB writes with fsync every 8 writes
+blade create disk burn --write --block-size 1024 --fsync-every 8`,
 			ActionPrograms:   []string{BurnIOBin},
 			ActionCategories: []string{category.SystemDisk},
 		},
@@ -90,7 +117,9 @@ func (b *BurnActionSpec) LongDesc() string {
 	if b.ActionLongDesc != "" {
 		return b.ActionLongDesc
 	}
-	return "Increase disk read and write io load"
+	return "Increase disk read and write io load by O_DIRECT io on the files under the path, the access pattern, the block size, " +
+		"the iodepth, the read and write mix and the fsync frequency are configurable. The iops and the throughput are reported " +
+		"in the log of chaos_burnio"
 }
 
 type BurnIOExecutor struct {
@@ -102,7 +131,7 @@ func (*BurnIOExecutor) Name() string {
 }
 
 func (be *BurnIOExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
-	commands := []string{"rm", "dd"}
+	commands := []string{"rm"}
 	if response, ok := channel.NewLocalChannel().IsAllCommandsAvailable(commands); !ok {
 		return response
 	}
@@ -140,12 +169,43 @@ func (be *BurnIOExecutor) Exec(uid string, ctx context.Context, model *spec.ExpM
 	if size == "" {
 		size = "10"
 	}
-	return be.start(ctx, readExists, writeExists, directory, size)
+	flags := fmt.Sprintf("--read=%t --write=%t --directory %s --size %s", readExists, writeExists, directory, size)
+	if pattern := model.ActionFlags["pattern"]; pattern != "" {
+		if pattern != "seq" && pattern != "rand" {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: pattern is illegal, it must be seq or rand", pattern))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "pattern"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "pattern"))
+		}
+		flags = fmt.Sprintf("%s --pattern %s", flags, pattern)
+	}
+	for _, name := range []string{"block-size", "iodepth", "rw-mix", "fsync-every"} {
+		valueStr := model.ActionFlags[name]
+		if valueStr == "" {
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		illegal := err != nil || value <= 0
+		switch name {
+		case "block-size":
+			illegal = illegal || value%4 != 0
+		case "rw-mix":
+			illegal = err != nil || value < 0 || value > 100
+		case "fsync-every":
+			illegal = err != nil || value < 0
+		}
+		if illegal {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal", valueStr, name))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		flags = fmt.Sprintf("%s --%s %d", flags, name, value)
+	}
+	return be.start(ctx, flags)
 }
 
-func (be *BurnIOExecutor) start(ctx context.Context, read, write bool, directory, size string) *spec.Response {
+func (be *BurnIOExecutor) start(ctx context.Context, flags string) *spec.Response {
 	return be.channel.Run(ctx, path.Join(be.channel.GetScriptPath(), BurnIOBin),
-		fmt.Sprintf("--read=%t --write=%t --directory %s --size %s --start --debug=%t", read, write, directory, size, util.Debug))
+		fmt.Sprintf("%s --start --debug=%t", flags, util.Debug))
 }
 
 func (be *BurnIOExecutor) stop(ctx context.Context, read, write bool, directory string) *spec.Response {
@@ -0,0 +1,146 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+type DiskErrorActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewDiskErrorActionSpec() spec.ExpActionCommandSpec {
+	return &DiskErrorActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: []spec.ExpFlagSpec{
+				diskFaultPathFlag,
+				&spec.ExpFlag{
+					Name: "up-interval",
+					Desc: "The seconds the device works in each cycle, default value is 0 which means the device always fails",
+				},
+				&spec.ExpFlag{
+					Name: "down-interval",
+					Desc: "The seconds the device fails in each cycle, default value is 1",
+				},
+				&spec.ExpFlag{
+					Name:   "drop-writes",
+					Desc:   "Drop the writes silently instead of returning EIO when the device fails, the reads work",
+					NoArgs: true,
+				},
+				&spec.ExpFlag{
+					Name:   "error-writes",
+					Desc:   "Return EIO for the writes only when the device fails, the reads work",
+					NoArgs: true,
+				},
+			},
+			ActionExecutor: &DiskErrorActionExecutor{},
+			ActionExample: `
+# All the io of the LVM volume mounted at /data return EIO
+blade create disk error --path /data
+
+# The volume works for 30s and returns EIO for 10s in turns
+blade create disk error --path /data --up-interval 30 --down-interval 10
+
+# Drop the writes silently, which loses the data written
+blade create disk error --path /data --drop-writes`,
+			ActionPrograms:   []string{DiskFaultBin},
+			ActionCategories: []string{category.SystemDisk},
+		},
+	}
+}
+
+func (*DiskErrorActionSpec) Name() string {
+	return "error"
+}
+
+func (*DiskErrorActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*DiskErrorActionSpec) ShortDesc() string {
+	return "Fail the io of a device-mapper device"
+}
+
+func (d *DiskErrorActionSpec) LongDesc() string {
+	if d.ActionLongDesc != "" {
+		return d.ActionLongDesc
+	}
+	return "Replace the table of the device-mapper device of the path by a dm-flakey target stacked on the original table, " +
+		"so the io returns EIO or the writes are dropped in the down intervals. The original table is restored when the experiment is destroyed"
+}
+
+type DiskErrorActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*DiskErrorActionExecutor) Name() string {
+	return "error"
+}
+
+func (dee *DiskErrorActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if dee.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return stopDiskFault(ctx, dee.channel, uid)
+	}
+	if response := checkDiskFaultPath(uid, model.ActionFlags["path"]); response != nil {
+		return response
+	}
+	flags := fmt.Sprintf("--start --uid %s --target flakey --path %s --debug=%t", uid, model.ActionFlags["path"], util.Debug)
+	for _, name := range []string{"up-interval", "down-interval"} {
+		valueStr := model.ActionFlags[name]
+		if valueStr == "" {
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		if err != nil || value < 0 || (name == "down-interval" && value == 0) {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal, it must be a positive integer", valueStr, name))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		flags = fmt.Sprintf("%s --%s %d", flags, name, value)
+	}
+	dropWrites := model.ActionFlags["drop-writes"] == "true"
+	errorWrites := model.ActionFlags["error-writes"] == "true"
+	if dropWrites && errorWrites {
+		util.Errorf(uid, util.GetRunFuncName(), "drop-writes and error-writes cannot be specified at the same time")
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "drop-writes|error-writes"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "drop-writes|error-writes"))
+	}
+	if dropWrites {
+		flags = fmt.Sprintf("%s --drop-writes", flags)
+	} else if errorWrites {
+		flags = fmt.Sprintf("%s --error-writes", flags)
+	}
+	return dee.channel.Run(ctx, path.Join(dee.channel.GetScriptPath(), DiskFaultBin), flags)
+}
+
+func (dee *DiskErrorActionExecutor) SetChannel(channel spec.Channel) {
+	dee.channel = channel
+}
@@ -61,6 +61,23 @@ func NewFillActionSpec() spec.ExpActionCommandSpec {
 					Desc:   "Whether to retain the big file handle, default value is false.",
 					NoArgs: true,
 				},
+				&spec.ExpFlag{
+					Name: "rate",
+					Desc: "The growth rate of the file, unit is MB/s. The file is extended gradually in the background instead of being allocated at once",
+				},
+				&spec.ExpFlag{
+					Name: "duration",
+					Desc: "The seconds to grow the file to the size, the rate is calculated by it if the rate flag does not exist",
+				},
+				&spec.ExpFlag{
+					Name: "mode",
+					Desc: "Fill the bytes or the inodes of the disk, bytes or inodes, default value is bytes. " +
+						"The inodes mode creates empty files in a sharded directory tree, only the percent and count flags are supported",
+				},
+				&spec.ExpFlag{
+					Name: "count",
+					Desc: "The number of the inodes to create in the inodes mode. If count and percent flags exist, use percent first. The percent flag is the percentage of the inodes used",
+				},
 			},
 			ActionExecutor: &FillActionExecutor{},
 			ActionExample: `
@@ -71,7 +88,19 @@ blade create disk fill --path /home --size 40000
 Command: "blade c disk fill --path /home --percent 80 --retain-handle
 
 # Perform a fixed-size experimental scenario
-blade c disk fill --path /home --reserve 1024`,
+blade c disk fill --path /home --reserve 1024
+
+# Grow the file by 10MB/s until the disk usage is 95%, like a log growing slowly
+blade c disk fill --path /home --percent 95 --rate 10
+
+# Grow the file to 40G in 10 minutes
+blade c disk fill --path /home --size 40960 --duration 600
+
+# Exhaust 90% of the inodes of the disk, the bytes are nearly untouched
+blade c disk fill --path /home --mode inodes --percent 90
+
+# Create 100000 empty files to consume the inodes
+blade c disk fill --path /home --mode inodes --count 100000`,
 			ActionPrograms:   []string{FillDiskBin},
 			ActionCategories: []string{category.SystemDisk},
 		},
@@ -94,7 +123,8 @@ func (f *FillActionSpec) LongDesc() string {
 	if f.ActionLongDesc != "" {
 		return f.ActionLongDesc
 	}
-	return "Fill the specified directory path. If the path is not directory or does not exist, an error message will be returned."
+	return "Fill the specified directory path. If the path is not directory or does not exist, an error message will be returned. " +
+		"The inodes mode exhausts the inodes of the disk by the empty files instead of the bytes"
 }
 
 type FillActionExecutor struct {
@@ -123,7 +153,30 @@ func (fae *FillActionExecutor) Exec(uid string, ctx context.Context, model *spec
 	if _, ok := spec.IsDestroy(ctx); ok {
 		return fae.stop(directory, ctx)
 	} else {
+		switch model.ActionFlags["mode"] {
+		case "", "bytes":
+		case "inodes":
+			return fae.startInodes(uid, directory, model.ActionFlags["count"], model.ActionFlags["percent"], ctx)
+		default:
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: mode is illegal, it must be bytes or inodes", model.ActionFlags["mode"]))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "mode"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "mode"))
+		}
 		retainHandle := model.ActionFlags["retain-handle"] == "true"
+		growth := ""
+		for _, name := range []string{"rate", "duration"} {
+			valueStr := model.ActionFlags[name]
+			if valueStr == "" {
+				continue
+			}
+			value, err := strconv.Atoi(valueStr)
+			if err != nil || value <= 0 {
+				util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal, it must be positive integer", valueStr, name))
+				return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+					fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+			}
+			growth = fmt.Sprintf("%s --%s %d", growth, name, value)
+		}
 		percent := model.ActionFlags["percent"]
 		if percent == "" {
 			reserve := model.ActionFlags["reserve"]
@@ -139,7 +192,7 @@ func (fae *FillActionExecutor) Exec(uid string, ctx context.Context, model *spec
 					return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "size"),
 						fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "size"))
 				}
-				return fae.start(directory, size, percent, reserve, retainHandle, ctx)
+				return fae.start(directory, size, percent, reserve, retainHandle, growth, ctx)
 			}
 			_, err := strconv.Atoi(reserve)
 			if err != nil {
@@ -147,7 +200,7 @@ func (fae *FillActionExecutor) Exec(uid string, ctx context.Context, model *spec
 				return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "reserve"),
 					fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "reserve"))
 			}
-			return fae.start(directory, "", percent, reserve, retainHandle, ctx)
+			return fae.start(directory, "", percent, reserve, retainHandle, growth, ctx)
 		}
 		_, err := strconv.Atoi(percent)
 		if err != nil {
@@ -155,11 +208,12 @@ func (fae *FillActionExecutor) Exec(uid string, ctx context.Context, model *spec
 			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "percent"),
 				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "percent"))
 		}
-		return fae.start(directory, "", percent, "", retainHandle, ctx)
+		return fae.start(directory, "", percent, "", retainHandle, growth, ctx)
 	}
 }
 
-func (fae *FillActionExecutor) start(directory, size, percent, reserve string, retainHandle bool, ctx context.Context) *spec.Response {
+// start fills the disk at once, or grows the file gradually if the growth contains the rate or duration flags
+func (fae *FillActionExecutor) start(directory, size, percent, reserve string, retainHandle bool, growth string, ctx context.Context) *spec.Response {
 	flags := fmt.Sprintf("--directory %s --start --debug=%t --retain-handle=%t", directory, util.Debug, retainHandle)
 	if percent != "" {
 		flags = fmt.Sprintf("%s --percent %s", flags, percent)
@@ -168,6 +222,29 @@ func (fae *FillActionExecutor) start(directory, size, percent, reserve string, r
 	} else {
 		flags = fmt.Sprintf("%s --size %s", flags, size)
 	}
+	flags = flags + growth
+	return fae.channel.Run(ctx, path.Join(fae.channel.GetScriptPath(), FillDiskBin), flags)
+}
+
+// startInodes fills the inodes by the percent of the inodes used, or the count of the inodes to create
+func (fae *FillActionExecutor) startInodes(uid, directory, count, percent string, ctx context.Context) *spec.Response {
+	flags := fmt.Sprintf("--directory %s --start --debug=%t --mode inodes", directory, util.Debug)
+	name, value := "percent", percent
+	if percent == "" {
+		name, value = "count", count
+	}
+	if value == "" {
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "count|percent"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "count|percent"))
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil || v <= 0 || (name == "percent" && v > 100) {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal, it must be positive integer, "+
+			"and the percent must not be bigger than 100", value, name))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+	}
+	flags = fmt.Sprintf("%s --%s %d", flags, name, v)
 	return fae.channel.Run(ctx, path.Join(fae.channel.GetScriptPath(), FillDiskBin), flags)
 }
 
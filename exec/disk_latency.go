@@ -0,0 +1,162 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+// DiskFaultBin swaps in the dm-delay or dm-flakey target for the disk latency and disk error actions
+const DiskFaultBin = "chaos_diskfault"
+
+// diskFaultPathFlag is the path on the device-mapper device of the disk latency and disk error actions
+var diskFaultPathFlag = &spec.ExpFlag{
+	Name:     "path",
+	Desc:     "The path on the device-mapper device with the linear or striped table, for example the mount point of a LVM volume or a dm-linear over a loop device",
+	Required: true,
+}
+
+type DiskLatencyActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewDiskLatencyActionSpec() spec.ExpActionCommandSpec {
+	return &DiskLatencyActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: []spec.ExpFlagSpec{
+				diskFaultPathFlag,
+				&spec.ExpFlag{
+					Name: "read-delay",
+					Desc: "The delay of the reads, unit is ms",
+				},
+				&spec.ExpFlag{
+					Name: "write-delay",
+					Desc: "The delay of the writes, unit is ms",
+				},
+			},
+			ActionExecutor: &DiskLatencyActionExecutor{},
+			ActionExample: `
+# Delay the reads and the writes of the LVM volume mounted at /data by 100ms
+blade create disk latency --path /data --read-delay 100 --write-delay 100
+
+# Delay the writes only by 1s, the volume is a dm-linear over a loop device, for example:
+#   losetup /dev/loop0 /tmp/disk.img
+#   echo "0 $(blockdev --getsz /dev/loop0) linear /dev/loop0 0" | dmsetup create data
+#   mkfs.ext4 /dev/mapper/data && mount /dev/mapper/data /data
+blade create disk latency --path /data --write-delay 1000`,
+			ActionPrograms:   []string{DiskFaultBin},
+			ActionCategories: []string{category.SystemDisk},
+		},
+	}
+}
+
+func (*DiskLatencyActionSpec) Name() string {
+	return "latency"
+}
+
+func (*DiskLatencyActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*DiskLatencyActionSpec) ShortDesc() string {
+	return "Delay the io of a device-mapper device"
+}
+
+func (d *DiskLatencyActionSpec) LongDesc() string {
+	if d.ActionLongDesc != "" {
+		return d.ActionLongDesc
+	}
+	return "Replace the table of the device-mapper device of the path by a dm-delay target stacked on the original table, " +
+		"so the reads and the writes are delayed. The original table is restored when the experiment is destroyed"
+}
+
+type DiskLatencyActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*DiskLatencyActionExecutor) Name() string {
+	return "latency"
+}
+
+func (dle *DiskLatencyActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if dle.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return stopDiskFault(ctx, dle.channel, uid)
+	}
+	if response := checkDiskFaultPath(uid, model.ActionFlags["path"]); response != nil {
+		return response
+	}
+	flags := fmt.Sprintf("--start --uid %s --target delay --path %s --debug=%t", uid, model.ActionFlags["path"], util.Debug)
+	delayed := false
+	for _, name := range []string{"read-delay", "write-delay"} {
+		valueStr := model.ActionFlags[name]
+		if valueStr == "" {
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		if err != nil || value <= 0 {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal, it must be a positive integer", valueStr, name))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		flags = fmt.Sprintf("%s --%s %d", flags, name, value)
+		delayed = true
+	}
+	if !delayed {
+		util.Errorf(uid, util.GetRunFuncName(), "less read-delay and write-delay")
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "read-delay|write-delay"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "read-delay|write-delay"))
+	}
+	return dle.channel.Run(ctx, path.Join(dle.channel.GetScriptPath(), DiskFaultBin), flags)
+}
+
+func (dle *DiskLatencyActionExecutor) SetChannel(channel spec.Channel) {
+	dle.channel = channel
+}
+
+// checkDiskFaultPath returns a failed response if the path does not exist
+func checkDiskFaultPath(uid, p string) *spec.Response {
+	if p == "" {
+		util.Errorf(uid, util.GetRunFuncName(), "less path flag")
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "path"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "path"))
+	}
+	if !util.IsExist(p) {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: path is illegal, it does not exist", p))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "path"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "path"))
+	}
+	return nil
+}
+
+// stopDiskFault restores the original table of the device-mapper device by the backup of the experiment
+func stopDiskFault(ctx context.Context, channel spec.Channel, uid string) *spec.Response {
+	return channel.Run(ctx, path.Join(channel.GetScriptPath(), DiskFaultBin),
+		fmt.Sprintf("--stop --uid %s --debug=%t", uid, util.Debug))
+}
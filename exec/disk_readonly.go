@@ -0,0 +1,129 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const ReadonlyDiskBin = "chaos_readonlydisk"
+
+type DiskReadonlyActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewDiskReadonlyActionSpec() spec.ExpActionCommandSpec {
+	return &DiskReadonlyActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name:     "path",
+					Desc:     "The path to make read-only, the remount mode makes the whole filesystem of the path read-only",
+					Required: true,
+				},
+				&spec.ExpFlag{
+					Name: "mode",
+					Desc: "The way to make the path read-only, remount|bind, default value is remount. " +
+						"The remount mode remounts the filesystem of the path read-only, which fails if the files are opened for writing. " +
+						"The bind mode bind-mounts the directory read-only over itself, the rest of the filesystem is not touched",
+				},
+			},
+			ActionExecutor: &DiskReadonlyActionExecutor{},
+			ActionExample: `
+# Remount the filesystem of /data read-only
+blade create disk readonly --path /data
+
+# Make the /data/mysql directory read-only only, the files opened before are still writable
+blade create disk readonly --path /data/mysql --mode bind`,
+			ActionPrograms:   []string{ReadonlyDiskBin},
+			ActionCategories: []string{category.SystemDisk},
+		},
+	}
+}
+
+func (*DiskReadonlyActionSpec) Name() string {
+	return "readonly"
+}
+
+func (*DiskReadonlyActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*DiskReadonlyActionSpec) ShortDesc() string {
+	return "Make the filesystem read-only"
+}
+
+func (d *DiskReadonlyActionSpec) LongDesc() string {
+	if d.ActionLongDesc != "" {
+		return d.ActionLongDesc
+	}
+	return "Remount the filesystem of the path read-only, or bind-mount the directory read-only over itself, " +
+		"to simulate the filesystem went read-only after an error. The mount is restored read-write when the experiment is destroyed"
+}
+
+type DiskReadonlyActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*DiskReadonlyActionExecutor) Name() string {
+	return "readonly"
+}
+
+func (dre *DiskReadonlyActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if dre.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return dre.channel.Run(ctx, path.Join(dre.channel.GetScriptPath(), ReadonlyDiskBin),
+			fmt.Sprintf("--stop --uid %s --debug=%t", uid, util.Debug))
+	}
+	directory := model.ActionFlags["path"]
+	if directory == "" {
+		util.Errorf(uid, util.GetRunFuncName(), "less path flag")
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "path"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "path"))
+	}
+	if !util.IsExist(directory) {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: path is illegal, it does not exist", directory))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "path"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "path"))
+	}
+	mode := model.ActionFlags["mode"]
+	if mode == "" {
+		mode = "remount"
+	}
+	if mode != "remount" && mode != "bind" {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: mode is illegal, only support remount and bind", mode))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "mode"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "mode"))
+	}
+	return dre.channel.Run(ctx, path.Join(dre.channel.GetScriptPath(), ReadonlyDiskBin),
+		fmt.Sprintf("--start --uid %s --path %s --mode %s --debug=%t", uid, directory, mode, util.Debug))
+}
+
+func (dre *DiskReadonlyActionExecutor) SetChannel(channel spec.Channel) {
+	dre.channel = channel
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const ThrottleDiskBin = "chaos_throttledisk"
+
+// ioLimitFlags are the limits of the disk throttle action
+var ioLimitFlags = []string{"read-bps", "write-bps", "read-iops", "write-iops"}
+
+type DiskThrottleActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewDiskThrottleActionSpec() spec.ExpActionCommandSpec {
+	return &DiskThrottleActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "path",
+					Desc: "The path on the disk to throttle, the disk is detected by it, default value is /",
+				},
+				&spec.ExpFlag{
+					Name: "cgroup-path",
+					Desc: "The cgroup to throttle, for example /sys/fs/cgroup/blkio/docker/<id> or /docker/<id>",
+				},
+				&spec.ExpFlag{
+					Name: "target-pid",
+					Desc: "The process whose cgroup is throttled",
+				},
+				&spec.ExpFlag{
+					Name: "read-bps",
+					Desc: "The read bytes per second limit, unit is MB",
+				},
+				&spec.ExpFlag{
+					Name: "write-bps",
+					Desc: "The write bytes per second limit, unit is MB",
+				},
+				&spec.ExpFlag{
+					Name: "read-iops",
+					Desc: "The read io operations per second limit",
+				},
+				&spec.ExpFlag{
+					Name: "write-iops",
+					Desc: "The write io operations per second limit",
+				},
+			},
+			ActionExecutor: &DiskThrottleActionExecutor{},
+			ActionExample: `
+# Limit the write of the process 1234 to the disk of /home to 1MB/s
+blade create disk throttle --path /home --target-pid 1234 --write-bps 1
+
+# Limit the read and write iops of the cgroup to the disk of / to 100
+blade create disk throttle --cgroup-path /docker/1234 --read-iops 100 --write-iops 100`,
+			ActionPrograms:   []string{ThrottleDiskBin},
+			ActionCategories: []string{category.SystemDisk},
+		},
+	}
+}
+
+func (*DiskThrottleActionSpec) Name() string {
+	return "throttle"
+}
+
+func (*DiskThrottleActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*DiskThrottleActionSpec) ShortDesc() string {
+	return "Throttle the disk io of a cgroup"
+}
+
+func (d *DiskThrottleActionSpec) LongDesc() string {
+	if d.ActionLongDesc != "" {
+		return d.ActionLongDesc
+	}
+	return "Limit the bps and iops of the disk backing the path for the cgroup of the cgroup-path or the target-pid, " +
+		"io.max in cgroup v2 or blkio.throttle in v1. The disk of a partition is throttled. The limits must be below the existing ones. " +
+		"The original limits are restored when the experiment is destroyed"
+}
+
+type DiskThrottleActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*DiskThrottleActionExecutor) Name() string {
+	return "throttle"
+}
+
+func (dte *DiskThrottleActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if dte.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return dte.stop(ctx, uid)
+	}
+	directory := "/"
+	if p := model.ActionFlags["path"]; p != "" {
+		directory = p
+	}
+	if !util.IsExist(directory) {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: path is illegal, it does not exist", directory))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "path"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "path"))
+	}
+	cgroupPath := model.ActionFlags["cgroup-path"]
+	targetPid := model.ActionFlags["target-pid"]
+	if cgroupPath == "" && targetPid == "" {
+		util.Errorf(uid, util.GetRunFuncName(), "less cgroup-path and target-pid")
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "cgroup-path|target-pid"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "cgroup-path|target-pid"))
+	}
+	if response := checkCgroupTarget(uid, cgroupPath, targetPid); response != nil {
+		return response
+	}
+	flags := fmt.Sprintf("--start --uid %s --path %s --debug=%t", uid, directory, util.Debug)
+	if cgroupPath != "" {
+		flags = fmt.Sprintf("%s --cgroup-path %s", flags, cgroupPath)
+	} else {
+		flags = fmt.Sprintf("%s --target-pid %s", flags, targetPid)
+	}
+	limited := false
+	for _, name := range ioLimitFlags {
+		valueStr := model.ActionFlags[name]
+		if valueStr == "" {
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		if err != nil || value <= 0 {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal, it must be a positive integer", valueStr, name))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		flags = fmt.Sprintf("%s --%s %d", flags, name, value)
+		limited = true
+	}
+	if !limited {
+		util.Errorf(uid, util.GetRunFuncName(), "less read-bps, write-bps, read-iops and write-iops")
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "read-bps|write-bps|read-iops|write-iops"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "read-bps|write-bps|read-iops|write-iops"))
+	}
+	return dte.channel.Run(ctx, path.Join(dte.channel.GetScriptPath(), ThrottleDiskBin), flags)
+}
+
+func (dte *DiskThrottleActionExecutor) stop(ctx context.Context, uid string) *spec.Response {
+	return dte.channel.Run(ctx, path.Join(dte.channel.GetScriptPath(), ThrottleDiskBin),
+		fmt.Sprintf("--stop --uid %s --debug=%t", uid, util.Debug))
+}
+
+func (dte *DiskThrottleActionExecutor) SetChannel(channel spec.Channel) {
+	dte.channel = channel
+}
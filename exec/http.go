@@ -0,0 +1,151 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+)
+
+// HttpProxyBin for http abort, delay and modify experiments
+const HttpProxyBin = "chaos_httpproxy"
+
+type HttpCommandSpec struct {
+	spec.BaseExpModelCommandSpec
+}
+
+func NewHttpCommandSpec() spec.ExpModelCommandSpec {
+	return &HttpCommandSpec{
+		spec.BaseExpModelCommandSpec{
+			ExpActions: []spec.ExpActionCommandSpec{
+				NewHttpAbortActionSpec(),
+				NewHttpDelayActionSpec(),
+				NewHttpModifyActionSpec(),
+			},
+			ExpFlags: []spec.ExpFlagSpec{},
+		},
+	}
+}
+
+func (*HttpCommandSpec) Name() string {
+	return "http"
+}
+
+func (*HttpCommandSpec) ShortDesc() string {
+	return "Http experiment"
+}
+
+func (*HttpCommandSpec) LongDesc() string {
+	return "Http experiment, inject faults into http requests by a local proxy, for example abort, delay or modify the response"
+}
+
+var httpCommFlags = []spec.ExpFlagSpec{
+	&spec.ExpFlag{
+		Name:                  "port",
+		Desc:                  "The local port the proxy listens on, clients must send requests to this port",
+		Required:              true,
+		RequiredWhenDestroyed: true,
+	},
+	&spec.ExpFlag{
+		Name:     "upstream",
+		Desc:     "The upstream address which the proxy forwards requests to, for example 127.0.0.1:8080 or http://10.0.0.1:8080",
+		Required: true,
+	},
+	&spec.ExpFlag{
+		Name: "method",
+		Desc: "The request methods to match, separated by commas, for example GET,POST",
+	},
+	&spec.ExpFlag{
+		Name: "path",
+		Desc: "The regular expression of the request path to match, for example ^/api/v1/.*",
+	},
+	&spec.ExpFlag{
+		Name: "header",
+		Desc: "The request header to match, the format is key:value, multiple headers separated by commas, for example X-User:test",
+	},
+	&spec.ExpFlag{
+		Name: "host",
+		Desc: "The request host to match, for example www.example.com",
+	},
+	&spec.ExpFlag{
+		Name: "percent",
+		Desc: "The percent of the matched requests to inject, [0, 100], default value is 100",
+	},
+}
+
+// getHttpCommArgs validates the common http flags and appends them to args, a failed response is returned if they are illegal
+func getHttpCommArgs(uid string, model *spec.ExpModel, args string) (string, *spec.Response) {
+	upstream := model.ActionFlags["upstream"]
+	if upstream == "" {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "upstream"))
+		return "", spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "upstream"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "upstream"))
+	}
+	args = fmt.Sprintf("%s --upstream %s", args, ShellQuote(upstream))
+	if method := model.ActionFlags["method"]; method != "" {
+		args = fmt.Sprintf("%s --method %s", args, ShellQuote(method))
+	}
+	if urlPath := model.ActionFlags["path"]; urlPath != "" {
+		args = fmt.Sprintf("%s --path %s", args, ShellQuote(urlPath))
+	}
+	if header := model.ActionFlags["header"]; header != "" {
+		args = fmt.Sprintf("%s --header %s", args, ShellQuote(header))
+	}
+	if host := model.ActionFlags["host"]; host != "" {
+		args = fmt.Sprintf("%s --host %s", args, ShellQuote(host))
+	}
+	if percent := model.ActionFlags["percent"]; percent != "" {
+		p, err := strconv.Atoi(percent)
+		if err != nil || p < 0 || p > 100 {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: percent is illegal, it must be in [0, 100]", percent))
+			return "", spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "percent"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "percent"))
+		}
+		args = fmt.Sprintf("%s --percent %d", args, p)
+	}
+	return args, nil
+}
+
+// checkHttpPort returns the port flag value or a failed response if it is illegal
+func checkHttpPort(uid string, model *spec.ExpModel) (string, *spec.Response) {
+	port := model.ActionFlags["port"]
+	if port == "" {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "port"))
+		return "", spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "port"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "port"))
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: port is illegal, it must be a positive integer", port))
+		return "", spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "port"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "port"))
+	}
+	return port, nil
+}
+
+func startHttpProxy(ctx context.Context, channel spec.Channel, args string) *spec.Response {
+	return channel.Run(ctx, path.Join(channel.GetScriptPath(), HttpProxyBin), args)
+}
+
+func stopHttpProxy(ctx context.Context, channel spec.Channel, port string) *spec.Response {
+	return channel.Run(ctx, path.Join(channel.GetScriptPath(), HttpProxyBin),
+		fmt.Sprintf("--stop --port %s --debug=%t", port, util.Debug))
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+type HttpAbortActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewHttpAbortActionSpec() spec.ExpActionCommandSpec {
+	return &HttpAbortActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: httpCommFlags,
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "code",
+					Desc: "The http status code returned to the client, default value is 503",
+				},
+				&spec.ExpFlag{
+					Name:   "reset",
+					Desc:   "Forward the request, then close the connection after sending the response headers and part of the body",
+					NoArgs: true,
+				},
+			},
+			ActionExecutor: &HttpAbortExecutor{},
+			ActionExample: `
+# Requests sent to local port 9090 are forwarded to 127.0.0.1:8080, and the requests for /api/ return 503
+blade create http abort --port 9090 --upstream 127.0.0.1:8080 --path ^/api/
+
+# Return 500 for half of the POST requests which contain the X-User:test header
+blade create http abort --port 9090 --upstream 127.0.0.1:8080 --method POST --header X-User:test --code 500 --percent 50
+
+# Abort the response body of the matched requests
+blade create http abort --port 9090 --upstream 127.0.0.1:8080 --path ^/download --reset`,
+			ActionPrograms:   []string{HttpProxyBin},
+			ActionCategories: []string{category.SystemHttp},
+		},
+	}
+}
+
+func (*HttpAbortActionSpec) Name() string {
+	return "abort"
+}
+
+func (*HttpAbortActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*HttpAbortActionSpec) ShortDesc() string {
+	return "Abort http requests"
+}
+
+func (a *HttpAbortActionSpec) LongDesc() string {
+	if a.ActionLongDesc != "" {
+		return a.ActionLongDesc
+	}
+	return "Abort the matched http requests with the specified status code, or abort the response body"
+}
+
+type HttpAbortExecutor struct {
+	channel spec.Channel
+}
+
+func (*HttpAbortExecutor) Name() string {
+	return "abort"
+}
+
+func (hae *HttpAbortExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if hae.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	port, response := checkHttpPort(uid, model)
+	if response != nil {
+		return response
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return stopHttpProxy(ctx, hae.channel, port)
+	}
+	code := model.ActionFlags["code"]
+	if code == "" {
+		code = "503"
+	}
+	if c, err := strconv.Atoi(code); err != nil || c < 100 || c > 599 {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: code is illegal, it must be a http status code", code))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "code"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "code"))
+	}
+	args := fmt.Sprintf("--start --type abort --port %s --code %s --debug=%t", port, code, util.Debug)
+	if model.ActionFlags["reset"] == "true" {
+		args = fmt.Sprintf("%s --reset", args)
+	}
+	args, response = getHttpCommArgs(uid, model, args)
+	if response != nil {
+		return response
+	}
+	return startHttpProxy(ctx, hae.channel, args)
+}
+
+func (hae *HttpAbortExecutor) SetChannel(channel spec.Channel) {
+	hae.channel = channel
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+type HttpDelayActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewHttpDelayActionSpec() spec.ExpActionCommandSpec {
+	return &HttpDelayActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: httpCommFlags,
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name:     "time",
+					Desc:     "Delay time of the response, ms",
+					Required: true,
+				},
+				&spec.ExpFlag{
+					Name: "offset",
+					Desc: "Delay offset time, ms",
+				},
+			},
+			ActionExecutor: &HttpDelayExecutor{},
+			ActionExample: `
+# Requests sent to local port 9090 are forwarded to 127.0.0.1:8080, and the responses of GET requests are delayed by 3 seconds
+blade create http delay --port 9090 --upstream 127.0.0.1:8080 --method GET --time 3000
+
+# Delay the requests for the host www.example.com by 1 second, and the delay time fluctuates by 500 milliseconds
+blade create http delay --port 9090 --upstream 10.0.0.1:80 --host www.example.com --time 1000 --offset 500`,
+			ActionPrograms:   []string{HttpProxyBin},
+			ActionCategories: []string{category.SystemHttp},
+		},
+	}
+}
+
+func (*HttpDelayActionSpec) Name() string {
+	return "delay"
+}
+
+func (*HttpDelayActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*HttpDelayActionSpec) ShortDesc() string {
+	return "Delay http responses"
+}
+
+func (d *HttpDelayActionSpec) LongDesc() string {
+	if d.ActionLongDesc != "" {
+		return d.ActionLongDesc
+	}
+	return "Delay the responses of the matched http requests"
+}
+
+type HttpDelayExecutor struct {
+	channel spec.Channel
+}
+
+func (*HttpDelayExecutor) Name() string {
+	return "delay"
+}
+
+func (hde *HttpDelayExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if hde.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	port, response := checkHttpPort(uid, model)
+	if response != nil {
+		return response
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return stopHttpProxy(ctx, hde.channel, port)
+	}
+	time := model.ActionFlags["time"]
+	if time == "" {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "time"))
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "time"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "time"))
+	}
+	if _, err := strconv.Atoi(time); err != nil {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: time is illegal, it must be a positive integer", time))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "time"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "time"))
+	}
+	offset := model.ActionFlags["offset"]
+	if offset == "" {
+		offset = "0"
+	}
+	if _, err := strconv.Atoi(offset); err != nil {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: offset is illegal, it must be a positive integer", offset))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "offset"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "offset"))
+	}
+	args := fmt.Sprintf("--start --type delay --port %s --time %s --offset %s --debug=%t", port, time, offset, util.Debug)
+	args, response = getHttpCommArgs(uid, model, args)
+	if response != nil {
+		return response
+	}
+	return startHttpProxy(ctx, hde.channel, args)
+}
+
+func (hde *HttpDelayExecutor) SetChannel(channel spec.Channel) {
+	hde.channel = channel
+}
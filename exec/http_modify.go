@@ -0,0 +1,143 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+type HttpModifyActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewHttpModifyActionSpec() spec.ExpActionCommandSpec {
+	return &HttpModifyActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: httpCommFlags,
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "code",
+					Desc: "Replace the status code of the upstream response",
+				},
+				&spec.ExpFlag{
+					Name: "set-header",
+					Desc: "Set the response headers, the format is key:value, multiple headers separated by commas, for example Cache-Control:no-cache",
+				},
+				&spec.ExpFlag{
+					Name: "remove-header",
+					Desc: "Remove the response headers, multiple headers separated by commas, for example Content-Type,ETag",
+				},
+				&spec.ExpFlag{
+					Name: "body",
+					Desc: "Replace the body of the upstream response",
+				},
+			},
+			ActionExecutor: &HttpModifyExecutor{},
+			ActionExample: `
+# Requests sent to local port 9090 are forwarded to 127.0.0.1:8080, and the Content-Type response header of /api/ requests is removed
+blade create http modify --port 9090 --upstream 127.0.0.1:8080 --path ^/api/ --remove-header Content-Type
+
+# Replace the status code and body of the upstream response
+blade create http modify --port 9090 --upstream 127.0.0.1:8080 --code 429 --body "too many requests" --set-header Retry-After:30`,
+			ActionPrograms:   []string{HttpProxyBin},
+			ActionCategories: []string{category.SystemHttp},
+		},
+	}
+}
+
+func (*HttpModifyActionSpec) Name() string {
+	return "modify"
+}
+
+func (*HttpModifyActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*HttpModifyActionSpec) ShortDesc() string {
+	return "Modify http responses"
+}
+
+func (m *HttpModifyActionSpec) LongDesc() string {
+	if m.ActionLongDesc != "" {
+		return m.ActionLongDesc
+	}
+	return "Modify the status code, headers or body of the upstream responses for the matched http requests"
+}
+
+type HttpModifyExecutor struct {
+	channel spec.Channel
+}
+
+func (*HttpModifyExecutor) Name() string {
+	return "modify"
+}
+
+func (hme *HttpModifyExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if hme.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	port, response := checkHttpPort(uid, model)
+	if response != nil {
+		return response
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return stopHttpProxy(ctx, hme.channel, port)
+	}
+	code := model.ActionFlags["code"]
+	setHeader := model.ActionFlags["set-header"]
+	removeHeader := model.ActionFlags["remove-header"]
+	body, bodyExists := model.ActionFlags["body"]
+	if code == "" && setHeader == "" && removeHeader == "" && !bodyExists {
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "code|set-header|remove-header|body"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "code|set-header|remove-header|body"))
+	}
+	args := fmt.Sprintf("--start --type modify --port %s --debug=%t", port, util.Debug)
+	if code != "" {
+		if c, err := strconv.Atoi(code); err != nil || c < 100 || c > 599 {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: code is illegal, it must be a http status code", code))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "code"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "code"))
+		}
+		args = fmt.Sprintf("%s --code %s", args, code)
+	}
+	if setHeader != "" {
+		args = fmt.Sprintf("%s --set-header %s", args, ShellQuote(setHeader))
+	}
+	if removeHeader != "" {
+		args = fmt.Sprintf("%s --remove-header %s", args, ShellQuote(removeHeader))
+	}
+	if bodyExists {
+		args = fmt.Sprintf("%s --replace-body --body %s", args, ShellQuote(body))
+	}
+	args, response = getHttpCommArgs(uid, model, args)
+	if response != nil {
+		return response
+	}
+	return startHttpProxy(ctx, hme.channel, args)
+}
+
+func (hme *HttpModifyExecutor) SetChannel(channel spec.Channel) {
+	hme.channel = channel
+}
@@ -42,7 +42,34 @@ func NewMemCommandModelSpec() spec.ExpModelCommandSpec {
 				&MemLoadActionCommand{
 					spec.BaseExpActionCommandSpec{
 						ActionMatchers: []spec.ExpFlagSpec{},
-						ActionFlags:    []spec.ExpFlagSpec{},
+						ActionFlags: []spec.ExpFlagSpec{
+							&spec.ExpFlag{
+								Name: "hold",
+								Desc: "The seconds to hold the memory after it reaches the target, then the memory is released and burned again. " +
+									"The memory is held until the experiment is destroyed if hold, release-rate and cycles are all absent",
+							},
+							&spec.ExpFlag{
+								Name: "release-rate",
+								Desc: "The rate of releasing the memory after holding, unit is M/S, default value is the rate flag or 100",
+							},
+							&spec.ExpFlag{
+								Name: "cycles",
+								Desc: "The times of burning, holding and releasing the memory, 0 means repeating until the experiment is destroyed, default value is 0",
+							},
+							&spec.ExpFlag{
+								Name: "cgroup-path",
+								Desc: "The memory cgroup to burn memory in, for example /sys/fs/cgroup/memory/docker/<id> or /docker/<id>, the mem-percent is relative to the limit of the cgroup",
+							},
+							&spec.ExpFlag{
+								Name: "target-pid",
+								Desc: "Burn memory in the memory cgroup of the process, the mem-percent is relative to the limit of the cgroup",
+							},
+							&spec.ExpFlag{
+								Name:   "lock",
+								Desc:   "Lock the burned memory by mlock, so it stays resident and is never swapped out, only support for ram mode",
+								NoArgs: true,
+							},
+						},
 						ActionExecutor: &memExecutor{},
 						ActionExample: `
 # The execution memory footprint is 50%
@@ -58,11 +85,26 @@ blade create mem load --mode ram --mem-percent 50 --include-buffer-cache
 blade create mem load --mode ram --mem-percent 50 --timeout 200
 
 # 200M memory is reserved
-blade create mem load --mode ram --reserve 200 --rate 100`,
+blade create mem load --mode ram --reserve 200 --rate 100
+
+# Burn 80% memory at 200M/S, hold it for 30 seconds, release it at 50M/S, and repeat 5 times
+blade create mem load --mode ram --mem-percent 80 --rate 200 --hold 30 --release-rate 50 --cycles 5
+
+# Push the container of the process 1234 to 90% of its memory limit
+blade create mem load --mode ram --mem-percent 90 --target-pid 1234
+
+# The execution memory footprint is 80%, and the burned memory is never swapped out
+blade create mem load --mode ram --mem-percent 80 --lock`,
 						ActionPrograms:   []string{BurnMemBin},
 						ActionCategories: []string{category.SystemMem},
 					},
 				},
+				NewOomActionSpec(),
+				NewReclaimPressureActionSpec(),
+				NewPageCacheActionSpec(),
+				NewSwapActionSpec(),
+				NewHugepageActionSpec(),
+				NewShmActionSpec(),
 			},
 			ExpFlags: []spec.ExpFlagSpec{
 				&spec.ExpFlag{
@@ -77,12 +119,12 @@ blade create mem load --mode ram --reserve 200 --rate 100`,
 				},
 				&spec.ExpFlag{
 					Name:     "rate",
-					Desc:     "burn memory rate, unit is M/S, only support for ram mode.",
+					Desc:     "burn memory rate, unit is M/S, only support for ram mode. It is the rate of paging out for the swap action.",
 					Required: false,
 				},
 				&spec.ExpFlag{
 					Name:     "mode",
-					Desc:     "burn memory mode, cache or ram.",
+					Desc:     "burn memory mode, cache or ram. The page-cache action supports drop or thrash.",
 					Required: false,
 				},
 				&spec.ExpFlag{
@@ -109,7 +151,7 @@ func (*MemCommandModelSpec) ShortDesc() string {
 }
 
 func (*MemCommandModelSpec) LongDesc() string {
-	return "Mem experiment, for example load"
+	return "Mem experiment, for example load, oom, reclaim-pressure, page-cache, swap, hugepage and shm"
 }
 
 func (*MemCommandModelSpec) Example() string {
@@ -187,19 +229,19 @@ func (ce *memExecutor) Exec(uid string, ctx context.Context, model *spec.ExpMode
 		var err error
 		memPercent, err = strconv.Atoi(memPercentStr)
 		if err != nil {
-			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: mem-percent  must be a positive integer", memPercent))
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: mem-percent  must be a positive integer", memPercentStr))
 			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "mem-percent"),
 				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "mem-percent"))
 		}
 		if memPercent > 100 || memPercent < 0 {
-			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: mem-percent  must be a positive integer and not bigger than 100", memPercent))
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: mem-percent  must be a positive integer and not bigger than 100", memPercentStr))
 			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "mem-percent"),
 				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "mem-percent"))
 		}
 	} else if memReserveStr != "" {
 		memReserve, err = strconv.Atoi(memReserveStr)
 		if err != nil {
-			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: reserve  must be a positive integer", memReserve))
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: reserve  must be a positive integer", memReserveStr))
 			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "reserve"),
 				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "reserve"))
 
@@ -222,15 +264,71 @@ func (ce *memExecutor) Exec(uid string, ctx context.Context, model *spec.ExpMode
 				"--rate value must be a positive integer")
 		}
 	}
-	return ce.start(ctx, memPercent, memReserve, memRate, burnMemModeStr, includeBufferCache, isHost)
+
+	// the memory is burned, held and released by cycles if any of the flags exists
+	cycleFlags := map[string]int{}
+	for _, name := range []string{"hold", "release-rate", "cycles"} {
+		valueStr := model.ActionFlags[name]
+		if valueStr == "" {
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		if err != nil || value < 0 || (value == 0 && name == "release-rate") {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s must be a positive integer", valueStr, name))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		cycleFlags[name] = value
+	}
+	if len(cycleFlags) > 0 && cycleFlags["release-rate"] == 0 {
+		cycleFlags["release-rate"] = memRate
+		if memRate <= 0 {
+			cycleFlags["release-rate"] = 100
+		}
+	}
+
+	cgroupPath := model.ActionFlags["cgroup-path"]
+	targetPid := model.ActionFlags["target-pid"]
+	if response := checkCgroupTarget(uid, cgroupPath, targetPid); response != nil {
+		return response
+	}
+	lock := model.ActionFlags["lock"] == "true"
+	if lock && burnMemModeStr != "ram" {
+		util.Errorf(uid, util.GetRunFuncName(), "lock only supports the ram mode")
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "lock"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "lock"))
+	}
+	if isHost && (cgroupPath != "" || targetPid != "") {
+		util.Errorf(uid, util.GetRunFuncName(), "isHost cannot be specified with cgroup-path or target-pid")
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "isHost"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "isHost"))
+	}
+	return ce.start(ctx, memPercent, memReserve, memRate, burnMemModeStr, includeBufferCache, isHost,
+		cycleFlags["hold"], cycleFlags["release-rate"], cycleFlags["cycles"], cgroupPath, targetPid, lock)
+}
+
+// memCgroupFlags are the flags of the target memory cgroup
+var memCgroupFlags = []spec.ExpFlagSpec{
+	&spec.ExpFlag{
+		Name: "cgroup-path",
+		Desc: "The memory cgroup, for example /sys/fs/cgroup/memory/docker/<id> or /docker/<id>",
+	},
+	&spec.ExpFlag{
+		Name: "target-pid",
+		Desc: "The process whose memory cgroup is the target",
+	},
 }
 
 // start burn mem
-func (ce *memExecutor) start(ctx context.Context, memPercent, memReserve, memRate int, burnMemMode string, includeBufferCache bool, isHost bool) *spec.Response {
+func (ce *memExecutor) start(ctx context.Context, memPercent, memReserve, memRate int, burnMemMode string, includeBufferCache bool, isHost bool,
+	hold, releaseRate, cycles int, cgroupPath, targetPid string, lock bool) *spec.Response {
 	args := fmt.Sprintf("--start --mem-percent %d --reserve %d --debug=%t", memPercent, memReserve, util.Debug)
 	if memRate != 0 {
 		args = fmt.Sprintf("%s --rate %d", args, memRate)
 	}
+	if releaseRate > 0 {
+		args = fmt.Sprintf("%s --hold %d --release-rate %d --cycles %d", args, hold, releaseRate, cycles)
+	}
 	if burnMemMode != "" {
 		args = fmt.Sprintf("%s --mode %s", args, burnMemMode)
 	}
@@ -240,6 +338,15 @@ func (ce *memExecutor) start(ctx context.Context, memPercent, memReserve, memRat
 	if includeBufferCache {
 		args = fmt.Sprintf("%s --include-buffer-cache=%t", args, includeBufferCache)
 	}
+	if cgroupPath != "" {
+		args = fmt.Sprintf("%s --cgroup-path %s", args, cgroupPath)
+	}
+	if targetPid != "" {
+		args = fmt.Sprintf("%s --target-pid %s", args, targetPid)
+	}
+	if lock {
+		args = fmt.Sprintf("%s --lock", args)
+	}
 	return ce.channel.Run(ctx, path.Join(ce.channel.GetScriptPath(), BurnMemBin), args)
 }
 
@@ -0,0 +1,152 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const MemHugepageBin = "chaos_memhugepage"
+
+type HugepageActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewHugepageActionSpec() spec.ExpActionCommandSpec {
+	return &HugepageActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "count",
+					Desc: "The number of the hugepages to reserve",
+				},
+				&spec.ExpFlag{
+					Name: "percent",
+					Desc: "The percent of the free hugepages to reserve, (0, 100], default value is 100. The count flag is used first if it exists",
+				},
+				&spec.ExpFlag{
+					Name: "page-size",
+					Desc: "The size of the hugepages without the directory flag, unit is KB, for example 2048 or 1048576, default value is the default hugepage size",
+				},
+				&spec.ExpFlag{
+					Name: "directory",
+					Desc: "The mount point of a hugetlbfs, the hugepages are reserved by a file in it, otherwise they are reserved by MAP_HUGETLB",
+				},
+			},
+			ActionExecutor: &HugepageActionExecutor{},
+			ActionExample: `
+# Reserve all the free hugepages of the default size
+blade create mem hugepage
+
+# Reserve 50% of the free 1GB hugepages
+blade create mem hugepage --percent 50 --page-size 1048576
+
+# Reserve 100 hugepages by a file in the hugetlbfs mounted at /dev/hugepages
+blade create mem hugepage --count 100 --directory /dev/hugepages`,
+			ActionPrograms:   []string{MemHugepageBin},
+			ActionCategories: []string{category.SystemMem},
+		},
+	}
+}
+
+func (*HugepageActionSpec) Name() string {
+	return "hugepage"
+}
+
+func (*HugepageActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*HugepageActionSpec) ShortDesc() string {
+	return "Exhaust the free hugepages"
+}
+
+func (h *HugepageActionSpec) LongDesc() string {
+	if h.ActionLongDesc != "" {
+		return h.ActionLongDesc
+	}
+	return "Reserve the free hugepages through a file in the hugetlbfs or MAP_HUGETLB, so the services using HugeTLB fail " +
+		"to get the hugepages. The hugepages are released when the experiment is destroyed"
+}
+
+type HugepageActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*HugepageActionExecutor) Name() string {
+	return "hugepage"
+}
+
+func (he *HugepageActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if he.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	directory := model.ActionFlags["directory"]
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return he.stop(ctx, uid, directory)
+	}
+	args := fmt.Sprintf("--start --uid %s --debug=%t", uid, util.Debug)
+	if directory != "" {
+		if !util.IsDir(directory) {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: directory is illegal, is not a directory", directory))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "directory"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "directory"))
+		}
+		args = fmt.Sprintf("%s --directory %s", args, directory)
+	}
+	names := []string{"count", "page-size"}
+	if model.ActionFlags["count"] == "" {
+		names = []string{"percent", "page-size"}
+	}
+	for _, name := range names {
+		valueStr := model.ActionFlags[name]
+		if valueStr == "" {
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		if err != nil || value <= 0 || (name == "percent" && value > 100) {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal, it must be a positive integer, "+
+				"and the percent must not be bigger than 100", valueStr, name))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		args = fmt.Sprintf("%s --%s %d", args, name, value)
+	}
+	return he.channel.Run(ctx, path.Join(he.channel.GetScriptPath(), MemHugepageBin), args)
+}
+
+func (he *HugepageActionExecutor) stop(ctx context.Context, uid, directory string) *spec.Response {
+	args := fmt.Sprintf("--stop --uid %s --debug=%t", uid, util.Debug)
+	if directory != "" {
+		args = fmt.Sprintf("%s --directory %s", args, directory)
+	}
+	return he.channel.Run(ctx, path.Join(he.channel.GetScriptPath(), MemHugepageBin), args)
+}
+
+func (he *HugepageActionExecutor) SetChannel(channel spec.Channel) {
+	he.channel = channel
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const MemOomBin = "chaos_memoom"
+
+type OomActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewOomActionSpec() spec.ExpActionCommandSpec {
+	return &OomActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: append(memCgroupFlags,
+				&spec.ExpFlag{
+					Name: "burner-score-adj",
+					Desc: "The oom_score_adj of the burning process, [-1000, 1000]. 1000 makes the burning process the victim, " +
+						"-1000 protects it so the other processes in the cgroup are killed",
+				},
+				&spec.ExpFlag{
+					Name: "victim-score-adj",
+					Desc: "The oom_score_adj of the target-pid process, [-1000, 1000], 1000 makes it the first victim of the oom killer",
+				},
+			),
+			ActionExecutor: &OomActionExecutor{},
+			ActionExample: `
+# Drive the memory cgroup of the process 1234 past its limit, and make the process the victim
+blade create mem oom --target-pid 1234 --victim-score-adj 1000
+
+# Drive the cgroup past its limit at 500M/S, the burning process is protected so the others are killed
+blade create mem oom --cgroup-path /docker/<id> --rate 500 --burner-score-adj -1000`,
+			ActionPrograms:   []string{MemOomBin},
+			ActionCategories: []string{category.SystemMem},
+		},
+	}
+}
+
+func (*OomActionSpec) Name() string {
+	return "oom"
+}
+
+func (*OomActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*OomActionSpec) ShortDesc() string {
+	return "Trigger the oom killer in a memory cgroup"
+}
+
+func (o *OomActionSpec) LongDesc() string {
+	if o.ActionLongDesc != "" {
+		return o.ActionLongDesc
+	}
+	return "Join the memory cgroup of the cgroup-path or the target-pid and allocate memory at the rate flag until the limit " +
+		"of the cgroup is exceeded, so the oom killer picks a victim. The oom_score_adj of the burning process and the target " +
+		"process can be set to choose the victim. The oom_score_adj is restored and the oom killed processes are reported " +
+		"when the experiment is destroyed"
+}
+
+type OomActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*OomActionExecutor) Name() string {
+	return "oom"
+}
+
+func (oae *OomActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if oae.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return oae.stop(ctx, uid)
+	}
+	cgroupPath := model.ActionFlags["cgroup-path"]
+	targetPid := model.ActionFlags["target-pid"]
+	if cgroupPath == "" && targetPid == "" {
+		util.Errorf(uid, util.GetRunFuncName(), "less cgroup-path and target-pid")
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "cgroup-path|target-pid"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "cgroup-path|target-pid"))
+	}
+	if response := checkCgroupTarget(uid, cgroupPath, targetPid); response != nil {
+		return response
+	}
+	flags := fmt.Sprintf("--start --uid %s --debug=%t", uid, util.Debug)
+	if cgroupPath != "" {
+		flags = fmt.Sprintf("%s --cgroup-path %s", flags, cgroupPath)
+	} else {
+		flags = fmt.Sprintf("%s --target-pid %s", flags, targetPid)
+	}
+	if rate := model.ActionFlags["rate"]; rate != "" {
+		if r, err := strconv.Atoi(rate); err != nil || r <= 0 {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: rate is illegal, it must be a positive integer", rate))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "rate"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "rate"))
+		}
+		flags = fmt.Sprintf("%s --rate %s", flags, rate)
+	}
+	for _, name := range []string{"burner-score-adj", "victim-score-adj"} {
+		value := model.ActionFlags[name]
+		if value == "" {
+			continue
+		}
+		if adj, err := strconv.Atoi(value); err != nil || adj < -1000 || adj > 1000 || (name == "victim-score-adj" && targetPid == "") {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal, it must be in [-1000, 1000], "+
+				"and victim-score-adj needs the target-pid flag", value, name))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		flags = fmt.Sprintf("%s --%s %s", flags, name, value)
+	}
+	return oae.channel.Run(ctx, path.Join(oae.channel.GetScriptPath(), MemOomBin), flags)
+}
+
+func (oae *OomActionExecutor) stop(ctx context.Context, uid string) *spec.Response {
+	return oae.channel.Run(ctx, path.Join(oae.channel.GetScriptPath(), MemOomBin),
+		fmt.Sprintf("--stop --uid %s --debug=%t", uid, util.Debug))
+}
+
+func (oae *OomActionExecutor) SetChannel(channel spec.Channel) {
+	oae.channel = channel
+}
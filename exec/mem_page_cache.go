@@ -0,0 +1,174 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const PageCacheBin = "chaos_pagecache"
+
+const (
+	DropPageCacheMode   = "drop"
+	ThrashPageCacheMode = "thrash"
+)
+
+type PageCacheActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewPageCacheActionSpec() spec.ExpActionCommandSpec {
+	return &PageCacheActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "interval",
+					Desc: "The seconds between two drops in the drop mode, default value is 10",
+				},
+				&spec.ExpFlag{
+					Name: "drop-level",
+					Desc: "The value written to /proc/sys/vm/drop_caches in the drop mode, 1 drops the page cache, " +
+						"2 drops the dentries and inodes, 3 drops both, default value is 1",
+				},
+				&spec.ExpFlag{
+					Name: "directory",
+					Desc: "The directory where the working set file is created in the thrash mode, it must be on a disk but not a tmpfs, required by the thrash mode",
+				},
+				&spec.ExpFlag{
+					Name: "size",
+					Desc: "The size of the working set file in the thrash mode, unit is MB, it must leave 10% of the filesystem free, " +
+						"default value is the total memory bounded by the free space",
+				},
+			},
+			ActionExecutor: &PageCacheActionExecutor{},
+			ActionExample: `
+# Drop the page cache every 10 seconds
+blade create mem page-cache --mode drop
+
+# Drop the page cache, the dentries and the inodes every 30 seconds
+blade create mem page-cache --mode drop --interval 30 --drop-level 3
+
+# Stream read a working set as large as the memory under /data, which pushes the other files out of the page cache
+blade create mem page-cache --mode thrash --directory /data`,
+			ActionPrograms:   []string{PageCacheBin},
+			ActionCategories: []string{category.SystemMem},
+		},
+	}
+}
+
+func (*PageCacheActionSpec) Name() string {
+	return "page-cache"
+}
+
+func (*PageCacheActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*PageCacheActionSpec) ShortDesc() string {
+	return "Drop or thrash the page cache"
+}
+
+func (p *PageCacheActionSpec) LongDesc() string {
+	if p.ActionLongDesc != "" {
+		return p.ActionLongDesc
+	}
+	return "Evict the hot file pages of the services to measure the cold cache latency. The drop mode drops the caches " +
+		"periodically, the thrash mode stream reads a working set file like a competing reader, which pushes the other " +
+		"files out of the page cache. The working set file is removed when the experiment is destroyed"
+}
+
+type PageCacheActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*PageCacheActionExecutor) Name() string {
+	return "page-cache"
+}
+
+func (pce *PageCacheActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if pce.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	directory := model.ActionFlags["directory"]
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return pce.stop(ctx, uid, directory)
+	}
+	mode := model.ActionFlags["mode"]
+	if mode == "" {
+		mode = DropPageCacheMode
+	}
+	args := fmt.Sprintf("--start --uid %s --debug=%t --mode %s", uid, util.Debug, mode)
+	var names []string
+	switch mode {
+	case DropPageCacheMode:
+		names = []string{"interval", "drop-level"}
+	case ThrashPageCacheMode:
+		if directory == "" {
+			util.Errorf(uid, util.GetRunFuncName(), "directory is required by the thrash mode")
+			return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "directory"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "directory"))
+		}
+		if !util.IsDir(directory) {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: directory is illegal, is not a directory", directory))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "directory"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "directory"))
+		}
+		args = fmt.Sprintf("%s --directory %s", args, directory)
+		names = []string{"size"}
+	default:
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: mode is illegal, it must be drop or thrash", mode))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "mode"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "mode"))
+	}
+	for _, name := range names {
+		valueStr := model.ActionFlags[name]
+		if valueStr == "" {
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		if err != nil || value <= 0 || (name == "drop-level" && value > 3) {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal, it must be a positive integer, "+
+				"and the drop-level must be 1, 2 or 3", valueStr, name))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		args = fmt.Sprintf("%s --%s %d", args, name, value)
+	}
+	return pce.channel.Run(ctx, path.Join(pce.channel.GetScriptPath(), PageCacheBin), args)
+}
+
+func (pce *PageCacheActionExecutor) stop(ctx context.Context, uid, directory string) *spec.Response {
+	args := fmt.Sprintf("--stop --uid %s --debug=%t", uid, util.Debug)
+	if directory != "" {
+		args = fmt.Sprintf("%s --directory %s", args, directory)
+	}
+	return pce.channel.Run(ctx, path.Join(pce.channel.GetScriptPath(), PageCacheBin), args)
+}
+
+func (pce *PageCacheActionExecutor) SetChannel(channel spec.Channel) {
+	pce.channel = channel
+}
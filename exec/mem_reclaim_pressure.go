@@ -0,0 +1,143 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const ReclaimPressureBin = "chaos_reclaimpressure"
+
+type ReclaimPressureActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewReclaimPressureActionSpec() spec.ExpActionCommandSpec {
+	return &ReclaimPressureActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: append(memCgroupFlags,
+				&spec.ExpFlag{
+					Name: "high",
+					Desc: "The memory.high to set, unit is MB, it must be lower than the current memory.high",
+				},
+				&spec.ExpFlag{
+					Name: "high-percent",
+					Desc: "The memory.high to set, percent of the current memory usage of the cgroup, (0, 100], default value is 80. " +
+						"The high flag is used first if it exists",
+				},
+			),
+			ActionExecutor: &ReclaimPressureActionExecutor{},
+			ActionExample: `
+# Lower the memory.high of the cgroup of the process 1234 to 80% of its usage
+blade create mem reclaim-pressure --target-pid 1234
+
+# Lower the memory.high of the cgroup to 256MB
+blade create mem reclaim-pressure --cgroup-path /kubepods/pod1234 --high 256`,
+			ActionPrograms:   []string{ReclaimPressureBin},
+			ActionCategories: []string{category.SystemMem},
+		},
+	}
+}
+
+func (*ReclaimPressureActionSpec) Name() string {
+	return "reclaim-pressure"
+}
+
+func (*ReclaimPressureActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*ReclaimPressureActionSpec) ShortDesc() string {
+	return "Throttle a memory cgroup by lowering memory.high"
+}
+
+func (r *ReclaimPressureActionSpec) LongDesc() string {
+	if r.ActionLongDesc != "" {
+		return r.ActionLongDesc
+	}
+	return "Lower the memory.high of the memory cgroup of the cgroup-path or the target-pid, so the processes in it are " +
+		"throttled and forced into direct reclaim without being oom killed. Only cgroup v2 is supported. " +
+		"The original memory.high is restored when the experiment is destroyed"
+}
+
+type ReclaimPressureActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*ReclaimPressureActionExecutor) Name() string {
+	return "reclaim-pressure"
+}
+
+func (rpe *ReclaimPressureActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if rpe.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return rpe.stop(ctx, uid)
+	}
+	cgroupPath := model.ActionFlags["cgroup-path"]
+	targetPid := model.ActionFlags["target-pid"]
+	if cgroupPath == "" && targetPid == "" {
+		util.Errorf(uid, util.GetRunFuncName(), "less cgroup-path and target-pid")
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "cgroup-path|target-pid"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "cgroup-path|target-pid"))
+	}
+	if response := checkCgroupTarget(uid, cgroupPath, targetPid); response != nil {
+		return response
+	}
+	flags := fmt.Sprintf("--start --uid %s --debug=%t", uid, util.Debug)
+	if cgroupPath != "" {
+		flags = fmt.Sprintf("%s --cgroup-path %s", flags, cgroupPath)
+	} else {
+		flags = fmt.Sprintf("%s --target-pid %s", flags, targetPid)
+	}
+	if high := model.ActionFlags["high"]; high != "" {
+		if h, err := strconv.Atoi(high); err != nil || h <= 0 {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: high is illegal, it must be a positive integer", high))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "high"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "high"))
+		}
+		flags = fmt.Sprintf("%s --high %s", flags, high)
+	} else if highPercent := model.ActionFlags["high-percent"]; highPercent != "" {
+		if p, err := strconv.Atoi(highPercent); err != nil || p <= 0 || p > 100 {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: high-percent is illegal, it must be in (0, 100]", highPercent))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "high-percent"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "high-percent"))
+		}
+		flags = fmt.Sprintf("%s --high-percent %s", flags, highPercent)
+	}
+	return rpe.channel.Run(ctx, path.Join(rpe.channel.GetScriptPath(), ReclaimPressureBin), flags)
+}
+
+func (rpe *ReclaimPressureActionExecutor) stop(ctx context.Context, uid string) *spec.Response {
+	return rpe.channel.Run(ctx, path.Join(rpe.channel.GetScriptPath(), ReclaimPressureBin),
+		fmt.Sprintf("--stop --uid %s --debug=%t", uid, util.Debug))
+}
+
+func (rpe *ReclaimPressureActionExecutor) SetChannel(channel spec.Channel) {
+	rpe.channel = channel
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const MemShmBin = "chaos_memshm"
+
+type ShmActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewShmActionSpec() spec.ExpActionCommandSpec {
+	return &ShmActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "size",
+					Desc: "The size to fill, unit is MB",
+				},
+				&spec.ExpFlag{
+					Name: "percent",
+					Desc: "The percent of the tmpfs to fill up to, (0, 100], default value is 100. The size flag is used first if it exists",
+				},
+				&spec.ExpFlag{
+					Name: "directory",
+					Desc: "The mount point of the tmpfs, default value is /dev/shm",
+				},
+			},
+			ActionExecutor: &ShmActionExecutor{},
+			ActionExample: `
+# Fill up /dev/shm
+blade create mem shm
+
+# Fill /dev/shm up to 80% of its size
+blade create mem shm --percent 80
+
+# Fill 512MB in the tmpfs mounted at /run/app
+blade create mem shm --size 512 --directory /run/app`,
+			ActionPrograms:   []string{MemShmBin},
+			ActionCategories: []string{category.SystemMem},
+		},
+	}
+}
+
+func (*ShmActionSpec) Name() string {
+	return "shm"
+}
+
+func (*ShmActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*ShmActionSpec) ShortDesc() string {
+	return "Fill the shared memory"
+}
+
+func (s *ShmActionSpec) LongDesc() string {
+	if s.ActionLongDesc != "" {
+		return s.ActionLongDesc
+	}
+	return "Fill /dev/shm or the tmpfs of the directory flag by a file, so the services using the shared memory fail to " +
+		"allocate it. The file is removed when the experiment is destroyed"
+}
+
+type ShmActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*ShmActionExecutor) Name() string {
+	return "shm"
+}
+
+func (se *ShmActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if se.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	directory := "/dev/shm"
+	if dir := model.ActionFlags["directory"]; dir != "" {
+		directory = dir
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return se.stop(ctx, uid, directory)
+	}
+	if !util.IsDir(directory) {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: directory is illegal, is not a directory", directory))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "directory"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "directory"))
+	}
+	args := fmt.Sprintf("--start --uid %s --directory %s --debug=%t", uid, directory, util.Debug)
+	name := "size"
+	if model.ActionFlags[name] == "" {
+		name = "percent"
+	}
+	if valueStr := model.ActionFlags[name]; valueStr != "" {
+		value, err := strconv.Atoi(valueStr)
+		if err != nil || value <= 0 || (name == "percent" && value > 100) {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal, it must be a positive integer, "+
+				"and the percent must not be bigger than 100", valueStr, name))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		args = fmt.Sprintf("%s --%s %d", args, name, value)
+	}
+	return se.channel.Run(ctx, path.Join(se.channel.GetScriptPath(), MemShmBin), args)
+}
+
+func (se *ShmActionExecutor) stop(ctx context.Context, uid, directory string) *spec.Response {
+	return se.channel.Run(ctx, path.Join(se.channel.GetScriptPath(), MemShmBin),
+		fmt.Sprintf("--stop --uid %s --directory %s --debug=%t", uid, directory, util.Debug))
+}
+
+func (se *ShmActionExecutor) SetChannel(channel spec.Channel) {
+	se.channel = channel
+}
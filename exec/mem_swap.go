@@ -0,0 +1,134 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const MemSwapBin = "chaos_memswap"
+
+type SwapActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewSwapActionSpec() spec.ExpActionCommandSpec {
+	return &SwapActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name:     "size",
+					Desc:     "The size of the anonymous memory pushed into swap, unit is MB",
+					Required: true,
+				},
+				&spec.ExpFlag{
+					Name:   "storm",
+					Desc:   "Touch the swapped pages and page them out again continuously, which causes a swap storm",
+					NoArgs: true,
+				},
+			},
+			ActionExecutor: &SwapActionExecutor{},
+			ActionExample: `
+# Push 1024MB anonymous memory into swap at 100M/S
+blade create mem swap --size 1024
+
+# Swap 512MB anonymous memory in and out continuously at 200M/S
+blade create mem swap --size 512 --rate 200 --storm`,
+			ActionPrograms:   []string{MemSwapBin},
+			ActionCategories: []string{category.SystemMem},
+		},
+	}
+}
+
+func (*SwapActionSpec) Name() string {
+	return "swap"
+}
+
+func (*SwapActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*SwapActionSpec) ShortDesc() string {
+	return "Push anonymous memory into swap"
+}
+
+func (s *SwapActionSpec) LongDesc() string {
+	if s.ActionLongDesc != "" {
+		return s.ActionLongDesc
+	}
+	return "Allocate the anonymous memory of the size and page it out to swap at the rate flag by madvise MADV_PAGEOUT, " +
+		"which needs linux 5.4 or later and enabled swap. The swapped memory is freed when the experiment is destroyed"
+}
+
+type SwapActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*SwapActionExecutor) Name() string {
+	return "swap"
+}
+
+func (se *SwapActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if se.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return se.stop(ctx, uid)
+	}
+	args := fmt.Sprintf("--start --uid %s --debug=%t", uid, util.Debug)
+	for _, name := range []string{"size", "rate"} {
+		valueStr := model.ActionFlags[name]
+		if valueStr == "" {
+			if name == "size" {
+				util.Errorf(uid, util.GetRunFuncName(), "less size flag")
+				return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, name),
+					fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, name))
+			}
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		if err != nil || value <= 0 {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal, it must be a positive integer", valueStr, name))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		args = fmt.Sprintf("%s --%s %d", args, name, value)
+	}
+	if model.ActionFlags["storm"] == "true" {
+		args = fmt.Sprintf("%s --storm", args)
+	}
+	return se.channel.Run(ctx, path.Join(se.channel.GetScriptPath(), MemSwapBin), args)
+}
+
+func (se *SwapActionExecutor) stop(ctx context.Context, uid string) *spec.Response {
+	return se.channel.Run(ctx, path.Join(se.channel.GetScriptPath(), MemSwapBin),
+		fmt.Sprintf("--stop --uid %s --debug=%t", uid, util.Debug))
+}
+
+func (se *SwapActionExecutor) SetChannel(channel spec.Channel) {
+	se.channel = channel
+}
@@ -40,6 +40,9 @@ func NewNetworkCommandSpec() spec.ExpModelCommandSpec {
 				NewCorruptActionSpec(),
 				NewReorderActionSpec(),
 				NewOccupyActionSpec(),
+				NewBandwidthHogActionSpec(),
+				NewConntrackActionSpec(),
+				NewMtuActionSpec(),
 			},
 			ExpFlags: []spec.ExpFlagSpec{},
 		},
@@ -78,6 +81,10 @@ var commFlags = []spec.ExpFlagSpec{
 		Name: "destination-ip",
 		Desc: "destination ip. Support for using mask to specify the ip range such as 92.168.1.0/24 or comma separated multiple ips, for example 10.0.0.1,11.0.0.1.",
 	},
+	&spec.ExpFlag{
+		Name: "destination-domain",
+		Desc: "destination domain. The domain is resolved to the A and AAAA records when the experiment is created, and re-resolved periodically to follow the changes. Support for comma separated multiple domains, for example www.example.com,api.example.com",
+	},
 	&spec.ExpFlag{
 		Name:   "ignore-peer-port",
 		Desc:   "ignore excluding all ports communicating with this port, generally used when the ss command does not exist",
@@ -100,7 +107,21 @@ var commFlags = []spec.ExpFlagSpec{
 	},
 }
 
-func getCommArgs(localPort, remotePort, excludePort, destinationIp, excludeIp string,
+// getCommFlagsByName returns the common flags with the names, so that other actions share the same matcher vocabulary
+func getCommFlagsByName(names ...string) []spec.ExpFlagSpec {
+	flags := make([]spec.ExpFlagSpec, 0, len(names))
+	for _, name := range names {
+		for _, flag := range commFlags {
+			if flag.FlagName() == name {
+				flags = append(flags, flag)
+				break
+			}
+		}
+	}
+	return flags
+}
+
+func getCommArgs(localPort, remotePort, excludePort, destinationIp, destinationDomain, excludeIp string,
 	args string, ignorePeerPort, force bool) (string, error) {
 	if localPort != "" {
 		localPorts, err := util.ParseIntegerListToStringSlice("local-port", localPort)
@@ -126,6 +147,9 @@ func getCommArgs(localPort, remotePort, excludePort, destinationIp, excludeIp st
 	if destinationIp != "" {
 		args = fmt.Sprintf("%s --destination-ip %s", args, destinationIp)
 	}
+	if destinationDomain != "" {
+		args = fmt.Sprintf("%s --destination-domain %s", args, destinationDomain)
+	}
 	if excludeIp != "" {
 		args = fmt.Sprintf("%s --exclude-ip %s", args, excludeIp)
 	}
@@ -0,0 +1,178 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+const BandwidthHogBin = "chaos_bandwidthhog"
+
+type BandwidthHogActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewBandwidthHogActionSpec() spec.ExpActionCommandSpec {
+	return &BandwidthHogActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name:                  "destination-ip",
+					Desc:                  "The ip of the target which the traffic is sent to",
+					Required:              true,
+					RequiredWhenDestroyed: true,
+				},
+				&spec.ExpFlag{
+					Name: "destination-port",
+					Desc: "The port of the target which the traffic is sent to, default value is 5001",
+				},
+			},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "protocol",
+					Desc: "The protocol of the traffic, udp or tcp, default value is udp. The tcp protocol needs a listening port on the target",
+				},
+				&spec.ExpFlag{
+					Name: "rate",
+					Desc: "The total sending rate, unit is Mbit/s, 0 means unlimited, default value is 0",
+				},
+				&spec.ExpFlag{
+					Name: "parallel",
+					Desc: "The number of parallel flows, default value is 1",
+				},
+				&spec.ExpFlag{
+					Name: "packet-size",
+					Desc: "The size of each packet or write, unit is byte, default value is 1400",
+				},
+			},
+			ActionExecutor: &BandwidthHogActionExecutor{},
+			ActionExample: `
+# Flood 10.0.0.2 with udp traffic as fast as possible
+blade create network bandwidth-hog --destination-ip 10.0.0.2
+
+# Send 500Mbit/s tcp traffic to the port 5201 of 10.0.0.2 by 4 flows
+blade create network bandwidth-hog --destination-ip 10.0.0.2 --destination-port 5201 --protocol tcp --rate 500 --parallel 4`,
+			ActionPrograms:   []string{BandwidthHogBin},
+			ActionCategories: []string{category.SystemNetwork},
+		},
+	}
+}
+
+func (*BandwidthHogActionSpec) Name() string {
+	return "bandwidth-hog"
+}
+
+func (*BandwidthHogActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*BandwidthHogActionSpec) ShortDesc() string {
+	return "Saturate the link to the target"
+}
+
+func (b *BandwidthHogActionSpec) LongDesc() string {
+	if b.ActionLongDesc != "" {
+		return b.ActionLongDesc
+	}
+	return "Flood the target with udp or tcp traffic, so that the other flows on the same network interface compete for bandwidth"
+}
+
+type BandwidthHogActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*BandwidthHogActionExecutor) Name() string {
+	return "bandwidth-hog"
+}
+
+func (bhe *BandwidthHogActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if bhe.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	destinationIp := model.ActionFlags["destination-ip"]
+	if destinationIp == "" {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "destination-ip"))
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "destination-ip"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "destination-ip"))
+	}
+	if net.ParseIP(destinationIp) == nil {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: destination-ip is illegal", destinationIp))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "destination-ip"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "destination-ip"))
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return bhe.stop(ctx, destinationIp)
+	}
+	protocol := model.ActionFlags["protocol"]
+	if protocol == "" {
+		protocol = "udp"
+	}
+	if protocol != "udp" && protocol != "tcp" {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: protocol is illegal, it must be udp or tcp", protocol))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "protocol"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "protocol"))
+	}
+	values := map[string]int{
+		"destination-port": 5001,
+		"rate":             0,
+		"parallel":         1,
+		"packet-size":      1400,
+	}
+	for _, name := range []string{"destination-port", "rate", "parallel", "packet-size"} {
+		valueStr := model.ActionFlags[name]
+		if valueStr == "" {
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		if err != nil || value < 0 || (value == 0 && name != "rate") {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: %s is illegal, it must be a positive integer", valueStr, name))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		values[name] = value
+	}
+	if values["destination-port"] > 65535 || values["packet-size"] > 65507 {
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "destination-port|packet-size"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "destination-port|packet-size"))
+	}
+	return bhe.start(ctx, destinationIp, protocol, values["destination-port"], values["rate"], values["parallel"], values["packet-size"])
+}
+
+func (bhe *BandwidthHogActionExecutor) start(ctx context.Context, destinationIp, protocol string, destinationPort, rate, parallel, packetSize int) *spec.Response {
+	return bhe.channel.Run(ctx, path.Join(bhe.channel.GetScriptPath(), BandwidthHogBin),
+		fmt.Sprintf("--start --destination-ip %s --destination-port %d --protocol %s --rate %d --parallel %d --packet-size %d --debug=%t",
+			destinationIp, destinationPort, protocol, rate, parallel, packetSize, util.Debug))
+}
+
+func (bhe *BandwidthHogActionExecutor) stop(ctx context.Context, destinationIp string) *spec.Response {
+	return bhe.channel.Run(ctx, path.Join(bhe.channel.GetScriptPath(), BandwidthHogBin),
+		fmt.Sprintf("--stop --destination-ip %s --debug=%t", destinationIp, util.Debug))
+}
+
+func (bhe *BandwidthHogActionExecutor) SetChannel(channel spec.Channel) {
+	bhe.channel = channel
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+// ConntrackBin for network conntrack experiment
+const ConntrackBin = "chaos_conntrack"
+
+const (
+	ConntrackFillMode  = "fill"
+	ConntrackFlushMode = "flush"
+)
+
+type ConntrackActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewConntrackActionSpec() spec.ExpActionCommandSpec {
+	matchers := getCommFlagsByName("local-port", "remote-port", "exclude-port", "destination-ip", "exclude-ip")
+	matchers = append(matchers, &spec.ExpFlag{
+		Name: "protocol",
+		Desc: "The protocol of the entries, tcp or udp. The default value is udp in fill mode, and all protocols in flush mode",
+	})
+	return &ConntrackActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: matchers,
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name:                  "mode",
+					Desc:                  "The mode of the experiment, fill or flush. The fill mode creates flows to the --destination-ip until the conntrack table reaches the --percent of nf_conntrack_max, the flush mode deletes the entries matched",
+					Required:              true,
+					RequiredWhenDestroyed: true,
+				},
+				&spec.ExpFlag{
+					Name: "percent",
+					Desc: "The percent of nf_conntrack_max to fill in fill mode, (0, 100], default value is 90",
+				},
+				&spec.ExpFlag{
+					Name: "interval",
+					Desc: "The interval of flushing in flush mode, unit is second. The default value is 0, it means flushing only once",
+				},
+			},
+			ActionExecutor: &ConntrackActionExecutor{},
+			ActionExample: `
+# Fill 95% of the conntrack table by udp flows to 10.0.0.0/24
+blade create network conntrack --mode fill --destination-ip 10.0.0.0/24 --percent 95
+
+# Fill the conntrack table by half-open tcp flows to the port 80 and 443 of 10.0.0.2
+blade create network conntrack --mode fill --protocol tcp --destination-ip 10.0.0.2 --remote-port 80,443
+
+# Delete the conntrack entries of the local port 8080 once
+blade create network conntrack --mode flush --local-port 8080
+
+# Delete the tcp entries to 10.0.0.2 every 10 seconds
+blade create network conntrack --mode flush --protocol tcp --destination-ip 10.0.0.2 --interval 10`,
+			ActionPrograms:   []string{ConntrackBin},
+			ActionCategories: []string{category.SystemNetwork},
+		},
+	}
+}
+
+func (*ConntrackActionSpec) Name() string {
+	return "conntrack"
+}
+
+func (*ConntrackActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*ConntrackActionSpec) ShortDesc() string {
+	return "Fill or flush the conntrack table"
+}
+
+func (c *ConntrackActionSpec) LongDesc() string {
+	if c.ActionLongDesc != "" {
+		return c.ActionLongDesc
+	}
+	return "Fill the conntrack table by udp or half-open tcp flows, or flush the conntrack entries matched. " +
+		"The rst packets replied by the local kernel to the half-open tcp flows are dropped by an iptables rule until the experiment is destroyed, " +
+		"the flows created in fill mode are released by the conntrack timeouts after the experiment is destroyed, " +
+		"and the flush mode lists and deletes the entries over ctnetlink without the conntrack command"
+}
+
+type ConntrackActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*ConntrackActionExecutor) Name() string {
+	return "conntrack"
+}
+
+func (cae *ConntrackActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	if cae.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	mode := model.ActionFlags["mode"]
+	if mode != ConntrackFillMode && mode != ConntrackFlushMode {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: mode is illegal, it must be fill or flush", mode))
+		return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "mode"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "mode"))
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return cae.stop(ctx, mode)
+	}
+	args := fmt.Sprintf("--start --mode %s --debug=%t", mode, util.Debug)
+	for _, name := range []string{"local-port", "remote-port", "exclude-port"} {
+		value := model.ActionFlags[name]
+		if value == "" {
+			continue
+		}
+		// validate the ports here, the ranges are expanded by the conntrack program
+		if _, err := util.ParseIntegerListToStringSlice(name, value); err != nil {
+			util.Errorf(uid, util.GetRunFuncName(), err.Error())
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, name),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, name))
+		}
+		args = fmt.Sprintf("%s --%s %s", args, name, value)
+	}
+	destinationIp := model.ActionFlags["destination-ip"]
+	if mode == ConntrackFillMode && destinationIp == "" {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "destination-ip"))
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "destination-ip"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "destination-ip"))
+	}
+	if destinationIp != "" {
+		args = fmt.Sprintf("%s --destination-ip %s", args, destinationIp)
+	}
+	if excludeIp := model.ActionFlags["exclude-ip"]; excludeIp != "" {
+		args = fmt.Sprintf("%s --exclude-ip %s", args, excludeIp)
+	}
+	protocol := model.ActionFlags["protocol"]
+	if protocol != "" {
+		if protocol != "tcp" && protocol != "udp" {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: protocol is illegal, it must be tcp or udp", protocol))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "protocol"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "protocol"))
+		}
+		args = fmt.Sprintf("%s --protocol %s", args, protocol)
+	}
+	if percent := model.ActionFlags["percent"]; percent != "" {
+		value, err := strconv.Atoi(percent)
+		if err != nil || value <= 0 || value > 100 {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: percent is illegal, it must be in (0, 100]", percent))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "percent"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "percent"))
+		}
+		args = fmt.Sprintf("%s --percent %d", args, value)
+	}
+	if interval := model.ActionFlags["interval"]; interval != "" {
+		value, err := strconv.Atoi(interval)
+		if err != nil || value < 0 {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: interval is illegal, it must be a non-negative integer", interval))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "interval"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "interval"))
+		}
+		args = fmt.Sprintf("%s --interval %d", args, value)
+	}
+	return cae.channel.Run(ctx, path.Join(cae.channel.GetScriptPath(), ConntrackBin), args)
+}
+
+func (cae *ConntrackActionExecutor) stop(ctx context.Context, mode string) *spec.Response {
+	return cae.channel.Run(ctx, path.Join(cae.channel.GetScriptPath(), ConntrackBin),
+		fmt.Sprintf("--stop --mode %s --debug=%t", mode, util.Debug))
+}
+
+func (cae *ConntrackActionExecutor) SetChannel(channel spec.Channel) {
+	cae.channel = channel
+}
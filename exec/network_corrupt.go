@@ -109,17 +109,18 @@ func (ce *NetworkCorruptExecutor) Exec(uid string, ctx context.Context, model *s
 		remotePort := model.ActionFlags["remote-port"]
 		excludePort := model.ActionFlags["exclude-port"]
 		destIp := model.ActionFlags["destination-ip"]
+		destDomain := model.ActionFlags["destination-domain"]
 		excludeIp := model.ActionFlags["exclude-ip"]
 		ignorePeerPort := model.ActionFlags["ignore-peer-port"] == "true"
 		force := model.ActionFlags["force"] == "true"
-		return ce.start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, percent, ignorePeerPort, force, ctx)
+		return ce.start(netInterface, localPort, remotePort, excludePort, destIp, destDomain, excludeIp, percent, ignorePeerPort, force, ctx)
 	}
 }
 
-func (ce *NetworkCorruptExecutor) start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, percent string,
+func (ce *NetworkCorruptExecutor) start(netInterface, localPort, remotePort, excludePort, destIp, destDomain, excludeIp, percent string,
 	ignorePeerPort, force bool, ctx context.Context) *spec.Response {
 	args := fmt.Sprintf("--start --type corrupt --interface %s --percent %s --debug=%t", netInterface, percent, util.Debug)
-	args, err := getCommArgs(localPort, remotePort, excludePort, destIp, excludeIp, args, ignorePeerPort, force)
+	args, err := getCommArgs(localPort, remotePort, excludePort, destIp, destDomain, excludeIp, args, ignorePeerPort, force)
 	if err != nil {
 		return spec.ResponseFailWaitResult(spec.ParameterIllegal, err.Error(), err.Error())
 	}
@@ -56,7 +56,10 @@ blade create network delay --time 3000 --offset 1000 --interface eth0 --local-po
 blade create network delay --time 3000 --interface eth0 --remote-port 80 --destination-ip 14.215.177.39
 
 # Do a 5 second delay for the entire network card eth0, excluding ports 22 and 8000 to 8080
-blade create network delay --time 5000 --interface eth0 --exclude-port 22,8000-8080`,
+blade create network delay --time 5000 --interface eth0 --exclude-port 22,8000-8080
+
+# Delay the traffic to the port 443 of www.example.com by 3 seconds, the ips of the domain are followed when they change
+blade create network delay --time 3000 --interface eth0 --remote-port 443 --destination-domain www.example.com`,
 			ActionPrograms:   []string{TcNetworkBin},
 			ActionCategories: []string{category.SystemNetwork},
 		},
@@ -123,17 +126,18 @@ func (de *NetworkDelayExecutor) Exec(uid string, ctx context.Context, model *spe
 		remotePort := model.ActionFlags["remote-port"]
 		excludePort := model.ActionFlags["exclude-port"]
 		destIp := model.ActionFlags["destination-ip"]
+		destDomain := model.ActionFlags["destination-domain"]
 		excludeIp := model.ActionFlags["exclude-ip"]
 		ignorePeerPort := model.ActionFlags["ignore-peer-port"] == "true"
 		force := model.ActionFlags["force"] == "true"
-		return de.start(localPort, remotePort, excludePort, destIp, excludeIp, time, offset, netInterface, ignorePeerPort, force, ctx)
+		return de.start(localPort, remotePort, excludePort, destIp, destDomain, excludeIp, time, offset, netInterface, ignorePeerPort, force, ctx)
 	}
 }
 
-func (de *NetworkDelayExecutor) start(localPort, remotePort, excludePort, destIp, excludeIp, time, offset, netInterface string,
+func (de *NetworkDelayExecutor) start(localPort, remotePort, excludePort, destIp, destDomain, excludeIp, time, offset, netInterface string,
 	ignorePeerPort, force bool, ctx context.Context) *spec.Response {
 	args := fmt.Sprintf("--start --type delay --interface %s --time %s --offset %s --debug=%t", netInterface, time, offset, util.Debug)
-	args, err := getCommArgs(localPort, remotePort, excludePort, destIp, excludeIp, args, ignorePeerPort, force)
+	args, err := getCommArgs(localPort, remotePort, excludePort, destIp, destDomain, excludeIp, args, ignorePeerPort, force)
 	if err != nil {
 		return spec.ResponseFailWaitResult(spec.ParameterIllegal, err.Error(), err.Error())
 	}
@@ -46,6 +46,10 @@ func NewDropActionSpec() spec.ExpActionCommandSpec {
 					Name: "destination-ip",
 					Desc: "The destination ip address of packet",
 				},
+				&spec.ExpFlag{
+					Name: "destination-domain",
+					Desc: "The destination domain of packet, the domain is re-resolved periodically to follow the ip changes. It is the remote side of both incoming and outgoing packets",
+				},
 				&spec.ExpFlag{
 					Name: "source-port",
 					Desc: "The source port of packet",
@@ -86,6 +90,9 @@ blade create network drop --string-pattern baidu.com --network-traffic out
 
 # Block outgoing connection to the specific domain on port 80
 blade create network drop --destination-port 80 --string-pattern baidu.com --network-traffic out
+
+# Block outgoing connection to the ips of the domain, the rules follow the changes of the ips
+blade create network drop --destination-domain www.example.com --network-traffic out
 `,
 			ActionPrograms:   []string{DropNetworkBin},
 			ActionCategories: []string{category.SystemNetwork},
@@ -131,18 +138,19 @@ func (ne *NetworkDropExecutor) Exec(suid string, ctx context.Context, model *spe
 	}
 	sourceIp := model.ActionFlags["source-ip"]
 	destinationIp := model.ActionFlags["destination-ip"]
+	destinationDomain := model.ActionFlags["destination-domain"]
 	sourcePort := model.ActionFlags["source-port"]
 	destinationPort := model.ActionFlags["destination-port"]
 	stringPattern := model.ActionFlags["string-pattern"]
 	networkTraffic := model.ActionFlags["network-traffic"]
 	if _, ok := spec.IsDestroy(ctx); ok {
-		return ne.stop(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic, ctx)
+		return ne.stop(sourceIp, destinationIp, destinationDomain, sourcePort, destinationPort, stringPattern, networkTraffic, ctx)
 	}
 
-	return ne.start(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic, ctx)
+	return ne.start(sourceIp, destinationIp, destinationDomain, sourcePort, destinationPort, stringPattern, networkTraffic, ctx)
 }
 
-func (ne *NetworkDropExecutor) start(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic string, ctx context.Context) *spec.Response {
+func (ne *NetworkDropExecutor) start(sourceIp, destinationIp, destinationDomain, sourcePort, destinationPort, stringPattern, networkTraffic string, ctx context.Context) *spec.Response {
 	args := fmt.Sprintf("--start --debug=%t", util.Debug)
 	if sourceIp != "" {
 		args = fmt.Sprintf("%s --source-ip %s", args, sourceIp)
@@ -150,6 +158,9 @@ func (ne *NetworkDropExecutor) start(sourceIp, destinationIp, sourcePort, destin
 	if destinationIp != "" {
 		args = fmt.Sprintf("%s --destination-ip %s", args, destinationIp)
 	}
+	if destinationDomain != "" {
+		args = fmt.Sprintf("%s --destination-domain %s", args, destinationDomain)
+	}
 	if sourcePort != "" {
 		args = fmt.Sprintf("%s --source-port %s", args, sourcePort)
 	}
@@ -165,7 +176,7 @@ func (ne *NetworkDropExecutor) start(sourceIp, destinationIp, sourcePort, destin
 	return ne.channel.Run(ctx, path.Join(ne.channel.GetScriptPath(), DropNetworkBin), args)
 }
 
-func (ne *NetworkDropExecutor) stop(sourceIp, destinationIp, sourcePort, destinationPort, stringPattern, networkTraffic string, ctx context.Context) *spec.Response {
+func (ne *NetworkDropExecutor) stop(sourceIp, destinationIp, destinationDomain, sourcePort, destinationPort, stringPattern, networkTraffic string, ctx context.Context) *spec.Response {
 	args := fmt.Sprintf("--stop --debug=%t", util.Debug)
 	if sourceIp != "" {
 		args = fmt.Sprintf("%s --source-ip %s", args, sourceIp)
@@ -173,6 +184,9 @@ func (ne *NetworkDropExecutor) stop(sourceIp, destinationIp, sourcePort, destina
 	if destinationIp != "" {
 		args = fmt.Sprintf("%s --destination-ip %s", args, destinationIp)
 	}
+	if destinationDomain != "" {
+		args = fmt.Sprintf("%s --destination-domain %s", args, destinationDomain)
+	}
 	if sourcePort != "" {
 		args = fmt.Sprintf("%s --source-port %s", args, sourcePort)
 	}
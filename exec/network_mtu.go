@@ -0,0 +1,169 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/chaosblade-io/chaosblade-spec-go/channel"
+	"github.com/chaosblade-io/chaosblade-spec-go/spec"
+	"github.com/chaosblade-io/chaosblade-spec-go/util"
+
+	"github.com/chaosblade-io/chaosblade-exec-os/exec/category"
+)
+
+// ChangeMtuBin for network mtu experiment
+const ChangeMtuBin = "chaos_changemtu"
+
+// Offloads are the offload features which can be disabled by network mtu experiment
+var Offloads = []string{"gso", "tso", "gro"}
+
+type MtuActionSpec struct {
+	spec.BaseExpActionCommandSpec
+}
+
+func NewMtuActionSpec() spec.ExpActionCommandSpec {
+	return &MtuActionSpec{
+		spec.BaseExpActionCommandSpec{
+			ActionMatchers: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name:                  "interface",
+					Desc:                  "Network interface, for example, eth0",
+					Required:              true,
+					RequiredWhenDestroyed: true,
+				},
+			},
+			ActionFlags: []spec.ExpFlagSpec{
+				&spec.ExpFlag{
+					Name: "mtu",
+					Desc: "The mtu of the interface, [68, 65535]",
+				},
+				&spec.ExpFlag{
+					Name: "disable-offload",
+					Desc: "The offload features to disable, support gso, tso and gro, separated by commas, for example gso,tso",
+				},
+			},
+			ActionExecutor: &MtuActionExecutor{},
+			ActionExample: `
+# Change the mtu of eth0 to 1300
+blade create network mtu --interface eth0 --mtu 1300
+
+# Change the mtu of eth0 to 1300 and disable the segmentation offloads, so that the packets on the wire respect the mtu
+blade create network mtu --interface eth0 --mtu 1300 --disable-offload gso,tso,gro`,
+			ActionPrograms:   []string{ChangeMtuBin},
+			ActionCategories: []string{category.SystemNetwork},
+		},
+	}
+}
+
+func (*MtuActionSpec) Name() string {
+	return "mtu"
+}
+
+func (*MtuActionSpec) Aliases() []string {
+	return []string{}
+}
+
+func (*MtuActionSpec) ShortDesc() string {
+	return "Change the mtu and offloads of the interface"
+}
+
+func (m *MtuActionSpec) LongDesc() string {
+	if m.ActionLongDesc != "" {
+		return m.ActionLongDesc
+	}
+	return "Change the mtu of the interface and disable the offload features, the original values are restored when the experiment is destroyed. " +
+		"It reproduces the path mtu blackhole combined with dropping the icmp packets"
+}
+
+type MtuActionExecutor struct {
+	channel spec.Channel
+}
+
+func (*MtuActionExecutor) Name() string {
+	return "mtu"
+}
+
+func (mae *MtuActionExecutor) Exec(uid string, ctx context.Context, model *spec.ExpModel) *spec.Response {
+	commands := []string{"ip"}
+	if response, ok := channel.NewLocalChannel().IsAllCommandsAvailable(commands); !ok {
+		return response
+	}
+	if mae.channel == nil {
+		util.Errorf(uid, util.GetRunFuncName(), spec.ResponseErr[spec.ChannelNil].ErrInfo)
+		return spec.ResponseFail(spec.ChannelNil, spec.ResponseErr[spec.ChannelNil].ErrInfo)
+	}
+	netInterface := model.ActionFlags["interface"]
+	if netInterface == "" {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "interface"))
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "interface"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "interface"))
+	}
+	if _, ok := spec.IsDestroy(ctx); ok {
+		return mae.stop(ctx, netInterface)
+	}
+	args := fmt.Sprintf("--start --interface %s --debug=%t", netInterface, util.Debug)
+	mtu := model.ActionFlags["mtu"]
+	if mtu != "" {
+		value, err := strconv.Atoi(mtu)
+		if err != nil || value < 68 || value > 65535 {
+			util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: mtu is illegal, it must be in [68, 65535]", mtu))
+			return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "mtu"),
+				fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "mtu"))
+		}
+		args = fmt.Sprintf("%s --mtu %d", args, value)
+	}
+	offload := model.ActionFlags["disable-offload"]
+	if offload != "" {
+		for _, feature := range strings.Split(offload, ",") {
+			if !isOffload(strings.TrimSpace(feature)) {
+				util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf("`%s`: disable-offload is illegal, only support %v", feature, Offloads))
+				return spec.ResponseFailWaitResult(spec.ParameterIllegal, fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].Err, "disable-offload"),
+					fmt.Sprintf(spec.ResponseErr[spec.ParameterIllegal].ErrInfo, "disable-offload"))
+			}
+		}
+		args = fmt.Sprintf("%s --disable-offload %s", args, strings.Replace(offload, " ", "", -1))
+	}
+	if mtu == "" && offload == "" {
+		util.Errorf(uid, util.GetRunFuncName(), fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "mtu|disable-offload"))
+		return spec.ResponseFailWaitResult(spec.ParameterLess, fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].Err, "mtu|disable-offload"),
+			fmt.Sprintf(spec.ResponseErr[spec.ParameterLess].ErrInfo, "mtu|disable-offload"))
+	}
+	return mae.channel.Run(ctx, path.Join(mae.channel.GetScriptPath(), ChangeMtuBin), args)
+}
+
+func isOffload(feature string) bool {
+	for _, offload := range Offloads {
+		if feature == offload {
+			return true
+		}
+	}
+	return false
+}
+
+func (mae *MtuActionExecutor) stop(ctx context.Context, netInterface string) *spec.Response {
+	return mae.channel.Run(ctx, path.Join(mae.channel.GetScriptPath(), ChangeMtuBin),
+		fmt.Sprintf("--stop --interface %s --debug=%t", netInterface, util.Debug))
+}
+
+func (mae *MtuActionExecutor) SetChannel(channel spec.Channel) {
+	mae.channel = channel
+}
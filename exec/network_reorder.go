@@ -131,22 +131,23 @@ func (ce *NetworkReorderExecutor) Exec(uid string, ctx context.Context, model *s
 		remotePort := model.ActionFlags["remote-port"]
 		excludePort := model.ActionFlags["exclude-port"]
 		destIp := model.ActionFlags["destination-ip"]
+		destDomain := model.ActionFlags["destination-domain"]
 		excludeIp := model.ActionFlags["exclude-ip"]
 		ignorePeerPort := model.ActionFlags["ignore-peer-port"] == "true"
 		force := model.ActionFlags["force"] == "true"
-		return ce.start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, percent,
+		return ce.start(netInterface, localPort, remotePort, excludePort, destIp, destDomain, excludeIp, percent,
 			ignorePeerPort, gap, time, correlation, force, ctx)
 	}
 }
 
-func (ce *NetworkReorderExecutor) start(netInterface, localPort, remotePort, excludePort, destIp, excludeIp, percent string,
+func (ce *NetworkReorderExecutor) start(netInterface, localPort, remotePort, excludePort, destIp, destDomain, excludeIp, percent string,
 	ignorePeerPort bool, gap, time, correlation string, force bool, ctx context.Context) *spec.Response {
 	args := fmt.Sprintf("--start --type reorder --interface %s --percent %s --correlation %s --time %s --debug=%t",
 		netInterface, percent, correlation, time, util.Debug)
 	if gap != "" {
 		args = fmt.Sprintf("%s --gap %s", args, gap)
 	}
-	args, err := getCommArgs(localPort, remotePort, excludePort, destIp, excludeIp, args, ignorePeerPort, force)
+	args, err := getCommArgs(localPort, remotePort, excludePort, destIp, destDomain, excludeIp, args, ignorePeerPort, force)
 	if err != nil {
 		return spec.ResponseFailWaitResult(spec.ParameterIllegal, err.Error(), err.Error())
 	}
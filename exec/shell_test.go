@@ -0,0 +1,34 @@
+/*
+ * Copyright 1999-2020 Alibaba Group Holding Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exec
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	for _, value := range []string{"", "/api/v1/.*", `{"msg": "it's ok"}`, "a'; echo injected; echo '", "$(id) `id` \\n"} {
+		output, err := exec.Command("sh", "-c", "printf %s "+ShellQuote(value)).Output()
+		if err != nil {
+			t.Fatalf("run the quoted value %s err, %v", value, err)
+		}
+		if string(output) != value {
+			t.Errorf("unexpected output: %s, expected: %s", string(output), value)
+		}
+	}
+}